@@ -0,0 +1,62 @@
+package common
+
+import (
+	"image"
+	"image/draw"
+)
+
+// CropSquare returns the largest square at the center of the image
+func CropSquare(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	size := bounds.Dx()
+	if bounds.Dy() < size {
+		size = bounds.Dy()
+	}
+	min := image.Pt(bounds.Min.X+(bounds.Dx()-size)/2, bounds.Min.Y+(bounds.Dy()-size)/2)
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(dst, dst.Bounds(), src, min, draw.Src)
+	return dst
+}
+
+// ResizeImage returns the image resized to width x height by averaging pixels (box filter)
+func ResizeImage(src *image.RGBA, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+	if srcW == 0 || srcH == 0 {
+		return dst
+	}
+	for y := 0; y < height; y++ {
+		y0, y1 := scaleRange(y, height, srcH)
+		for x := 0; x < width; x++ {
+			x0, x1 := scaleRange(x, width, srcW)
+			var r, g, b, a, count int
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(src.Bounds().Min.X+x0, src.Bounds().Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(src.Pix[offset])
+					g += int(src.Pix[offset+1])
+					b += int(src.Pix[offset+2])
+					a += int(src.Pix[offset+3])
+					offset += 4
+					count++
+				}
+			}
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / count)
+			dst.Pix[offset+1] = uint8(g / count)
+			dst.Pix[offset+2] = uint8(b / count)
+			dst.Pix[offset+3] = uint8(a / count)
+		}
+	}
+	return dst
+}
+
+// scaleRange returns source range [from, to) of destination position, the range has at least 1 pixel
+func scaleRange(pos, dstSize, srcSize int) (from, to int) {
+	from = pos * srcSize / dstSize
+	to = (pos + 1) * srcSize / dstSize
+	if to <= from {
+		to = from + 1
+	}
+	return
+}
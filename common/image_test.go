@@ -0,0 +1,67 @@
+package common
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newStripedImage returns an image whose columns (or rows if vertical) are filled with specified gray levels
+func newStripedImage(width, height int, vertical bool, levels ...uint8) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := x
+			if vertical {
+				i = y
+			}
+			img.Set(x, y, color.Gray{Y: levels[i]})
+		}
+	}
+	return img
+}
+
+func TestCropSquare(t *testing.T) {
+	// Wide image is cropped at the center column
+	wide := CropSquare(newStripedImage(4, 2, false, 10, 20, 30, 40))
+	assert.Equal(t, image.Rect(0, 0, 2, 2), wide.Bounds())
+	assert.Equal(t, color.RGBA{20, 20, 20, 255}, wide.RGBAAt(0, 1))
+	assert.Equal(t, color.RGBA{30, 30, 30, 255}, wide.RGBAAt(1, 1))
+
+	// Tall image is cropped at the center row
+	tall := CropSquare(newStripedImage(1, 3, true, 10, 20, 30))
+	assert.Equal(t, image.Rect(0, 0, 1, 1), tall.Bounds())
+	assert.Equal(t, color.RGBA{20, 20, 20, 255}, tall.RGBAAt(0, 0))
+
+	// Image whose bounds do not start at origin
+	sub := newStripedImage(4, 2, false, 10, 20, 30, 40).SubImage(image.Rect(1, 0, 4, 2))
+	cropped := CropSquare(sub)
+	assert.Equal(t, image.Rect(0, 0, 2, 2), cropped.Bounds())
+	assert.Equal(t, color.RGBA{20, 20, 20, 255}, cropped.RGBAAt(0, 0))
+}
+
+func TestResizeImage(t *testing.T) {
+	src := newStripedImage(4, 4, false, 10, 20, 30, 40)
+
+	// Shrinking averages pixels
+	small := ResizeImage(src, 2, 2)
+	assert.Equal(t, image.Rect(0, 0, 2, 2), small.Bounds())
+	assert.Equal(t, color.RGBA{15, 15, 15, 255}, small.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{35, 35, 35, 255}, small.RGBAAt(1, 1))
+
+	// Enlarging repeats pixels
+	large := ResizeImage(src, 8, 8)
+	assert.Equal(t, image.Rect(0, 0, 8, 8), large.Bounds())
+	assert.Equal(t, color.RGBA{10, 10, 10, 255}, large.RGBAAt(1, 7))
+	assert.Equal(t, color.RGBA{40, 40, 40, 255}, large.RGBAAt(6, 0))
+
+	// Resizing a sub image reads pixels inside its bounds
+	sub := src.SubImage(image.Rect(2, 0, 4, 2)).(*image.RGBA)
+	assert.Equal(t, color.RGBA{35, 35, 35, 255}, ResizeImage(sub, 1, 1).RGBAAt(0, 0))
+
+	// Empty image results transparent
+	empty := ResizeImage(image.NewRGBA(image.Rect(0, 0, 0, 0)), 2, 2)
+	assert.Equal(t, color.RGBA{}, empty.RGBAAt(1, 1))
+}
@@ -0,0 +1,105 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// rankDigits are digits of rank in ascending order.
+// Only digits and lower case letters are used, so that ranks are sorted in same order by any collation of databases.
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+const rankBase = len(rankDigits)
+
+// IsValidRank checks whether rank consists of rank digits and does not end with the smallest digit.
+// Rank ending with the smallest digit has no room before it. ex) nothing is between "a" and "a0"
+func IsValidRank(rank string) bool {
+	if rank == "" || rank[len(rank)-1] == rankDigits[0] {
+		return false
+	}
+	for i := 0; i < len(rank); i++ {
+		if strings.IndexByte(rankDigits, rank[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// RankBetween returns a rank which is sorted between prev and next lexicographically.
+// prev is empty if there is no previous item, and next is empty if there is no next item.
+func RankBetween(prev, next string) (string, error) {
+	if (prev != "" && !IsValidRank(prev)) || (next != "" && !IsValidRank(next)) {
+		return "", fmt.Errorf("Invalid rank. prev:%s next:%s", prev, next)
+	}
+	if next != "" && prev >= next {
+		return "", fmt.Errorf("prev must be less than next. prev:%s next:%s", prev, next)
+	}
+	return midRank(prev, next), nil
+}
+
+// midRank returns the middle of prev and next, regarding them as fractions. next is infinity if empty.
+func midRank(prev, next string) string {
+	if next != "" {
+		// Skip common prefix, prev is padded with the smallest digit
+		n := 0
+		for n < len(next) && rankDigit(prev, n) == rankDigit(next, n) {
+			n++
+		}
+		if n > 0 {
+			return next[:n] + midRank(suffix(prev, n), next[n:])
+		}
+	}
+	prevDigit := rankDigit(prev, 0)
+	nextDigit := rankBase
+	if next != "" {
+		nextDigit = rankDigit(next, 0)
+	}
+	if nextDigit-prevDigit > 1 {
+		return string(rankDigits[(prevDigit+nextDigit)/2])
+	}
+	// Digits are consecutive
+	if len(next) > 1 {
+		return next[:1]
+	}
+	return string(rankDigits[prevDigit]) + midRank(suffix(prev, 1), "")
+}
+
+func rankDigit(rank string, i int) int {
+	if i >= len(rank) {
+		return 0
+	}
+	return strings.IndexByte(rankDigits, rank[i])
+}
+
+func suffix(rank string, i int) string {
+	if i >= len(rank) {
+		return ""
+	}
+	return rank[i:]
+}
+
+// EvenRanks returns count ranks of same length in ascending order, which are spaced evenly.
+// It is used to rebalance ranks which become long.
+func EvenRanks(count int) []string {
+	length := 1
+	space := int64(rankBase)
+	// Keep space of 2 or more between ranks, so that changing the last smallest digit never makes duplicates
+	for space < int64(count+1)*2 {
+		length++
+		space *= int64(rankBase)
+	}
+	result := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		value := space * int64(i+1) / int64(count+1)
+		digits := make([]byte, length)
+		for j := length - 1; j >= 0; j-- {
+			digits[j] = rankDigits[value%int64(rankBase)]
+			value /= int64(rankBase)
+		}
+		if digits[length-1] == rankDigits[0] {
+			digits[length-1] = rankDigits[1]
+		}
+		result = append(result, string(digits))
+	}
+	return result
+}
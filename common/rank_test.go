@@ -0,0 +1,77 @@
+package common
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRankBetween(t *testing.T) {
+	cases := []struct {
+		prev, next, expected string
+	}{
+		{"", "", "i"},
+		{"i", "", "r"},
+		{"", "i", "9"},
+		{"", "1", "0i"},
+		{"a5", "a6", "a5i"},
+		{"a", "a1", "a0i"},
+		{"az", "b", "azi"},
+		{"z", "", "zi"},
+	}
+	for _, c := range cases {
+		rank, err := RankBetween(c.prev, c.next)
+		if assert.NoError(t, err) {
+			assert.Equal(t, c.expected, rank, "prev:%s next:%s", c.prev, c.next)
+		}
+	}
+
+	for _, c := range [][2]string{{"b", "a"}, {"a", "a"}, {"a0", ""}, {"", "A"}} {
+		_, err := RankBetween(c[0], c[1])
+		assert.Error(t, err, "prev:%s next:%s", c[0], c[1])
+	}
+}
+
+func TestRankBetween_Random(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	ranks := []string{}
+	for i := 0; i < 1000; i++ {
+		// Insert at random position
+		position := random.Intn(len(ranks) + 1)
+		prev, next := "", ""
+		if position > 0 {
+			prev = ranks[position-1]
+		}
+		if position < len(ranks) {
+			next = ranks[position]
+		}
+		rank, err := RankBetween(prev, next)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if !assert.True(t, IsValidRank(rank), rank) {
+			return
+		}
+		ranks = append(ranks[:position], append([]string{rank}, ranks[position:]...)...)
+	}
+	assert.True(t, sort.StringsAreSorted(ranks))
+	for i := 1; i < len(ranks); i++ {
+		assert.NotEqual(t, ranks[i-1], ranks[i])
+	}
+}
+
+func TestEvenRanks(t *testing.T) {
+	for _, count := range []int{0, 1, 17, 36, 1000} {
+		ranks := EvenRanks(count)
+		assert.Len(t, ranks, count)
+		assert.True(t, sort.StringsAreSorted(ranks))
+		for i, rank := range ranks {
+			assert.True(t, IsValidRank(rank), rank)
+			if i > 0 {
+				assert.NotEqual(t, ranks[i-1], rank)
+			}
+		}
+	}
+}
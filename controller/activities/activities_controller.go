@@ -0,0 +1,72 @@
+package activities
+
+import (
+	"net/http"
+	"taskboard-api-go/controller/api"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type endPoint struct {
+	activity    string
+	taskHistory string
+	taskid      string
+}
+
+// EndPoint presents activities endpoint
+var EndPoint = endPoint{
+	activity:    "/activity",
+	taskHistory: "/tasks/:taskid/history",
+	taskid:      "taskid",
+}
+
+// RegisterRoute registers API endpoints for activities
+func (p *endPoint) RegisterRoute(route *gin.RouterGroup) (err error) {
+	route.GET(p.activity, list)
+	route.GET(p.taskHistory, listTaskHistory)
+	return
+}
+
+// find activities of all entities, the newest comes first
+func list(c *gin.Context) {
+	req, serr := getListActivityRequest(c)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	tx := orm.GetDB() // No transction
+	srvc := service.NewActivityService(tx, api.GetActor(c))
+	condition := &model.Activity{
+		EntityKind:  req.EntityKind,
+		EntityID:    req.EntityID,
+		ActorUserID: req.ActorUserID,
+	}
+	activities, serr := srvc.FindActivities(condition, req.Offset, req.Limit)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	res := convertListActivityResponse(activities)
+	c.IndentedJSON(http.StatusOK, res)
+}
+
+// find history of a task. The history of deleted task can be also found.
+func listTaskHistory(c *gin.Context) {
+	taskID, serr := api.GetPathParameter(c, EndPoint.taskid)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	tx := orm.GetDB() // No transction
+	srvc := service.NewActivityService(tx, api.GetActor(c))
+	activities, serr := srvc.FindEntityHistory(model.EntityKindTask, taskID)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	res := convertListActivityResponse(activities)
+	c.IndentedJSON(http.StatusOK, res)
+}
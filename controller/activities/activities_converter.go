@@ -0,0 +1,94 @@
+package activities
+
+import (
+	"strconv"
+	"taskboard-api-go/model"
+	"taskboard-api-go/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ID            string    `gorm:"primary_key;size:32"`
+// EntityKind    string    `gorm:"not null;size:32;index:idx_activity_entity"`
+// EntityID      string    `gorm:"not null;size:32;index:idx_activity_entity"`
+// EntityVersion int       `gorm:"not null"` // Version of the entity after the change
+// Action        string    `gorm:"not null;size:32"`
+// Field         string    `gorm:"size:64"`   // Empty when action is create or delete
+// OldValue      string    `gorm:"size:8000"` // Empty when action is create
+// NewValue      string    `gorm:"size:8000"` // Empty when action is delete
+// ActorUserID   string    `gorm:"not null;size:32"`
+// CreatedDate   time.Time `gorm:"not null;index"`
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+type activityResponse struct {
+	ID            string `json:"id"`
+	EntityKind    string `json:"entityKind"`
+	EntityID      string `json:"entityId"`
+	EntityVersion int    `json:"entityVersion"`
+	Action        string `json:"action"`
+	Field         string `json:"field"`
+	OldValue      string `json:"oldValue"`
+	NewValue      string `json:"newValue"`
+	ActorUserID   string `json:"actorUserId"`
+	CreatedDate   string `json:"createDate"`
+}
+
+type listActivityRequest struct {
+	EntityKind  string
+	EntityID    string
+	ActorUserID string
+	Offset      int
+	Limit       int
+}
+
+func convertActivityResponse(activity *model.Activity) *activityResponse {
+	return &activityResponse{
+		ID:            activity.ID,
+		EntityKind:    activity.EntityKind,
+		EntityID:      activity.EntityID,
+		EntityVersion: activity.EntityVersion,
+		Action:        activity.Action,
+		Field:         activity.Field,
+		OldValue:      activity.OldValue,
+		NewValue:      activity.NewValue,
+		ActorUserID:   activity.ActorUserID,
+		CreatedDate:   activity.CreatedDate.Format(time.RFC3339),
+	}
+}
+
+func convertListActivityResponse(activities []model.Activity) (res []*activityResponse) {
+	res = make([]*activityResponse, 0, len(activities))
+	for _, activity := range activities {
+		res = append(res, convertActivityResponse(&activity))
+	}
+	return
+}
+
+func getListActivityRequest(c *gin.Context) (*listActivityRequest, error) {
+	req := &listActivityRequest{
+		EntityKind:  c.Query("entityKind"),
+		EntityID:    c.Query("entityId"),
+		ActorUserID: c.Query("actorUserId"),
+		Offset:      0,
+		Limit:       defaultLimit,
+	}
+	var err error
+	if offset := c.Query("offset"); offset != "" {
+		req.Offset, err = strconv.Atoi(offset)
+		if err != nil || req.Offset < 0 {
+			return nil, service.NewSvcErrorf(service.ErrorCodeBadRequest, err, "Invalid offset:%s", offset)
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		req.Limit, err = strconv.Atoi(limit)
+		if err != nil || req.Limit <= 0 || req.Limit > maxLimit {
+			return nil, service.NewSvcErrorf(service.ErrorCodeBadRequest, err, "Invalid limit:%s", limit)
+		}
+	}
+	return req, nil
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"strings"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	authorizationHeader = "Authorization"
+	bearerPrefix        = "Bearer "
+	// Browsers can not set headers to websocket or event stream requests, so token is also accepted as query
+	tokenQueryKey = "token"
+	// ContextUserKey is a key of authenticated user in gin context
+	ContextUserKey = "taskboard-user"
+)
+
+// Authenticate returns middleware which rejects requests without valid access token
+func Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := getAccessToken(c)
+		if token == "" {
+			SetErrorStatus(c, service.NewSvcError(service.ErrorCodeUnauthenticated, nil, "Access token is not specified"))
+			c.Abort()
+			return
+		}
+		srvc := service.NewUserService(orm.GetDB(), nil) // No transaction
+		user, serr := srvc.Authenticate(token, time.Now().UTC())
+		if serr != nil {
+			SetErrorStatus(c, serr)
+			c.Abort()
+			return
+		}
+		c.Set(ContextUserKey, user)
+		c.Next()
+	}
+}
+
+func getAccessToken(c *gin.Context) string {
+	header := c.GetHeader(authorizationHeader)
+	if strings.HasPrefix(header, bearerPrefix) {
+		return strings.TrimSpace(strings.TrimPrefix(header, bearerPrefix))
+	}
+	return c.Query(tokenQueryKey)
+}
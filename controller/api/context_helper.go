@@ -1,6 +1,11 @@
 package api
 
 import (
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"taskboard-api-go/controller/websocket"
+	"taskboard-api-go/model"
 	"taskboard-api-go/service"
 
 	"github.com/gin-gonic/gin"
@@ -14,3 +19,51 @@ func GetPathParameter(c *gin.Context, key string) (string, error) {
 	}
 	return value, nil
 }
+
+// multipartOverhead is size allowed for boundaries, headers and other fields of multipart form in addition to a file
+const multipartOverhead = 64 * 1024
+
+// GetFormFile gets file of multipart form, whose size must not exceed maxSize.
+// Request body is limited before it is parsed, so that too large body is never read entirely nor spooled to disk.
+func GetFormFile(c *gin.Context, key string, maxSize int64) (*multipart.FileHeader, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+	header, err := c.FormFile(key)
+	if err != nil {
+		// MaxBytesReader returns the error only by its message
+		if strings.Contains(err.Error(), "request body too large") {
+			return nil, service.NewSvcErrorf(service.ErrorCodeInvalidArguments, err, "File is too large. Max size is %d bytes", maxSize)
+		}
+		return nil, service.NewBadRequestError(err)
+	}
+	if header.Size > maxSize {
+		return nil, service.NewSvcErrorf(service.ErrorCodeInvalidArguments, nil, "File is too large. Max size is %d bytes", maxSize)
+	}
+	return header, nil
+}
+
+// GetActor gets the authenticated user which is set by Authenticate middleware. If not authenticated, returns nil.
+func GetActor(c *gin.Context) *model.User {
+	value, exists := c.Get(ContextUserKey)
+	if !exists {
+		return nil
+	}
+	user, _ := value.(*model.User)
+	return user
+}
+
+// GetUserID gets ID of the authenticated user. If not authenticated, returns empty string.
+func GetUserID(c *gin.Context) string {
+	user := GetActor(c)
+	if user == nil {
+		return ""
+	}
+	return user.ID
+}
+
+// GetOrigin gets the authenticated user and the websocket connection ID of the request, which are used to notify other clients.
+func GetOrigin(c *gin.Context) websocket.Origin {
+	return websocket.Origin{
+		UserID:       GetUserID(c),
+		ConnectionID: c.GetHeader(websocket.ConnectionIDHeader),
+	}
+}
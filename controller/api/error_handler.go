@@ -43,6 +43,10 @@ func SetErrorStatus(c *gin.Context, err error) {
 		status = http.StatusPreconditionFailed
 	case service.ErrorCodeUnauthenticated:
 		status = http.StatusUnauthorized
+	case service.ErrorCodeForbidden:
+		status = http.StatusForbidden
+	case service.ErrorCodeConflict:
+		status = http.StatusConflict
 	}
 	errorResponse := &ErrorResponse{
 		Code:    string(serr.Code),
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"taskboard-api-go/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Headers of conditional requests
+const (
+	ETagHeader        = "ETag"
+	IfMatchHeader     = "If-Match"
+	IfNoneMatchHeader = "If-None-Match"
+)
+
+// ETag returns entity tag of the response, which is a digest of its JSON. ex. "9f86d081884c7d659a2feaa0c55ad015"
+// It changes whenever the response changes, even if the version of entity does not (ex. rank, labels or progress of task).
+func ETag(res interface{}) string {
+	content, err := json.Marshal(res)
+	if err != nil {
+		return ""
+	}
+	digest := sha256.Sum256(content)
+	return strconv.Quote(hex.EncodeToString(digest[:16]))
+}
+
+// SetETag sets ETag header by the response
+func SetETag(c *gin.Context, res interface{}) {
+	if etag := ETag(res); etag != "" {
+		c.Header(ETagHeader, etag)
+	}
+}
+
+// matchETags checks whether header value (comma separated entity tags or "*") contains the entity tag
+func matchETags(value string, etag string) bool {
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || (etag != "" && tag == etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckNotModified sets ETag header, and responds 304 Not Modified if If-None-Match header matches the response.
+// It returns true if responded.
+func CheckNotModified(c *gin.Context, res interface{}) bool {
+	SetETag(c, res)
+	value := c.GetHeader(IfNoneMatchHeader)
+	if value == "" || !matchETags(value, ETag(res)) {
+		return false
+	}
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// CheckIfMatch checks If-Match header with the response of the current entity.
+// It returns true if If-Match header is specified and matches, then the version of request must be replaced with currentVersion.
+// If it does not match, OptimisticLockFailure error is returned with the current version in details.
+func CheckIfMatch(c *gin.Context, res interface{}, currentVersion int) (bool, error) {
+	value := c.GetHeader(IfMatchHeader)
+	if value == "" {
+		return false, nil
+	}
+	etag := ETag(res)
+	if !matchETags(value, etag) {
+		return false, service.NewSvcErrorWithDetails(service.ErrorCodeOptimisticLockFailure, nil,
+			fmt.Sprintf("If-Match does not match the current entity. ETag:%s", etag),
+			[]string{strconv.Itoa(currentVersion)})
+	}
+	return true, nil
+}
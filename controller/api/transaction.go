@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"taskboard-api-go/orm"
 	"taskboard-api-go/service"
 
 	"github.com/jinzhu/gorm"
@@ -25,3 +26,17 @@ func Rollback(tx *gorm.DB) {
 		fmt.Printf("Failed to rollback transaction Error:%+v\n", err)
 	}
 }
+
+// InTransaction executes f in a transaction and commits it, or rollbacks it if f returns error.
+// The transaction is retried if it failed by concurrent transactions, so f may be called more than once.
+func InTransaction(f func(tx *gorm.DB) error) error {
+	err := orm.Transaction(orm.GetDB(), f)
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*service.SvcError); ok {
+		return err
+	}
+	// Failed to begin or commit
+	return service.NewSvcError(service.ErrorCodeDB, err, "Failed to execute transaction")
+}
@@ -0,0 +1,204 @@
+package attachments
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"taskboard-api-go/controller/api"
+	"taskboard-api-go/controller/websocket"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type endPoint struct {
+	attachments  string
+	content      string
+	taskid       string
+	attachmentid string
+	file         string
+	ws           *websocket.WsManager
+}
+
+// EndPoint presents attachments endpoint
+var EndPoint = endPoint{
+	attachments:  "/tasks/:taskid/attachments",
+	content:      "/content",
+	taskid:       "taskid",
+	attachmentid: "attachmentid",
+	file:         "file",
+}
+
+// SetWsManager sets websocket manager to EndPoint
+func SetWsManager(ws *websocket.WsManager) {
+	EndPoint.ws = ws
+}
+
+// RegisterRoute registers API endpoints for attachments
+func (p *endPoint) RegisterRoute(route *gin.RouterGroup) (err error) {
+	route.GET(p.attachments, list)
+	route.POST(p.attachments, create)
+	route.GET(p.attachments+"/:"+p.attachmentid, get)
+	route.GET(p.attachments+"/:"+p.attachmentid+p.content, download)
+	route.DELETE(p.attachments+"/:"+p.attachmentid, delete)
+	return
+}
+
+// find all attachments of a task
+func list(c *gin.Context) {
+	tx := orm.GetDB() // No transction
+	task, err := findTaskByPathParameter(c, tx)
+	if err != nil {
+		return
+	}
+	srvc := service.NewAttachmentService(tx, api.GetActor(c))
+	attachments, serr := srvc.FindAttachments(&model.Attachment{TaskID: task.ID}, []string{"created_date, id"})
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	res := convertListAttachmentResponse(attachments)
+	c.IndentedJSON(http.StatusOK, res)
+}
+
+// upload an attachment by multipart form
+func create(c *gin.Context) {
+	tx := orm.GetDB().Begin()
+	task, err := findTaskByPathParameter(c, tx)
+	if err != nil {
+		api.Rollback(tx)
+		return
+	}
+	attachment, header, serr := getAttachmentByCreateRequest(c, task, api.GetUserID(c))
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, service.NewBadRequestError(err))
+		return
+	}
+	defer file.Close()
+
+	// create attachment
+	srvc := service.NewAttachmentService(tx, api.GetActor(c))
+	serr = srvc.CreateAttachment(attachment, file)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	serr = api.Commit(tx)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+
+	res := convertAttachmentResponse(attachment)
+	c.IndentedJSON(http.StatusOK, res)
+
+	// websocket send message
+	EndPoint.ws.SendUpdateAttachmentMessage(api.GetOrigin(c), res, task.ID)
+}
+
+// get metadata of an attachment
+func get(c *gin.Context) {
+	tx := orm.GetDB() // No transaction
+	srvc := service.NewAttachmentService(tx, api.GetActor(c))
+	find, err := findAttachmentByPathParameter(c, srvc)
+	if err != nil {
+		return
+	}
+	res := convertAttachmentResponse(find)
+	c.IndentedJSON(http.StatusOK, res)
+}
+
+// download content of an attachment
+func download(c *gin.Context) {
+	tx := orm.GetDB() // No transaction
+	srvc := service.NewAttachmentService(tx, api.GetActor(c))
+	find, err := findAttachmentByPathParameter(c, srvc)
+	if err != nil {
+		return
+	}
+	reader, serr := srvc.OpenAttachment(find)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	defer reader.Close()
+	headers := map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": find.FileName}),
+	}
+	c.DataFromReader(http.StatusOK, find.Size, find.ContentType, reader, headers)
+}
+
+func findTaskByPathParameter(c *gin.Context, tx *gorm.DB) (find *model.Task, serr error) {
+	taskID, serr := api.GetPathParameter(c, EndPoint.taskid)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	find, serr = service.NewTaskService(tx, api.GetActor(c)).FindTask(&model.Task{ID: taskID})
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	return
+}
+
+func findAttachmentByPathParameter(c *gin.Context, srvc *service.AttachmentService) (find *model.Attachment, serr error) {
+	taskID, serr := api.GetPathParameter(c, EndPoint.taskid)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	attachmentID, serr := api.GetPathParameter(c, EndPoint.attachmentid)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	find, serr = srvc.FindAttachment(&model.Attachment{ID: attachmentID, TaskID: taskID})
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	return
+}
+
+// delete attachment
+func delete(c *gin.Context) {
+	tx := orm.GetDB().Begin()
+	srvc := service.NewAttachmentService(tx, api.GetActor(c))
+	find, err := findAttachmentByPathParameter(c, srvc)
+	if err != nil {
+		api.Rollback(tx)
+		return
+	}
+	// delete attachment
+	serr := srvc.DeleteAttachment(find)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	serr = api.Commit(tx)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	// Content is deleted after commit, the attachment is already deleted even if it fails
+	if serr = service.DeleteAttachmentContents([]model.Attachment{*find}); serr != nil {
+		fmt.Printf("Failed to delete content of attachment. error:%+v\n", serr)
+	}
+	c.Status(http.StatusOK)
+
+	// websocket send message
+	EndPoint.ws.SendUpdateAttachmentMessage(api.GetOrigin(c), nil, find.TaskID)
+}
@@ -0,0 +1,62 @@
+package attachments
+
+import (
+	"mime/multipart"
+	"path/filepath"
+	"taskboard-api-go/controller/api"
+	"taskboard-api-go/model"
+	"taskboard-api-go/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ID             string    `gorm:"primary_key;size:32"`
+// TaskID         string    `gorm:"not null;size:32;index"`
+// FileName       string    `gorm:"not null;size:255"`
+// ContentType    string    `gorm:"not null;size:255"`
+// Size           int64     `gorm:"not null"`
+// StorageKey     string    `gorm:"not null;size:64"`
+// UploaderUserID string    `gorm:"not null;size:32"`
+// CreatedDate    time.Time `gorm:"not null"`
+
+type attachmentResponse struct {
+	ID             string `json:"id"`
+	TaskID         string `json:"taskId"`
+	FileName       string `json:"fileName"`
+	ContentType    string `json:"contentType"`
+	Size           int64  `json:"size"`
+	UploaderUserID string `json:"uploaderUserId"`
+	CreatedDate    string `json:"createDate"`
+}
+
+func convertAttachmentResponse(attachment *model.Attachment) *attachmentResponse {
+	return &attachmentResponse{
+		ID:             attachment.ID,
+		TaskID:         attachment.TaskID,
+		FileName:       attachment.FileName,
+		ContentType:    attachment.ContentType,
+		Size:           attachment.Size,
+		UploaderUserID: attachment.UploaderUserID,
+		CreatedDate:    attachment.CreatedDate.Format(time.RFC3339),
+	}
+}
+
+func convertListAttachmentResponse(attachments []model.Attachment) (res []*attachmentResponse) {
+	res = make([]*attachmentResponse, 0, len(attachments))
+	for _, attachment := range attachments {
+		res = append(res, convertAttachmentResponse(&attachment))
+	}
+	return
+}
+
+// getAttachmentByCreateRequest returns new attachment and header of uploaded file in multipart form
+func getAttachmentByCreateRequest(c *gin.Context, task *model.Task, uploaderUserID string) (*model.Attachment, *multipart.FileHeader, error) {
+	header, serr := api.GetFormFile(c, EndPoint.file, service.MaxAttachmentSize)
+	if serr != nil {
+		return nil, nil, serr
+	}
+	// Content type and size are set by service
+	attachment := model.NewAttachment(task.ID, filepath.Base(header.Filename), "", 0, uploaderUserID, time.Now().UTC())
+	return attachment, header, nil
+}
@@ -9,26 +9,28 @@ import (
 	"taskboard-api-go/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 )
 
 type endPoint struct {
-	boards          string
-	boardid         string
-	boardtasks      string
-	boardorders     string
-	taskorders      string
-	taskboardFromID string
-	ws              *websocket.WsManager
+	boards      string
+	boardid     string
+	boardtasks  string
+	boardorders string
+	taskorders  string
+	ws          *websocket.WsManager
 }
 
 // EndPoint presents boards endpoint
 var EndPoint = endPoint{
-	boards:          "/boards",
-	boardorders:     "/boardorders",
-	boardid:         "boardid",
-	taskboardFromID: "taskboard-from-id",
+	boards:      "/boards",
+	boardorders: "/boardorders",
+	boardid:     "boardid",
 }
 
+// newBoardService creates BoardService on the transaction, tests replace it to use repositories in memory
+var newBoardService = service.NewBoardService
+
 // SetWsManager sets websocket manager to EndPoint
 func SetWsManager(ws *websocket.WsManager) {
 	EndPoint.ws = ws
@@ -48,8 +50,8 @@ func (p *endPoint) RegisterRoute(route *gin.RouterGroup) (err error) {
 // find all boards
 func list(c *gin.Context) {
 	tx := orm.GetDB() // No transction
-	srvc := service.NewBoardService(tx)
-	boards, serr := srvc.FindBoards(&model.Board{}, []string{"disp_order, created_date"})
+	srvc := newBoardService(tx, api.GetActor(c))
+	boards, serr := srvc.FindBoards(&model.Board{}, []string{"sort_rank, created_date"})
 	if serr != nil {
 		api.SetErrorStatus(c, serr)
 		return
@@ -66,15 +68,9 @@ func create(c *gin.Context) {
 	}
 
 	// create board
-	tx := orm.GetDB().Begin()
-	srvc := service.NewBoardService(tx)
-	serr = srvc.CreateBoard(board)
-	if serr != nil {
-		api.Rollback(tx)
-		api.SetErrorStatus(c, serr)
-		return
-	}
-	serr = api.Commit(tx)
+	serr = api.InTransaction(func(tx *gorm.DB) error {
+		return newBoardService(tx, api.GetActor(c)).CreateBoard(board)
+	})
 	if serr != nil {
 		api.SetErrorStatus(c, serr)
 		return
@@ -84,19 +80,22 @@ func create(c *gin.Context) {
 	c.IndentedJSON(http.StatusOK, res)
 
 	// websocket send message
-	EndPoint.ws.SendUpdateBoardMessage(c.GetHeader(EndPoint.taskboardFromID))
+	EndPoint.ws.SendUpdateBoardMessage(api.GetOrigin(c), res, board.ID)
 }
 
 // get a board
 func get(c *gin.Context) {
 	tx := orm.GetDB() // No transaction
-	srvc := service.NewBoardService(tx)
+	srvc := newBoardService(tx, api.GetActor(c))
 	find, err := findBoardByPathParameter(c, srvc)
 	if err != nil {
 		api.Rollback(tx)
 		return
 	}
 	res := convertBoardResponse(find)
+	if api.CheckNotModified(c, res) {
+		return
+	}
 	c.IndentedJSON(http.StatusOK, res)
 }
 
@@ -116,57 +115,69 @@ func findBoardByPathParameter(c *gin.Context, srvc *service.BoardService) (find
 
 // update board
 func update(c *gin.Context) {
-	tx := orm.GetDB().Begin()
-	srvc := service.NewBoardService(tx)
-	find, err := findBoardByPathParameter(c, srvc)
-	if err != nil {
-		api.Rollback(tx)
-		return
-	}
-	board, serr := getBoardByUpdateRequest(c, find)
+	boardID, serr := api.GetPathParameter(c, EndPoint.boardid)
 	if serr != nil {
-		api.Rollback(tx)
 		api.SetErrorStatus(c, serr)
 		return
 	}
-
-	// update board
-	serr = srvc.UpdateBoard(board)
+	req, serr := getUpdateRequest(c)
 	if serr != nil {
-		api.Rollback(tx)
 		api.SetErrorStatus(c, serr)
 		return
 	}
-	serr = api.Commit(tx)
+
+	// update board
+	var board *model.Board
+	serr = api.InTransaction(func(tx *gorm.DB) error {
+		srvc := newBoardService(tx, api.GetActor(c))
+		find, serr := srvc.FindBoard(&model.Board{ID: boardID})
+		if serr != nil {
+			return serr
+		}
+		matched, serr := api.CheckIfMatch(c, convertBoardResponse(find), find.Version)
+		if serr != nil {
+			return serr
+		}
+		board = getBoardByUpdateRequest(req, find)
+		if matched {
+			// If-Match is used as optimistic lock token instead of version in request
+			board.Version = find.Version
+		}
+		return srvc.UpdateBoard(find, board)
+	})
 	if serr != nil {
 		api.SetErrorStatus(c, serr)
 		return
 	}
 
 	res := convertBoardResponse(board)
+	api.SetETag(c, res)
 	c.IndentedJSON(http.StatusOK, res)
 
 	// websocket send message
-	EndPoint.ws.SendUpdateBoardMessage(c.GetHeader(EndPoint.taskboardFromID), board.ID)
+	EndPoint.ws.SendUpdateBoardMessage(api.GetOrigin(c), res, board.ID)
 }
 
 // delete board
 func delete(c *gin.Context) {
-	tx := orm.GetDB().Begin()
-	srvc := service.NewBoardService(tx)
-	find, err := findBoardByPathParameter(c, srvc)
-	if err != nil {
-		api.Rollback(tx)
-		return
-	}
-	// delete board
-	serr := srvc.DeleteBoard(find)
+	boardID, serr := api.GetPathParameter(c, EndPoint.boardid)
 	if serr != nil {
-		api.Rollback(tx)
 		api.SetErrorStatus(c, serr)
 		return
 	}
-	serr = api.Commit(tx)
+	// delete board, retried if ranks of moved tasks are taken by tasks moved to icebox concurrently
+	var find *model.Board
+	serr = api.InTransaction(func(tx *gorm.DB) (serr error) {
+		srvc := newBoardService(tx, api.GetActor(c))
+		find, serr = srvc.FindBoard(&model.Board{ID: boardID})
+		if serr != nil {
+			return
+		}
+		if _, serr = api.CheckIfMatch(c, convertBoardResponse(find), find.Version); serr != nil {
+			return
+		}
+		return srvc.DeleteBoard(find)
+	})
 	if serr != nil {
 		api.SetErrorStatus(c, serr)
 		return
@@ -174,25 +185,19 @@ func delete(c *gin.Context) {
 	c.Status(http.StatusOK)
 
 	// websocket send message
-	EndPoint.ws.SendUpdateBoardMessage(c.GetHeader(EndPoint.taskboardFromID), find.ID, model.SystemBoardIcebox.ID)
+	EndPoint.ws.SendUpdateBoardMessage(api.GetOrigin(c), nil, find.ID, model.SystemBoardIcebox.ID)
 }
 
-// update order of all boards
+// move a board between other boards
 func updateBoardOrders(c *gin.Context) {
 	req, serr := getUpdateBoardOrdersRequest(c)
 	if serr != nil {
 		api.SetErrorStatus(c, serr)
 		return
 	}
-	tx := orm.GetDB().Begin()
-	srvc := service.NewBoardService(tx)
-	serr = srvc.UpdateBoardOrders(req.BoardIDs)
-	if serr != nil {
-		api.Rollback(tx)
-		api.SetErrorStatus(c, serr)
-		return
-	}
-	serr = api.Commit(tx)
+	serr = api.InTransaction(func(tx *gorm.DB) error {
+		return newBoardService(tx, api.GetActor(c)).UpdateBoardOrders(req.BoardID, req.PrevBoardID, req.NextBoardID)
+	})
 	if serr != nil {
 		api.SetErrorStatus(c, serr)
 		return
@@ -200,6 +205,6 @@ func updateBoardOrders(c *gin.Context) {
 	c.Status(http.StatusOK)
 
 	// websocket send message
-	EndPoint.ws.SendUpdateBoardMessage(c.GetHeader(EndPoint.taskboardFromID))
-	EndPoint.ws.SendUpdateTaskBoardMessage(c.GetHeader(EndPoint.taskboardFromID))
+	EndPoint.ws.SendUpdateBoardMessage(api.GetOrigin(c), req)
+	EndPoint.ws.SendUpdateTaskBoardMessage(api.GetOrigin(c), nil)
 }
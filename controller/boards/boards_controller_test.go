@@ -0,0 +1,174 @@
+package boards
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"taskboard-api-go/controller/api"
+	"taskboard-api-go/controller/websocket"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/repository/memory"
+	"taskboard-api-go/service"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/olahol/melody.v1"
+)
+
+// Boards are stored in memory, and database is used only to begin and commit transactions
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	if err := orm.Init(orm.DialectSQLite3, ":memory:"); err != nil {
+		fmt.Printf("Failed to init test db: %+v\n", err)
+		os.Exit(1)
+	}
+	ws, err := websocket.NewWsManager(melody.New(), websocket.NewLocalPublisher())
+	if err != nil {
+		fmt.Printf("Failed to create websocket manager: %+v\n", err)
+		os.Exit(1)
+	}
+	SetWsManager(ws)
+	os.Exit(m.Run())
+}
+
+// newTestRouter returns router of boards endpoint whose services use new store, and requests are sent by actor
+func newTestRouter(t *testing.T, actor *model.User) *gin.Engine {
+	repos := memory.NewRepositories(memory.NewStore())
+	newBoardService = func(tx *gorm.DB, actor *model.User) *service.BoardService {
+		return service.NewBoardServiceWithRepositories(repos, actor)
+	}
+	if serr := newBoardService(nil, model.SystemUser).CreateSystemBoards(); serr != nil {
+		t.Fatalf("Failed to create system boards: %+v", serr)
+	}
+	router := gin.New()
+	group := router.Group("", func(c *gin.Context) {
+		c.Set(api.ContextUserKey, actor)
+	})
+	if err := EndPoint.RegisterRoute(group); err != nil {
+		t.Fatalf("Failed to register route: %+v", err)
+	}
+	return router
+}
+
+func serve(router *gin.Engine, method, path string, body interface{}, headers map[string]string) *httptest.ResponseRecorder {
+	var content bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&content).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &content)
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
+
+func listBoardNames(t *testing.T, router *gin.Engine) []string {
+	res := serve(router, http.MethodGet, "/boards", nil, nil)
+	if res.Code != http.StatusOK {
+		t.Fatalf("Failed to list boards: %d %s", res.Code, res.Body.String())
+	}
+	var boards []boardResponse
+	if err := json.Unmarshal(res.Body.Bytes(), &boards); err != nil {
+		t.Fatalf("Failed to parse response: %+v", err)
+	}
+	names := make([]string, 0, len(boards))
+	for _, board := range boards {
+		names = append(names, board.Name)
+	}
+	return names
+}
+
+func TestBoardsController_Create(t *testing.T) {
+	admin := &model.User{ID: "user_admin", Name: "admin", Role: model.RoleAdmin}
+	router := newTestRouter(t, admin)
+
+	res := serve(router, http.MethodPost, "/boards", &createRequest{Name: "Review"}, nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	var created boardResponse
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &created))
+	assert.Equal(t, "Review", created.Name)
+	assert.Equal(t, 1, created.Version)
+	assert.Equal(t, []string{"Icebox", "Todo", "Doing", "Done", "Review"}, listBoardNames(t, router))
+
+	// Member can not create board
+	member := &model.User{ID: "user_member", Name: "member", Role: model.RoleMember}
+	res = serve(newTestRouter(t, member), http.MethodPost, "/boards", &createRequest{Name: "Review"}, nil)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+}
+
+func TestBoardsController_UpdateBoardOrders(t *testing.T) {
+	router := newTestRouter(t, &model.User{ID: "user_admin", Name: "admin", Role: model.RoleAdmin})
+
+	req := &updateBoardOrdersRequest{BoardID: model.SystemBoardDone.ID, NextBoardID: model.SystemBoardIcebox.ID}
+	res := serve(router, http.MethodPut, "/boardorders", req, nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, []string{"Done", "Icebox", "Todo", "Doing"}, listBoardNames(t, router))
+
+	// Neighbors in the old order conflict
+	req = &updateBoardOrdersRequest{BoardID: model.SystemBoardTodo.ID, PrevBoardID: model.SystemBoardDoing.ID, NextBoardID: model.SystemBoardDone.ID}
+	res = serve(router, http.MethodPut, "/boardorders", req, nil)
+	assert.Equal(t, http.StatusConflict, res.Code)
+}
+
+func TestBoardsController_Update(t *testing.T) {
+	router := newTestRouter(t, &model.User{ID: "user_admin", Name: "admin", Role: model.RoleAdmin})
+	path := "/boards/" + model.SystemBoardTodo.ID
+
+	res := serve(router, http.MethodGet, path, nil, nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	var found boardResponse
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &found))
+	etag := res.Header().Get(api.ETagHeader)
+	assert.Equal(t, api.ETag(&found), etag)
+
+	// Version is replaced with If-Match
+	req := &updateRequest{Name: "Ready", IsSystem: true}
+	res = serve(router, http.MethodPut, path, req, map[string]string{api.IfMatchHeader: etag})
+	assert.Equal(t, http.StatusOK, res.Code)
+	var updated boardResponse
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &updated))
+	assert.Equal(t, 2, updated.Version)
+	assert.Equal(t, api.ETag(&updated), res.Header().Get(api.ETagHeader))
+
+	// Rank and created date are kept
+	assert.Equal(t, found.Rank, updated.Rank)
+	assert.Equal(t, found.CreatedDate, updated.CreatedDate)
+	assert.Equal(t, []string{"Icebox", "Ready", "Doing", "Done"}, listBoardNames(t, router))
+
+	// Stale If-Match fails
+	res = serve(router, http.MethodPut, path, req, map[string]string{api.IfMatchHeader: etag})
+	assert.Equal(t, http.StatusPreconditionFailed, res.Code)
+
+	etag = api.ETag(&updated)
+	res = serve(router, http.MethodGet, path, nil, map[string]string{api.IfNoneMatchHeader: etag})
+	assert.Equal(t, http.StatusNotModified, res.Code)
+
+	// Moving the board changes its ETag, though its version is not changed
+	orders := &updateBoardOrdersRequest{BoardID: model.SystemBoardTodo.ID, PrevBoardID: model.SystemBoardDone.ID}
+	res = serve(router, http.MethodPut, "/boardorders", orders, nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	res = serve(router, http.MethodGet, path, nil, map[string]string{api.IfNoneMatchHeader: etag})
+	assert.Equal(t, http.StatusOK, res.Code)
+	res = serve(router, http.MethodPut, path, req, map[string]string{api.IfMatchHeader: etag})
+	assert.Equal(t, http.StatusPreconditionFailed, res.Code)
+}
+
+func TestBoardsController_Delete(t *testing.T) {
+	router := newTestRouter(t, &model.User{ID: "user_admin", Name: "admin", Role: model.RoleAdmin})
+
+	res := serve(router, http.MethodDelete, "/boards/"+model.SystemBoardDoing.ID, nil, nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, []string{"Icebox", "Todo", "Done"}, listBoardNames(t, router))
+
+	res = serve(router, http.MethodDelete, "/boards/"+model.SystemBoardDoing.ID, nil, nil)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
@@ -10,7 +10,7 @@ import (
 
 // ID          string       `gorm:"primary_key;size:32"`
 // Name        string       `gorm:"unique;size:255"`
-// DispOrder   int          `gorm:"not null"`
+// Rank        string       `gorm:"column:sort_rank;not null;size:255"`
 // IsSystem    bool         `gorm:"not null"`
 // IsClosed    bool         `gorm:"not null"`
 // CreatedDate time.Time    `gorm:"not null"`
@@ -19,7 +19,7 @@ import (
 type boardResponse struct {
 	ID          string `json:"id"`
 	Name        string `json:"name"`
-	DispOrder   int    `json:"dispOrder"`
+	Rank        string `json:"rank"`
 	IsSystem    bool   `json:"isSystem"`
 	IsClosed    bool   `json:"isClosed"`
 	CreatedDate string `json:"createDate"`
@@ -40,14 +40,16 @@ type updateRequest struct {
 }
 
 type updateBoardOrdersRequest struct {
-	BoardIDs []string `json:"boardIds"`
+	BoardID     string `json:"boardId"`
+	PrevBoardID string `json:"prevBoardId"` // Empty to move to the first
+	NextBoardID string `json:"nextBoardId"` // Empty to move to the last
 }
 
 func convertBoardResponse(board *model.Board) *boardResponse {
 	return &boardResponse{
 		ID:          board.ID,
 		Name:        board.Name,
-		DispOrder:   board.DispOrder,
+		Rank:        board.Rank,
 		IsSystem:    board.IsSystem,
 		IsClosed:    board.IsClosed,
 		CreatedDate: board.CreatedDate.Format(time.RFC3339),
@@ -78,19 +80,25 @@ func getBoardByCreateRequest(c *gin.Context) (*model.Board, error) {
 	return board, nil
 }
 
-func getBoardByUpdateRequest(c *gin.Context, find *model.Board) (*model.Board, error) {
+func getUpdateRequest(c *gin.Context) (*updateRequest, error) {
 	var req updateRequest
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
 		return nil, service.NewBadRequestError(err)
 	}
+	return &req, nil
+}
+
+func getBoardByUpdateRequest(req *updateRequest, find *model.Board) *model.Board {
 	return &model.Board{
-		ID:       find.ID,
-		Name:     req.Name,
-		IsSystem: req.IsSystem,
-		IsClosed: req.IsClosed,
-		Version:  req.Version,
-	}, nil
+		ID:          find.ID,
+		Name:        req.Name,
+		IsSystem:    req.IsSystem,
+		IsClosed:    req.IsClosed,
+		Rank:        find.Rank,
+		CreatedDate: find.CreatedDate,
+		Version:     req.Version,
+	}
 }
 
 func getUpdateBoardOrdersRequest(c *gin.Context) (*updateBoardOrdersRequest, error) {
@@ -0,0 +1,212 @@
+package checklists
+
+import (
+	"net/http"
+	"taskboard-api-go/controller/api"
+	"taskboard-api-go/controller/websocket"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type endPoint struct {
+	checklist string
+	taskid    string
+	itemid    string
+	ws        *websocket.WsManager
+}
+
+// EndPoint presents checklists endpoint
+var EndPoint = endPoint{
+	checklist: "/tasks/:taskid/checklist",
+	taskid:    "taskid",
+	itemid:    "itemid",
+}
+
+// SetWsManager sets websocket manager to EndPoint
+func SetWsManager(ws *websocket.WsManager) {
+	EndPoint.ws = ws
+}
+
+// RegisterRoute registers API endpoints for checklist items
+func (p *endPoint) RegisterRoute(route *gin.RouterGroup) (err error) {
+	route.GET(p.checklist, list)
+	route.POST(p.checklist, create)
+	route.GET(p.checklist+"/:"+p.itemid, get)
+	route.PUT(p.checklist+"/:"+p.itemid, update)
+	route.DELETE(p.checklist+"/:"+p.itemid, delete)
+	return
+}
+
+// find all checklist items of a task
+func list(c *gin.Context) {
+	tx := orm.GetDB() // No transction
+	task, err := findTaskByPathParameter(c, tx)
+	if err != nil {
+		return
+	}
+	srvc := service.NewChecklistService(tx, api.GetActor(c))
+	checklistItems, serr := srvc.FindChecklistItems(&model.ChecklistItem{TaskID: task.ID}, []string{"disp_order, id"})
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	res := convertListChecklistItemResponse(checklistItems)
+	c.IndentedJSON(http.StatusOK, res)
+}
+
+func create(c *gin.Context) {
+	tx := orm.GetDB().Begin()
+	task, err := findTaskByPathParameter(c, tx)
+	if err != nil {
+		api.Rollback(tx)
+		return
+	}
+	checklistItem, serr := getChecklistItemByCreateRequest(c, task)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+
+	// create checklist item
+	srvc := service.NewChecklistService(tx, api.GetActor(c))
+	serr = srvc.CreateChecklistItem(checklistItem)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	serr = api.Commit(tx)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+
+	res := convertChecklistItemResponse(checklistItem)
+	c.IndentedJSON(http.StatusOK, res)
+
+	// websocket send message
+	EndPoint.ws.SendUpdateTaskMessage(api.GetOrigin(c), nil, []string{task.BoardID}, task.ID)
+}
+
+// get a checklist item
+func get(c *gin.Context) {
+	tx := orm.GetDB() // No transaction
+	srvc := service.NewChecklistService(tx, api.GetActor(c))
+	find, err := findChecklistItemByPathParameter(c, srvc)
+	if err != nil {
+		return
+	}
+	res := convertChecklistItemResponse(find)
+	c.IndentedJSON(http.StatusOK, res)
+}
+
+func findTaskByPathParameter(c *gin.Context, tx *gorm.DB) (find *model.Task, serr error) {
+	taskID, serr := api.GetPathParameter(c, EndPoint.taskid)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	find, serr = service.NewTaskService(tx, api.GetActor(c)).FindTask(&model.Task{ID: taskID})
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	return
+}
+
+func findChecklistItemByPathParameter(c *gin.Context, srvc *service.ChecklistService) (find *model.ChecklistItem, serr error) {
+	taskID, serr := api.GetPathParameter(c, EndPoint.taskid)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	checklistItemID, serr := api.GetPathParameter(c, EndPoint.itemid)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	find, serr = srvc.FindChecklistItem(&model.ChecklistItem{ID: checklistItemID, TaskID: taskID})
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	return
+}
+
+// update checklist item
+func update(c *gin.Context) {
+	tx := orm.GetDB().Begin()
+	task, err := findTaskByPathParameter(c, tx)
+	if err != nil {
+		api.Rollback(tx)
+		return
+	}
+	srvc := service.NewChecklistService(tx, api.GetActor(c))
+	find, err := findChecklistItemByPathParameter(c, srvc)
+	if err != nil {
+		api.Rollback(tx)
+		return
+	}
+	checklistItem, serr := getChecklistItemByUpdateRequest(c, find)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+
+	// update checklist item
+	serr = srvc.UpdateChecklistItem(checklistItem)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	serr = api.Commit(tx)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+
+	res := convertChecklistItemResponse(checklistItem)
+	c.IndentedJSON(http.StatusOK, res)
+
+	// websocket send message
+	EndPoint.ws.SendUpdateTaskMessage(api.GetOrigin(c), nil, []string{task.BoardID}, task.ID)
+}
+
+// delete checklist item
+func delete(c *gin.Context) {
+	tx := orm.GetDB().Begin()
+	task, err := findTaskByPathParameter(c, tx)
+	if err != nil {
+		api.Rollback(tx)
+		return
+	}
+	srvc := service.NewChecklistService(tx, api.GetActor(c))
+	find, err := findChecklistItemByPathParameter(c, srvc)
+	if err != nil {
+		api.Rollback(tx)
+		return
+	}
+	// delete checklist item
+	serr := srvc.DeleteChecklistItem(find)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	serr = api.Commit(tx)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	c.Status(http.StatusOK)
+
+	// websocket send message
+	EndPoint.ws.SendUpdateTaskMessage(api.GetOrigin(c), nil, []string{task.BoardID}, task.ID)
+}
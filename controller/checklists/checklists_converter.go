@@ -0,0 +1,92 @@
+package checklists
+
+import (
+	"taskboard-api-go/model"
+	"taskboard-api-go/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ID          string    `gorm:"primary_key;size:32"`
+// TaskID      string    `gorm:"not null;size:32;index"`
+// Name        string    `gorm:"not null;size:255"`
+// IsDone      bool      `gorm:"not null"`
+// DispOrder   int       `gorm:"not null"`
+// CreatedDate time.Time `gorm:"not null"`
+// Version     int       `gorm:"not null"` // Version for optimistic lock
+
+type checklistItemResponse struct {
+	ID          string `json:"id"`
+	TaskID      string `json:"taskId"`
+	Name        string `json:"name"`
+	IsDone      bool   `json:"isDone"`
+	DispOrder   int    `json:"dispOrder"`
+	CreatedDate string `json:"createDate"`
+	Version     int    `json:"version"`
+}
+
+type createRequest struct {
+	Name   string `json:"name"`
+	IsDone bool   `json:"isDone"`
+}
+
+type updateRequest struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	IsDone    bool   `json:"isDone"`
+	DispOrder int    `json:"dispOrder"` // Not changed if 0
+	Version   int    `json:"version"`
+}
+
+func convertChecklistItemResponse(item *model.ChecklistItem) *checklistItemResponse {
+	return &checklistItemResponse{
+		ID:          item.ID,
+		TaskID:      item.TaskID,
+		Name:        item.Name,
+		IsDone:      item.IsDone,
+		DispOrder:   item.DispOrder,
+		CreatedDate: item.CreatedDate.Format(time.RFC3339),
+		Version:     item.Version,
+	}
+}
+
+func convertListChecklistItemResponse(items []model.ChecklistItem) (res []*checklistItemResponse) {
+	res = make([]*checklistItemResponse, 0, len(items))
+	for _, item := range items {
+		res = append(res, convertChecklistItemResponse(&item))
+	}
+	return
+}
+
+func getChecklistItemByCreateRequest(c *gin.Context, task *model.Task) (*model.ChecklistItem, error) {
+	var req createRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return nil, service.NewBadRequestError(err)
+	}
+	item := model.NewChecklistItem(task.ID, req.Name, time.Now().UTC())
+	item.IsDone = req.IsDone
+	return item, nil
+}
+
+func getChecklistItemByUpdateRequest(c *gin.Context, find *model.ChecklistItem) (*model.ChecklistItem, error) {
+	var req updateRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return nil, service.NewBadRequestError(err)
+	}
+	item := &model.ChecklistItem{
+		ID:          find.ID,
+		TaskID:      find.TaskID,
+		Name:        req.Name,
+		IsDone:      req.IsDone,
+		DispOrder:   find.DispOrder,
+		CreatedDate: find.CreatedDate,
+		Version:     req.Version,
+	}
+	if req.DispOrder > 0 {
+		item.DispOrder = req.DispOrder
+	}
+	return item, nil
+}
@@ -0,0 +1,202 @@
+package comments
+
+import (
+	"net/http"
+	"taskboard-api-go/controller/api"
+	"taskboard-api-go/controller/websocket"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type endPoint struct {
+	comments  string
+	taskid    string
+	commentid string
+	ws        *websocket.WsManager
+}
+
+// EndPoint presents comments endpoint
+var EndPoint = endPoint{
+	comments:  "/tasks/:taskid/comments",
+	taskid:    "taskid",
+	commentid: "commentid",
+}
+
+// SetWsManager sets websocket manager to EndPoint
+func SetWsManager(ws *websocket.WsManager) {
+	EndPoint.ws = ws
+}
+
+// RegisterRoute registers API endpoints for comments
+func (p *endPoint) RegisterRoute(route *gin.RouterGroup) (err error) {
+	route.GET(p.comments, list)
+	route.POST(p.comments, create)
+	route.GET(p.comments+"/:"+p.commentid, get)
+	route.PUT(p.comments+"/:"+p.commentid, update)
+	route.DELETE(p.comments+"/:"+p.commentid, delete)
+	return
+}
+
+// find all comments of a task
+func list(c *gin.Context) {
+	tx := orm.GetDB() // No transction
+	task, err := findTaskByPathParameter(c, tx)
+	if err != nil {
+		return
+	}
+	srvc := service.NewCommentService(tx, api.GetActor(c))
+	comments, serr := srvc.FindComments(&model.Comment{TaskID: task.ID}, []string{"created_date, id"})
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	res := convertListCommentResponse(comments)
+	c.IndentedJSON(http.StatusOK, res)
+}
+
+func create(c *gin.Context) {
+	tx := orm.GetDB().Begin()
+	task, err := findTaskByPathParameter(c, tx)
+	if err != nil {
+		api.Rollback(tx)
+		return
+	}
+	comment, serr := getCommentByCreateRequest(c, task, api.GetUserID(c))
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+
+	// create comment
+	srvc := service.NewCommentService(tx, api.GetActor(c))
+	serr = srvc.CreateComment(comment)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	serr = api.Commit(tx)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+
+	res := convertCommentResponse(comment)
+	c.IndentedJSON(http.StatusOK, res)
+
+	// websocket send message
+	EndPoint.ws.SendUpdateCommentMessage(api.GetOrigin(c), res, task.ID)
+}
+
+// get a comment
+func get(c *gin.Context) {
+	tx := orm.GetDB() // No transaction
+	srvc := service.NewCommentService(tx, api.GetActor(c))
+	find, err := findCommentByPathParameter(c, srvc)
+	if err != nil {
+		return
+	}
+	res := convertCommentResponse(find)
+	c.IndentedJSON(http.StatusOK, res)
+}
+
+func findTaskByPathParameter(c *gin.Context, tx *gorm.DB) (find *model.Task, serr error) {
+	taskID, serr := api.GetPathParameter(c, EndPoint.taskid)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	find, serr = service.NewTaskService(tx, api.GetActor(c)).FindTask(&model.Task{ID: taskID})
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	return
+}
+
+func findCommentByPathParameter(c *gin.Context, srvc *service.CommentService) (find *model.Comment, serr error) {
+	taskID, serr := api.GetPathParameter(c, EndPoint.taskid)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	commentID, serr := api.GetPathParameter(c, EndPoint.commentid)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	find, serr = srvc.FindComment(&model.Comment{ID: commentID, TaskID: taskID})
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	return
+}
+
+// update comment
+func update(c *gin.Context) {
+	tx := orm.GetDB().Begin()
+	srvc := service.NewCommentService(tx, api.GetActor(c))
+	find, err := findCommentByPathParameter(c, srvc)
+	if err != nil {
+		api.Rollback(tx)
+		return
+	}
+	comment, serr := getCommentByUpdateRequest(c, find)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+
+	// update comment
+	serr = srvc.UpdateComment(find, comment)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	serr = api.Commit(tx)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+
+	res := convertCommentResponse(comment)
+	c.IndentedJSON(http.StatusOK, res)
+
+	// websocket send message
+	EndPoint.ws.SendUpdateCommentMessage(api.GetOrigin(c), res, comment.TaskID)
+}
+
+// delete comment
+func delete(c *gin.Context) {
+	tx := orm.GetDB().Begin()
+	srvc := service.NewCommentService(tx, api.GetActor(c))
+	find, err := findCommentByPathParameter(c, srvc)
+	if err != nil {
+		api.Rollback(tx)
+		return
+	}
+	// delete comment
+	serr := srvc.DeleteComment(find)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	serr = api.Commit(tx)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	c.Status(http.StatusOK)
+
+	// websocket send message
+	EndPoint.ws.SendUpdateCommentMessage(api.GetOrigin(c), nil, find.TaskID)
+}
@@ -0,0 +1,87 @@
+package comments
+
+import (
+	"taskboard-api-go/model"
+	"taskboard-api-go/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ID           string     `gorm:"primary_key;size:32"`
+// TaskID       string     `gorm:"not null;size:32;index"`
+// AuthorUserID string     `gorm:"not null;size:32"`
+// Body         string     `gorm:"not null;size:8000"`
+// CreatedDate  time.Time  `gorm:"not null"`
+// EditedDate   *time.Time // Null until edited
+// Version      int        `gorm:"not null"` // Version for optimistic lock
+
+type commentResponse struct {
+	ID           string `json:"id"`
+	TaskID       string `json:"taskId"`
+	AuthorUserID string `json:"authorUserId"`
+	Body         string `json:"body"`
+	CreatedDate  string `json:"createDate"`
+	EditedDate   string `json:"editedDate"`
+	Version      int    `json:"version"`
+}
+
+type createRequest struct {
+	Body string `json:"body"`
+}
+
+type updateRequest struct {
+	ID      string `json:"id"`
+	Body    string `json:"body"`
+	Version int    `json:"version"`
+}
+
+func convertCommentResponse(comment *model.Comment) *commentResponse {
+	editedDate := ""
+	if comment.EditedDate != nil {
+		editedDate = comment.EditedDate.Format(time.RFC3339)
+	}
+	return &commentResponse{
+		ID:           comment.ID,
+		TaskID:       comment.TaskID,
+		AuthorUserID: comment.AuthorUserID,
+		Body:         comment.Body,
+		CreatedDate:  comment.CreatedDate.Format(time.RFC3339),
+		EditedDate:   editedDate,
+		Version:      comment.Version,
+	}
+}
+
+func convertListCommentResponse(comments []model.Comment) (res []*commentResponse) {
+	res = make([]*commentResponse, 0, len(comments))
+	for _, comment := range comments {
+		res = append(res, convertCommentResponse(&comment))
+	}
+	return
+}
+
+func getCommentByCreateRequest(c *gin.Context, task *model.Task, authorUserID string) (*model.Comment, error) {
+	var req createRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return nil, service.NewBadRequestError(err)
+	}
+	return model.NewComment(task.ID, authorUserID, req.Body, time.Now().UTC()), nil
+}
+
+func getCommentByUpdateRequest(c *gin.Context, find *model.Comment) (*model.Comment, error) {
+	var req updateRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return nil, service.NewBadRequestError(err)
+	}
+	comment := &model.Comment{
+		ID:           find.ID,
+		TaskID:       find.TaskID,
+		AuthorUserID: find.AuthorUserID,
+		CreatedDate:  find.CreatedDate,
+		Version:      req.Version,
+	}
+	comment.SetBody(req.Body, time.Now().UTC())
+	return comment, nil
+}
@@ -0,0 +1,88 @@
+package events
+
+import (
+	"io"
+	"strconv"
+	"taskboard-api-go/controller/api"
+	"taskboard-api-go/controller/websocket"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeatInterval is interval of comment lines sent to keep the stream open through proxies
+const heartbeatInterval = 30 * time.Second
+
+const lastEventIDHeader = "Last-Event-ID"
+
+// sinceQueryKey is used instead of Last-Event-ID at first connection, same as websocket endpoint
+const sinceQueryKey = "since"
+
+type endPoint struct {
+	events  string
+	boardID string
+	taskID  string
+	ws      *websocket.WsManager
+}
+
+// EndPoint presents events endpoint
+var EndPoint = endPoint{
+	events:  "/events",
+	boardID: "boardId",
+	taskID:  "taskId",
+}
+
+// SetWsManager sets websocket manager to EndPoint
+func SetWsManager(ws *websocket.WsManager) {
+	EndPoint.ws = ws
+}
+
+// RegisterRoute registers API endpoints for Server-Sent Events
+func (p *endPoint) RegisterRoute(route *gin.RouterGroup) (err error) {
+	route.GET(p.events, stream)
+	return
+}
+
+// stream sends the same events as websocket by Server-Sent Events.
+// Event ID is sequence number of the event, and client can resume by Last-Event-ID header or since query parameter.
+// Events can be filtered by boardId and taskId query parameters, which can be repeated.
+func stream(c *gin.Context) {
+	lastEventID := c.GetHeader(lastEventIDHeader)
+	if lastEventID == "" {
+		lastEventID = c.Query(sinceQueryKey)
+	}
+	stream := EndPoint.ws.OpenStream(
+		api.GetUserID(c),
+		lastEventID,
+		c.QueryArray(EndPoint.boardID),
+		c.QueryArray(EndPoint.taskID),
+	)
+	defer EndPoint.ws.CloseStream(stream)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // Disable buffering of nginx
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	clientGone := c.Request.Context().Done()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-stream.Events():
+			if !ok {
+				// Closed by server because client is too slow, client will reconnect with Last-Event-ID
+				return false
+			}
+			c.Render(-1, sse.Event{
+				Id:    strconv.FormatInt(event.Seq, 10),
+				Event: event.Type,
+				Data:  event,
+			})
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ":\n\n")
+			return err == nil
+		case <-clientGone:
+			return false
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package labels
+
+import (
+	"net/http"
+	"taskboard-api-go/controller/api"
+	"taskboard-api-go/controller/websocket"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type endPoint struct {
+	labels  string
+	labelid string
+	ws      *websocket.WsManager
+}
+
+// EndPoint presents labels endpoint
+var EndPoint = endPoint{
+	labels:  "/labels",
+	labelid: "labelid",
+}
+
+// SetWsManager sets websocket manager to EndPoint
+func SetWsManager(ws *websocket.WsManager) {
+	EndPoint.ws = ws
+}
+
+// RegisterRoute registers API endpoints for labels
+func (p *endPoint) RegisterRoute(route *gin.RouterGroup) (err error) {
+	route.GET(p.labels, list)
+	route.POST(p.labels, create)
+	route.GET(p.labels+"/:"+p.labelid, get)
+	route.PUT(p.labels+"/:"+p.labelid, update)
+	route.DELETE(p.labels+"/:"+p.labelid, delete)
+	return
+}
+
+// find all labels
+func list(c *gin.Context) {
+	tx := orm.GetDB() // No transction
+	srvc := service.NewLabelService(tx, api.GetActor(c))
+	labels, serr := srvc.FindLabels(&model.Label{}, []string{"name"})
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	res := convertListLabelResponse(labels)
+	c.IndentedJSON(http.StatusOK, res)
+}
+
+func create(c *gin.Context) {
+	label, serr := getLabelByCreateRequest(c)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+
+	// create label
+	tx := orm.GetDB().Begin()
+	srvc := service.NewLabelService(tx, api.GetActor(c))
+	serr = srvc.CreateLabel(label)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	serr = api.Commit(tx)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+
+	res := convertLabelResponse(label)
+	c.IndentedJSON(http.StatusOK, res)
+
+	// websocket send message
+	EndPoint.ws.SendUpdateLabelMessage(api.GetOrigin(c), res, label.ID)
+}
+
+// get a label
+func get(c *gin.Context) {
+	tx := orm.GetDB() // No transaction
+	srvc := service.NewLabelService(tx, api.GetActor(c))
+	find, err := findLabelByPathParameter(c, srvc)
+	if err != nil {
+		return
+	}
+	res := convertLabelResponse(find)
+	c.IndentedJSON(http.StatusOK, res)
+}
+
+func findLabelByPathParameter(c *gin.Context, srvc *service.LabelService) (find *model.Label, serr error) {
+	labelID, serr := api.GetPathParameter(c, EndPoint.labelid)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	find, serr = srvc.FindLabel(&model.Label{ID: labelID})
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return nil, serr
+	}
+	return
+}
+
+// update label
+func update(c *gin.Context) {
+	tx := orm.GetDB().Begin()
+	srvc := service.NewLabelService(tx, api.GetActor(c))
+	find, err := findLabelByPathParameter(c, srvc)
+	if err != nil {
+		api.Rollback(tx)
+		return
+	}
+	label, serr := getLabelByUpdateRequest(c, find)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+
+	// update label
+	serr = srvc.UpdateLabel(label)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	serr = api.Commit(tx)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+
+	res := convertLabelResponse(label)
+	c.IndentedJSON(http.StatusOK, res)
+
+	// websocket send message
+	EndPoint.ws.SendUpdateLabelMessage(api.GetOrigin(c), res, label.ID)
+}
+
+// delete label
+func delete(c *gin.Context) {
+	tx := orm.GetDB().Begin()
+	srvc := service.NewLabelService(tx, api.GetActor(c))
+	find, err := findLabelByPathParameter(c, srvc)
+	if err != nil {
+		api.Rollback(tx)
+		return
+	}
+	// delete label
+	serr := srvc.DeleteLabel(find)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	serr = api.Commit(tx)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	c.Status(http.StatusOK)
+
+	// websocket send message
+	EndPoint.ws.SendUpdateLabelMessage(api.GetOrigin(c), nil, find.ID)
+}
@@ -0,0 +1,46 @@
+package presence
+
+import (
+	"net/http"
+	"taskboard-api-go/controller/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+type endPoint struct {
+	presence string
+	boardID  string
+	ws       *websocket.WsManager
+}
+
+// EndPoint presents presence endpoint
+var EndPoint = endPoint{
+	presence: "/presence",
+	boardID:  "boardId",
+}
+
+// SetWsManager sets websocket manager to EndPoint
+func SetWsManager(ws *websocket.WsManager) {
+	EndPoint.ws = ws
+}
+
+// RegisterRoute registers API endpoints for presence
+func (p *endPoint) RegisterRoute(route *gin.RouterGroup) (err error) {
+	route.GET(p.presence, list)
+	return
+}
+
+// find presence of users. If boardId is specified, only users viewing the board are returned.
+// Response is the same as payload of UPDATE_PRESENCE message.
+func list(c *gin.Context) {
+	presences := EndPoint.ws.Presences()
+	boardID := c.Query(EndPoint.boardID)
+	res := make([]websocket.Presence, 0, len(presences))
+	for _, presence := range presences {
+		if boardID != "" && !presence.IsViewingBoard(boardID) {
+			continue
+		}
+		res = append(res, presence)
+	}
+	c.IndentedJSON(http.StatusOK, res)
+}
@@ -0,0 +1,35 @@
+package tasks
+
+import (
+	"fmt"
+	"taskboard-api-go/common"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/repository"
+	"time"
+)
+
+// Names of background jobs, each of them runs only on the api instance holding its lease
+const (
+	overdueCheckerJob  = "overdue_checker"
+	taskLockCleanerJob = "task_lock_cleaner"
+	rankRebalancerJob  = "rank_rebalancer"
+)
+
+// jobLeaseIntervals is the number of intervals for which a lease is kept without renewal.
+// Another instance takes over the job if the holder stops renewing it.
+const jobLeaseIntervals = 3
+
+// jobLeaseHolder is ID of this api instance holding leases
+var jobLeaseHolder = common.GenerateID()
+
+// holdJobLease acquires or renews the lease of the job which runs every interval, and returns whether it is held
+func holdJobLease(name string, interval time.Duration) bool {
+	now := time.Now().UTC()
+	repo := repository.NewJobLeaseRepository(orm.GetDB()) // No transaction
+	acquired, err := repo.AcquireJobLease(name, jobLeaseHolder, now, now.Add(jobLeaseIntervals*interval))
+	if err != nil {
+		fmt.Printf("Failed to acquire lease of job. name:%s error:%+v\n", name, err)
+		return false
+	}
+	return acquired
+}
@@ -0,0 +1,51 @@
+package tasks
+
+import (
+	"fmt"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/service"
+	"time"
+)
+
+// StartOverdueChecker starts background checker which sends websocket message
+// when tasks become overdue. It checks every interval, only on the api instance holding its lease.
+func StartOverdueChecker(interval time.Duration) {
+	go func() {
+		lastChecked := time.Now().UTC()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			now := time.Now().UTC()
+			if !holdJobLease(overdueCheckerJob, interval) {
+				// Tasks are checked by the holder, and this instance checks from now when it takes over
+				lastChecked = now
+				continue
+			}
+			if err := checkOverdueTasks(lastChecked, now); err != nil {
+				// Retry same period at next time
+				fmt.Printf("Failed to check overdue tasks. error:%+v\n", err)
+				continue
+			}
+			lastChecked = now
+		}
+	}()
+}
+
+// checkOverdueTasks sends message of tasks which became overdue in the period (from, to]
+func checkOverdueTasks(from, to time.Time) error {
+	srvc := service.NewTaskService(orm.GetDB(), model.SystemUser)
+	tasks, serr := srvc.FindTasksBecomingOverdue(from, to)
+	if serr != nil {
+		return serr
+	}
+	if len(tasks) == 0 || EndPoint.ws == nil {
+		return nil
+	}
+	taskIDs := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		taskIDs = append(taskIDs, task.ID)
+	}
+	EndPoint.ws.SendOverdueTaskMessage(taskIDs...)
+	return nil
+}
@@ -0,0 +1,58 @@
+package tasks
+
+import (
+	"fmt"
+	"taskboard-api-go/controller/api"
+	"taskboard-api-go/controller/websocket"
+	"taskboard-api-go/model"
+	"taskboard-api-go/service"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// StartRankRebalancer starts background rebalancer which reassigns evenly spaced ranks
+// to tasks and boards whose ranks become too long by moving. It checks every interval,
+// only on the api instance holding its lease.
+func StartRankRebalancer(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if !holdJobLease(rankRebalancerJob, interval) {
+				continue
+			}
+			if err := rebalanceRanks(service.MaxRankLength); err != nil {
+				fmt.Printf("Failed to rebalance ranks. error:%+v\n", err)
+			}
+		}
+	}()
+}
+
+// rebalanceRanks rebalances ranks longer than maxLength, and sends message of rebalanced boards
+func rebalanceRanks(maxLength int) error {
+	var boardIDs []string
+	boardsRebalanced := false
+	serr := api.InTransaction(func(tx *gorm.DB) (serr error) {
+		boardIDs, serr = service.NewTaskService(tx, model.SystemUser).RebalanceTaskRanks(maxLength)
+		if serr != nil {
+			return
+		}
+		boardsRebalanced, serr = service.NewBoardService(tx, model.SystemUser).RebalanceBoardRanks(maxLength)
+		return
+	})
+	if serr != nil {
+		return serr
+	}
+	if EndPoint.ws == nil {
+		return nil
+	}
+	// Order is not changed, but clients need new ranks to move tasks and boards
+	if len(boardIDs) > 0 {
+		EndPoint.ws.SendUpdateTaskBoardMessage(websocket.Origin{}, nil, boardIDs...)
+	}
+	if boardsRebalanced {
+		EndPoint.ws.SendUpdateBoardMessage(websocket.Origin{}, nil)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package tasks
+
+import (
+	"fmt"
+	"taskboard-api-go/controller/api"
+	"taskboard-api-go/controller/websocket"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/service"
+	"time"
+)
+
+// StartTaskLockCleaner starts background cleaner which releases expired task locks
+// and sends websocket message of them. It checks every interval, only on the api instance holding its lease.
+func StartTaskLockCleaner(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if !holdJobLease(taskLockCleanerJob, interval) {
+				continue
+			}
+			if err := releaseExpiredTaskLocks(time.Now().UTC()); err != nil {
+				fmt.Printf("Failed to release expired task locks. error:%+v\n", err)
+			}
+		}
+	}()
+}
+
+// releaseExpiredTaskLocks releases locks expired at specified time, and sends message of them
+func releaseExpiredTaskLocks(now time.Time) error {
+	tx := orm.GetDB().Begin()
+	locks, serr := service.NewTaskLockService(tx, model.SystemUser).ReleaseExpiredTaskLocks(now)
+	if serr != nil {
+		api.Rollback(tx)
+		return serr
+	}
+	if serr = api.Commit(tx); serr != nil {
+		return serr
+	}
+	if len(locks) == 0 || EndPoint.ws == nil {
+		return nil
+	}
+	taskIDs := make([]string, 0, len(locks))
+	for _, lock := range locks {
+		taskIDs = append(taskIDs, lock.TaskID)
+	}
+	// Boards are unknown here, so sent to subscribers of the tasks and clients not subscribing
+	EndPoint.ws.SendUpdateTaskLockMessage(websocket.Origin{}, nil, nil, taskIDs...)
+	return nil
+}
@@ -0,0 +1,101 @@
+package tasks
+
+import (
+	"net/http"
+	"taskboard-api-go/controller/api"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// get the lock of a task, returns not found if not locked
+func getLock(c *gin.Context) {
+	tx := orm.GetDB() // No transaction
+	find, err := findTaskByPathParameter(c, service.NewTaskService(tx, api.GetActor(c)))
+	if err != nil {
+		return
+	}
+	srvc := service.NewTaskLockService(tx, api.GetActor(c))
+	lock, serr := srvc.FindTaskLock(find, time.Now().UTC())
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	holder, serr := service.NewUserService(tx, api.GetActor(c)).FindUser(&model.User{ID: lock.UserID})
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	res := convertTaskLockResponse(lock, holder)
+	c.IndentedJSON(http.StatusOK, res)
+}
+
+// lock a task before editing
+func acquireLock(c *gin.Context) {
+	changeLock(c, (*service.TaskLockService).AcquireTaskLock)
+}
+
+// extend the lock while editing
+func renewLock(c *gin.Context) {
+	changeLock(c, (*service.TaskLockService).RenewTaskLock)
+}
+
+func changeLock(c *gin.Context,
+	change func(srvc *service.TaskLockService, task *model.Task, now time.Time) (*model.TaskLock, error),
+) {
+	taskID, serr := api.GetPathParameter(c, EndPoint.taskid)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	var find *model.Task
+	var lock *model.TaskLock
+	serr = api.InTransaction(func(tx *gorm.DB) (serr error) {
+		find, serr = service.NewTaskService(tx, api.GetActor(c)).FindTask(&model.Task{ID: taskID})
+		if serr != nil {
+			return
+		}
+		lock, serr = change(service.NewTaskLockService(tx, api.GetActor(c)), find, time.Now().UTC())
+		return
+	})
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+
+	res := convertTaskLockResponse(lock, api.GetActor(c))
+	c.IndentedJSON(http.StatusOK, res)
+
+	// websocket send message
+	EndPoint.ws.SendUpdateTaskLockMessage(api.GetOrigin(c), res, []string{find.BoardID}, find.ID)
+}
+
+// release the lock after editing
+func releaseLock(c *gin.Context) {
+	tx := orm.GetDB().Begin()
+	find, err := findTaskByPathParameter(c, service.NewTaskService(tx, api.GetActor(c)))
+	if err != nil {
+		api.Rollback(tx)
+		return
+	}
+	srvc := service.NewTaskLockService(tx, api.GetActor(c))
+	serr := srvc.ReleaseTaskLock(find, time.Now().UTC())
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	serr = api.Commit(tx)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	c.Status(http.StatusOK)
+
+	// websocket send message
+	EndPoint.ws.SendUpdateTaskLockMessage(api.GetOrigin(c), nil, []string{find.BoardID}, find.ID)
+}
@@ -0,0 +1,32 @@
+package tasks
+
+import (
+	"taskboard-api-go/model"
+	"time"
+)
+
+// TaskID      string    `gorm:"primary_key;size:32"`
+// UserID      string    `gorm:"not null;size:32"`
+// ExpiresDate time.Time `gorm:"not null;index"`
+// CreatedDate time.Time `gorm:"not null"`
+// Version     int       `gorm:"not null"` // Version for optimistic lock
+
+type taskLockResponse struct {
+	TaskID      string `json:"taskId"`
+	UserID      string `json:"userId"`
+	UserName    string `json:"userName"`
+	ExpiresDate string `json:"expiresDate"`
+	CreatedDate string `json:"createDate"`
+	Version     int    `json:"version"`
+}
+
+func convertTaskLockResponse(lock *model.TaskLock, holder *model.User) *taskLockResponse {
+	return &taskLockResponse{
+		TaskID:      lock.TaskID,
+		UserID:      lock.UserID,
+		UserName:    holder.Name,
+		ExpiresDate: lock.ExpiresDate.Format(time.RFC3339),
+		CreatedDate: lock.CreatedDate.Format(time.RFC3339),
+		Version:     lock.Version,
+	}
+}
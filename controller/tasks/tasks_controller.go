@@ -1,32 +1,46 @@
 package tasks
 
 import (
+	"fmt"
 	"net/http"
 	"taskboard-api-go/controller/api"
 	"taskboard-api-go/controller/websocket"
 	"taskboard-api-go/model"
 	"taskboard-api-go/orm"
+	"taskboard-api-go/repository"
 	"taskboard-api-go/service"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 )
 
 type endPoint struct {
-	tasks           string
-	taskorders      string
-	taskid          string
-	boardid         string
-	taskboardFromID string
-	ws              *websocket.WsManager
+	tasks      string
+	taskorders string
+	taskid     string
+	blockers   string
+	blockerid  string
+	lock       string
+	boardid    string
+	label      string
+	dueBefore  string
+	overdue    string
+	ws         *websocket.WsManager
 }
 
 // EndPoint presents boards endpoint
 var EndPoint = endPoint{
-	tasks:           "/tasks",
-	taskorders:      "/taskorders",
-	taskid:          "taskid",
-	boardid:         "boardid",
-	taskboardFromID: "taskboard-from-id",
+	tasks:      "/tasks",
+	taskorders: "/taskorders",
+	taskid:     "taskid",
+	blockers:   "blockers",
+	blockerid:  "blockerid",
+	lock:       "lock",
+	boardid:    "boardid",
+	label:      "label",
+	dueBefore:  "dueBefore",
+	overdue:    "overdue",
 }
 
 // SetWsManager sets websocket manager to EndPoint
@@ -42,67 +56,118 @@ func (p *endPoint) RegisterRoute(route *gin.RouterGroup) (err error) {
 	route.PUT(p.tasks+"/:"+p.taskid, update)
 	route.DELETE(p.tasks+"/:"+p.taskid, delete)
 	route.PUT(p.taskorders, updateTaskOrders)
+	route.POST(p.tasks+"/:"+p.taskid+"/"+p.blockers, addBlocker)
+	route.DELETE(p.tasks+"/:"+p.taskid+"/"+p.blockers+"/:"+p.blockerid, removeBlocker)
+	route.GET(p.tasks+"/:"+p.taskid+"/"+p.lock, getLock)
+	route.POST(p.tasks+"/:"+p.taskid+"/"+p.lock, acquireLock)
+	route.PUT(p.tasks+"/:"+p.taskid+"/"+p.lock, renewLock)
+	route.DELETE(p.tasks+"/:"+p.taskid+"/"+p.lock, releaseLock)
 	return
 }
 
 func list(c *gin.Context) {
 	tx := orm.GetDB() // No transction
-	srvc := service.NewTaskService(tx)
+	srvc := service.NewTaskService(tx, api.GetActor(c))
 	boardID := c.Query(EndPoint.boardid)
 	condition := &model.Task{}
 	if boardID != "" {
 		condition = &model.Task{BoardID: boardID}
 	}
-	tasks, serr := srvc.FindTasks(condition, []string{"disp_order, created_date, name"})
+	filter, serr := getTaskFilterByQuery(c)
 	if serr != nil {
 		api.SetErrorStatus(c, serr)
 		return
 	}
-	res := convertListTaskResponse(tasks)
+	tasks, serr := srvc.FindTasksByFilter(condition, filter, []string{"sort_rank, created_date, name"})
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	relations, serr := srvc.FindTaskRelations(tasks)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	res := convertListTaskResponse(tasks, relations)
 	c.IndentedJSON(http.StatusOK, res)
 }
 
 func create(c *gin.Context) {
-	task, serr := getTaskByCreateRequest(c)
+	task, labelIDs, serr := getTaskByCreateRequest(c)
 	if serr != nil {
 		api.SetErrorStatus(c, serr)
 		return
 	}
 
-	// create task
-	tx := orm.GetDB().Begin()
-	srvc := service.NewTaskService(tx)
-	serr = srvc.CreateTask(task)
-	if serr != nil {
-		api.Rollback(tx)
-		api.SetErrorStatus(c, serr)
+	// create task, retried if the rank is taken by another task created concurrently
+	var relations *service.TaskRelations
+	serr = api.InTransaction(func(tx *gorm.DB) (serr error) {
+		srvc := service.NewTaskService(tx, api.GetActor(c))
+		if serr = srvc.CreateTask(task); serr != nil {
+			return
+		}
+		relations, serr = setTaskLabels(srvc, task, labelIDs)
 		return
-	}
-	serr = api.Commit(tx)
+	})
 	if serr != nil {
 		api.SetErrorStatus(c, serr)
 		return
 	}
 
-	res := convertTaskResponse(task)
+	res := convertTaskResponse(task, relations)
 	c.IndentedJSON(http.StatusOK, res)
 
 	// websocket send message
-	EndPoint.ws.SendUpdateTaskBoardMessage(c.GetHeader(EndPoint.taskboardFromID), task.BoardID)
+	EndPoint.ws.SendUpdateTaskBoardMessage(api.GetOrigin(c), res, task.BoardID)
 }
 
 func get(c *gin.Context) {
 	tx := orm.GetDB() // No transaction
-	srvc := service.NewTaskService(tx)
+	srvc := service.NewTaskService(tx, api.GetActor(c))
 	find, err := findTaskByPathParameter(c, srvc)
 	if err != nil {
 		api.Rollback(tx)
 		return
 	}
-	res := convertTaskResponse(find)
+	relations, serr := srvc.FindTaskRelations([]model.Task{*find})
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	res := convertTaskResponse(find, relations)
+	if api.CheckNotModified(c, res) {
+		return
+	}
 	c.IndentedJSON(http.StatusOK, res)
 }
 
+// getTaskFilterByQuery returns filter by query parameters (label, dueBefore, overdue)
+func getTaskFilterByQuery(c *gin.Context) (*repository.TaskFilter, error) {
+	filter := &repository.TaskFilter{
+		LabelIDs: c.QueryArray(EndPoint.label),
+	}
+	dueBefore, serr := parseDate(c.Query(EndPoint.dueBefore))
+	if serr != nil {
+		return nil, serr
+	}
+	filter.DueBefore = dueBefore
+	if c.Query(EndPoint.overdue) == "true" {
+		now := time.Now().UTC()
+		filter.OverdueAt = &now
+	}
+	return filter, nil
+}
+
+// setTaskLabels sets labels of task if labelIDs is not nil, and returns relations of the task
+func setTaskLabels(srvc *service.TaskService, task *model.Task, labelIDs []string) (*service.TaskRelations, error) {
+	if labelIDs != nil {
+		if serr := srvc.SetTaskLabels(task, labelIDs); serr != nil {
+			return nil, serr
+		}
+	}
+	return srvc.FindTaskRelations([]model.Task{*task})
+}
+
 func findTaskByPathParameter(c *gin.Context, srvc *service.TaskService) (find *model.Task, serr error) {
 	taskID, serr := api.GetPathParameter(c, EndPoint.taskid)
 	if serr != nil {
@@ -117,51 +182,83 @@ func findTaskByPathParameter(c *gin.Context, srvc *service.TaskService) (find *m
 	return
 }
 
-func update(c *gin.Context) {
-	tx := orm.GetDB().Begin()
-	srvc := service.NewTaskService(tx)
-	find, err := findTaskByPathParameter(c, srvc)
-	if err != nil {
-		api.Rollback(tx)
-		return
+// checkIfMatch checks If-Match header with the response of the current task, which includes its relations
+func checkIfMatch(c *gin.Context, srvc *service.TaskService, find *model.Task) (bool, error) {
+	relations, serr := srvc.FindTaskRelations([]model.Task{*find})
+	if serr != nil {
+		return false, serr
 	}
-	task, serr := getTaskByUpdateRequest(c, find)
+	return api.CheckIfMatch(c, convertTaskResponse(find, relations), find.Version)
+}
+
+func update(c *gin.Context) {
+	taskID, serr := api.GetPathParameter(c, EndPoint.taskid)
 	if serr != nil {
-		api.Rollback(tx)
 		api.SetErrorStatus(c, serr)
 		return
 	}
-
-	// update task
-	serr = srvc.UpdateTask(find, task)
+	req, serr := getUpdateRequest(c)
 	if serr != nil {
-		api.Rollback(tx)
 		api.SetErrorStatus(c, serr)
 		return
 	}
-	serr = api.Commit(tx)
+
+	// update task, retried if the new rank is taken by tasks moved concurrently
+	var find, task *model.Task
+	var relations *service.TaskRelations
+	serr = api.InTransaction(func(tx *gorm.DB) (serr error) {
+		srvc := service.NewTaskService(tx, api.GetActor(c))
+		find, serr = srvc.FindTask(&model.Task{ID: taskID})
+		if serr != nil {
+			return
+		}
+		matched, serr := checkIfMatch(c, srvc, find)
+		if serr != nil {
+			return
+		}
+		var labelIDs []string
+		task, labelIDs, serr = getTaskByUpdateRequest(req, find)
+		if serr != nil {
+			return
+		}
+		if matched {
+			// If-Match is used as optimistic lock token instead of version in request
+			task.Version = find.Version
+		}
+		if serr = srvc.UpdateTask(find, task); serr != nil {
+			return
+		}
+		relations, serr = setTaskLabels(srvc, task, labelIDs)
+		return
+	})
 	if serr != nil {
 		api.SetErrorStatus(c, serr)
 		return
 	}
 
-	res := convertTaskResponse(task)
+	res := convertTaskResponse(task, relations)
+	api.SetETag(c, res)
 	c.IndentedJSON(http.StatusOK, res)
 
 	// websocket send message
-	EndPoint.ws.SendUpdateTaskMessage(c.GetHeader(EndPoint.taskboardFromID), task.ID)
+	EndPoint.ws.SendUpdateTaskMessage(api.GetOrigin(c), res, []string{find.BoardID, task.BoardID}, task.ID)
 }
 
 func delete(c *gin.Context) {
 	tx := orm.GetDB().Begin()
-	srvc := service.NewTaskService(tx)
+	srvc := service.NewTaskService(tx, api.GetActor(c))
 	find, err := findTaskByPathParameter(c, srvc)
 	if err != nil {
 		api.Rollback(tx)
 		return
 	}
+	if _, serr := checkIfMatch(c, srvc, find); serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
 	// delete task
-	serr := srvc.DeleteTask(find)
+	attachments, serr := srvc.DeleteTask(find)
 	if serr != nil {
 		api.Rollback(tx)
 		api.SetErrorStatus(c, serr)
@@ -172,10 +269,14 @@ func delete(c *gin.Context) {
 		api.SetErrorStatus(c, serr)
 		return
 	}
+	// Contents are deleted after commit, the task is already deleted even if it fails
+	if serr = service.DeleteAttachmentContents(attachments); serr != nil {
+		fmt.Printf("Failed to delete contents of attachments. error:%+v\n", serr)
+	}
 	c.Status(http.StatusOK)
 
 	// websocket send message
-	EndPoint.ws.SendUpdateTaskBoardMessage(c.GetHeader(EndPoint.taskboardFromID), find.BoardID)
+	EndPoint.ws.SendUpdateTaskBoardMessage(api.GetOrigin(c), nil, find.BoardID)
 }
 
 // update order of tasks
@@ -185,11 +286,70 @@ func updateTaskOrders(c *gin.Context) {
 		api.SetErrorStatus(c, serr)
 		return
 	}
+	fromBoardID := ""
+	serr = api.InTransaction(func(tx *gorm.DB) (serr error) {
+		srvc := service.NewTaskService(tx, api.GetActor(c))
+		fromBoardID, serr = srvc.UpdateTaskOrders(req.TaskID, req.ToBoardID, req.PrevTaskID, req.NextTaskID)
+		return
+	})
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	c.Status(http.StatusOK)
+
+	// websocket send message
+	req.FromBoardID = fromBoardID
+	if req.FromBoardID == req.ToBoardID {
+		EndPoint.ws.SendUpdateTaskBoardMessage(api.GetOrigin(c), req, req.FromBoardID)
+	} else {
+		EndPoint.ws.SendUpdateTaskBoardMessage(api.GetOrigin(c), req, req.FromBoardID, req.ToBoardID)
+	}
+}
+
+// add a task which blocks the task
+func addBlocker(c *gin.Context) {
+	req, serr := getAddBlockerRequest(c)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	changeBlocker(c, req.TaskID, (*service.TaskService).AddTaskDependency)
+}
+
+// remove a task which blocks the task
+func removeBlocker(c *gin.Context) {
+	blockerID, serr := api.GetPathParameter(c, EndPoint.blockerid)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	changeBlocker(c, blockerID, (*service.TaskService).RemoveTaskDependency)
+}
+
+func changeBlocker(c *gin.Context, blockerID string,
+	change func(srvc *service.TaskService, blocking *model.Task, blocked *model.Task) error,
+) {
 	tx := orm.GetDB().Begin()
-	srvc := service.NewTaskService(tx)
-	serr = srvc.UpdateTaskOrders(
-		req.TaskID, req.FromBoardID, req.FromDispOrder, req.ToBoardID, req.ToDispOrder,
-	)
+	srvc := service.NewTaskService(tx, api.GetActor(c))
+	find, err := findTaskByPathParameter(c, srvc)
+	if err != nil {
+		api.Rollback(tx)
+		return
+	}
+	blocker, serr := srvc.FindTask(&model.Task{ID: blockerID})
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	serr = change(srvc, blocker, find)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	relations, serr := srvc.FindTaskRelations([]model.Task{*find})
 	if serr != nil {
 		api.Rollback(tx)
 		api.SetErrorStatus(c, serr)
@@ -200,12 +360,10 @@ func updateTaskOrders(c *gin.Context) {
 		api.SetErrorStatus(c, serr)
 		return
 	}
-	c.Status(http.StatusOK)
+
+	res := convertTaskResponse(find, relations)
+	c.IndentedJSON(http.StatusOK, res)
 
 	// websocket send message
-	if req.FromBoardID == req.ToBoardID {
-		EndPoint.ws.SendUpdateTaskBoardMessage(c.GetHeader(EndPoint.taskboardFromID), req.FromBoardID)
-	} else {
-		EndPoint.ws.SendUpdateTaskBoardMessage(c.GetHeader(EndPoint.taskboardFromID), req.FromBoardID, req.ToBoardID)
-	}
+	EndPoint.ws.SendUpdateTaskMessage(api.GetOrigin(c), res, []string{find.BoardID, blocker.BoardID}, find.ID, blocker.ID)
 }
@@ -14,81 +14,158 @@ import (
 // Description    string         `gorm:"size:8000"`
 // AssigneeUserID sql.NullString `gorm:"size:32"`           // Null or String
 // BoardID        string         `gorm:"not null; size:32"` // Default is IceboxBoardID
-// DispOrder      int            `gorm:"not null"`
+// Rank           string         `gorm:"column:sort_rank;not null;size:255"`
 // CreatedDate    time.Time      `gorm:"not null"`
 // IsClosed       bool           `gorm:"not null"`
 // Version        int            `gorm:"not null"` // Version for optimistic lock
 // EstimateSize   int
+// StartDate      *time.Time // Null or Time
+// DueDate        *time.Time `gorm:"index"` // Null or Time
+// ParentTaskID   sql.NullString `gorm:"size:32;index"` // Null or String
 
 type taskResponse struct {
-	ID             string `json:"id"`
-	Name           string `json:"name"`
-	Description    string `json:"description"`
-	AssigneeUserID string `json:"assigneeUserId"`
-	BoardID        string `json:"boardId"`
-	DispOrder      int    `json:"dispOrder"`
-	CreatedDate    string `json:"createDate"`
-	IsClosed       bool   `json:"isClosed"`
-	Version        int    `json:"version"`
-	EstimateSize   int    `json:"estimateSize"`
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	Description     string   `json:"description"`
+	AssigneeUserID  string   `json:"assigneeUserId"`
+	BoardID         string   `json:"boardId"`
+	Rank            string   `json:"rank"`
+	CreatedDate     string   `json:"createDate"`
+	IsClosed        bool     `json:"isClosed"`
+	Version         int      `json:"version"`
+	EstimateSize    int      `json:"estimateSize"`
+	LabelIDs        []string `json:"labelIds"`
+	StartDate       string   `json:"startDate"`
+	DueDate         string   `json:"dueDate"`
+	IsOverdue       bool     `json:"isOverdue"`
+	ParentTaskID    string   `json:"parentTaskId"`
+	Progress        progress `json:"progress"`
+	BlockingTaskIDs []string `json:"blockingTaskIds"`
+	BlockedTaskIDs  []string `json:"blockedTaskIds"`
+}
+
+type addBlockerRequest struct {
+	TaskID string `json:"taskId"` // ID of the task which blocks
+}
+
+type progress struct {
+	ChecklistDone       int `json:"checklistDone"`
+	ChecklistTotal      int `json:"checklistTotal"`
+	SubtaskDone         int `json:"subtaskDone"`
+	SubtaskTotal        int `json:"subtaskTotal"`
+	SubtaskEstimateSize int `json:"subtaskEstimateSize"`
 }
 
 type createRequest struct {
-	Name           string `json:"name"`
-	Description    string `json:"description"`
-	AssigneeUserID string `json:"assigneeUserId"`
-	BoardID        string `json:"boardId"`
-	IsClosed       bool   `json:"isClosed"`
-	EstimateSize   int    `json:"estimateSize"`
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	AssigneeUserID string   `json:"assigneeUserId"`
+	BoardID        string   `json:"boardId"`
+	IsClosed       bool     `json:"isClosed"`
+	EstimateSize   int      `json:"estimateSize"`
+	LabelIDs       []string `json:"labelIds"`
+	StartDate      string   `json:"startDate"` // RFC3339, null if empty
+	DueDate        string   `json:"dueDate"`   // RFC3339, null if empty
+	ParentTaskID   string   `json:"parentTaskId"`
 }
 
 type updateRequest struct {
-	ID             string `json:"id"`
-	Name           string `json:"name"`
-	Description    string `json:"description"`
-	AssigneeUserID string `json:"assigneeUserId"`
-	BoardID        string `json:"boardId"`
-	IsClosed       bool   `json:"isClosed"`
-	Version        int    `json:"version"`
-	EstimateSize   int    `json:"estimateSize"`
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	AssigneeUserID string   `json:"assigneeUserId"`
+	BoardID        string   `json:"boardId"`
+	IsClosed       bool     `json:"isClosed"`
+	Version        int      `json:"version"`
+	EstimateSize   int      `json:"estimateSize"`
+	LabelIDs       []string `json:"labelIds"`  // Labels are not changed if null
+	StartDate      string   `json:"startDate"` // RFC3339, null if empty
+	DueDate        string   `json:"dueDate"`   // RFC3339, null if empty
+	ParentTaskID   string   `json:"parentTaskId"`
 }
 
 type updateTaskOrdersRequest struct {
-	TaskID        string `json:"taskId"`
-	FromBoardID   string `json:"fromBoardId"`
-	FromDispOrder int    `json:"fromDispOrder"`
-	ToBoardID     string `json:"toBoardId"`
-	ToDispOrder   int    `json:"toDispOrder"`
+	TaskID      string `json:"taskId"`
+	FromBoardID string `json:"fromBoardId"` // Set by the server, the value of client is ignored
+	ToBoardID   string `json:"toBoardId"`
+	PrevTaskID  string `json:"prevTaskId"` // Empty to move to the top of the board
+	NextTaskID  string `json:"nextTaskId"` // Empty to move to the bottom of the board
 }
 
-func convertTaskResponse(task *model.Task) *taskResponse {
+func convertTaskResponse(task *model.Task, relations *service.TaskRelations) *taskResponse {
+	taskProgress := relations.Progress[task.ID]
+	if taskProgress == nil {
+		taskProgress = &service.TaskProgress{}
+	}
 	return &taskResponse{
-		ID:             task.ID,
-		Name:           task.Name,
-		Description:    task.Description,
-		AssigneeUserID: task.AssigneeUserID.String,
-		BoardID:        task.BoardID,
-		DispOrder:      task.DispOrder,
-		CreatedDate:    task.CreatedDate.Format(time.RFC3339),
-		IsClosed:       task.IsClosed,
-		Version:        task.Version,
-		EstimateSize:   task.EstimateSize,
+		ID:              task.ID,
+		Name:            task.Name,
+		Description:     task.Description,
+		AssigneeUserID:  task.AssigneeUserID.String,
+		BoardID:         task.BoardID,
+		Rank:            task.Rank,
+		CreatedDate:     task.CreatedDate.Format(time.RFC3339),
+		IsClosed:        task.IsClosed,
+		Version:         task.Version,
+		EstimateSize:    task.EstimateSize,
+		LabelIDs:        emptyIfNil(relations.LabelIDs[task.ID]),
+		StartDate:       formatDate(task.StartDate),
+		DueDate:         formatDate(task.DueDate),
+		IsOverdue:       task.IsOverdue(time.Now().UTC()),
+		ParentTaskID:    task.ParentTaskID.String,
+		BlockingTaskIDs: emptyIfNil(relations.BlockingTaskIDs[task.ID]),
+		BlockedTaskIDs:  emptyIfNil(relations.BlockedTaskIDs[task.ID]),
+		Progress: progress{
+			ChecklistDone:       taskProgress.ChecklistDone,
+			ChecklistTotal:      taskProgress.ChecklistTotal,
+			SubtaskDone:         taskProgress.SubtaskDone,
+			SubtaskTotal:        taskProgress.SubtaskTotal,
+			SubtaskEstimateSize: taskProgress.SubtaskEstimateSize,
+		},
+	}
+}
+
+func emptyIfNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func formatDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format(time.RFC3339)
+}
+
+// parseDate parses RFC3339 date, returns nil if value is empty
+func parseDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, service.NewBadRequestError(err)
 	}
+	date = date.UTC()
+	return &date, nil
 }
 
-func convertListTaskResponse(tasks []model.Task) (res []*taskResponse) {
+func convertListTaskResponse(tasks []model.Task, relations *service.TaskRelations) (res []*taskResponse) {
 	res = make([]*taskResponse, 0, len(tasks))
 	for _, task := range tasks {
-		res = append(res, convertTaskResponse(&task))
+		res = append(res, convertTaskResponse(&task, relations))
 	}
 	return
 }
 
-func getTaskByCreateRequest(c *gin.Context) (*model.Task, error) {
+// getTaskByCreateRequest returns new task and its label IDs
+func getTaskByCreateRequest(c *gin.Context) (*model.Task, []string, error) {
 	var req createRequest
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		return nil, service.NewBadRequestError(err)
+		return nil, nil, service.NewBadRequestError(err)
 	}
 	task := model.NewTask(
 		req.Name,
@@ -99,15 +176,28 @@ func getTaskByCreateRequest(c *gin.Context) (*model.Task, error) {
 	task.SetAssigneeUserID(req.AssigneeUserID)
 	task.SetBoardID(req.BoardID)
 	task.EstimateSize = req.EstimateSize
-	return task, nil
+	task.SetParentTaskID(req.ParentTaskID)
+	if task.StartDate, err = parseDate(req.StartDate); err != nil {
+		return nil, nil, err
+	}
+	if task.DueDate, err = parseDate(req.DueDate); err != nil {
+		return nil, nil, err
+	}
+	return task, req.LabelIDs, nil
 }
 
-func getTaskByUpdateRequest(c *gin.Context, find *model.Task) (*model.Task, error) {
+func getUpdateRequest(c *gin.Context) (*updateRequest, error) {
 	var req updateRequest
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
 		return nil, service.NewBadRequestError(err)
 	}
+	return &req, nil
+}
+
+// getTaskByUpdateRequest returns updated task and its label IDs, label IDs are nil if not changed
+func getTaskByUpdateRequest(req *updateRequest, find *model.Task) (*model.Task, []string, error) {
+	var err error
 	newAssigneeUserID := sql.NullString{}
 	if req.AssigneeUserID != "" {
 		// Set only if not empty
@@ -120,14 +210,30 @@ func getTaskByUpdateRequest(c *gin.Context, find *model.Task) (*model.Task, erro
 		Description:    req.Description,
 		AssigneeUserID: newAssigneeUserID,
 		BoardID:        req.BoardID,
-		DispOrder:      find.DispOrder,
+		Rank:           find.Rank,
 		CreatedDate:    find.CreatedDate,
 		IsClosed:       req.IsClosed,
 		Version:        req.Version,
 		EstimateSize:   req.EstimateSize,
 	}
 	task.SetAssigneeUserID(req.AssigneeUserID)
-	return task, nil
+	task.SetParentTaskID(req.ParentTaskID)
+	if task.StartDate, err = parseDate(req.StartDate); err != nil {
+		return nil, nil, err
+	}
+	if task.DueDate, err = parseDate(req.DueDate); err != nil {
+		return nil, nil, err
+	}
+	return task, req.LabelIDs, nil
+}
+
+func getAddBlockerRequest(c *gin.Context) (*addBlockerRequest, error) {
+	var req addBlockerRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return nil, service.NewBadRequestError(err)
+	}
+	return &req, nil
 }
 
 func getUpdateTaskOrdersRequest(c *gin.Context) (*updateTaskOrdersRequest, error) {
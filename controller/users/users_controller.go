@@ -1,45 +1,77 @@
 package users
 
 import (
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"path/filepath"
+	"strings"
 	"taskboard-api-go/controller/api"
 	"taskboard-api-go/controller/websocket"
 	"taskboard-api-go/model"
 	"taskboard-api-go/orm"
 	"taskboard-api-go/service"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type endPoint struct {
-	login           string
-	users           string
-	userid          string
-	taskboardFromID string
-	ws              *websocket.WsManager
+	login   string
+	refresh string
+	users   string
+	userid  string
+	avatar  string
+	avatars string
+	file    string
+	name    string
+	ws      *websocket.WsManager
 }
 
 // EndPoint presents boards endpoint
 var EndPoint = endPoint{
-	login:           "/login",
-	users:           "/users",
-	userid:          "userid",
-	taskboardFromID: "taskboard-from-id",
+	login:   "/login",
+	refresh: "/login/refresh",
+	users:   "/users",
+	userid:  "userid",
+	avatar:  "/avatar",
+	avatars: "/avatars",
+	file:    "file",
+	name:    "name",
 }
 
+// defaultAvatarDir is directory of built-in avatar images
+const defaultAvatarDir = "./static/avatars"
+
+// defaultAvatarURL is base URL of built-in avatar images
+const defaultAvatarURL = "/taskboard/static/avatars/"
+
+// uploadedAvatarURL is base URL of uploaded avatar images
+const uploadedAvatarURL = "/taskboard/avatars/"
+
 // SetWsManager sets websocket manager to EndPoint
 func SetWsManager(ws *websocket.WsManager) {
 	EndPoint.ws = ws
 }
 
+// RegisterPublicRoute registers API endpoints for users which don't require authentication
+func (p *endPoint) RegisterPublicRoute(route *gin.RouterGroup) (err error) {
+	route.POST(p.login, login)
+	route.POST(p.refresh, refresh)
+	// Avatar images are referred from <img> tags which can not send token
+	route.GET(p.avatars, listDefaultAvatars)
+	route.GET(p.avatars+"/:"+p.name, getAvatar)
+	return
+}
+
 // RegisterRoute registers API endpoints for users
 func (p *endPoint) RegisterRoute(route *gin.RouterGroup) (err error) {
-	route.POST(p.login, login)
 	route.GET(p.users, list)
 	route.POST(p.users, create)
 	route.GET(p.users+"/:"+p.userid, get)
 	route.PUT(p.users+"/:"+p.userid, update)
 	route.DELETE(p.users+"/:"+p.userid, delete)
+	route.PUT(p.users+"/:"+p.userid+p.avatar, uploadAvatar)
 	return
 }
 
@@ -50,19 +82,47 @@ func login(c *gin.Context) {
 		api.SetErrorStatus(c, serr)
 		return
 	}
-	srvc := service.NewUserService(tx)
+	srvc := service.NewUserService(tx, nil) // No actor before login
 	user, serr := srvc.Login(req.Name, req.Password)
 	if serr != nil {
 		api.SetErrorStatus(c, serr)
 		return
 	}
-	res := convertUserResponse(user)
+	token, serr := service.IssueAuthToken(user.ID, time.Now().UTC())
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	res := convertLoginResponse(user, token)
+	c.IndentedJSON(http.StatusOK, res)
+}
+
+func refresh(c *gin.Context) {
+	tx := orm.GetDB() // No transction
+	req, serr := getRefreshRequest(c)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	srvc := service.NewUserService(tx, nil) // No actor before login
+	now := time.Now().UTC()
+	user, serr := srvc.RefreshLogin(req.RefreshToken, now)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	token, serr := service.IssueAuthToken(user.ID, now)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	res := convertLoginResponse(user, token)
 	c.IndentedJSON(http.StatusOK, res)
 }
 
 func list(c *gin.Context) {
 	tx := orm.GetDB() // No transction
-	srvc := service.NewUserService(tx)
+	srvc := service.NewUserService(tx, api.GetActor(c))
 	users, serr := srvc.FindUsers(&model.User{}, []string{"name"})
 	if serr != nil {
 		api.SetErrorStatus(c, serr)
@@ -81,7 +141,7 @@ func create(c *gin.Context) {
 
 	// create user
 	tx := orm.GetDB().Begin()
-	srvc := service.NewUserService(tx)
+	srvc := service.NewUserService(tx, api.GetActor(c))
 	serr = srvc.CreateUser(user)
 	if serr != nil {
 		api.Rollback(tx)
@@ -98,18 +158,21 @@ func create(c *gin.Context) {
 	c.IndentedJSON(http.StatusOK, res)
 
 	// websocket send message
-	EndPoint.ws.SendUpdateUserMessage(c.GetHeader(EndPoint.taskboardFromID))
+	EndPoint.ws.SendUpdateUserMessage(api.GetOrigin(c), res, user.ID)
 }
 
 func get(c *gin.Context) {
 	tx := orm.GetDB() // No transaction
-	srvc := service.NewUserService(tx)
+	srvc := service.NewUserService(tx, api.GetActor(c))
 	find, err := findUserByPathParameter(c, srvc)
 	if err != nil {
 		api.Rollback(tx)
 		return
 	}
 	res := convertUserResponse(find)
+	if api.CheckNotModified(c, res) {
+		return
+	}
 	c.IndentedJSON(http.StatusOK, res)
 }
 
@@ -129,21 +192,31 @@ func findUserByPathParameter(c *gin.Context, srvc *service.UserService) (find *m
 
 func update(c *gin.Context) {
 	tx := orm.GetDB().Begin()
-	srvc := service.NewUserService(tx)
+	srvc := service.NewUserService(tx, api.GetActor(c))
 	find, err := findUserByPathParameter(c, srvc)
 	if err != nil {
 		api.Rollback(tx)
 		return
 	}
+	matched, serr := api.CheckIfMatch(c, convertUserResponse(find), find.Version)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
 	user, serr := getUserByUpdateRequest(c, find)
 	if serr != nil {
 		api.Rollback(tx)
 		api.SetErrorStatus(c, serr)
 		return
 	}
+	if matched {
+		// If-Match is used as optimistic lock token instead of version in request
+		user.Version = find.Version
+	}
 
 	// update user
-	serr = srvc.UpdateUser(user)
+	serr = srvc.UpdateUser(find, user)
 	if serr != nil {
 		api.Rollback(tx)
 		api.SetErrorStatus(c, serr)
@@ -154,22 +227,31 @@ func update(c *gin.Context) {
 		api.SetErrorStatus(c, serr)
 		return
 	}
+	if find.Avatar != user.Avatar {
+		deleteUnusedAvatar(c, find)
+	}
 
 	res := convertUserResponse(user)
+	api.SetETag(c, res)
 	c.IndentedJSON(http.StatusOK, res)
 
 	// websocket send message
-	EndPoint.ws.SendUpdateUserMessage(c.GetHeader(EndPoint.taskboardFromID), user.ID)
+	EndPoint.ws.SendUpdateUserMessage(api.GetOrigin(c), res, user.ID)
 }
 
 func delete(c *gin.Context) {
 	tx := orm.GetDB().Begin()
-	srvc := service.NewUserService(tx)
+	srvc := service.NewUserService(tx, api.GetActor(c))
 	find, err := findUserByPathParameter(c, srvc)
 	if err != nil {
 		api.Rollback(tx)
 		return
 	}
+	if _, serr := api.CheckIfMatch(c, convertUserResponse(find), find.Version); serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
 	// delete user
 	serr := srvc.DeleteUser(find)
 	if serr != nil {
@@ -182,8 +264,104 @@ func delete(c *gin.Context) {
 		api.SetErrorStatus(c, serr)
 		return
 	}
+	deleteUnusedAvatar(c, find)
 	c.Status(http.StatusOK)
 
 	// websocket send message
-	EndPoint.ws.SendUpdateUserMessage(c.GetHeader(EndPoint.taskboardFromID))
+	EndPoint.ws.SendUpdateUserMessage(api.GetOrigin(c), nil, find.ID)
+}
+
+// upload avatar image of user by multipart form
+func uploadAvatar(c *gin.Context) {
+	header, serr := api.GetFormFile(c, EndPoint.file, service.MaxAvatarSize)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		api.SetErrorStatus(c, service.NewBadRequestError(err))
+		return
+	}
+	defer file.Close()
+
+	tx := orm.GetDB().Begin()
+	srvc := service.NewUserService(tx, api.GetActor(c))
+	find, err := findUserByPathParameter(c, srvc)
+	if err != nil {
+		api.Rollback(tx)
+		return
+	}
+	if _, serr := api.CheckIfMatch(c, convertUserResponse(find), find.Version); serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	user, serr := srvc.UploadAvatar(find, file)
+	if serr != nil {
+		api.Rollback(tx)
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	serr = api.Commit(tx)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	if find.Avatar != user.Avatar {
+		deleteUnusedAvatar(c, find)
+	}
+
+	res := convertUserResponse(user)
+	api.SetETag(c, res)
+	c.IndentedJSON(http.StatusOK, res)
+
+	// websocket send message
+	EndPoint.ws.SendUpdateUserMessage(api.GetOrigin(c), res, user.ID)
+}
+
+// deleteUnusedAvatar deletes images of the old avatar after commit, the user is already updated even if it fails
+func deleteUnusedAvatar(c *gin.Context, old *model.User) {
+	srvc := service.NewUserService(orm.GetDB(), api.GetActor(c)) // No transaction
+	if serr := srvc.DeleteUnusedAvatar(old); serr != nil {
+		fmt.Printf("Failed to delete unused avatar. error:%+v\n", serr)
+	}
+}
+
+// get uploaded avatar image
+func getAvatar(c *gin.Context) {
+	name, serr := api.GetPathParameter(c, EndPoint.name)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	reader, serr := service.OpenAvatar(name)
+	if serr != nil {
+		api.SetErrorStatus(c, serr)
+		return
+	}
+	defer reader.Close()
+	// File name contains hash of content, so it is never changed
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.DataFromReader(http.StatusOK, -1, "image/png", reader, nil)
+}
+
+// list built-in avatar images which can be selected as avatar
+func listDefaultAvatars(c *gin.Context) {
+	files, err := ioutil.ReadDir(defaultAvatarDir)
+	if err != nil {
+		api.SetErrorStatus(c, service.NewSvcError(service.ErrorCodeUnexpected, err, "Failed to read default avatars"))
+		return
+	}
+	res := make([]*defaultAvatarResponse, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".png" {
+			continue
+		}
+		res = append(res, &defaultAvatarResponse{
+			Name: strings.TrimSuffix(file.Name(), ".png"),
+			URL:  defaultAvatarURL + file.Name(),
+		})
+	}
+	c.IndentedJSON(http.StatusOK, res)
 }
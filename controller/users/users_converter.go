@@ -1,8 +1,10 @@
 package users
 
 import (
+	"strconv"
 	"taskboard-api-go/model"
 	"taskboard-api-go/service"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,6 +13,7 @@ import (
 // Name         string `gorm:"size:255;not null;unique"`
 // PasswordHash string `gorm:"size:255;not null;"`
 // Avatar       string `gorm:"size:255"`
+// Role         string `gorm:"not null;size:32;default:'member'"`
 // Version      int    `gorm:"not null"` // Version for optimistic lock
 
 type loginRequest struct {
@@ -18,17 +21,37 @@ type loginRequest struct {
 	Password string `json:"password"`
 }
 
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
 type userResponse struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Avatar  string `json:"avatar"`
-	Version int    `json:"version"`
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Avatar     string            `json:"avatar"`
+	AvatarURLs map[string]string `json:"avatarUrls"` // URLs of uploaded avatar keyed by size, empty if built-in
+	Role       string            `json:"role"`
+	Version    int               `json:"version"`
+}
+
+type defaultAvatarResponse struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type loginResponse struct {
+	*userResponse
+	Token                 string `json:"token"`
+	TokenExpiresAt        string `json:"tokenExpiresAt"`
+	RefreshToken          string `json:"refreshToken"`
+	RefreshTokenExpiresAt string `json:"refreshTokenExpiresAt"`
 }
 
 type createRequest struct {
 	Name     string `json:"name"`
 	Password string `json:"password"`
 	Avatar   string `json:"avatar"`
+	Role     string `json:"role"`
 }
 
 type updateRequest struct {
@@ -36,6 +59,7 @@ type updateRequest struct {
 	Name     string `json:"name"`
 	Password string `json:"password"`
 	Avatar   string `json:"avatar"`
+	Role     string `json:"role"`
 	Version  int    `json:"version"`
 }
 
@@ -48,15 +72,48 @@ func getLoginRequest(c *gin.Context) (*loginRequest, error) {
 	return &req, nil
 }
 
+func getRefreshRequest(c *gin.Context) (*refreshRequest, error) {
+	var req refreshRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return nil, service.NewBadRequestError(err)
+	}
+	return &req, nil
+}
+
+func convertLoginResponse(user *model.User, token *service.AuthToken) *loginResponse {
+	return &loginResponse{
+		userResponse:          convertUserResponse(user),
+		Token:                 token.AccessToken,
+		TokenExpiresAt:        token.AccessExpiresAt.Format(time.RFC3339),
+		RefreshToken:          token.RefreshToken,
+		RefreshTokenExpiresAt: token.RefreshExpiresAt.Format(time.RFC3339),
+	}
+}
+
 func convertUserResponse(user *model.User) *userResponse {
 	return &userResponse{
-		ID:      user.ID,
-		Name:    user.Name,
-		Avatar:  user.Avatar,
-		Version: user.Version,
+		ID:         user.ID,
+		Name:       user.Name,
+		Avatar:     user.Avatar,
+		AvatarURLs: convertAvatarURLs(user),
+		Role:       user.Role,
+		Version:    user.Version,
 	}
 }
 
+func convertAvatarURLs(user *model.User) map[string]string {
+	result := make(map[string]string, len(service.AvatarSizes))
+	hash := user.UploadedAvatarHash()
+	if hash == "" {
+		return result
+	}
+	for _, size := range service.AvatarSizes {
+		result[strconv.Itoa(size)] = uploadedAvatarURL + service.AvatarFileName(hash, size)
+	}
+	return result
+}
+
 func convertListUserResponse(users []model.User) (res []*userResponse) {
 	res = make([]*userResponse, 0, len(users))
 	for _, user := range users {
@@ -71,7 +128,11 @@ func getUserByCreateRequest(c *gin.Context) (*model.User, error) {
 	if err != nil {
 		return nil, service.NewBadRequestError(err)
 	}
-	return model.NewUser(req.Name, req.Password, req.Avatar), nil
+	role := req.Role
+	if role == "" {
+		role = model.RoleMember
+	}
+	return model.NewUser(req.Name, req.Password, req.Avatar, role), nil
 }
 
 func getUserByUpdateRequest(c *gin.Context, find *model.User) (*model.User, error) {
@@ -84,8 +145,13 @@ func getUserByUpdateRequest(c *gin.Context, find *model.User) (*model.User, erro
 		ID:      find.ID,
 		Name:    req.Name,
 		Avatar:  req.Avatar,
+		Role:    req.Role,
 		Version: req.Version,
 	}
+	if user.Role == "" {
+		// Keep current role if not specified
+		user.Role = find.Role
+	}
 	user.PasswordHash = find.PasswordHash
 	if req.Password != "" {
 		user.SetPassword(req.Password)
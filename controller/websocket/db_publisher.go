@@ -0,0 +1,98 @@
+package websocket
+
+import (
+	"fmt"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/repository"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Default settings of DBPublisher
+const (
+	DefaultPollInterval   = 500 * time.Millisecond
+	DefaultEventRetention = time.Hour
+	pollLimit             = 1000
+	gapTimeout            = 10 * time.Second
+)
+
+// DBPublisher is a publisher for plural api instances sharing the database.
+// Messages are inserted into realtime event table, and each instance polls new ones.
+// ID of the table is used as sequence number. On PostgreSQL and MySQL, IDs are assigned at insert but
+// become visible at commit, so an event can appear after events with larger IDs, and IDs of failed inserts
+// are never used. Pollers track such a gap of IDs, and don't deliver events after it until the missing event
+// appears or gapTimeout passes. So events are delivered in order of ID unless the insert takes longer than gapTimeout.
+type DBPublisher struct {
+	pollInterval time.Duration
+	retention    time.Duration
+}
+
+// NewDBPublisher returns new instance of DBPublisher.
+// Events older than retention are deleted while polling.
+func NewDBPublisher(pollInterval time.Duration, retention time.Duration) *DBPublisher {
+	return &DBPublisher{
+		pollInterval: pollInterval,
+		retention:    retention,
+	}
+}
+
+// Publish inserts message into realtime event table
+func (p *DBPublisher) Publish(message []byte) error {
+	repo := repository.NewRealtimeEventRepository(orm.GetDB()) // No transaction
+	err := repo.CreateRealtimeEvent(model.NewRealtimeEvent(string(message), time.Now().UTC()))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Start starts polling messages published after now
+func (p *DBPublisher) Start(handler func(seq int64, message []byte)) (int64, error) {
+	repo := repository.NewRealtimeEventRepository(orm.GetDB()) // No transaction
+	lastSeq, err := repo.MaxRealtimeEventID()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	go p.poll(lastSeq, handler)
+	return lastSeq, nil
+}
+
+func (p *DBPublisher) poll(lastSeq int64, handler func(seq int64, message []byte)) {
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+	lastCleaned := time.Now()
+	var gapFound time.Time // When the event next to lastSeq is found missing, zero if no gap
+	for range ticker.C {
+		repo := repository.NewRealtimeEventRepository(orm.GetDB()) // No transaction
+		events, err := repo.FindRealtimeEventsAfter(lastSeq, pollLimit)
+		if err != nil {
+			fmt.Printf("Failed to poll realtime events. error:%+v\n", err)
+			continue
+		}
+		for _, event := range events {
+			if event.ID != lastSeq+1 {
+				// Wait for the missing event which may be committed later, or skip it if its insert failed
+				if gapFound.IsZero() {
+					gapFound = time.Now()
+				}
+				if time.Since(gapFound) < gapTimeout {
+					break
+				}
+			}
+			gapFound = time.Time{}
+			handler(event.ID, []byte(event.Message))
+			lastSeq = event.ID
+		}
+
+		if time.Since(lastCleaned) < p.retention {
+			continue
+		}
+		lastCleaned = time.Now()
+		err = repo.DeleteRealtimeEventsBefore(time.Now().UTC().Add(-p.retention))
+		if err != nil {
+			fmt.Printf("Failed to delete old realtime events. error:%+v\n", err)
+		}
+	}
+}
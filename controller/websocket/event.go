@@ -0,0 +1,106 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ProtocolVersion is version of JSON event protocol
+const ProtocolVersion = 1
+
+// Protocols of websocket messages, negotiated by query parameter "protocol" of websocket endpoint
+const (
+	ProtocolJSON   = "json"   // JSON event (default)
+	ProtocolLegacy = "legacy" // Plain string like "UPDATE_TASKS id1 id2"
+)
+
+const protocolQueryKey = "protocol"
+
+// sinceQueryKey is query parameter of websocket endpoint to replay events after the sequence number
+const sinceQueryKey = "since"
+
+// Definition of kinds of entity which is changed
+const (
+	EventKindTask       = "task"
+	EventKindBoard      = "board"
+	EventKindUser       = "user"
+	EventKindComment    = "comment"
+	EventKindLabel      = "label"
+	EventKindAttachment = "attachment"
+	EventKindTaskLock   = "taskLock"
+)
+
+// Event presents a message sent to clients by JSON protocol
+type Event struct {
+	Version   int         `json:"version"`
+	Seq       int64       `json:"seq"`               // Increases monotonically, current sequence number for CONNECTED and RESYNC_REQUIRED
+	Type      string      `json:"type"`              // Same as legacy message type like UPDATE_TASKS
+	Kind      string      `json:"kind"`              // Kind of changed entity
+	IDs       []string    `json:"ids"`               // Same IDs as legacy message (ex. task IDs for UPDATE_COMMENTS)
+	Actor     string      `json:"actor"`             // User ID who changed, empty if changed by system
+	Timestamp string      `json:"timestamp"`         // RFC3339
+	Payload   interface{} `json:"payload,omitempty"` // Changed entity or diff, null if deleted or unknown
+
+	target *eventTarget // Boards and tasks to route the event, nil to send to all
+}
+
+func newEvent(eventType, kind, actor string, ids []string, payload interface{}) *Event {
+	if ids == nil {
+		ids = []string{}
+	}
+	return &Event{
+		Version:   ProtocolVersion,
+		Type:      eventType,
+		Kind:      kind,
+		IDs:       ids,
+		Actor:     actor,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Payload:   payload,
+	}
+}
+
+// legacyMessage returns message of legacy protocol
+func (e *Event) legacyMessage() []byte {
+	return []byte(fmt.Sprintf("%s %s", e.Type, strings.Join(e.IDs, " ")))
+}
+
+// jsonMessage returns message of JSON protocol
+func (e *Event) jsonMessage() ([]byte, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return data, nil
+}
+
+// requestProtocol returns protocol requested by client
+func requestProtocol(r *http.Request) string {
+	if r.URL.Query().Get(protocolQueryKey) == ProtocolLegacy {
+		return ProtocolLegacy
+	}
+	return ProtocolJSON
+}
+
+// requestSince returns sequence number of the last event received by reconnecting client.
+// requested is false if client doesn't request replay, and seq is -1 if the value is invalid.
+func requestSince(r *http.Request) (seq int64, requested bool) {
+	return parseSince(r.URL.Query().Get(sinceQueryKey))
+}
+
+// parseSince parses sequence number of the last event received by client, same as requestSince
+func parseSince(value string) (seq int64, requested bool) {
+	if value == "" {
+		return 0, false
+	}
+	seq, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return -1, true
+	}
+	return seq, true
+}
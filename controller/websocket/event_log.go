@@ -0,0 +1,61 @@
+package websocket
+
+// DefaultEventLogSize is the number of events kept to replay for reconnecting clients
+const DefaultEventLogSize = 1000
+
+// eventLog keeps recent events in a ring buffer. Sequence numbers of events must increase, but can have gaps.
+// It is not thread safe, callers must lock it.
+type eventLog struct {
+	events       []*Event
+	head         int   // Index of the oldest event
+	count        int   // The number of kept events
+	lastSeq      int64 // Sequence number of the latest event, 0 if no event is emitted
+	discardedSeq int64 // Events whose sequence number is less than or equal to it are not kept
+}
+
+func newEventLog(size int) *eventLog {
+	if size <= 0 {
+		// Programing error!!
+		panic("size of event log must be positive")
+	}
+	return &eventLog{
+		events: make([]*Event, size),
+	}
+}
+
+// reset discards all events, and starts keeping events after specified sequence number
+func (l *eventLog) reset(lastSeq int64) {
+	l.head = 0
+	l.count = 0
+	l.lastSeq = lastSeq
+	l.discardedSeq = lastSeq
+}
+
+// append keeps event, the oldest event is discarded if full
+func (l *eventLog) append(event *Event) {
+	l.lastSeq = event.Seq
+	tail := (l.head + l.count) % len(l.events)
+	if l.count < len(l.events) {
+		l.count++
+	} else {
+		l.discardedSeq = l.events[l.head].Seq
+		l.head = (l.head + 1) % len(l.events)
+	}
+	l.events[tail] = event
+}
+
+// since returns events after specified sequence number.
+// ok is false if some of them are already discarded, or seq is unknown (ex. server restarted).
+func (l *eventLog) since(seq int64) (events []*Event, ok bool) {
+	if seq < l.discardedSeq || seq > l.lastSeq {
+		return nil, false
+	}
+	events = []*Event{}
+	for i := 0; i < l.count; i++ {
+		event := l.events[(l.head+i)%len(l.events)]
+		if event.Seq > seq {
+			events = append(events, event)
+		}
+	}
+	return events, true
+}
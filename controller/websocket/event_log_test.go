@@ -0,0 +1,69 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func seqs(events []*Event) []int64 {
+	result := make([]int64, 0, len(events))
+	for _, event := range events {
+		result = append(result, event.Seq)
+	}
+	return result
+}
+
+func TestEventLog_Since(t *testing.T) {
+	l := newEventLog(3)
+	for i := 1; i <= 5; i++ {
+		event := newEvent(updateTasksMessage, EventKindTask, "", nil, nil)
+		event.Seq = int64(i)
+		l.append(event)
+	}
+	assert.Equal(t, int64(5), l.lastSeq)
+
+	t.Run("Kept events are returned", func(t *testing.T) {
+		events, ok := l.since(2)
+		assert.True(t, ok)
+		assert.Equal(t, []int64{3, 4, 5}, seqs(events))
+
+		events, ok = l.since(4)
+		assert.True(t, ok)
+		assert.Equal(t, []int64{5}, seqs(events))
+	})
+
+	t.Run("No event is returned if client is up to date", func(t *testing.T) {
+		events, ok := l.since(5)
+		assert.True(t, ok)
+		assert.Len(t, events, 0)
+	})
+
+	t.Run("Resync is required if events are discarded or unknown", func(t *testing.T) {
+		for _, seq := range []int64{-1, 0, 1, 6} {
+			_, ok := l.since(seq)
+			assert.False(t, ok, "seq=%d", seq)
+		}
+	})
+}
+
+func TestEventLog_SinceWithGap(t *testing.T) {
+	l := newEventLog(3)
+	l.reset(10)
+	for _, seq := range []int64{12, 15} {
+		event := newEvent(updateTasksMessage, EventKindTask, "", nil, nil)
+		event.Seq = seq
+		l.append(event)
+	}
+
+	events, ok := l.since(10)
+	assert.True(t, ok)
+	assert.Equal(t, []int64{12, 15}, seqs(events))
+	events, ok = l.since(13)
+	assert.True(t, ok)
+	assert.Equal(t, []int64{15}, seqs(events))
+
+	// Events before reset are unknown
+	_, ok = l.since(9)
+	assert.False(t, ok)
+}
@@ -0,0 +1,180 @@
+package websocket
+
+import (
+	"sort"
+	"time"
+
+	"gopkg.in/olahol/melody.v1"
+)
+
+// Viewing presents the board and the task which a client reports viewing
+type Viewing struct {
+	BoardID string `json:"boardId"`
+	TaskID  string `json:"taskId,omitempty"` // Empty if not viewing a task
+}
+
+// Presence presents whether a user is online, and where the user is viewing
+type Presence struct {
+	UserID   string    `json:"userId"`
+	Online   bool      `json:"online"`
+	LastSeen time.Time `json:"lastSeen"`
+	Viewing  []Viewing `json:"viewing"` // Viewing of all connections of the user, empty if offline
+}
+
+// IsViewingBoard checks whether the user is viewing specified board
+func (p *Presence) IsViewingBoard(boardID string) bool {
+	for _, viewing := range p.Viewing {
+		if viewing.BoardID == boardID {
+			return true
+		}
+	}
+	return false
+}
+
+// Presences returns presence of users who have connected since the server started, sorted by user ID.
+// Users connected to other api instances are included, as reported by the instances through publisher.
+// (Presences reported by an instance which stopped without notifying remain until it starts again)
+func (w *WsManager) Presences() []Presence {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	userIDs := make([]string, 0, len(w.lastSeen))
+	for userID := range w.lastSeen {
+		userIDs = append(userIDs, userID)
+	}
+	for userID := range w.remotePresences {
+		if _, exists := w.lastSeen[userID]; !exists {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	sort.Strings(userIDs)
+	result := make([]Presence, 0, len(userIDs))
+	for _, userID := range userIDs {
+		result = append(result, w.presence(userID))
+	}
+	return result
+}
+
+// presence returns presence of the user merged among all api instances, caller must lock
+func (w *WsManager) presence(userID string) Presence {
+	presence := Presence{
+		UserID:  userID,
+		Viewing: []Viewing{},
+	}
+	reports := []Presence{w.localPresence(userID)}
+	for _, reported := range w.remotePresences[userID] {
+		reports = append(reports, reported)
+	}
+	found := make(map[Viewing]bool)
+	for _, reported := range reports {
+		presence.Online = presence.Online || reported.Online
+		if reported.LastSeen.After(presence.LastSeen) {
+			presence.LastSeen = reported.LastSeen
+		}
+		for _, viewing := range reported.Viewing {
+			if found[viewing] {
+				continue
+			}
+			found[viewing] = true
+			presence.Viewing = append(presence.Viewing, viewing)
+		}
+	}
+	sortViewing(presence.Viewing)
+	return presence
+}
+
+// localPresence returns presence of the user by connections to this api instance, caller must lock
+func (w *WsManager) localPresence(userID string) Presence {
+	presence := Presence{
+		UserID:   userID,
+		Online:   len(w.userConnections[userID]) > 0,
+		LastSeen: w.lastSeen[userID],
+		Viewing:  []Viewing{},
+	}
+	found := make(map[Viewing]bool)
+	for _, conn := range w.userConnections[userID] {
+		if conn.viewing == (Viewing{}) || found[conn.viewing] {
+			continue
+		}
+		found[conn.viewing] = true
+		presence.Viewing = append(presence.Viewing, conn.viewing)
+	}
+	sortViewing(presence.Viewing)
+	return presence
+}
+
+func sortViewing(viewing []Viewing) {
+	sort.Slice(viewing, func(i, j int) bool {
+		if viewing[i].BoardID != viewing[j].BoardID {
+			return viewing[i].BoardID < viewing[j].BoardID
+		}
+		return viewing[i].TaskID < viewing[j].TaskID
+	})
+}
+
+// touch updates last seen time of the user, caller must lock
+func (w *WsManager) touch(userID string) {
+	w.lastSeen[userID] = time.Now().UTC()
+}
+
+// sendPresence queues presence of the user to publish it to all api instances by unlock, caller must lock.
+// It is not published while locking, because publisher may dispatch it synchronously.
+func (w *WsManager) sendPresence(userID string) {
+	w.pendingPresences = append(w.pendingPresences, w.localPresence(userID))
+}
+
+// unlock releases the lock, and then publishes presences queued while locking
+func (w *WsManager) unlock() {
+	for _, presence := range w.releaseLock() {
+		w.publishPresence(presence)
+	}
+}
+
+// releaseLock releases the lock, and returns presences queued while locking
+func (w *WsManager) releaseLock() []Presence {
+	presences := w.pendingPresences
+	w.pendingPresences = nil
+	w.lock.Unlock()
+	return presences
+}
+
+// publishPresence publishes presence of the user on this api instance
+func (w *WsManager) publishPresence(presence Presence) {
+	event := newEvent(updatePresenceMessage, EventKindUser, presence.UserID, []string{presence.UserID}, nil)
+	w.publish(&publishedEvent{Origin: Origin{UserID: presence.UserID}, Event: event, Instance: w.instanceID, Presence: &presence})
+}
+
+// dispatchPresence records presence reported by an api instance, and sends merged presence of the user to all clients.
+// Presence is not recorded to event log, because it is out of date when replayed. Caller must lock.
+func (w *WsManager) dispatchPresence(instanceID string, reported *Presence) {
+	userID := reported.UserID
+	// Presence of this instance is already up to date by its connections
+	if instanceID != w.instanceID {
+		reports, exists := w.remotePresences[userID]
+		if !exists {
+			reports = make(map[string]Presence)
+			w.remotePresences[userID] = reports
+		}
+		// Presences published concurrently can be delivered out of order
+		if last, exists := reports[instanceID]; exists && last.LastSeen.After(reported.LastSeen) {
+			return
+		}
+		reports[instanceID] = *reported
+	}
+	event := newEvent(updatePresenceMessage, EventKindUser, userID, []string{userID}, w.presence(userID))
+	event.Seq = w.eventLog.lastSeq
+	w.broadcast(event, func(s *melody.Session) bool {
+		return true
+	})
+	for stream := range w.streams {
+		w.sendToStream(stream, event)
+	}
+}
+
+// Pong updates last seen time of the user by pong from client
+func (w *WsManager) Pong(s *melody.Session) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if conn, exists := w.connections[s]; exists {
+		w.touch(conn.userID)
+	}
+}
@@ -0,0 +1,165 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/olahol/melody.v1"
+)
+
+// presenceEvents returns presences notified to the session by UPDATE_PRESENCE messages
+func presenceEvents(t *testing.T, b *fakeBroadcaster, s *melody.Session) []Presence {
+	result := []Presence{}
+	for _, message := range b.messages(s) {
+		event := struct {
+			Type    string   `json:"type"`
+			Payload Presence `json:"payload"`
+		}{}
+		if err := json.Unmarshal(message, &event); err != nil {
+			t.Fatalf("Failed to unmarshal: %+v", err)
+		}
+		if event.Type == updatePresenceMessage {
+			result = append(result, event.Payload)
+		}
+	}
+	return result
+}
+
+func TestWsManager_Presences(t *testing.T) {
+	b := newFakeBroadcaster()
+	w := newTestWsManager(t, b)
+
+	observer := b.newSession("observer", ProtocolJSON)
+	b.connect(w, observer)
+	first := b.newSession("user1", ProtocolJSON)
+	second := b.newSession("user1", ProtocolJSON)
+
+	t.Run("User becomes online by first connection", func(t *testing.T) {
+		b.clear()
+		b.connect(w, first)
+		b.connect(w, second)
+		presences := presenceEvents(t, b, observer)
+		if assert.Len(t, presences, 1) {
+			assert.Equal(t, "user1", presences[0].UserID)
+			assert.True(t, presences[0].Online)
+		}
+	})
+
+	t.Run("Viewing of all connections are merged", func(t *testing.T) {
+		b.clear()
+		w.HandleMessage(first, []byte(`{"type":"VIEW","boardId":"board2"}`))
+		w.HandleMessage(second, []byte(`{"type":"VIEW","boardId":"board1","taskId":"task1"}`))
+		w.HandleMessage(second, []byte(`{"type":"VIEW","boardId":"board1","taskId":"task1"}`)) // Not changed
+		assert.Len(t, presenceEvents(t, b, observer), 2)
+
+		presences := w.Presences()
+		if assert.Len(t, presences, 2) {
+			assert.Equal(t, "observer", presences[0].UserID)
+			assert.Equal(t, "user1", presences[1].UserID)
+			assert.Equal(t, []Viewing{{BoardID: "board1", TaskID: "task1"}, {BoardID: "board2"}}, presences[1].Viewing)
+			assert.True(t, presences[1].IsViewingBoard("board1"))
+			assert.False(t, presences[1].IsViewingBoard("board3"))
+		}
+	})
+
+	t.Run("User becomes offline by last disconnection", func(t *testing.T) {
+		b.clear()
+		b.disconnect(w, first)
+		presences := presenceEvents(t, b, observer)
+		if assert.Len(t, presences, 1) {
+			assert.True(t, presences[0].Online)
+			assert.Equal(t, []Viewing{{BoardID: "board1", TaskID: "task1"}}, presences[0].Viewing)
+		}
+
+		b.clear()
+		b.disconnect(w, second)
+		presences = presenceEvents(t, b, observer)
+		if assert.Len(t, presences, 1) {
+			assert.False(t, presences[0].Online)
+			assert.Len(t, presences[0].Viewing, 0)
+			assert.False(t, presences[0].LastSeen.IsZero())
+		}
+		// Offline user remains with last seen time
+		assert.Len(t, w.Presences(), 2)
+	})
+}
+
+// sharedPublisher delivers messages to all api instances in process, like a publisher sharing the database
+type sharedPublisher struct {
+	lock     sync.Mutex
+	lastSeq  int64
+	handlers []func(seq int64, message []byte)
+}
+
+func (p *sharedPublisher) Publish(message []byte) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.lastSeq++
+	for _, handler := range p.handlers {
+		handler(p.lastSeq, message)
+	}
+	return nil
+}
+
+func (p *sharedPublisher) Start(handler func(seq int64, message []byte)) (int64, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.handlers = append(p.handlers, handler)
+	return p.lastSeq, nil
+}
+
+func TestWsManager_PresencesOfInstances(t *testing.T) {
+	publisher := &sharedPublisher{}
+	b1 := newFakeBroadcaster()
+	w1 := newWsManager(b1)
+	b2 := newFakeBroadcaster()
+	w2 := newWsManager(b2)
+	for _, w := range []*WsManager{w1, w2} {
+		if err := w.start(publisher); err != nil {
+			t.Fatalf("Failed to start: %+v", err)
+		}
+	}
+
+	observer := b2.newSession("observer", ProtocolJSON)
+	b2.connect(w2, observer)
+	first := b1.newSession("user1", ProtocolJSON)
+	second := b2.newSession("user1", ProtocolJSON)
+
+	// Presence on other instance is notified
+	b2.clear()
+	b1.connect(w1, first)
+	w1.HandleMessage(first, []byte(`{"type":"VIEW","boardId":"board1"}`))
+	presences := presenceEvents(t, b2, observer)
+	if assert.Len(t, presences, 2) {
+		assert.Equal(t, "user1", presences[1].UserID)
+		assert.True(t, presences[1].Online)
+		assert.Equal(t, []Viewing{{BoardID: "board1"}}, presences[1].Viewing)
+	}
+
+	// Viewing on both instances are merged, and the user is online while connected to either of them
+	b2.connect(w2, second)
+	w2.HandleMessage(second, []byte(`{"type":"VIEW","boardId":"board2"}`))
+	b2.clear()
+	b1.disconnect(w1, first)
+	presences = presenceEvents(t, b2, observer)
+	if assert.Len(t, presences, 1) {
+		assert.True(t, presences[0].Online)
+		assert.Equal(t, []Viewing{{BoardID: "board2"}}, presences[0].Viewing)
+	}
+	for _, w := range []*WsManager{w1, w2} {
+		presences = w.Presences()
+		if assert.Len(t, presences, 2) {
+			assert.Equal(t, "user1", presences[1].UserID)
+			assert.True(t, presences[1].Online)
+			assert.Equal(t, []Viewing{{BoardID: "board2"}}, presences[1].Viewing)
+		}
+	}
+
+	b2.disconnect(w2, second)
+	presences = w1.Presences()
+	if assert.Len(t, presences, 2) {
+		assert.False(t, presences[1].Online)
+	}
+}
@@ -0,0 +1,82 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Publisher delivers events published by any api instance to all api instances including itself.
+// It assigns sequence number to each event, which must increase monotonically and be same among instances.
+type Publisher interface {
+	// Publish sends message to all instances
+	Publish(message []byte) error
+	// Start starts delivering published messages to handler in order of sequence number,
+	// and returns sequence number of the last message published before start
+	Start(handler func(seq int64, message []byte)) (lastSeq int64, err error)
+}
+
+// publishedEvent presents an event and information to route it, which is published as message
+type publishedEvent struct {
+	Origin   Origin       `json:"origin"`
+	Event    *Event       `json:"event"`
+	Target   *eventTarget `json:"target"`             // Nil to send to all
+	Instance string       `json:"instance,omitempty"` // ID of api instance which reports presence
+	Presence *Presence    `json:"presence,omitempty"` // Presence of the user on the instance, only for UPDATE_PRESENCE
+}
+
+func marshalPublishedEvent(published *publishedEvent) ([]byte, error) {
+	data, err := json.Marshal(published)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return data, nil
+}
+
+func unmarshalPublishedEvent(seq int64, message []byte) (*publishedEvent, error) {
+	published := &publishedEvent{}
+	if err := json.Unmarshal(message, published); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if published.Event == nil {
+		return nil, errors.New("event is empty")
+	}
+	published.Event.Seq = seq
+	published.Event.target = published.Target
+	return published, nil
+}
+
+// LocalPublisher is a publisher for single api instance, which delivers messages in process
+type LocalPublisher struct {
+	lock    *sync.Mutex
+	lastSeq int64
+	handler func(seq int64, message []byte)
+}
+
+// NewLocalPublisher returns new instance of LocalPublisher
+func NewLocalPublisher() *LocalPublisher {
+	return &LocalPublisher{
+		lock: new(sync.Mutex),
+	}
+}
+
+// Publish delivers message to handler synchronously
+func (p *LocalPublisher) Publish(message []byte) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.handler == nil {
+		return errors.New("publisher is not started")
+	}
+	p.lastSeq++
+	p.handler(p.lastSeq, message)
+	return nil
+}
+
+// Start sets handler of messages
+func (p *LocalPublisher) Start(handler func(seq int64, message []byte)) (int64, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.handler = handler
+	return p.lastSeq, nil
+}
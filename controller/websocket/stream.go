@@ -0,0 +1,73 @@
+package websocket
+
+// streamBufferMargin is the number of events which can be buffered in a stream in addition to replayed events
+const streamBufferMargin = 100
+
+// Stream presents a connection by Server-Sent Events, which receives the same events as websocket sessions.
+// Events are buffered, and the stream is closed if the client can't receive them in time.
+// The client can resume by Last-Event-ID after reconnecting.
+type Stream struct {
+	conn   *connection
+	events chan *Event
+}
+
+// Events returns channel of events, which is closed when the stream is closed
+func (s *Stream) Events() <-chan *Event {
+	return s.events
+}
+
+// OpenStream registers new Server-Sent Events stream of the user.
+// lastEventID is sequence number of the last event received by client, empty if not reconnecting.
+// If boardIDs or taskIDs are specified, events are routed as same as SUBSCRIBE message of websocket.
+func (w *WsManager) OpenStream(userID string, lastEventID string, boardIDs []string, taskIDs []string) *Stream {
+	w.lock.Lock()
+	defer w.unlock()
+	stream := &Stream{
+		conn: newConnection(userID),
+		// Enough to replay all events in event log without blocking
+		events: make(chan *Event, len(w.eventLog.events)+streamBufferMargin),
+	}
+	if len(boardIDs) > 0 || len(taskIDs) > 0 {
+		stream.conn.subscription.subscribe(boardIDs, taskIDs)
+	}
+	w.streams[stream] = true
+	becomeOnline := w.register(stream.conn)
+
+	since, requested := parseSince(lastEventID)
+	w.sendInitialEvents(stream.conn, since, requested, func(event *Event) {
+		w.sendToStream(stream, event)
+	})
+	if becomeOnline {
+		w.sendPresence(userID)
+	}
+	return stream
+}
+
+// CloseStream unregisters the stream. It can be called after the stream is closed by server.
+func (w *WsManager) CloseStream(stream *Stream) {
+	w.lock.Lock()
+	defer w.unlock()
+	w.closeStream(stream)
+}
+
+// closeStream unregisters the stream and closes its channel, caller must lock
+func (w *WsManager) closeStream(stream *Stream) {
+	if !w.streams[stream] {
+		return
+	}
+	delete(w.streams, stream)
+	close(stream.events)
+	w.unregister(stream.conn)
+}
+
+// sendToStream sends event to stream without blocking, the stream is closed if its buffer is full. Caller must lock.
+func (w *WsManager) sendToStream(stream *Stream, event *Event) {
+	if !w.streams[stream] {
+		return
+	}
+	select {
+	case stream.events <- event:
+	default:
+		w.closeStream(stream)
+	}
+}
@@ -0,0 +1,84 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// receive returns types of events buffered in the stream
+func receive(stream *Stream) (types []string, open bool) {
+	types = []string{}
+	for {
+		select {
+		case event, ok := <-stream.Events():
+			if !ok {
+				return types, false
+			}
+			types = append(types, event.Type)
+		default:
+			return types, true
+		}
+	}
+}
+
+func TestWsManager_OpenStream(t *testing.T) {
+	b := newFakeBroadcaster()
+	w := newTestWsManager(t, b)
+	w.SendUpdateUserMessage(Origin{}, nil, "user1")
+	w.SendUpdateUserMessage(Origin{}, nil, "user2")
+
+	t.Run("Missed events are sent after CONNECTED by Last-Event-ID", func(t *testing.T) {
+		stream := w.OpenStream("user1", "1", nil, nil)
+		defer w.CloseStream(stream)
+		types, open := receive(stream)
+		assert.True(t, open)
+		assert.Equal(t, []string{connectedMessage, updateUsersMessage, updatePresenceMessage}, types)
+	})
+
+	t.Run("RESYNC_REQUIRED is sent if Last-Event-ID is invalid", func(t *testing.T) {
+		stream := w.OpenStream("user1", "abc", nil, nil)
+		defer w.CloseStream(stream)
+		types, _ := receive(stream)
+		assert.Equal(t, []string{connectedMessage, resyncRequiredMessage, updatePresenceMessage}, types)
+	})
+
+	t.Run("Events are routed by subscription, and not sent to origin", func(t *testing.T) {
+		subscriber := w.OpenStream("user1", "", []string{"board1"}, nil)
+		defer w.CloseStream(subscriber)
+		origin := w.OpenStream("user2", "", nil, nil)
+		defer w.CloseStream(origin)
+		receive(subscriber)
+		originID := (<-origin.Events()).IDs[0]
+		receive(origin)
+
+		w.SendUpdateTaskBoardMessage(Origin{UserID: "user2", ConnectionID: originID}, nil, "board1")
+		w.SendUpdateTaskBoardMessage(Origin{}, nil, "board2")
+		types, _ := receive(subscriber)
+		assert.Equal(t, []string{updateTaskBoardsMessage}, types)
+		types, _ = receive(origin)
+		assert.Equal(t, []string{updateTaskBoardsMessage}, types) // Only board2
+	})
+
+	t.Run("Stream is closed if buffer is full", func(t *testing.T) {
+		stream := w.OpenStream("user3", "", nil, nil)
+		for i := 0; i < cap(stream.events)+1; i++ {
+			w.SendUpdateUserMessage(Origin{}, nil, "user1")
+		}
+		_, open := receive(stream)
+		assert.False(t, open)
+		assert.Len(t, w.streams, 0)
+		w.CloseStream(stream) // Can be called after closed
+	})
+
+	t.Run("User becomes offline by closing the last stream", func(t *testing.T) {
+		stream := w.OpenStream("user4", "", nil, nil)
+		w.CloseStream(stream)
+		presences := w.Presences()
+		if assert.NotEmpty(t, presences) {
+			last := presences[len(presences)-1]
+			assert.Equal(t, "user4", last.UserID)
+			assert.False(t, last.Online)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/olahol/melody.v1"
+)
+
+// Types of messages sent by clients
+const (
+	subscribeMessage   = "SUBSCRIBE"
+	unsubscribeMessage = "UNSUBSCRIBE"
+	viewMessage        = "VIEW"
+)
+
+// clientMessage presents a message sent by client to change subscription or to report viewing.
+// ex. {"type":"SUBSCRIBE","boardIds":["board1"],"taskIds":["task1"]} or {"type":"VIEW","boardId":"board1","taskId":"task1"}
+type clientMessage struct {
+	Type     string   `json:"type"`
+	BoardIDs []string `json:"boardIds"`
+	TaskIDs  []string `json:"taskIds"`
+	BoardID  string   `json:"boardId"`
+	TaskID   string   `json:"taskId"`
+}
+
+// subscription presents boards and tasks which a connection is interested in.
+// A connection which has never subscribed receives all messages.
+type subscription struct {
+	subscribed bool
+	boardIDs   map[string]bool
+	taskIDs    map[string]bool
+}
+
+func newSubscription() *subscription {
+	return &subscription{
+		boardIDs: make(map[string]bool),
+		taskIDs:  make(map[string]bool),
+	}
+}
+
+func (s *subscription) subscribe(boardIDs, taskIDs []string) {
+	s.subscribed = true
+	for _, id := range boardIDs {
+		s.boardIDs[id] = true
+	}
+	for _, id := range taskIDs {
+		s.taskIDs[id] = true
+	}
+}
+
+func (s *subscription) unsubscribe(boardIDs, taskIDs []string) {
+	for _, id := range boardIDs {
+		delete(s.boardIDs, id)
+	}
+	for _, id := range taskIDs {
+		delete(s.taskIDs, id)
+	}
+}
+
+// eventTarget presents boards and tasks which an event is related to
+type eventTarget struct {
+	BoardIDs []string `json:"boardIds"`
+	TaskIDs  []string `json:"taskIds"`
+}
+
+// isInterested checks whether the subscription matches with target. Event without target matches with all.
+func (s *subscription) isInterested(target *eventTarget) bool {
+	if target == nil || !s.subscribed {
+		return true
+	}
+	for _, id := range target.BoardIDs {
+		if s.boardIDs[id] {
+			return true
+		}
+	}
+	for _, id := range target.TaskIDs {
+		if s.taskIDs[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleMessage changes subscription or viewing of the connection by message from client
+func (w *WsManager) HandleMessage(s *melody.Session, msg []byte) {
+	req := clientMessage{}
+	if err := json.Unmarshal(msg, &req); err != nil {
+		fmt.Printf("Invalid websocket message is received. message:%s\n", string(msg))
+		return
+	}
+
+	w.lock.Lock()
+	defer w.unlock()
+	conn, exists := w.connections[s]
+	if !exists {
+		return
+	}
+	w.touch(conn.userID)
+	switch req.Type {
+	case subscribeMessage:
+		conn.subscription.subscribe(req.BoardIDs, req.TaskIDs)
+	case unsubscribeMessage:
+		conn.subscription.unsubscribe(req.BoardIDs, req.TaskIDs)
+	case viewMessage:
+		viewing := Viewing{BoardID: req.BoardID, TaskID: req.TaskID}
+		if conn.viewing != viewing {
+			conn.viewing = viewing
+			w.sendPresence(conn.userID)
+		}
+	default:
+		fmt.Printf("Unknown websocket message type is received. type:%s\n", req.Type)
+	}
+}
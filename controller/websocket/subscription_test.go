@@ -0,0 +1,70 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/olahol/melody.v1"
+)
+
+func TestWsManager_HandleMessage(t *testing.T) {
+	b := newFakeBroadcaster()
+	w := newTestWsManager(t, b)
+
+	boardSubscriber := b.newSession("user1", ProtocolJSON)
+	taskSubscriber := b.newSession("user2", ProtocolLegacy)
+	notSubscriber := b.newSession("user3", ProtocolJSON)
+	for _, s := range []*melody.Session{boardSubscriber, taskSubscriber, notSubscriber} {
+		b.connect(w, s)
+	}
+	w.HandleMessage(boardSubscriber, []byte(`{"type":"SUBSCRIBE","boardIds":["board1","board2"]}`))
+	w.HandleMessage(taskSubscriber, []byte(`{"type":"SUBSCRIBE","taskIds":["task1"]}`))
+
+	t.Run("Update of taskboard is sent to subscribers of the board", func(t *testing.T) {
+		b.clear()
+		w.SendUpdateTaskBoardMessage(Origin{}, nil, "board1")
+		assert.Len(t, b.messages(boardSubscriber), 1)
+		assert.Len(t, b.messages(taskSubscriber), 0)
+		assert.Len(t, b.messages(notSubscriber), 1)
+	})
+
+	t.Run("Update of tasks is sent to subscribers of the task or the board", func(t *testing.T) {
+		b.clear()
+		w.SendUpdateTaskMessage(Origin{}, nil, []string{"board2"}, "task1")
+		assert.Len(t, b.messages(boardSubscriber), 1)
+		assert.Len(t, b.messages(taskSubscriber), 1)
+		assert.Len(t, b.messages(notSubscriber), 1)
+
+		b.clear()
+		w.SendUpdateTaskMessage(Origin{}, nil, []string{"board3"}, "task2")
+		assert.Len(t, b.messages(boardSubscriber), 0)
+		assert.Len(t, b.messages(taskSubscriber), 0)
+		assert.Len(t, b.messages(notSubscriber), 1)
+	})
+
+	t.Run("Update of board list and users are sent to all", func(t *testing.T) {
+		b.clear()
+		w.SendUpdateTaskBoardMessage(Origin{}, nil)
+		w.SendUpdateBoardMessage(Origin{}, nil, "board3")
+		w.SendUpdateUserMessage(Origin{}, nil, "user1")
+		assert.Len(t, b.messages(boardSubscriber), 3)
+		assert.Len(t, b.messages(taskSubscriber), 3)
+		assert.Len(t, b.messages(notSubscriber), 3)
+	})
+
+	t.Run("Unsubscribed board is not routed", func(t *testing.T) {
+		w.HandleMessage(boardSubscriber, []byte(`{"type":"UNSUBSCRIBE","boardIds":["board1"]}`))
+		b.clear()
+		w.SendUpdateTaskBoardMessage(Origin{}, nil, "board1")
+		w.SendUpdateTaskBoardMessage(Origin{}, nil, "board2")
+		assert.Len(t, b.messages(boardSubscriber), 1)
+	})
+
+	t.Run("Invalid message is ignored", func(t *testing.T) {
+		w.HandleMessage(taskSubscriber, []byte(`SUBSCRIBE board1`))
+		w.HandleMessage(taskSubscriber, []byte(`{"type":"UNKNOWN","boardIds":["board1"]}`))
+		b.clear()
+		w.SendUpdateTaskBoardMessage(Origin{}, nil, "board1")
+		assert.Len(t, b.messages(taskSubscriber), 0)
+	})
+}
@@ -1,87 +1,350 @@
 package websocket
 
 import (
+	"context"
 	"fmt"
-	"strings"
+	"net/http"
 	"sync"
+	"taskboard-api-go/common"
+	"time"
 
 	"gopkg.in/olahol/melody.v1"
 )
 
+// ConnectionIDHeader is HTTP header to pass the connection ID of websocket, which is notified by CONNECTED message.
+// The connection is excluded from receivers of messages caused by the request.
+const ConnectionIDHeader = "taskboard-from-id"
+
+// broadcaster sends messages to sessions matching filter, it is implemented by melody.Melody
+type broadcaster interface {
+	BroadcastFilter(msg []byte, fn func(*melody.Session) bool) error
+}
+
+// connection presents a websocket session or a Server-Sent Events stream of a user
+type connection struct {
+	id           string
+	userID       string
+	session      *melody.Session // Nil if connected by Server-Sent Events
+	subscription *subscription
+	viewing      Viewing
+}
+
+// Origin presents the user and the websocket connection which cause an event
+type Origin struct {
+	UserID       string
+	ConnectionID string // Empty if unknown
+}
+
 // WsManager manages session of websocket
 type WsManager struct {
-	lock     *sync.Mutex
-	sessions map[string]*melody.Session
-	mrouter  *melody.Melody
+	instanceID       string // ID of this api instance to distinguish presences reported by others
+	lock             *sync.Mutex
+	connections      map[*melody.Session]*connection
+	userConnections  map[string]map[string]*connection // Connections of each user keyed by connection ID
+	streams          map[*Stream]bool                  // Server-Sent Events streams
+	lastSeen         map[string]time.Time              // Last seen time of each user
+	remotePresences  map[string]map[string]Presence    // Presences reported by other api instances keyed by user ID and instance ID
+	pendingPresences []Presence                        // Presences to be published after unlocking
+	eventLog         *eventLog
+	mrouter          broadcaster
+	publisher        Publisher
 }
 
-// NewWsManager creates new instance of WsManager(Websocket Manager)
-func NewWsManager(mrouter *melody.Melody) *WsManager {
-	ws := &WsManager{
-		lock:     new(sync.Mutex),
-		sessions: make(map[string]*melody.Session, 0),
-		mrouter:  mrouter,
+// NewWsManager creates new instance of WsManager(Websocket Manager).
+// Events are sent through publisher, so that clients connected to other api instances also receive them.
+func NewWsManager(mrouter *melody.Melody, publisher Publisher) (*WsManager, error) {
+	ws := newWsManager(mrouter)
+	if err := ws.start(publisher); err != nil {
+		return nil, err
 	}
 	mrouter.HandleConnect(ws.Connect)
 	mrouter.HandleDisconnect(ws.Disconnect)
-	return ws
+	mrouter.HandleMessage(ws.HandleMessage)
+	mrouter.HandlePong(ws.Pong)
+	return ws, nil
+}
+
+func newWsManager(mrouter broadcaster) *WsManager {
+	return &WsManager{
+		instanceID:      common.GenerateID(),
+		lock:            new(sync.Mutex),
+		connections:     make(map[*melody.Session]*connection),
+		userConnections: make(map[string]map[string]*connection),
+		streams:         make(map[*Stream]bool),
+		lastSeen:        make(map[string]time.Time),
+		remotePresences: make(map[string]map[string]Presence),
+		eventLog:        newEventLog(DefaultEventLogSize),
+		mrouter:         mrouter,
+	}
+}
+
+// start starts receiving events from publisher. Events published before start can't be replayed.
+func (w *WsManager) start(publisher Publisher) error {
+	// Lock until event log is reset, not to dispatch events before it
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	lastSeq, err := publisher.Start(w.dispatch)
+	if err != nil {
+		return err
+	}
+	w.eventLog.reset(lastSeq)
+	w.publisher = publisher
+	return nil
 }
 
 const (
-	updateTasksMessage      = "UPDATE_TASKS"
-	updateBoardsMessage     = "UPDATE_BOARDS"
-	updateTaskBoardsMessage = "UPDATE_TASKBOARDS"
-	updateUsersMessage      = "UPDATE_USERS"
-	queryFromUserIDKey      = "from"
+	updateTasksMessage       = "UPDATE_TASKS"
+	updateBoardsMessage      = "UPDATE_BOARDS"
+	updateTaskBoardsMessage  = "UPDATE_TASKBOARDS"
+	updateUsersMessage       = "UPDATE_USERS"
+	updateCommentsMessage    = "UPDATE_COMMENTS"
+	updateLabelsMessage      = "UPDATE_LABELS"
+	overdueTasksMessage      = "OVERDUE_TASKS"
+	updateAttachmentsMessage = "UPDATE_ATTACHMENTS"
+	connectedMessage         = "CONNECTED"
+	resyncRequiredMessage    = "RESYNC_REQUIRED"
+	updatePresenceMessage    = "UPDATE_PRESENCE"
+	updateTaskLocksMessage   = "UPDATE_TASK_LOCKS"
 )
 
-// SendUpdateTaskMessage sends a message to update tasks for other clients
-func (w *WsManager) SendUpdateTaskMessage(fromUserID string, taskIDs ...string) {
-	w.sendMessage(fromUserID, fmt.Sprintf("%s %s", updateTasksMessage, strings.Join(taskIDs, " ")))
+type contextKey string
+
+const userIDContextKey contextKey = "userID"
+
+// WithUserID returns a shallow copy of request which carries the authenticated user ID.
+// The request must be passed to melody.HandleRequest so that Connect can identify the user.
+func WithUserID(r *http.Request, userID string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userIDContextKey, userID))
+}
+
+func getUserID(s *melody.Session) string {
+	userID, _ := s.Request.Context().Value(userIDContextKey).(string)
+	return userID
 }
 
-// SendUpdateTaskBoardMessage sends a message to update taskboards for other clients
-func (w *WsManager) SendUpdateTaskBoardMessage(fromUserID string, boardIDs ...string) {
-	w.sendMessage(fromUserID, fmt.Sprintf("%s %s", updateTaskBoardsMessage, strings.Join(boardIDs, " ")))
+// SendUpdateTaskMessage sends a message to update tasks for other clients subscribing the tasks or boards of them
+func (w *WsManager) SendUpdateTaskMessage(from Origin, payload interface{}, boardIDs []string, taskIDs ...string) {
+	event := newEvent(updateTasksMessage, EventKindTask, from.UserID, taskIDs, payload)
+	event.target = &eventTarget{BoardIDs: boardIDs, TaskIDs: taskIDs}
+	w.sendEvent(from, event)
+}
+
+// SendUpdateTaskBoardMessage sends a message to update taskboards for other clients subscribing the boards.
+// If boardIDs is empty, the message is sent to all clients.
+func (w *WsManager) SendUpdateTaskBoardMessage(from Origin, payload interface{}, boardIDs ...string) {
+	event := newEvent(updateTaskBoardsMessage, EventKindTask, from.UserID, boardIDs, payload)
+	if len(boardIDs) > 0 {
+		event.target = &eventTarget{BoardIDs: boardIDs}
+	}
+	w.sendEvent(from, event)
 }
 
 // SendUpdateBoardMessage sends a message to update boards for other clients
-func (w *WsManager) SendUpdateBoardMessage(fromUserID string, boardIDs ...string) {
-	w.sendMessage(fromUserID, fmt.Sprintf("%s %s", updateBoardsMessage, strings.Join(boardIDs, " ")))
+func (w *WsManager) SendUpdateBoardMessage(from Origin, payload interface{}, boardIDs ...string) {
+	w.sendEvent(from, newEvent(updateBoardsMessage, EventKindBoard, from.UserID, boardIDs, payload))
 }
 
 // SendUpdateUserMessage sends a message to update users for other clients
-func (w *WsManager) SendUpdateUserMessage(fromUserID string, userIDs ...string) {
-	w.sendMessage(fromUserID, fmt.Sprintf("%s %s", updateUsersMessage, strings.Join(userIDs, " ")))
+func (w *WsManager) SendUpdateUserMessage(from Origin, payload interface{}, userIDs ...string) {
+	w.sendEvent(from, newEvent(updateUsersMessage, EventKindUser, from.UserID, userIDs, payload))
+}
+
+// SendUpdateCommentMessage sends a message to update comment threads of tasks for other clients
+func (w *WsManager) SendUpdateCommentMessage(from Origin, payload interface{}, taskIDs ...string) {
+	w.sendEvent(from, newEvent(updateCommentsMessage, EventKindComment, from.UserID, taskIDs, payload))
+}
+
+// SendUpdateLabelMessage sends a message to update labels for other clients
+func (w *WsManager) SendUpdateLabelMessage(from Origin, payload interface{}, labelIDs ...string) {
+	w.sendEvent(from, newEvent(updateLabelsMessage, EventKindLabel, from.UserID, labelIDs, payload))
+}
+
+// SendUpdateAttachmentMessage sends a message to update attachments of tasks for other clients
+func (w *WsManager) SendUpdateAttachmentMessage(from Origin, payload interface{}, taskIDs ...string) {
+	w.sendEvent(from, newEvent(updateAttachmentsMessage, EventKindAttachment, from.UserID, taskIDs, payload))
 }
 
-func (w *WsManager) sendMessage(fromUserID string, message string) {
+// SendUpdateTaskLockMessage sends a message to update locks of tasks for other clients subscribing the tasks or boards of them
+func (w *WsManager) SendUpdateTaskLockMessage(from Origin, payload interface{}, boardIDs []string, taskIDs ...string) {
+	event := newEvent(updateTaskLocksMessage, EventKindTaskLock, from.UserID, taskIDs, payload)
+	event.target = &eventTarget{BoardIDs: boardIDs, TaskIDs: taskIDs}
+	w.sendEvent(from, event)
+}
+
+// SendOverdueTaskMessage sends a message to notify tasks became overdue for all clients
+func (w *WsManager) SendOverdueTaskMessage(taskIDs ...string) {
+	w.sendEvent(Origin{}, newEvent(overdueTasksMessage, EventKindTask, "", taskIDs, nil))
+}
+
+// sendEvent publishes event to all api instances
+func (w *WsManager) sendEvent(from Origin, event *Event) {
+	w.publish(&publishedEvent{Origin: from, Event: event, Target: event.target})
+}
+
+// publish publishes event to all api instances, it must not be called while locking
+func (w *WsManager) publish(published *publishedEvent) {
+	message, err := marshalPublishedEvent(published)
+	if err != nil {
+		// Programing error!! payload must be able to be marshaled
+		fmt.Printf("Failed to marshal websocket event. error:%+v\n", err)
+		return
+	}
+	if err := w.publisher.Publish(message); err != nil {
+		fmt.Printf("Failed to publish websocket event. error:%+v\n", err)
+	}
+}
+
+// dispatch records published event to event log, and sends it to sessions and streams interested in it
+// except the connection of origin.
+// Lock is held while sending, so that each client receives events in order of sequence number.
+func (w *WsManager) dispatch(seq int64, message []byte) {
+	published, err := unmarshalPublishedEvent(seq, message)
+	if err != nil {
+		fmt.Printf("Failed to unmarshal published event. error:%+v\n", err)
+		return
+	}
 	w.lock.Lock()
-	defer w.lock.Unlock()
-	s, exists := w.sessions[fromUserID]
-	if !exists {
-		w.mrouter.Broadcast([]byte(message))
-	} else {
-		w.mrouter.BroadcastOthers([]byte(message), s)
+	defer func() {
+		// Publisher may hold its lock while dispatching, so presences changed by closing streams are published later
+		if presences := w.releaseLock(); len(presences) > 0 {
+			go func() {
+				for _, presence := range presences {
+					w.publishPresence(presence)
+				}
+			}()
+		}
+	}()
+	if published.Presence != nil {
+		w.dispatchPresence(published.Instance, published.Presence)
+		return
+	}
+	event := published.Event
+	w.eventLog.append(event)
+	origin := w.findConnection(published.Origin)
+	excluded := make(map[*melody.Session]bool)
+	for s, conn := range w.connections {
+		if conn == origin || !conn.subscription.isInterested(event.target) {
+			excluded[s] = true
+		}
+	}
+	w.broadcast(event, func(s *melody.Session) bool {
+		return !excluded[s]
+	})
+	for stream := range w.streams {
+		if stream.conn != origin && stream.conn.subscription.isInterested(event.target) {
+			w.sendToStream(stream, event)
+		}
 	}
 }
 
-// Connect put a session to session's map
+// findConnection returns the connection of origin, or nil if not found.
+// Connection of other user is never returned, not to suppress messages to others by fake ID.
+func (w *WsManager) findConnection(from Origin) *connection {
+	if from.ConnectionID == "" {
+		return nil
+	}
+	return w.userConnections[from.UserID][from.ConnectionID]
+}
+
+// broadcast sends event to sessions matching filter, in the protocol of each session
+func (w *WsManager) broadcast(event *Event, filter func(s *melody.Session) bool) {
+	jsonMessage, err := event.jsonMessage()
+	if err != nil {
+		// Programing error!! payload must be able to be marshaled
+		fmt.Printf("Failed to marshal websocket event. error:%+v\n", err)
+		return
+	}
+	w.mrouter.BroadcastFilter(jsonMessage, func(s *melody.Session) bool {
+		return filter(s) && requestProtocol(s.Request) == ProtocolJSON
+	})
+	w.mrouter.BroadcastFilter(event.legacyMessage(), func(s *melody.Session) bool {
+		return filter(s) && requestProtocol(s.Request) == ProtocolLegacy
+	})
+}
+
+// Connect registers a session as new connection of the user, and notifies the connection ID to the session.
+// If the client requests by since parameter, events missed by the client are sent again, or RESYNC_REQUIRED
+// is sent if they are already discarded.
 func (w *WsManager) Connect(s *melody.Session) {
+	conn := newConnection(getUserID(s))
+	conn.session = s
 	w.lock.Lock()
-	defer w.lock.Unlock()
-	fromUserID := s.Request.URL.Query().Get(queryFromUserIDKey)
-	w.sessions[fromUserID] = s
+	defer w.unlock()
+	w.connections[s] = conn
+	becomeOnline := w.register(conn)
+
+	since, requested := requestSince(s.Request)
+	w.sendInitialEvents(conn, since, requested, func(event *Event) {
+		w.broadcast(event, func(target *melody.Session) bool {
+			return target == s
+		})
+	})
+	if becomeOnline {
+		w.sendPresence(conn.userID)
+	}
 }
 
-// Disconnect remove a session from session's map
+// Disconnect unregisters the connection of a session
 func (w *WsManager) Disconnect(s *melody.Session) {
 	w.lock.Lock()
-	defer w.lock.Unlock()
-	for id, session := range w.sessions {
-		if s == session {
-			delete(w.sessions, id)
-			return
-		}
+	defer w.unlock()
+	conn, exists := w.connections[s]
+	if !exists {
+		return
+	}
+	delete(w.connections, s)
+	w.unregister(conn)
+}
+
+func newConnection(userID string) *connection {
+	return &connection{
+		id:           common.GenerateID(),
+		userID:       userID,
+		subscription: newSubscription(),
+	}
+}
+
+// register adds connection to connections of the user, and returns whether the user becomes online. Caller must lock.
+func (w *WsManager) register(conn *connection) (becomeOnline bool) {
+	becomeOnline = len(w.userConnections[conn.userID]) == 0
+	if becomeOnline {
+		w.userConnections[conn.userID] = make(map[string]*connection)
+	}
+	w.userConnections[conn.userID][conn.id] = conn
+	w.touch(conn.userID)
+	return
+}
+
+// unregister removes connection from connections of the user, and notifies presence if changed. Caller must lock.
+func (w *WsManager) unregister(conn *connection) {
+	delete(w.userConnections[conn.userID], conn.id)
+	becomeOffline := len(w.userConnections[conn.userID]) == 0
+	if becomeOffline {
+		delete(w.userConnections, conn.userID)
+	}
+	w.touch(conn.userID)
+	if becomeOffline || conn.viewing != (Viewing{}) {
+		w.sendPresence(conn.userID)
+	}
+}
+
+// sendInitialEvents sends CONNECTED to new connection, and then events after since if requested. Caller must lock.
+func (w *WsManager) sendInitialEvents(conn *connection, since int64, requested bool, send func(event *Event)) {
+	connected := newEvent(connectedMessage, EventKindUser, conn.userID, []string{conn.id}, nil)
+	connected.Seq = w.eventLog.lastSeq
+	send(connected)
+	if !requested {
+		return
+	}
+	missed, ok := w.eventLog.since(since)
+	if !ok {
+		resync := newEvent(resyncRequiredMessage, EventKindUser, "", nil, nil)
+		resync.Seq = w.eventLog.lastSeq
+		send(resync)
+		return
+	}
+	// Events caused by the client itself are also sent, because its connection ID was changed
+	for _, event := range missed {
+		send(event)
 	}
 }
@@ -0,0 +1,328 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/olahol/melody.v1"
+)
+
+// fakeBroadcaster records messages instead of writing them to sessions
+type fakeBroadcaster struct {
+	lock     sync.Mutex
+	sessions []*melody.Session
+	received map[*melody.Session][][]byte
+}
+
+func newFakeBroadcaster() *fakeBroadcaster {
+	return &fakeBroadcaster{received: make(map[*melody.Session][][]byte)}
+}
+
+func (b *fakeBroadcaster) newSession(userID string, protocol string) *melody.Session {
+	r := httptest.NewRequest("GET", "/taskboard/ws?protocol="+protocol, nil)
+	return &melody.Session{Request: WithUserID(r, userID)}
+}
+
+// connect opens session like melody, which adds session to broadcast targets before calling Connect
+func (b *fakeBroadcaster) connect(w *WsManager, s *melody.Session) {
+	b.lock.Lock()
+	b.sessions = append(b.sessions, s)
+	b.lock.Unlock()
+	w.Connect(s)
+}
+
+// disconnect closes session like melody, which removes session from broadcast targets before calling Disconnect
+func (b *fakeBroadcaster) disconnect(w *WsManager, s *melody.Session) {
+	b.lock.Lock()
+	for i, session := range b.sessions {
+		if session == s {
+			b.sessions = append(b.sessions[:i], b.sessions[i+1:]...)
+			break
+		}
+	}
+	b.lock.Unlock()
+	w.Disconnect(s)
+}
+
+func (b *fakeBroadcaster) BroadcastFilter(msg []byte, fn func(*melody.Session) bool) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, s := range b.sessions {
+		if fn(s) {
+			b.received[s] = append(b.received[s], msg)
+		}
+	}
+	return nil
+}
+
+func (b *fakeBroadcaster) messages(s *melody.Session) [][]byte {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.received[s]
+}
+
+func (b *fakeBroadcaster) clear() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.received = make(map[*melody.Session][][]byte)
+}
+
+// newTestWsManager returns WsManager which publishes events in process
+func newTestWsManager(t *testing.T, b *fakeBroadcaster) *WsManager {
+	w := newWsManager(b)
+	if err := w.start(NewLocalPublisher()); err != nil {
+		t.Fatalf("Failed to start: %+v", err)
+	}
+	return w
+}
+
+// connectionID returns the ID notified to the session by CONNECTED message
+func connectionID(t *testing.T, b *fakeBroadcaster, s *melody.Session) string {
+	messages := b.messages(s)
+	if len(messages) == 0 {
+		t.Fatalf("CONNECTED message is not sent")
+	}
+	event := Event{}
+	if err := json.Unmarshal(messages[0], &event); err != nil {
+		t.Fatalf("Failed to unmarshal: %+v", err)
+	}
+	assert.Equal(t, connectedMessage, event.Type)
+	if !assert.Len(t, event.IDs, 1) {
+		t.FailNow()
+	}
+	return event.IDs[0]
+}
+
+func TestWsManager_Connect(t *testing.T) {
+	b := newFakeBroadcaster()
+	w := newTestWsManager(t, b)
+
+	first := b.newSession("user1", ProtocolJSON)
+	second := b.newSession("user1", ProtocolLegacy)
+	b.connect(w, first)
+	b.connect(w, second)
+
+	// Connection ID is notified only to the connected session, in the protocol of the session
+	firstID := connectionID(t, b, first)
+	assert.Len(t, b.messages(first), 2) // CONNECTED and UPDATE_PRESENCE
+	if assert.Len(t, b.messages(second), 1) {
+		assert.Regexp(t, "^CONNECTED [0-9a-zA-Z]+$", string(b.messages(second)[0]))
+		assert.NotEqual(t, "CONNECTED "+firstID, string(b.messages(second)[0]))
+	}
+	assert.Len(t, w.userConnections["user1"], 2)
+}
+
+func TestWsManager_Protocol(t *testing.T) {
+	b := newFakeBroadcaster()
+	w := newTestWsManager(t, b)
+
+	legacy := b.newSession("user1", ProtocolLegacy)
+	versioned := b.newSession("user1", ProtocolJSON)
+	// Without protocol query, or with unknown protocol, JSON is used
+	defaults := &melody.Session{Request: WithUserID(httptest.NewRequest("GET", "/taskboard/ws", nil), "user1")}
+	unknown := b.newSession("user1", "v2")
+	for _, s := range []*melody.Session{legacy, versioned, defaults, unknown} {
+		b.connect(w, s)
+	}
+
+	b.clear()
+	w.SendUpdateUserMessage(Origin{UserID: "user2"}, map[string]string{"name": "renamed"}, "user1", "user2")
+
+	if assert.Len(t, b.messages(legacy), 1) {
+		assert.Equal(t, "UPDATE_USERS user1 user2", string(b.messages(legacy)[0]))
+	}
+	for name, s := range map[string]*melody.Session{"json": versioned, "default": defaults, "unknown": unknown} {
+		if !assert.Len(t, b.messages(s), 1, name) {
+			continue
+		}
+		event := map[string]interface{}{}
+		if !assert.NoError(t, json.Unmarshal(b.messages(s)[0], &event), name) {
+			continue
+		}
+		assert.Equal(t, float64(ProtocolVersion), event["version"], name)
+		assert.Equal(t, updateUsersMessage, event["type"], name)
+		assert.Equal(t, EventKindUser, event["kind"], name)
+		assert.Equal(t, []interface{}{"user1", "user2"}, event["ids"], name)
+		assert.Equal(t, "user2", event["actor"], name)
+		assert.Equal(t, map[string]interface{}{"name": "renamed"}, event["payload"], name)
+	}
+}
+
+func TestWsManager_SendEvent(t *testing.T) {
+	b := newFakeBroadcaster()
+	w := newTestWsManager(t, b)
+
+	first := b.newSession("user1", ProtocolJSON)
+	second := b.newSession("user1", ProtocolJSON)
+	other := b.newSession("user2", ProtocolJSON)
+	for _, s := range []*melody.Session{first, second, other} {
+		b.connect(w, s)
+	}
+	firstID := connectionID(t, b, first)
+	otherID := connectionID(t, b, other)
+
+	t.Run("Only the originating connection is excluded", func(t *testing.T) {
+		b.clear()
+		w.SendUpdateUserMessage(Origin{UserID: "user1", ConnectionID: firstID}, nil, "user1")
+		assert.Len(t, b.messages(first), 0)
+		assert.Len(t, b.messages(second), 1)
+		assert.Len(t, b.messages(other), 1)
+	})
+
+	t.Run("No connection is excluded if connection ID is unknown", func(t *testing.T) {
+		b.clear()
+		w.SendUpdateUserMessage(Origin{UserID: "user1"}, nil, "user1")
+		assert.Len(t, b.messages(first), 1)
+		assert.Len(t, b.messages(second), 1)
+		assert.Len(t, b.messages(other), 1)
+	})
+
+	t.Run("Connection of other user is not excluded", func(t *testing.T) {
+		b.clear()
+		w.SendUpdateUserMessage(Origin{UserID: "user1", ConnectionID: otherID}, nil, "user1")
+		assert.Len(t, b.messages(other), 1)
+	})
+
+	t.Run("Disconnected connection is not excluded", func(t *testing.T) {
+		b.disconnect(w, first)
+		b.clear()
+		w.SendUpdateUserMessage(Origin{UserID: "user1", ConnectionID: firstID}, nil, "user1")
+		assert.Len(t, b.messages(second), 1)
+		assert.Len(t, b.messages(other), 1)
+	})
+}
+
+func TestWsManager_ConnectAndDisconnectConcurrently(t *testing.T) {
+	b := newFakeBroadcaster()
+	w := newTestWsManager(t, b)
+
+	const (
+		users           = 5
+		sessionsPerUser = 20
+	)
+	sessions := make([]*melody.Session, 0, users*sessionsPerUser)
+	for i := 0; i < users; i++ {
+		for j := 0; j < sessionsPerUser; j++ {
+			sessions = append(sessions, b.newSession(fmt.Sprintf("user%d", i), ProtocolJSON))
+		}
+	}
+
+	// Connect all sessions while sending messages
+	var wg sync.WaitGroup
+	for _, s := range sessions {
+		wg.Add(2)
+		go func(s *melody.Session) {
+			defer wg.Done()
+			b.connect(w, s)
+		}(s)
+		go func(s *melody.Session) {
+			defer wg.Done()
+			w.SendUpdateUserMessage(Origin{UserID: getUserID(s), ConnectionID: "unknown"}, nil, "user1")
+		}(s)
+	}
+	wg.Wait()
+	assert.Len(t, w.connections, users*sessionsPerUser)
+	for i := 0; i < users; i++ {
+		assert.Len(t, w.userConnections[fmt.Sprintf("user%d", i)], sessionsPerUser)
+	}
+
+	// Disconnect half of sessions, and disconnect some of them twice
+	for i, s := range sessions {
+		if i%2 == 0 {
+			continue
+		}
+		wg.Add(2)
+		go func(s *melody.Session) {
+			defer wg.Done()
+			b.disconnect(w, s)
+		}(s)
+		go func(s *melody.Session) {
+			defer wg.Done()
+			b.disconnect(w, s)
+		}(s)
+	}
+	wg.Wait()
+	assert.Len(t, w.connections, users*sessionsPerUser/2)
+	for i := 0; i < users; i++ {
+		assert.Len(t, w.userConnections[fmt.Sprintf("user%d", i)], sessionsPerUser/2)
+	}
+
+	// Disconnect all, then no connection remains
+	for _, s := range sessions {
+		wg.Add(1)
+		go func(s *melody.Session) {
+			defer wg.Done()
+			b.disconnect(w, s)
+		}(s)
+	}
+	wg.Wait()
+	assert.Len(t, w.connections, 0)
+	assert.Len(t, w.userConnections, 0)
+}
+
+func TestWsManager_ConnectWithSince(t *testing.T) {
+	b := newFakeBroadcaster()
+	w := newTestWsManager(t, b)
+	w.eventLog = newEventLog(3)
+	for i := 0; i < 5; i++ {
+		w.SendUpdateUserMessage(Origin{UserID: "user1"}, nil, fmt.Sprintf("user%d", i))
+	}
+
+	events := func(s *melody.Session) []Event {
+		result := []Event{}
+		for _, message := range b.messages(s) {
+			event := Event{}
+			if err := json.Unmarshal(message, &event); err != nil {
+				t.Fatalf("Failed to unmarshal: %+v", err)
+			}
+			result = append(result, event)
+		}
+		return result
+	}
+
+	t.Run("Missed events are sent after CONNECTED", func(t *testing.T) {
+		s := b.newSession("user1", ProtocolJSON)
+		s.Request.URL.RawQuery += "&since=3"
+		b.connect(w, s)
+		received := events(s)
+		if assert.Len(t, received, 4) {
+			assert.Equal(t, connectedMessage, received[0].Type)
+			assert.Equal(t, int64(5), received[0].Seq)
+			assert.Equal(t, int64(4), received[1].Seq)
+			assert.Equal(t, []string{"user3"}, received[1].IDs)
+			assert.Equal(t, int64(5), received[2].Seq)
+			assert.Equal(t, updatePresenceMessage, received[3].Type)
+		}
+	})
+
+	t.Run("RESYNC_REQUIRED is sent if events are discarded", func(t *testing.T) {
+		s := b.newSession("user1", ProtocolLegacy)
+		s.Request.URL.RawQuery += "&since=1"
+		b.connect(w, s)
+		if assert.Len(t, b.messages(s), 2) {
+			assert.Equal(t, "RESYNC_REQUIRED ", string(b.messages(s)[1]))
+		}
+	})
+
+	t.Run("Nothing is replayed without since", func(t *testing.T) {
+		s := b.newSession("user1", ProtocolJSON)
+		b.connect(w, s)
+		assert.Len(t, b.messages(s), 1)
+	})
+
+	t.Run("Sequence number increases for each event", func(t *testing.T) {
+		s := b.newSession("user2", ProtocolJSON)
+		b.connect(w, s)
+		w.SendUpdateUserMessage(Origin{UserID: "user1"}, nil, "user1")
+		received := events(s)
+		if assert.Len(t, received, 3) {
+			// Presences published in between also take sequence numbers, though they are not recorded
+			assert.True(t, received[2].Seq > received[0].Seq)
+			assert.Equal(t, w.eventLog.lastSeq, received[2].Seq)
+		}
+	})
+}
@@ -7,11 +7,13 @@ require (
 	github.com/gin-contrib/cors v1.3.0
 	github.com/gin-contrib/sse v0.1.0
 	github.com/gin-gonic/gin v1.4.0
+	github.com/go-sql-driver/mysql v1.4.1
 	github.com/golang/protobuf v1.3.1
 	github.com/google/uuid v1.1.1
 	github.com/jinzhu/gorm v1.9.9
 	github.com/jinzhu/inflection v1.0.0
 	github.com/json-iterator/go v1.1.6
+	github.com/lib/pq v1.1.1
 	github.com/mattn/go-isatty v0.0.8
 	github.com/mattn/go-sqlite3 v1.10.0
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd
@@ -4,14 +4,24 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"taskboard-api-go/controller/activities"
 	"taskboard-api-go/controller/api"
+	"taskboard-api-go/controller/attachments"
 	"taskboard-api-go/controller/boards"
+	"taskboard-api-go/controller/checklists"
+	"taskboard-api-go/controller/comments"
+	"taskboard-api-go/controller/events"
+	"taskboard-api-go/controller/labels"
+	"taskboard-api-go/controller/presence"
 	"taskboard-api-go/controller/tasks"
 	"taskboard-api-go/controller/users"
 	"taskboard-api-go/controller/websocket"
+	"taskboard-api-go/migration"
 	"taskboard-api-go/model"
 	"taskboard-api-go/orm"
 	"taskboard-api-go/service"
+	"taskboard-api-go/storage"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -21,27 +31,39 @@ import (
 func main() {
 	// Init database
 	fmt.Println("Initializing database...")
-	databasePath := "./taskboard.sqlite3"
-	err := orm.Init(databasePath)
+	dialect, dsn := getDatabase()
+	err := orm.Init(dialect, dsn)
 	if err != nil {
 		fmt.Printf("Failed to initialize database. error:%+v\n", err)
 		return
 	}
 
-	// Create or Update tables
-	err = orm.Migrate(
-		&model.User{},
-		&model.Task{},
-		&model.Board{},
-	)
+	// Run migrate subcommand instead of server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrateCommand(os.Args[2:]))
+	}
+
+	// Check tables are up to date, they are created or updated only by migrate subcommand
+	if err = migration.Check(orm.GetDB()); err != nil {
+		fmt.Printf("Failed to start with the database. error:%+v\n", err)
+		return
+	}
+	pending, err := migration.Pending(orm.GetDB())
 	if err != nil {
-		fmt.Printf("Failed to update tables. error:%+v\n", err)
+		fmt.Printf("Failed to check migrations. error:%+v\n", err)
+		return
+	}
+	if len(pending) > 0 {
+		for _, m := range pending {
+			fmt.Printf("Pending migration %04d %s\n", m.Version, m.Name)
+		}
+		fmt.Println("Database schema is not up to date. Run \"migrate up\" subcommand before starting the server")
 		return
 	}
 
 	// Create system boards(Icebox, Todo, Doing, Done)
 	tx := orm.GetDB().Begin()
-	srvc := service.NewBoardService(tx)
+	srvc := service.NewBoardService(tx, model.SystemUser)
 	if err = srvc.CreateSystemBoards(); err != nil {
 		fmt.Printf("Failed to create system boards. error:%+v\n", err)
 		api.Rollback(tx)
@@ -51,29 +73,92 @@ func main() {
 		return
 	}
 
+	// Create admin user if no admin exists
+	tx = orm.GetDB().Begin()
+	adminName, adminPassword := getAdminUser()
+	createdPassword, err := service.NewUserService(tx, model.SystemUser).CreateAdminUserIfNotExists(adminName, adminPassword)
+	if err != nil {
+		fmt.Printf("Failed to create admin user. error:%+v\n", err)
+		api.Rollback(tx)
+		return
+	}
+	if err = api.Commit(tx); err != nil {
+		return
+	}
+	if createdPassword != "" && adminPassword == "" {
+		// Shown only once, it can not be known later
+		fmt.Printf("Admin user [%s] is created with generated password: %s\nChange password of the admin user soon.\n",
+			adminName, createdPassword)
+	}
+
 	// Init router of REST apis
 	router := gin.Default()
-	//config := cors.DefaultConfig()
-	//config.AllowAllOrigins = true
-	//config.AllowHeaders = []string{"Content-Type"}
-	//router.Use(cors.New(config))
-	router.Use(cors.Default())
+	config := cors.DefaultConfig()
+	config.AllowAllOrigins = true
+	// Conditional requests by ETag of entities
+	config.AddAllowHeaders(api.IfMatchHeader, api.IfNoneMatchHeader)
+	config.AddExposeHeaders(api.ETagHeader)
+	router.Use(cors.New(config))
 	// Include static/avatars
 	router.Static("/taskboard/static", "./static")
 
+	// Set secret of authentication token
+	if err = service.SetTokenSecret(getTokenSecret()); err != nil {
+		fmt.Printf("Failed to set token secret. error:%+v\n", err)
+		return
+	}
+
+	// Set storage of attachments
+	attachmentStorage, err := storage.NewLocalStorage(getAttachmentDir())
+	if err != nil {
+		fmt.Printf("Failed to initialize attachment storage. error:%+v\n", err)
+		return
+	}
+	service.SetAttachmentStorage(attachmentStorage)
+
+	// Set storage of uploaded avatars
+	avatarStorage, err := storage.NewLocalStorage(getAvatarDir())
+	if err != nil {
+		fmt.Printf("Failed to initialize avatar storage. error:%+v\n", err)
+		return
+	}
+	service.SetAvatarStorage(avatarStorage)
+
 	// Register api path
 	routeGroup := router.Group("/taskboard")
-	users.EndPoint.RegisterRoute(routeGroup)
-	boards.EndPoint.RegisterRoute(routeGroup)
-	tasks.EndPoint.RegisterRoute(routeGroup)
+	users.EndPoint.RegisterPublicRoute(routeGroup)
+	authGroup := routeGroup.Group("", api.Authenticate())
+	users.EndPoint.RegisterRoute(authGroup)
+	boards.EndPoint.RegisterRoute(authGroup)
+	tasks.EndPoint.RegisterRoute(authGroup)
+	comments.EndPoint.RegisterRoute(authGroup)
+	activities.EndPoint.RegisterRoute(authGroup)
+	labels.EndPoint.RegisterRoute(authGroup)
+	checklists.EndPoint.RegisterRoute(authGroup)
+	attachments.EndPoint.RegisterRoute(authGroup)
+	presence.EndPoint.RegisterRoute(authGroup)
+	events.EndPoint.RegisterRoute(authGroup)
 	mrouter := melody.New()
-	ws := websocket.NewWsManager(mrouter)
+	ws, err := websocket.NewWsManager(mrouter, getRealtimePublisher())
+	if err != nil {
+		fmt.Printf("Failed to start websocket manager. error:%+v\n", err)
+		return
+	}
 	users.SetWsManager(ws)
 	boards.SetWsManager(ws)
 	tasks.SetWsManager(ws)
-	router.GET("/taskboard/ws", func(c *gin.Context) {
-		mrouter.HandleRequest(c.Writer, c.Request)
+	comments.SetWsManager(ws)
+	labels.SetWsManager(ws)
+	checklists.SetWsManager(ws)
+	attachments.SetWsManager(ws)
+	presence.SetWsManager(ws)
+	events.SetWsManager(ws)
+	authGroup.GET("/ws", func(c *gin.Context) {
+		mrouter.HandleRequest(c.Writer, websocket.WithUserID(c.Request, api.GetUserID(c)))
 	})
+	tasks.StartOverdueChecker(time.Minute)
+	tasks.StartTaskLockCleaner(30 * time.Second)
+	tasks.StartRankRebalancer(10 * time.Minute)
 
 	// Set listening host:port
 	url := getListeningURL()
@@ -105,3 +190,74 @@ func getListeningURL() string {
 	}
 	return fmt.Sprintf("%s:%d", host, port)
 }
+
+// getDatabase returns dialect and data source name of database.
+// ex) postgres "host=localhost port=5432 user=taskboard dbname=taskboard password=taskboard sslmode=disable"
+// ex) mysql "taskboard:taskboard@tcp(localhost:3306)/taskboard?charset=utf8mb4&parseTime=true"
+func getDatabase() (dialect, dsn string) {
+	dialect = os.Getenv("TASKBOARD_API_DB_DIALECT")
+	if dialect == "" {
+		fmt.Println("Environment variable [TASKBOARD_API_DB_DIALECT] is not set. sqlite3 is used as default")
+		dialect = orm.DialectSQLite3
+	}
+	dsn = os.Getenv("TASKBOARD_API_DB_DSN")
+	if dsn == "" && dialect == orm.DialectSQLite3 {
+		fmt.Println("Environment variable [TASKBOARD_API_DB_DSN] is not set. ./taskboard.sqlite3 is used as default")
+		dsn = "./taskboard.sqlite3"
+	}
+	return
+}
+
+func getAdminUser() (name, password string) {
+	name = os.Getenv("TASKBOARD_API_ADMIN_NAME")
+	if name == "" {
+		fmt.Println("Environment variable [TASKBOARD_API_ADMIN_NAME] is not set, admin is used as default.")
+		name = "admin"
+	}
+	password = os.Getenv("TASKBOARD_API_ADMIN_PASSWORD")
+	if password == "" {
+		fmt.Println("Environment variable [TASKBOARD_API_ADMIN_PASSWORD] is not set, random password is generated if admin user is created.")
+	}
+	return
+}
+
+func getTokenSecret() string {
+	secret := os.Getenv("TASKBOARD_API_TOKEN_SECRET")
+	if secret == "" {
+		fmt.Println("Environment variable [TASKBOARD_API_TOKEN_SECRET] is not set, random secret is used. Tokens are invalidated by restart.")
+	}
+	return secret
+}
+
+func getAttachmentDir() string {
+	dir := os.Getenv("TASKBOARD_API_ATTACHMENT_DIR")
+	if dir == "" {
+		fmt.Println("Environment variable [TASKBOARD_API_ATTACHMENT_DIR] is not set. ./attachments is used as default")
+		dir = "./attachments"
+	}
+	return dir
+}
+
+func getAvatarDir() string {
+	dir := os.Getenv("TASKBOARD_API_AVATAR_DIR")
+	if dir == "" {
+		fmt.Println("Environment variable [TASKBOARD_API_AVATAR_DIR] is not set. ./avatars is used as default")
+		dir = "./avatars"
+	}
+	return dir
+}
+
+// getRealtimePublisher returns publisher of websocket events.
+// "database" must be used when plural api instances share the database.
+func getRealtimePublisher() websocket.Publisher {
+	backend := os.Getenv("TASKBOARD_API_REALTIME_BACKEND")
+	switch backend {
+	case "database":
+		return websocket.NewDBPublisher(websocket.DefaultPollInterval, websocket.DefaultEventRetention)
+	case "local":
+		return websocket.NewLocalPublisher()
+	default:
+		fmt.Println("Environment variable [TASKBOARD_API_REALTIME_BACKEND] is not set or invalid. local is used as default")
+		return websocket.NewLocalPublisher()
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"taskboard-api-go/migration"
+	"taskboard-api-go/orm"
+	"time"
+)
+
+const migrateUsage = `usage: taskboard-api-go migrate <command>
+  up [version]  apply migrations until the version (default: latest)
+  down [steps]  rollback the number of applied migrations (default: 1)
+  status        show migrations and whether they are applied`
+
+// runMigrateCommand runs migrate subcommand, and returns exit code
+func runMigrateCommand(args []string) int {
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Println(migrateUsage)
+		return 2
+	}
+	number := 0
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			fmt.Printf("Invalid number:%s\n%s\n", args[1], migrateUsage)
+			return 2
+		}
+		number = n
+	}
+	db := orm.GetDB()
+	switch args[0] {
+	case "up":
+		applied, err := migration.Up(db, number)
+		for _, m := range applied {
+			fmt.Printf("Applied %04d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Printf("Failed to apply migrations. error:%+v\n", err)
+			return 1
+		}
+	case "down":
+		if number == 0 {
+			number = 1
+		}
+		rolledBack, err := migration.Down(db, number)
+		for _, m := range rolledBack {
+			fmt.Printf("Rolled back %04d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Printf("Failed to rollback migrations. error:%+v\n", err)
+			return 1
+		}
+	case "status":
+		if len(args) != 1 {
+			fmt.Println(migrateUsage)
+			return 2
+		}
+		statuses, err := migration.Statuses(db)
+		if err != nil {
+			fmt.Printf("Failed to get status of migrations. error:%+v\n", err)
+			return 1
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedDate != nil {
+				state = "applied at " + status.AppliedDate.Format(time.RFC3339)
+			}
+			if status.Unknown {
+				state += " (unknown to this binary)"
+			}
+			fmt.Printf("%04d %-40s %s\n", status.Version, status.Name, state)
+		}
+	default:
+		fmt.Println(migrateUsage)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,213 @@
+package migration
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// Migration is a numbered step to change database schema.
+// Up and Down are called in a transaction, Down must revert what Up did.
+// On MySQL, DDL statements (ex. CREATE TABLE, ALTER TABLE) commit the transaction implicitly, so a step which
+// fails partway is not rolled back and not recorded as applied. Changes made before the failure must be reverted
+// manually before applying it again, check them by the error and Up of the step.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// Migrations are all steps which this binary knows, in ascending order of version.
+// Never change released steps, add a new step instead.
+var Migrations = []Migration{
+	{1, "initial schema", upInitialSchema, downInitialSchema},
+	{2, "sort rank of tasks and boards", upSortRank, downSortRank},
+	{3, "unique rank of tasks in board", upUniqueTaskRank, downUniqueTaskRank},
+}
+
+// schemaMigration is a record of applied migration
+type schemaMigration struct {
+	Version     int       `gorm:"primary_key;auto_increment:false"`
+	Name        string    `gorm:"not null;size:255"`
+	AppliedDate time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status presents whether a migration is applied
+type Status struct {
+	Version     int
+	Name        string
+	AppliedDate *time.Time // Null if not applied
+	Unknown     bool       // True if applied by newer binary
+}
+
+// LatestVersion returns the latest version of schema which this binary knows
+func LatestVersion() int {
+	if len(Migrations) == 0 {
+		return 0
+	}
+	return Migrations[len(Migrations)-1].Version
+}
+
+// CurrentVersion returns the version of applied schema, returns 0 if no migration is applied
+func CurrentVersion(db *gorm.DB) (int, error) {
+	applied, err := findApplied(db)
+	if err != nil || len(applied) == 0 {
+		return 0, err
+	}
+	return applied[len(applied)-1].Version, nil
+}
+
+// Check returns error if the database schema is newer than this binary knows
+func Check(db *gorm.DB) error {
+	current, err := CurrentVersion(db)
+	if err != nil {
+		return err
+	}
+	if current > LatestVersion() {
+		return fmt.Errorf("Database schema version %d is newer than %d which this binary knows. Upgrade the binary", current, LatestVersion())
+	}
+	return nil
+}
+
+// Pending returns migrations which are not applied yet, in ascending order of version
+func Pending(db *gorm.DB) ([]Migration, error) {
+	current, err := CurrentVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	result := []Migration{}
+	for _, m := range Migrations {
+		if m.Version > current {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+// Up applies migrations until target version. All migrations are applied if target is 0.
+// It returns applied migrations.
+func Up(db *gorm.DB, target int) ([]Migration, error) {
+	if err := Check(db); err != nil {
+		return nil, err
+	}
+	current, err := CurrentVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	result := []Migration{}
+	for _, m := range Migrations {
+		if m.Version <= current || (target > 0 && m.Version > target) {
+			continue
+		}
+		err := inTransaction(db, func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedDate: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return result, errors.Wrapf(err, "Failed to apply migration %d %s", m.Version, m.Name)
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+// Down rolls back specified number of applied migrations from the latest one.
+// It returns rolled back migrations.
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	applied, err := findApplied(db)
+	if err != nil {
+		return nil, err
+	}
+	result := []Migration{}
+	for i := len(applied) - 1; i >= 0 && len(result) < steps; i-- {
+		m, exists := findMigration(applied[i].Version)
+		if !exists {
+			return result, fmt.Errorf("Migration %d is unknown, rollback it by the binary which applied it", applied[i].Version)
+		}
+		err := inTransaction(db, func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{Version: m.Version}).Error
+		})
+		if err != nil {
+			return result, errors.Wrapf(err, "Failed to rollback migration %d %s", m.Version, m.Name)
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+// Statuses returns status of known migrations and applied unknown migrations, in ascending order of version
+func Statuses(db *gorm.DB) ([]Status, error) {
+	applied, err := findApplied(db)
+	if err != nil {
+		return nil, err
+	}
+	appliedMap := make(map[int]schemaMigration, len(applied))
+	for _, a := range applied {
+		appliedMap[a.Version] = a
+	}
+	result := make([]Status, 0, len(Migrations)+len(applied))
+	for _, m := range Migrations {
+		status := Status{Version: m.Version, Name: m.Name}
+		if a, exists := appliedMap[m.Version]; exists {
+			appliedDate := a.AppliedDate
+			status.AppliedDate = &appliedDate
+		}
+		result = append(result, status)
+	}
+	for _, a := range applied {
+		if _, exists := findMigration(a.Version); !exists {
+			appliedDate := a.AppliedDate
+			result = append(result, Status{Version: a.Version, Name: a.Name, AppliedDate: &appliedDate, Unknown: true})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+	return result, nil
+}
+
+// findApplied returns applied migrations in ascending order of version, the table is created if not exists
+func findApplied(db *gorm.DB) ([]schemaMigration, error) {
+	if err := db.AutoMigrate(&schemaMigration{}).Error; err != nil {
+		return nil, errors.Wrap(err, "Failed to create schema_migrations table")
+	}
+	var result []schemaMigration
+	if err := db.Order("version").Find(&result).Error; err != nil {
+		return nil, errors.Wrap(err, "Failed to find applied migrations")
+	}
+	return result, nil
+}
+
+func findMigration(version int) (Migration, bool) {
+	for _, m := range Migrations {
+		if m.Version == version {
+			return m, true
+		}
+	}
+	return Migration{}, false
+}
+
+func inTransaction(db *gorm.DB, f func(tx *gorm.DB) error) error {
+	tx := db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
@@ -0,0 +1,243 @@
+package migration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"taskboard-api-go/model"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite" //to load dialect
+	"github.com/stretchr/testify/assert"
+)
+
+func openTestDB(t *testing.T) (*gorm.DB, func()) {
+	dir, err := ioutil.TempDir("", "migration_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %+v", err)
+	}
+	db, err := gorm.Open("sqlite3", filepath.Join(dir, "migration_test.sqlite3"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("Failed to open database: %+v", err)
+	}
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// models must be same as tables created by all migrations
+var models = []interface{}{
+	&model.User{},
+	&model.Task{},
+	&model.Board{},
+	&model.Comment{},
+	&model.Activity{},
+	&model.Label{},
+	&model.TaskLabel{},
+	&model.ChecklistItem{},
+	&model.TaskDependency{},
+	&model.Attachment{},
+	&model.RealtimeEvent{},
+	&model.TaskLock{},
+	&model.JobLease{},
+}
+
+func TestMigrations(t *testing.T) {
+	for i, m := range Migrations {
+		assert.NotNil(t, m.Up, "Up of %d", m.Version)
+		assert.NotNil(t, m.Down, "Down of %d", m.Version)
+		if i > 0 {
+			assert.True(t, Migrations[i-1].Version < m.Version, "Version must be ascending. %d", m.Version)
+		}
+	}
+}
+
+func TestUpAndDown(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	applied, err := Up(db, 0)
+	if err != nil {
+		t.Fatalf("Failed to apply migrations: %+v", err)
+	}
+	assert.Len(t, applied, len(Migrations))
+	current, err := CurrentVersion(db)
+	assert.NoError(t, err)
+	assert.Equal(t, LatestVersion(), current)
+	// All columns of models exist
+	for _, m := range models {
+		scope := db.NewScope(m)
+		for _, field := range scope.Fields() {
+			if field.IsNormal && !field.IsIgnored {
+				assert.True(t, db.Dialect().HasColumn(scope.TableName(), field.DBName),
+					"%s.%s does not exist", scope.TableName(), field.DBName)
+			}
+		}
+	}
+	// Nothing is applied twice
+	applied, err = Up(db, 0)
+	assert.NoError(t, err)
+	assert.Len(t, applied, 0)
+	statuses, err := Statuses(db)
+	assert.NoError(t, err)
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedDate, "%d is not applied", status.Version)
+	}
+
+	rolledBack, err := Down(db, len(Migrations))
+	if err != nil {
+		t.Fatalf("Failed to rollback migrations: %+v", err)
+	}
+	assert.Len(t, rolledBack, len(Migrations))
+	current, err = CurrentVersion(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, current)
+	for _, m := range models {
+		assert.False(t, db.HasTable(m), "%s remains", db.NewScope(m).TableName())
+	}
+}
+
+func TestCheck(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	pending, err := Pending(db)
+	assert.NoError(t, err)
+	assert.Len(t, pending, len(Migrations))
+	if _, err := Up(db, 0); err != nil {
+		t.Fatalf("Failed to apply migrations: %+v", err)
+	}
+	assert.NoError(t, Check(db))
+	pending, err = Pending(db)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 0)
+
+	// The latest migration is reverted
+	if _, err := Down(db, 1); err != nil {
+		t.Fatalf("Failed to revert migrations: %+v", err)
+	}
+	pending, err = Pending(db)
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, LatestVersion(), pending[0].Version)
+	}
+	if _, err := Up(db, 0); err != nil {
+		t.Fatalf("Failed to apply migrations: %+v", err)
+	}
+
+	// Applied by newer binary
+	newer := &schemaMigration{Version: LatestVersion() + 1, Name: "newer", AppliedDate: time.Now().UTC()}
+	if err := db.Create(newer).Error; err != nil {
+		t.Fatalf("Failed to insert migration: %+v", err)
+	}
+	assert.Error(t, Check(db))
+	_, err = Up(db, 0)
+	assert.Error(t, err)
+	_, err = Down(db, 1)
+	assert.Error(t, err)
+	statuses, err := Statuses(db)
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, len(Migrations)+1) {
+		assert.True(t, statuses[len(statuses)-1].Unknown)
+	}
+}
+
+func TestSortRank(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	if _, err := Up(db, 1); err != nil {
+		t.Fatalf("Failed to apply migrations: %+v", err)
+	}
+	now := time.Now().UTC()
+	rows := []interface{}{
+		&v1Board{ID: "board2", Name: "board2", DispOrder: 1, CreatedDate: now, Version: 1},
+		&v1Board{ID: "board1", Name: "board1", DispOrder: 0, CreatedDate: now, Version: 1},
+		&v1Task{ID: "task3", Name: "task3", BoardID: "board1", DispOrder: 2, CreatedDate: now, Version: 1},
+		&v1Task{ID: "task1", Name: "task1", BoardID: "board1", DispOrder: 1, CreatedDate: now, Version: 1},
+		&v1Task{ID: "task2", Name: "task2", BoardID: "board1", DispOrder: 1, CreatedDate: now.Add(time.Second), Version: 1},
+		&v1Task{ID: "task4", Name: "task4", BoardID: "board2", DispOrder: 5, CreatedDate: now, Version: 1},
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("Failed to insert row: %+v", err)
+		}
+	}
+
+	if _, err := Up(db, 2); err != nil {
+		t.Fatalf("Failed to apply migrations: %+v", err)
+	}
+	assert.False(t, db.Dialect().HasColumn("tasks", "disp_order"))
+	assert.False(t, db.Dialect().HasColumn("boards", "disp_order"))
+	var boards []v2Board
+	assert.NoError(t, db.Order("sort_rank").Find(&boards).Error)
+	if assert.Len(t, boards, 2) {
+		assert.Equal(t, "board1", boards[0].ID)
+		assert.Equal(t, "board2", boards[1].ID)
+	}
+	var tasks []v2Task
+	assert.NoError(t, db.Where("board_id = ?", "board1").Order("sort_rank").Find(&tasks).Error)
+	if assert.Len(t, tasks, 3) {
+		assert.Equal(t, "task1", tasks[0].ID)
+		assert.Equal(t, "task2", tasks[1].ID)
+		assert.Equal(t, "task3", tasks[2].ID)
+		assert.Equal(t, "task1", tasks[0].Name, "Other columns are kept")
+	}
+
+	if _, err := Down(db, 1); err != nil {
+		t.Fatalf("Failed to rollback migrations: %+v", err)
+	}
+	assert.False(t, db.Dialect().HasColumn("tasks", "sort_rank"))
+	var oldTasks []v1Task
+	assert.NoError(t, db.Order("board_id, disp_order").Find(&oldTasks).Error)
+	if assert.Len(t, oldTasks, 4) {
+		for i, id := range []string{"task1", "task2", "task3", "task4"} {
+			assert.Equal(t, id, oldTasks[i].ID)
+		}
+		assert.Equal(t, 3, oldTasks[2].DispOrder)
+	}
+}
+
+func TestUniqueTaskRank(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	if _, err := Up(db, 2); err != nil {
+		t.Fatalf("Failed to apply migrations: %+v", err)
+	}
+	now := time.Now().UTC()
+	rows := []interface{}{
+		&v2Task{ID: "task2", Name: "task2", BoardID: "board1", Rank: "i", CreatedDate: now.Add(time.Second), Version: 1},
+		&v2Task{ID: "task1", Name: "task1", BoardID: "board1", Rank: "i", CreatedDate: now, Version: 1},
+		&v2Task{ID: "task3", Name: "task3", BoardID: "board1", Rank: "r", CreatedDate: now, Version: 1},
+		&v2Task{ID: "task4", Name: "task4", BoardID: "board2", Rank: "i", CreatedDate: now, Version: 1},
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("Failed to insert row: %+v", err)
+		}
+	}
+
+	if _, err := Up(db, 3); err != nil {
+		t.Fatalf("Failed to apply migrations: %+v", err)
+	}
+	var tasks []v2Task
+	assert.NoError(t, db.Order("board_id, sort_rank").Find(&tasks).Error)
+	if assert.Len(t, tasks, 4) {
+		for i, id := range []string{"task1", "task2", "task3", "task4"} {
+			assert.Equal(t, id, tasks[i].ID)
+		}
+		assert.Equal(t, "i", tasks[3].Rank, "Ranks of board without duplication are kept")
+	}
+	duplicated := &v2Task{ID: "task5", Name: "task5", BoardID: "board2", Rank: "i", CreatedDate: now, Version: 1}
+	assert.Error(t, db.Create(duplicated).Error)
+
+	if _, err := Down(db, 1); err != nil {
+		t.Fatalf("Failed to rollback migrations: %+v", err)
+	}
+	assert.NoError(t, db.Create(duplicated).Error)
+}
@@ -0,0 +1,188 @@
+package migration
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Tables at version 1, which were created by AutoMigrate of models before migrations are introduced.
+// It also upgrades databases created by AutoMigrate, because AutoMigrate only adds missing tables, columns and indexes.
+// Snapshots are used instead of models, so that later changes of models never change this step.
+
+type v1User struct {
+	ID           string `gorm:"primary_key;size:32"`
+	Name         string `gorm:"not null;size:255;unique"`
+	PasswordHash string `gorm:"not null;size:255"`
+	Avatar       string `gorm:"size:255"`
+	Role         string `gorm:"not null;size:32;default:'member'"`
+	Version      int    `gorm:"not null"`
+}
+
+func (v1User) TableName() string { return "users" }
+
+type v1Task struct {
+	ID             string         `gorm:"primary_key;size:32"`
+	Name           string         `gorm:"not null;size:255"`
+	Description    string         `gorm:"size:8000"`
+	AssigneeUserID sql.NullString `gorm:"size:32"`
+	BoardID        string         `gorm:"not null;size:32"`
+	DispOrder      int            `gorm:"not null"`
+	CreatedDate    time.Time      `gorm:"not null"`
+	IsClosed       bool           `gorm:"not null"`
+	Version        int            `gorm:"not null"`
+	EstimateSize   int
+	StartDate      *time.Time
+	DueDate        *time.Time     `gorm:"index"`
+	ParentTaskID   sql.NullString `gorm:"size:32;index"`
+}
+
+func (v1Task) TableName() string { return "tasks" }
+
+type v1Board struct {
+	ID          string    `gorm:"primary_key;size:32"`
+	Name        string    `gorm:"unique;size:255"`
+	DispOrder   int       `gorm:"not null"`
+	IsSystem    bool      `gorm:"not null"`
+	IsClosed    bool      `gorm:"not null"`
+	CreatedDate time.Time `gorm:"not null"`
+	Version     int       `gorm:"not null"`
+}
+
+func (v1Board) TableName() string { return "boards" }
+
+type v1Comment struct {
+	ID           string    `gorm:"primary_key;size:32"`
+	TaskID       string    `gorm:"not null;size:32;index"`
+	AuthorUserID string    `gorm:"not null;size:32"`
+	Body         string    `gorm:"not null;size:8000"`
+	CreatedDate  time.Time `gorm:"not null"`
+	EditedDate   *time.Time
+	Version      int `gorm:"not null"`
+}
+
+func (v1Comment) TableName() string { return "comments" }
+
+type v1Activity struct {
+	ID            string    `gorm:"primary_key;size:32"`
+	EntityKind    string    `gorm:"not null;size:32;index:idx_activity_entity"`
+	EntityID      string    `gorm:"not null;size:32;index:idx_activity_entity"`
+	EntityVersion int       `gorm:"not null"`
+	Action        string    `gorm:"not null;size:32"`
+	Field         string    `gorm:"size:64"`
+	OldValue      string    `gorm:"type:text"`
+	NewValue      string    `gorm:"type:text"`
+	ActorUserID   string    `gorm:"not null;size:32"`
+	CreatedDate   time.Time `gorm:"not null;index"`
+}
+
+func (v1Activity) TableName() string { return "activities" }
+
+type v1Label struct {
+	ID          string    `gorm:"primary_key;size:32"`
+	Name        string    `gorm:"not null;size:255;unique"`
+	Color       string    `gorm:"not null;size:7"`
+	CreatedDate time.Time `gorm:"not null"`
+	Version     int       `gorm:"not null"`
+}
+
+func (v1Label) TableName() string { return "labels" }
+
+type v1TaskLabel struct {
+	TaskID  string `gorm:"primary_key;size:32"`
+	LabelID string `gorm:"primary_key;size:32;index"`
+}
+
+func (v1TaskLabel) TableName() string { return "task_labels" }
+
+type v1ChecklistItem struct {
+	ID          string    `gorm:"primary_key;size:32"`
+	TaskID      string    `gorm:"not null;size:32;index"`
+	Name        string    `gorm:"not null;size:255"`
+	IsDone      bool      `gorm:"not null"`
+	DispOrder   int       `gorm:"not null"`
+	CreatedDate time.Time `gorm:"not null"`
+	Version     int       `gorm:"not null"`
+}
+
+func (v1ChecklistItem) TableName() string { return "checklist_items" }
+
+type v1TaskDependency struct {
+	BlockingTaskID string    `gorm:"primary_key;size:32"`
+	BlockedTaskID  string    `gorm:"primary_key;size:32;index"`
+	CreatedDate    time.Time `gorm:"not null"`
+}
+
+func (v1TaskDependency) TableName() string { return "task_dependencies" }
+
+type v1Attachment struct {
+	ID             string    `gorm:"primary_key;size:32"`
+	TaskID         string    `gorm:"not null;size:32;index"`
+	FileName       string    `gorm:"not null;size:255"`
+	ContentType    string    `gorm:"not null;size:255"`
+	Size           int64     `gorm:"not null"`
+	StorageKey     string    `gorm:"not null;size:64"`
+	UploaderUserID string    `gorm:"not null;size:32"`
+	CreatedDate    time.Time `gorm:"not null"`
+}
+
+func (v1Attachment) TableName() string { return "attachments" }
+
+type v1RealtimeEvent struct {
+	ID          int64     `gorm:"primary_key;AUTO_INCREMENT"`
+	Message     string    `gorm:"not null;type:text"`
+	CreatedDate time.Time `gorm:"not null;index"`
+}
+
+func (v1RealtimeEvent) TableName() string { return "realtime_events" }
+
+type v1TaskLock struct {
+	TaskID      string    `gorm:"primary_key;size:32"`
+	UserID      string    `gorm:"not null;size:32"`
+	ExpiresDate time.Time `gorm:"not null;index"`
+	CreatedDate time.Time `gorm:"not null"`
+	Version     int       `gorm:"not null"`
+}
+
+func (v1TaskLock) TableName() string { return "task_locks" }
+
+type v1JobLease struct {
+	Name        string    `gorm:"primary_key;size:64"`
+	Holder      string    `gorm:"not null;size:32"`
+	ExpiresDate time.Time `gorm:"not null"`
+}
+
+func (v1JobLease) TableName() string { return "job_leases" }
+
+func v1Tables() []interface{} {
+	return []interface{}{
+		&v1User{},
+		&v1Task{},
+		&v1Board{},
+		&v1Comment{},
+		&v1Activity{},
+		&v1Label{},
+		&v1TaskLabel{},
+		&v1ChecklistItem{},
+		&v1TaskDependency{},
+		&v1Attachment{},
+		&v1RealtimeEvent{},
+		&v1TaskLock{},
+		&v1JobLease{},
+	}
+}
+
+func upInitialSchema(tx *gorm.DB) error {
+	return tx.AutoMigrate(v1Tables()...).Error
+}
+
+func downInitialSchema(tx *gorm.DB) error {
+	tables := v1Tables()
+	for i := len(tables) - 1; i >= 0; i-- {
+		if err := tx.DropTableIfExists(tables[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package migration
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"taskboard-api-go/common"
+	"taskboard-api-go/orm"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Tables at version 2, which replace integer disp_order of tasks and boards by sort_rank.
+// sort_rank is a string sorted lexicographically, so that moving an item updates only the row of it.
+
+type v2Task struct {
+	ID             string         `gorm:"primary_key;size:32"`
+	Name           string         `gorm:"not null;size:255"`
+	Description    string         `gorm:"size:8000"`
+	AssigneeUserID sql.NullString `gorm:"size:32"`
+	BoardID        string         `gorm:"not null;size:32;index:idx_tasks_board_id_sort_rank"`
+	Rank           string         `gorm:"column:sort_rank;not null;size:255;default:'';index:idx_tasks_board_id_sort_rank"`
+	CreatedDate    time.Time      `gorm:"not null"`
+	IsClosed       bool           `gorm:"not null"`
+	Version        int            `gorm:"not null"`
+	EstimateSize   int
+	StartDate      *time.Time
+	DueDate        *time.Time     `gorm:"index"`
+	ParentTaskID   sql.NullString `gorm:"size:32;index"`
+}
+
+func (v2Task) TableName() string { return "tasks" }
+
+type v2Board struct {
+	ID          string    `gorm:"primary_key;size:32"`
+	Name        string    `gorm:"unique;size:255"`
+	Rank        string    `gorm:"column:sort_rank;not null;size:255;default:''"`
+	IsSystem    bool      `gorm:"not null"`
+	IsClosed    bool      `gorm:"not null"`
+	CreatedDate time.Time `gorm:"not null"`
+	Version     int       `gorm:"not null"`
+}
+
+func (v2Board) TableName() string { return "boards" }
+
+// v1DispOrder restores disp_order column at rollback, default is required to add not null column to existing rows
+type v1DispOrder struct {
+	DispOrder int `gorm:"not null;default:0"`
+}
+
+type rankedRow struct {
+	ID      string
+	BoardID string
+	Rank    string `gorm:"column:sort_rank"`
+}
+
+func upSortRank(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&v2Task{}, &v2Board{}).Error; err != nil {
+		return err
+	}
+	// Ranks are assigned in order of disp_order, ties are sorted by created date
+	var boards []rankedRow
+	err := tx.Table("boards").Select("id").Order("disp_order, created_date, id").Scan(&boards).Error
+	if err != nil {
+		return err
+	}
+	if err = updateSortRanks(tx, "boards", boards); err != nil {
+		return err
+	}
+	var tasks []rankedRow
+	err = tx.Table("tasks").Select("id, board_id").Order("board_id, disp_order, created_date, id").Scan(&tasks).Error
+	if err != nil {
+		return err
+	}
+	for _, group := range groupByBoard(tasks) {
+		if err = updateSortRanks(tx, "tasks", group); err != nil {
+			return err
+		}
+	}
+	if err = dropColumn(tx, &v2Task{}, "disp_order"); err != nil {
+		return err
+	}
+	return dropColumn(tx, &v2Board{}, "disp_order")
+}
+
+func downSortRank(tx *gorm.DB) error {
+	if err := tx.Table("tasks").AutoMigrate(&v1DispOrder{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Table("boards").AutoMigrate(&v1DispOrder{}).Error; err != nil {
+		return err
+	}
+	// Boards were numbered from 0 and tasks were numbered from 1 in each board
+	var boards []rankedRow
+	err := tx.Table("boards").Select("id").Order("sort_rank, id").Scan(&boards).Error
+	if err != nil {
+		return err
+	}
+	if err = updateDispOrders(tx, "boards", boards, 0); err != nil {
+		return err
+	}
+	var tasks []rankedRow
+	err = tx.Table("tasks").Select("id, board_id").Order("board_id, sort_rank, id").Scan(&tasks).Error
+	if err != nil {
+		return err
+	}
+	for _, group := range groupByBoard(tasks) {
+		if err = updateDispOrders(tx, "tasks", group, 1); err != nil {
+			return err
+		}
+	}
+	if err = tx.Model(&v2Task{}).RemoveIndex("idx_tasks_board_id_sort_rank").Error; err != nil {
+		return err
+	}
+	if err = dropColumn(tx, &v1Task{}, "sort_rank"); err != nil {
+		return err
+	}
+	return dropColumn(tx, &v1Board{}, "sort_rank")
+}
+
+// groupByBoard splits rows sorted by board id into groups of each board
+func groupByBoard(rows []rankedRow) [][]rankedRow {
+	groups := [][]rankedRow{}
+	start := 0
+	for i := 1; i <= len(rows); i++ {
+		if i == len(rows) || rows[i].BoardID != rows[start].BoardID {
+			groups = append(groups, rows[start:i])
+			start = i
+		}
+	}
+	return groups
+}
+
+func updateSortRanks(tx *gorm.DB, table string, rows []rankedRow) error {
+	ranks := common.EvenRanks(len(rows))
+	for i, row := range rows {
+		if err := tx.Table(table).Where("id = ?", row.ID).Update("sort_rank", ranks[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func updateDispOrders(tx *gorm.DB, table string, rows []rankedRow, first int) error {
+	for i, row := range rows {
+		if err := tx.Table(table).Where("id = ?", row.ID).Update("disp_order", first+i).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropColumn drops the column from the table of snapshot, which must not have the column.
+// SQLite cannot drop column, so the table is rebuilt by the snapshot and rows are copied.
+func dropColumn(tx *gorm.DB, snapshot interface{}, column string) error {
+	scope := tx.NewScope(snapshot)
+	table := scope.TableName()
+	if tx.Dialect().GetName() != orm.DialectSQLite3 {
+		return tx.Table(table).DropColumn(column).Error
+	}
+	// Index names are unique in the database, so drop them before the snapshot creates them again
+	rows, err := tx.Raw("SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", table).Rows()
+	if err != nil {
+		return err
+	}
+	indexes := []string{}
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		indexes = append(indexes, name)
+	}
+	rows.Close()
+	for _, index := range indexes {
+		if err = tx.Exec("DROP INDEX " + scope.Quote(index)).Error; err != nil {
+			return err
+		}
+	}
+	old := table + "_old"
+	if err = tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", scope.Quote(table), scope.Quote(old))).Error; err != nil {
+		return err
+	}
+	if err = tx.AutoMigrate(snapshot).Error; err != nil {
+		return err
+	}
+	columns := []string{}
+	for _, field := range scope.Fields() {
+		if field.IsNormal && !field.IsIgnored {
+			columns = append(columns, scope.Quote(field.DBName))
+		}
+	}
+	err = tx.Exec(fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
+		scope.Quote(table), strings.Join(columns, ", "), strings.Join(columns, ", "), scope.Quote(old))).Error
+	if err != nil {
+		return err
+	}
+	return tx.DropTable(old).Error
+}
@@ -0,0 +1,44 @@
+package migration
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// Rank of tasks becomes unique in each board, so that concurrent moves to the same position never make duplicated ranks.
+// Duplicated ranks made before this step are rebalanced, keeping their order by created date.
+
+func upUniqueTaskRank(tx *gorm.DB) error {
+	var tasks []rankedRow
+	err := tx.Table("tasks").Select("id, board_id, sort_rank").Order("board_id, sort_rank, created_date, id").Scan(&tasks).Error
+	if err != nil {
+		return err
+	}
+	for _, group := range groupByBoard(tasks) {
+		if !hasDuplicatedRank(group) {
+			continue
+		}
+		if err = updateSortRanks(tx, "tasks", group); err != nil {
+			return err
+		}
+	}
+	if err = tx.Model(&v2Task{}).RemoveIndex("idx_tasks_board_id_sort_rank").Error; err != nil {
+		return err
+	}
+	return tx.Model(&v2Task{}).AddUniqueIndex("idx_tasks_board_id_sort_rank", "board_id", "sort_rank").Error
+}
+
+func downUniqueTaskRank(tx *gorm.DB) error {
+	if err := tx.Model(&v2Task{}).RemoveIndex("idx_tasks_board_id_sort_rank").Error; err != nil {
+		return err
+	}
+	return tx.Model(&v2Task{}).AddIndex("idx_tasks_board_id_sort_rank", "board_id", "sort_rank").Error
+}
+
+func hasDuplicatedRank(rows []rankedRow) bool {
+	for i := 1; i < len(rows); i++ {
+		if rows[i-1].Rank == rows[i].Rank {
+			return true
+		}
+	}
+	return false
+}
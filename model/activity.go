@@ -0,0 +1,56 @@
+package model
+
+import (
+	"taskboard-api-go/common"
+	"time"
+)
+
+// Definition of entity kinds whose activities are recorded
+const (
+	EntityKindTask  = "task"
+	EntityKindBoard = "board"
+	EntityKindUser  = "user"
+)
+
+// Definition of activity actions
+const (
+	ActivityActionCreate  = "create"
+	ActivityActionUpdate  = "update"
+	ActivityActionDelete  = "delete"
+	ActivityActionReorder = "reorder"
+)
+
+// Activity presents an immutable history entry of a change of an entity.
+// An update of plural fields is recorded as plural activities (one activity per field).
+type Activity struct {
+	ID            string    `gorm:"primary_key;size:32"`
+	EntityKind    string    `gorm:"not null;size:32;index:idx_activity_entity"`
+	EntityID      string    `gorm:"not null;size:32;index:idx_activity_entity"`
+	EntityVersion int       `gorm:"not null"` // Version of the entity after the change
+	Action        string    `gorm:"not null;size:32"`
+	Field         string    `gorm:"size:64"`   // Empty when action is create or delete
+	OldValue      string    `gorm:"type:text"` // Empty when action is create
+	NewValue      string    `gorm:"type:text"` // Empty when action is delete
+	ActorUserID   string    `gorm:"not null;size:32"`
+	CreatedDate   time.Time `gorm:"not null;index"`
+}
+
+// NewActivity returns created new activity
+func NewActivity(entityKind, entityID string, entityVersion int, action, actorUserID string, now time.Time) *Activity {
+	return &Activity{
+		ID:            "activity_" + common.GenerateID(),
+		EntityKind:    entityKind,
+		EntityID:      entityID,
+		EntityVersion: entityVersion,
+		Action:        action,
+		ActorUserID:   actorUserID,
+		CreatedDate:   now,
+	}
+}
+
+// SetChange sets changed field and its values
+func (a *Activity) SetChange(field, oldValue, newValue string) {
+	a.Field = field
+	a.OldValue = oldValue
+	a.NewValue = newValue
+}
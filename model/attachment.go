@@ -0,0 +1,34 @@
+package model
+
+import (
+	"taskboard-api-go/common"
+	"time"
+)
+
+// Attachment presents a file attached to a task.
+// Content of the file is stored in storage by StorageKey.
+type Attachment struct {
+	ID             string    `gorm:"primary_key;size:32"`
+	TaskID         string    `gorm:"not null;size:32;index"`
+	FileName       string    `gorm:"not null;size:255"`
+	ContentType    string    `gorm:"not null;size:255"`
+	Size           int64     `gorm:"not null"`
+	StorageKey     string    `gorm:"not null;size:64"`
+	UploaderUserID string    `gorm:"not null;size:32"`
+	CreatedDate    time.Time `gorm:"not null"`
+}
+
+// NewAttachment returns created new attachment, its storage key is same as its ID
+func NewAttachment(taskID, fileName, contentType string, size int64, uploaderUserID string, now time.Time) *Attachment {
+	id := "attachment_" + common.GenerateID()
+	return &Attachment{
+		ID:             id,
+		TaskID:         taskID,
+		FileName:       fileName,
+		ContentType:    contentType,
+		Size:           size,
+		StorageKey:     id,
+		UploaderUserID: uploaderUserID,
+		CreatedDate:    now,
+	}
+}
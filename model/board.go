@@ -9,7 +9,7 @@ import (
 type Board struct {
 	ID          string    `gorm:"primary_key;size:32"`
 	Name        string    `gorm:"unique;size:255"`
-	DispOrder   int       `gorm:"not null"`
+	Rank        string    `gorm:"column:sort_rank;not null;size:255"` // Sorted lexicographically
 	IsSystem    bool      `gorm:"not null"`
 	IsClosed    bool      `gorm:"not null"`
 	CreatedDate time.Time `gorm:"not null"`
@@ -20,7 +20,6 @@ type Board struct {
 var SystemBoardIcebox = &Board{
 	ID:          "board_icebox",
 	Name:        "Icebox",
-	DispOrder:   0,
 	IsSystem:    true,
 	CreatedDate: time.Now().UTC(),
 	Version:     1,
@@ -30,7 +29,6 @@ var SystemBoardIcebox = &Board{
 var SystemBoardTodo = &Board{
 	ID:          "board_todo",
 	Name:        "Todo",
-	DispOrder:   1,
 	IsSystem:    true,
 	CreatedDate: time.Now().UTC(),
 	Version:     1,
@@ -40,7 +38,6 @@ var SystemBoardTodo = &Board{
 var SystemBoardDoing = &Board{
 	ID:          "board_doing",
 	Name:        "Doing",
-	DispOrder:   2,
 	IsSystem:    true,
 	CreatedDate: time.Now().UTC(),
 	Version:     1,
@@ -50,7 +47,6 @@ var SystemBoardDoing = &Board{
 var SystemBoardDone = &Board{
 	ID:          "board_done",
 	Name:        "Done",
-	DispOrder:   3,
 	IsSystem:    true,
 	CreatedDate: time.Now().UTC(),
 	Version:     1,
@@ -61,7 +57,6 @@ func NewBoard(name string, isSystem, isClosed bool, now time.Time) *Board {
 	return &Board{
 		ID:          "board_" + common.GenerateID(),
 		Name:        name,
-		DispOrder:   0,
 		IsSystem:    isSystem,
 		IsClosed:    isClosed,
 		CreatedDate: now,
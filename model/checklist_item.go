@@ -0,0 +1,30 @@
+package model
+
+import (
+	"taskboard-api-go/common"
+	"time"
+)
+
+// ChecklistItem presents a lightweight check item inside a task
+type ChecklistItem struct {
+	ID          string    `gorm:"primary_key;size:32"`
+	TaskID      string    `gorm:"not null;size:32;index"`
+	Name        string    `gorm:"not null;size:255"`
+	IsDone      bool      `gorm:"not null"`
+	DispOrder   int       `gorm:"not null"`
+	CreatedDate time.Time `gorm:"not null"`
+	Version     int       `gorm:"not null"` // Version for optimistic lock
+}
+
+// NewChecklistItem returns created new checklist item
+func NewChecklistItem(taskID, name string, now time.Time) *ChecklistItem {
+	return &ChecklistItem{
+		ID:          "check_" + common.GenerateID(),
+		TaskID:      taskID,
+		Name:        name,
+		IsDone:      false,
+		DispOrder:   0,
+		CreatedDate: now,
+		Version:     1,
+	}
+}
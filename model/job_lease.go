@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// JobLease presents a lease of a background job, so that only one of api instances sharing the database runs the job.
+// The holder renews it before it expires, and another instance takes it over after it expires.
+type JobLease struct {
+	Name        string    `gorm:"primary_key;size:64"`
+	Holder      string    `gorm:"not null;size:32"` // ID of api instance
+	ExpiresDate time.Time `gorm:"not null"`
+}
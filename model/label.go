@@ -0,0 +1,32 @@
+package model
+
+import (
+	"taskboard-api-go/common"
+	"time"
+)
+
+// Label presents a label(tag) which can be attached to tasks
+type Label struct {
+	ID          string    `gorm:"primary_key;size:32"`
+	Name        string    `gorm:"not null;size:255;unique"`
+	Color       string    `gorm:"not null;size:7"` // #rrggbb
+	CreatedDate time.Time `gorm:"not null"`
+	Version     int       `gorm:"not null"` // Version for optimistic lock
+}
+
+// TaskLabel presents a relation between a task and a label (many-to-many)
+type TaskLabel struct {
+	TaskID  string `gorm:"primary_key;size:32"`
+	LabelID string `gorm:"primary_key;size:32;index"`
+}
+
+// NewLabel returns created new label
+func NewLabel(name, color string, now time.Time) *Label {
+	return &Label{
+		ID:          "label_" + common.GenerateID(),
+		Name:        name,
+		Color:       color,
+		CreatedDate: now,
+		Version:     1,
+	}
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// RealtimeEvent presents a websocket event published to all api instances through database.
+// ID is used as sequence number of the event, so it is assigned by database.
+type RealtimeEvent struct {
+	ID          int64     `gorm:"primary_key;AUTO_INCREMENT"`
+	Message     string    `gorm:"not null;type:text"` // Serialized event
+	CreatedDate time.Time `gorm:"not null;index"`
+}
+
+// NewRealtimeEvent returns created new realtime event
+func NewRealtimeEvent(message string, now time.Time) *RealtimeEvent {
+	return &RealtimeEvent{
+		Message:     message,
+		CreatedDate: now,
+	}
+}
@@ -11,13 +11,16 @@ type Task struct {
 	ID             string         `gorm:"primary_key;size:32"`
 	Name           string         `gorm:"not null;size:255"`
 	Description    string         `gorm:"size:8000"`
-	AssigneeUserID sql.NullString `gorm:"size:32"`          // Null or String
-	BoardID        string         `gorm:"not null;size:32"` // Default is IceboxBoardID
-	DispOrder      int            `gorm:"not null"`
+	AssigneeUserID sql.NullString `gorm:"size:32"`                                                                      // Null or String
+	BoardID        string         `gorm:"not null;size:32;unique_index:idx_tasks_board_id_sort_rank"`                   // Default is IceboxBoardID
+	Rank           string         `gorm:"column:sort_rank;not null;size:255;unique_index:idx_tasks_board_id_sort_rank"` // Sorted lexicographically in the board
 	CreatedDate    time.Time      `gorm:"not null"`
 	IsClosed       bool           `gorm:"not null"`
 	Version        int            `gorm:"not null"` // Version for optimistic lock
 	EstimateSize   int
+	StartDate      *time.Time     // Null or Time
+	DueDate        *time.Time     `gorm:"index"`         // Null or Time
+	ParentTaskID   sql.NullString `gorm:"size:32;index"` // Null or String
 }
 
 // NewTask returns created new task
@@ -29,7 +32,7 @@ func NewTask(name, description string, isClosed bool, now time.Time) *Task {
 		IsClosed:       isClosed,
 		BoardID:        SystemBoardIcebox.ID,
 		AssigneeUserID: sql.NullString{Valid: false},
-		DispOrder:      0,
+		ParentTaskID:   sql.NullString{Valid: false},
 		CreatedDate:    now,
 		Version:        1,
 	}
@@ -50,3 +53,21 @@ func (t *Task) SetBoardID(boardID string) {
 		t.BoardID = boardID
 	}
 }
+
+// SetParentTaskID updates parentTaskID by specifed value, clears it if empty
+func (t *Task) SetParentTaskID(parentTaskID string) {
+	t.ParentTaskID = sql.NullString{String: parentTaskID, Valid: parentTaskID != ""}
+}
+
+// IsDone checks whether the task is closed or on the done board
+func (t *Task) IsDone() bool {
+	return t.IsClosed || t.BoardID == SystemBoardDone.ID
+}
+
+// IsOverdue checks whether due date of the task has passed at specified time while the task is not done yet
+func (t *Task) IsOverdue(now time.Time) bool {
+	if t.DueDate == nil || t.IsDone() {
+		return false
+	}
+	return t.DueDate.Before(now)
+}
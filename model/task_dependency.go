@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// TaskDependency presents that the blocking task blocks the blocked task
+// (the blocked task can not be done until the blocking task is done)
+type TaskDependency struct {
+	BlockingTaskID string    `gorm:"primary_key;size:32"`
+	BlockedTaskID  string    `gorm:"primary_key;size:32;index"`
+	CreatedDate    time.Time `gorm:"not null"`
+}
+
+// NewTaskDependency returns created new dependency between tasks
+func NewTaskDependency(blockingTaskID, blockedTaskID string, now time.Time) *TaskDependency {
+	return &TaskDependency{
+		BlockingTaskID: blockingTaskID,
+		BlockedTaskID:  blockedTaskID,
+		CreatedDate:    now,
+	}
+}
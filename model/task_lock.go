@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// TaskLockDuration is duration of a lease of task lock, which is extended by heartbeat
+const TaskLockDuration = 2 * time.Minute
+
+// TaskLock presents a soft lock of a task being edited by a user. It expires unless renewed by heartbeat.
+type TaskLock struct {
+	TaskID      string    `gorm:"primary_key;size:32"`
+	UserID      string    `gorm:"not null;size:32"`
+	ExpiresDate time.Time `gorm:"not null;index"`
+	CreatedDate time.Time `gorm:"not null"`
+	Version     int       `gorm:"not null"` // Version for optimistic lock
+}
+
+// NewTaskLock returns created new task lock which expires after TaskLockDuration
+func NewTaskLock(taskID, userID string, now time.Time) *TaskLock {
+	return &TaskLock{
+		TaskID:      taskID,
+		UserID:      userID,
+		ExpiresDate: now.Add(TaskLockDuration),
+		CreatedDate: now,
+		Version:     1,
+	}
+}
+
+// Extend extends expiry of the lock by TaskLockDuration from now
+func (l *TaskLock) Extend(now time.Time) {
+	l.ExpiresDate = now.Add(TaskLockDuration)
+}
+
+// IsExpired checks whether the lock is expired at specified time
+func (l *TaskLock) IsExpired(now time.Time) bool {
+	return !l.ExpiresDate.After(now)
+}
+
+// IsHeldBy checks whether the lock is held by specified user at specified time
+func (l *TaskLock) IsHeldBy(userID string, now time.Time) bool {
+	return l.UserID == userID && !l.IsExpired(now)
+}
@@ -1,26 +1,48 @@
 package model
 
 import (
+	"strings"
 	"taskboard-api-go/common"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Definition of user roles
+const (
+	RoleAdmin  = "admin"  // can manage users and boards
+	RoleMember = "member" // can edit tasks
+	RoleViewer = "viewer" // can only read
+)
+
+// AvatarUploadPrefix is prefix of avatar which is uploaded by user, followed by hash of the image.
+// Avatars without the prefix are names of built-in images.
+const AvatarUploadPrefix = "upload:"
+
 // User is user of the app.
 type User struct {
 	ID           string `gorm:"primary_key;size:32"`
 	Name         string `gorm:"not null;size:255;unique"`
 	PasswordHash string `gorm:"not null;size:255"`
 	Avatar       string `gorm:"size:255"`
+	Role         string `gorm:"not null;size:32;default:'member'"`
 	Version      int    `gorm:"not null"` // Version for optimistic lock
 }
 
+// SystemUser is a user who operates internal processes(initialization, background jobs). It is not stored in database.
+var SystemUser = &User{
+	ID:      "user_system",
+	Name:    "System",
+	Role:    RoleAdmin,
+	Version: 1,
+}
+
 // NewUser returns created new user
-func NewUser(name, rawpassword, avatar string) *User {
+func NewUser(name, rawpassword, avatar, role string) *User {
 	result := &User{
 		ID:      "user_" + common.GenerateID(),
 		Name:    name,
 		Avatar:  avatar,
+		Role:    role,
 		Version: 1,
 	}
 	result.SetPassword(rawpassword)
@@ -37,3 +59,25 @@ func (user *User) SetPassword(password string) {
 func (user *User) VerifyPassword(password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 }
+
+// IsValidRole checks whether role is one of defined roles
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleMember, RoleViewer:
+		return true
+	}
+	return false
+}
+
+// SetUploadedAvatar sets avatar to uploaded image of specified hash
+func (user *User) SetUploadedAvatar(hash string) {
+	user.Avatar = AvatarUploadPrefix + hash
+}
+
+// UploadedAvatarHash returns hash of uploaded avatar image, or empty if avatar is built-in one
+func (user *User) UploadedAvatarHash() string {
+	if !strings.HasPrefix(user.Avatar, AvatarUploadPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(user.Avatar, AvatarUploadPrefix)
+}
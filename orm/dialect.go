@@ -0,0 +1,74 @@
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite" //to load dialect
+)
+
+// Definition of supported dialects
+const (
+	DialectSQLite3  = "sqlite3"
+	DialectPostgres = "postgres"
+	DialectMySQL    = "mysql"
+)
+
+// checkDriver checks that the driver of dialect is linked.
+// Drivers except sqlite3 are linked only when built with the tag of the dialect. ex) go build -tags postgres
+func checkDriver(dialect string) error {
+	switch dialect {
+	case DialectSQLite3, DialectPostgres, DialectMySQL:
+	default:
+		return fmt.Errorf("Unsupported dialect:%s", dialect)
+	}
+	for _, driver := range sql.Drivers() {
+		if driver == dialect {
+			return nil
+		}
+	}
+	return fmt.Errorf("Driver of dialect %s is not linked. Build with -tags %s", dialect, dialect)
+}
+
+// Increment returns expression to add delta to the column, which is used as a value of Update()
+func Increment(tx *gorm.DB, column string, delta int) interface{} {
+	return gorm.Expr(tx.Dialect().Quote(column)+" + ?", delta)
+}
+
+// MaxInt returns max of the integer column selected by query, returns 0 if no row is selected
+func MaxInt(query *gorm.DB, column string) (max int64, err error) {
+	var out sql.NullInt64
+	err = query.Select("MAX(" + query.Dialect().Quote(column) + ")").Row().Scan(&out)
+	return out.Int64, err
+}
+
+// MaxString returns max of the string column selected by query, returns empty if no row is selected
+func MaxString(query *gorm.DB, column string) (max string, err error) {
+	var out sql.NullString
+	err = query.Select("MAX(" + query.Dialect().Quote(column) + ")").Row().Scan(&out)
+	return out.String, err
+}
+
+// sqliteDSN adds parameters for concurrent transactions to dsn of sqlite3, unless they are specified.
+// Transactions begin with BEGIN IMMEDIATE, which takes the write lock at first.
+// Otherwise two transactions which have read can not upgrade to write each other, and one fails without waiting.
+// Waiting for the lock is limited by busy timeout, then the transaction fails as busy and it is retried.
+func sqliteDSN(dsn string) string {
+	params := []string{}
+	if !strings.Contains(dsn, "_txlock=") {
+		params = append(params, "_txlock=immediate")
+	}
+	if !strings.Contains(dsn, "_busy_timeout=") && !strings.Contains(dsn, "_timeout=") {
+		params = append(params, "_busy_timeout=5000")
+	}
+	if len(params) == 0 {
+		return dsn
+	}
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + strings.Join(params, "&")
+}
@@ -0,0 +1,40 @@
+//go:build mysql
+// +build mysql
+
+package orm
+
+import (
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/jinzhu/gorm/dialects/mysql" //to load dialect
+)
+
+// Error numbers of MySQL server
+const (
+	mysqlErrorDuplicateEntry  = 1062
+	mysqlErrorLockWaitTimeout = 1205
+	mysqlErrorDeadlock        = 1213
+)
+
+func init() {
+	retryableErrorCheckers = append(retryableErrorCheckers, isRetryableMySQLError)
+	duplicateKeyErrorCheckers = append(duplicateKeyErrorCheckers, isMySQLDuplicateKeyError)
+}
+
+func isRetryableMySQLError(err error) bool {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	if !ok {
+		return false
+	}
+	switch mysqlErr.Number {
+	case mysqlErrorLockWaitTimeout, mysqlErrorDeadlock:
+		return true
+	case mysqlErrorDuplicateEntry:
+		return isRankConflict(mysqlErr.Message)
+	}
+	return false
+}
+
+func isMySQLDuplicateKeyError(err error) bool {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	return ok && mysqlErr.Number == mysqlErrorDuplicateEntry
+}
@@ -0,0 +1,33 @@
+//go:build postgres
+// +build postgres
+
+package orm
+
+import (
+	_ "github.com/jinzhu/gorm/dialects/postgres" //to load dialect
+	"github.com/lib/pq"
+)
+
+func init() {
+	retryableErrorCheckers = append(retryableErrorCheckers, isRetryablePostgresError)
+	duplicateKeyErrorCheckers = append(duplicateKeyErrorCheckers, isPostgresDuplicateKeyError)
+}
+
+func isRetryablePostgresError(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	if !ok {
+		return false
+	}
+	switch pqErr.Code.Name() {
+	case "serialization_failure", "deadlock_detected", "lock_not_available":
+		return true
+	case "unique_violation":
+		return isRankConflict(pqErr.Constraint)
+	}
+	return false
+}
+
+func isPostgresDuplicateKeyError(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code.Name() == "unique_violation"
+}
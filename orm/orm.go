@@ -6,7 +6,6 @@ import (
 	"reflect"
 
 	"github.com/jinzhu/gorm"
-	_ "github.com/jinzhu/gorm/dialects/sqlite" //to load dialect
 )
 
 var instance *gorm.DB
@@ -17,9 +16,19 @@ const NoLimit = math.MaxInt32
 // ErrorRecordNotFound is an error when record not found
 var ErrorRecordNotFound = gorm.ErrRecordNotFound
 
-// Init opens database
-func Init(databasePath string) (err error) {
-	opened, err := gorm.Open("sqlite3", databasePath)
+// ErrorOptimisticLock is an error when record is updated by others after it was read
+var ErrorOptimisticLock = errors.New("record is updated by others (optimistic lock failure)")
+
+// Init opens database of specified dialect(sqlite3, postgres or mysql).
+// dsn is the file path for sqlite3, and the data source name of the driver for others.
+func Init(dialect string, dsn string) (err error) {
+	if err = checkDriver(dialect); err != nil {
+		return
+	}
+	if dialect == DialectSQLite3 {
+		dsn = sqliteDSN(dsn)
+	}
+	opened, err := gorm.Open(dialect, dsn)
 	if err != nil {
 		return
 	}
@@ -34,15 +43,6 @@ func GetDB() *gorm.DB {
 	return instance
 }
 
-// Migrate create tables of models
-func Migrate(models ...interface{}) error {
-	db := GetDB()
-	if db == nil {
-		return errors.New("Database is not initilazed. Must call orm.Init() before calling this method")
-	}
-	return db.AutoMigrate(models...).Error
-}
-
 // TableName rerturns table name of model
 func TableName(model interface{}) string {
 	return gorm.ToTableName(reflect.TypeOf(model).Name())
@@ -66,3 +66,47 @@ func ColumnNames(model interface{}) []string {
 func IsRecordNotFoundError(err error) bool {
 	return gorm.IsRecordNotFoundError(err)
 }
+
+// IsOptimisticLockError checks whether err is due to version mismatch of optimistic lock
+func IsOptimisticLockError(err error) bool {
+	return err == ErrorOptimisticLock
+}
+
+// UpdateWithVersion updates all columns of value's record, only if its version is oldVersion.
+// It returns ErrorRecordNotFound if the record does not exist, and ErrorOptimisticLock if the version does not match.
+// value must have a non-zero primary key and a Version column.
+func UpdateWithVersion(tx *gorm.DB, value interface{}, oldVersion int) error {
+	scope := tx.NewScope(value)
+	columns := make(map[string]interface{})
+	for _, field := range scope.Fields() {
+		if field.IsNormal && !field.IsIgnored && !field.IsPrimaryKey {
+			columns[field.DBName] = field.Field.Interface()
+		}
+	}
+	// Save() is not used, because it inserts the record when no row is affected.
+	return UpdateColumnsWithVersion(tx, value, oldVersion, columns)
+}
+
+// UpdateColumnsWithVersion updates specified columns of value's record, only if its version is oldVersion.
+// Errors are same as UpdateWithVersion, and columns should contain the new version.
+func UpdateColumnsWithVersion(tx *gorm.DB, value interface{}, oldVersion int, columns map[string]interface{}) error {
+	if tx.NewScope(value).PrimaryKeyZero() {
+		// To avoid updating all records, return here.
+		return ErrorRecordNotFound
+	}
+	db := tx.Model(value).Where("version = ?", oldVersion).Updates(columns)
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected > 0 {
+		return nil
+	}
+	count := 0
+	if err := tx.Model(value).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrorRecordNotFound
+	}
+	return ErrorOptimisticLock
+}
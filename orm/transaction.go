@@ -0,0 +1,106 @@
+package orm
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// MaxTransactionRetries is number of retries of a transaction which failed by concurrent transactions
+const MaxTransactionRetries = 3
+
+// retryableErrorCheckers check errors of each driver. Drivers linked by build tags add their checkers.
+var retryableErrorCheckers = []func(err error) bool{isRetryableSQLiteError}
+
+// duplicateKeyErrorCheckers check errors of each driver. Drivers linked by build tags add their checkers.
+var duplicateKeyErrorCheckers = []func(err error) bool{isSQLiteDuplicateKeyError}
+
+// IsRetryableError checks whether the transaction failed by concurrent transactions, and may succeed by retrying it.
+// ex) database is busy, deadlock, serialization failure or rank is taken by another transaction
+// Wrapped errors are unwrapped by Unwrap() or Cause().
+func IsRetryableError(err error) bool {
+	return matchError(err, retryableErrorCheckers)
+}
+
+// IsDuplicateKeyError checks whether a primary key or unique constraint is violated.
+// ex) another transaction inserted the row of the same key concurrently
+// Wrapped errors are unwrapped by Unwrap() or Cause().
+func IsDuplicateKeyError(err error) bool {
+	return matchError(err, duplicateKeyErrorCheckers)
+}
+
+// matchError checks whether err or an error wrapped by it matches any of checkers
+func matchError(err error, checkers []func(err error) bool) bool {
+	for err != nil {
+		for _, check := range checkers {
+			if check(err) {
+				return true
+			}
+		}
+		switch wrapped := err.(type) {
+		case interface{ Unwrap() error }:
+			err = wrapped.Unwrap()
+		case interface{ Cause() error }:
+			err = wrapped.Cause()
+		default:
+			err = nil
+		}
+	}
+	return false
+}
+
+// isRankConflict checks whether the unique constraint of rank is violated.
+// Another transaction took the rank between same neighbors, so a new rank is chosen by retrying.
+func isRankConflict(message string) bool {
+	return strings.Contains(message, "sort_rank")
+}
+
+func isRetryableSQLiteError(err error) bool {
+	sqliteErr, ok := err.(sqlite3.Error)
+	if !ok {
+		return false
+	}
+	switch {
+	case sqliteErr.Code == sqlite3.ErrBusy, sqliteErr.Code == sqlite3.ErrLocked:
+		return true
+	case sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique:
+		return isRankConflict(sqliteErr.Error())
+	}
+	return false
+}
+
+func isSQLiteDuplicateKeyError(err error) bool {
+	sqliteErr, ok := err.(sqlite3.Error)
+	if !ok {
+		return false
+	}
+	return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+}
+
+// Transaction executes f in a transaction of db and commits it, or rollbacks it if f returns error.
+// The transaction is retried up to MaxTransactionRetries times if it failed by concurrent transactions,
+// so f must not have side effects outside of the transaction.
+func Transaction(db *gorm.DB, f func(tx *gorm.DB) error) (err error) {
+	for retry := 0; ; retry++ {
+		err = transaction(db, f)
+		if err == nil || retry >= MaxTransactionRetries || !IsRetryableError(err) {
+			return
+		}
+		// Wait a little longer every time, to let the concurrent transaction finish
+		time.Sleep(time.Duration(retry+1) * 20 * time.Millisecond)
+	}
+}
+
+func transaction(db *gorm.DB, f func(tx *gorm.DB) error) error {
+	tx := db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
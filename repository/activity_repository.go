@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"taskboard-api-go/model"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ActivityRepository is repository of activity table.
+// Activities are immutable, so update and delete are not provided.
+type ActivityRepository struct {
+	tx *gorm.DB
+}
+
+// NewActivityRepository returns new instance of ActivityRepository
+func NewActivityRepository(tx *gorm.DB) *ActivityRepository {
+	if tx == nil {
+		// Programing error!!
+		panic("tx must be set")
+	}
+	return &ActivityRepository{
+		tx: tx,
+	}
+}
+
+// FindActivities returns Activities matching with specified condition
+func (repo *ActivityRepository) FindActivities(condition interface{}, offset int, limit int, sortOrders []string) (result []model.Activity, err error) {
+	query := repo.tx.Where(condition)
+	if offset >= 0 {
+		query = query.Offset(offset)
+	}
+	if limit >= 0 {
+		query = query.Limit(limit)
+	}
+
+	if sortOrders == nil {
+		sortOrders = []string{}
+	}
+	for _, activity := range sortOrders {
+		query = query.Order(activity)
+	}
+
+	err = query.Find(&result).Error
+	return
+}
+
+// CountActivities returns the number of Activities matching specfied condition
+func (repo *ActivityRepository) CountActivities(condition interface{}) (count int, err error) {
+	var activities []model.Activity
+	err = repo.tx.Where(condition).Find(&activities).Count(&count).Error
+	return
+}
+
+// FindChangesAfterVersion returns update and reorder activities of the entity recorded after specified version,
+// in chronological order
+func (repo *ActivityRepository) FindChangesAfterVersion(entityKind, entityID string, version int) (result []model.Activity, err error) {
+	err = repo.tx.Where(&model.Activity{EntityKind: entityKind, EntityID: entityID}).
+		Where("action IN (?)", []string{model.ActivityActionUpdate, model.ActivityActionReorder}).
+		Where("entity_version > ?", version).
+		Order("entity_version").Order("created_date").Order("id").
+		Find(&result).Error
+	return
+}
+
+// CreateActivity inserts new Activity record
+func (repo *ActivityRepository) CreateActivity(activity *model.Activity) error {
+	return repo.CreateActivities([]*model.Activity{activity})
+}
+
+// CreateActivities inserts new Activity records.
+func (repo *ActivityRepository) CreateActivities(activities []*model.Activity) (err error) {
+	for _, activity := range activities {
+		err = repo.tx.Create(activity).Error
+		if err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"fmt"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+////
+/// Model specific functions (Only replace model name, take care names are casesencitive!!)
+//
+func newTxAndActivityRepository() (tx *gorm.DB, repo *ActivityRepository) {
+	tx = orm.GetDB().Begin()
+	repo = NewActivityRepository(tx)
+	return
+}
+
+func createActivityTestData(tx *gorm.DB, idFormat string, findIdentify string, count int) []*model.Activity {
+	result := make([]*model.Activity, 0, count)
+	for i := 0; i < count; i++ {
+		activity := model.NewActivity(
+			model.EntityKindTask,
+			findIdentify,
+			i+1,
+			model.ActivityActionUpdate,
+			"actorUserID",
+			time.Now().UTC(),
+		)
+		activity.SetChange("name", fmt.Sprintf("old-%03d", i), fmt.Sprintf("new-%03d", i))
+		activity.ID = fmt.Sprintf("%s-%03d", idFormat, i)
+		result = append(result, activity)
+	}
+	return result
+}
+
+func insertActivityTestData(tx *gorm.DB, activities []*model.Activity) (err error) {
+	for _, activity := range activities {
+		err = tx.Create(activity).Error
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+////
+/// Common repository functions' test
+//
+func TestActivityRepository_FindActivities(t *testing.T) {
+	tx, repo := newTxAndActivityRepository()
+	defer tx.Rollback()
+
+	firstActivities := createActivityTestData(tx, "activityID-find", "findEntityID", 5)
+	secondActivities := createActivityTestData(tx, "activityID-not-find", "notFindEntityID", 4)
+	insertActivities := append(firstActivities, secondActivities...)
+	err := insertActivityTestData(tx, insertActivities)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	// Get 3 data from 2nd(index=1) position
+	const (
+		offset = 1
+		limit  = 3
+	)
+	activities, err := repo.FindActivities(&model.Activity{EntityID: "findEntityID"}, offset, limit, []string{"id"})
+	if err != nil {
+		t.Fatalf("Failed to execute find: %+v", err)
+	}
+
+	// Length of data must be limit
+	if len(activities) != limit {
+		t.Errorf("Expected result size = %d, but got %d", limit, len(activities))
+		return
+	}
+	// Head must be 001
+	head := activities[0]
+	headExpected := "activityID-find-001"
+	if head.ID != headExpected {
+		t.Errorf("head ID must be %s, but got %s", headExpected, head.ID)
+	}
+	// Tail must be 003
+	tail := activities[len(activities)-1]
+	tailExpected := "activityID-find-003"
+	if tail.ID != tailExpected {
+		t.Errorf("tail ID must be %s, but got %s", tailExpected, tail.ID)
+	}
+}
+
+func TestActivityRepository_CountActivities(t *testing.T) {
+	tx, repo := newTxAndActivityRepository()
+	defer tx.Rollback()
+
+	expected := 5
+	firstActivities := createActivityTestData(tx, "activityID-find", "findEntityID", 5)
+	secondActivities := createActivityTestData(tx, "activityID-not-find", "notFindEntityID", 4)
+	insertActivities := append(firstActivities, secondActivities...)
+	err := insertActivityTestData(tx, insertActivities)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	count, err := repo.CountActivities(&model.Activity{EntityID: "findEntityID"})
+	if err != nil {
+		t.Fatalf("failed to count Activity: %+v", err)
+	}
+	if expected != count {
+		t.Errorf("expected %d records, but got %d record", expected, count)
+	}
+}
+
+func TestActivityRepository_FindChangesAfterVersion(t *testing.T) {
+	tx, repo := newTxAndActivityRepository()
+	defer tx.Rollback()
+
+	// Versions are 1 to 5, and version 5 is a reorder
+	insertActivities := createActivityTestData(tx, "activityID-after", "afterEntityID", 5)
+	insertActivities[4].Action = model.ActivityActionReorder
+	deleted := model.NewActivity(model.EntityKindTask, "afterEntityID", 5, model.ActivityActionDelete, "actorUserID", time.Now().UTC())
+	insertActivities = append(insertActivities, deleted)
+	if err := insertActivityTestData(tx, insertActivities); err != nil {
+		t.Fatalf("Failed to create activity: %+v", err)
+	}
+
+	result, err := repo.FindChangesAfterVersion(model.EntityKindTask, "afterEntityID", 3)
+	if err != nil {
+		t.Fatalf("Failed to find activities: %+v", err)
+	}
+	if assert.Len(t, result, 2) {
+		assert.Equal(t, insertActivities[3].ID, result[0].ID)
+		assert.Equal(t, insertActivities[4].ID, result[1].ID)
+	}
+}
+
+func TestActivityRepository_CreateActivity(t *testing.T) {
+	tx, repo := newTxAndActivityRepository()
+	defer tx.Rollback()
+
+	// Create 1 record
+	insertActivities := createActivityTestData(tx, "activityID-create", "createEntityID", 1)
+	created := insertActivities[0]
+	if err := repo.CreateActivity(created); err != nil {
+		t.Fatalf("Failed to create activity: %+v", err)
+	}
+
+	// Find by ID
+	var find = model.Activity{}
+	if err := tx.Where(&model.Activity{ID: created.ID}).First(&find).Error; err != nil {
+		t.Fatalf("Failed to find activity: %+v", err)
+	}
+	// Verify created equals find
+	if !assert.Equal(t, find, *created) {
+		t.Errorf("expected: %+v, but got %+v", created.ID, find.ID)
+	}
+}
+
+// Test for CreateActivities is ommitted,
+// because that it is called internally in single version
+
+////
+/// Other fuctions' test should be written in below
+//
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"taskboard-api-go/model"
+
+	"github.com/jinzhu/gorm"
+)
+
+// AttachmentRepository is repository of attachment table.
+// Attachments are immutable, so update is not provided.
+type AttachmentRepository struct {
+	tx *gorm.DB
+}
+
+// NewAttachmentRepository returns new instance of AttachmentRepository
+func NewAttachmentRepository(tx *gorm.DB) *AttachmentRepository {
+	if tx == nil {
+		// Programing error!!
+		panic("tx must be set")
+	}
+	return &AttachmentRepository{
+		tx: tx,
+	}
+}
+
+// FindFirstAttachment returns first Attachment matching with specified condition
+func (repo *AttachmentRepository) FindFirstAttachment(condition interface{}, sortOrders []string) (result model.Attachment, err error) {
+	query := repo.tx.Where(condition)
+	if sortOrders == nil {
+		sortOrders = []string{}
+	}
+
+	for _, sortOrder := range sortOrders {
+		query = query.Order(sortOrder)
+	}
+	err = query.First(&result).Error
+	return
+}
+
+// FindAttachments returns Attachments matching with specified condition
+func (repo *AttachmentRepository) FindAttachments(condition interface{}, offset int, limit int, sortOrders []string) (result []model.Attachment, err error) {
+	query := repo.tx.Where(condition)
+	if offset >= 0 {
+		query = query.Offset(offset)
+	}
+	if limit >= 0 {
+		query = query.Limit(limit)
+	}
+
+	if sortOrders == nil {
+		sortOrders = []string{}
+	}
+	for _, attachment := range sortOrders {
+		query = query.Order(attachment)
+	}
+
+	err = query.Find(&result).Error
+	return
+}
+
+// CountAttachments returns the number of Attachments matching specfied condition
+func (repo *AttachmentRepository) CountAttachments(condition interface{}) (count int, err error) {
+	var attachments []model.Attachment
+	err = repo.tx.Where(condition).Find(&attachments).Count(&count).Error
+	return
+}
+
+// CreateAttachment inserts new Attachment record
+func (repo *AttachmentRepository) CreateAttachment(attachment *model.Attachment) error {
+	return repo.CreateAttachments([]*model.Attachment{attachment})
+}
+
+// DeleteAttachment deletes Attachment record
+func (repo *AttachmentRepository) DeleteAttachment(attachment *model.Attachment) error {
+	return repo.DeleteAttachments([]*model.Attachment{attachment})
+}
+
+// CreateAttachments inserts new Attachment records.
+func (repo *AttachmentRepository) CreateAttachments(attachments []*model.Attachment) (err error) {
+	for _, attachment := range attachments {
+		err = repo.tx.Create(attachment).Error
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// DeleteAttachments deletes Attachment records
+func (repo *AttachmentRepository) DeleteAttachments(attachments []*model.Attachment) (err error) {
+	for _, attachment := range attachments {
+		if attachment.ID == "" {
+			continue // To avoid deleting all due to gorm warning, continue here.
+		}
+		err = repo.tx.Delete(attachment).Error
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// DeleteAttachmentsByTaskID deletes all Attachment records of specified task
+func (repo *AttachmentRepository) DeleteAttachmentsByTaskID(taskID string) error {
+	if taskID == "" {
+		return nil // To avoid deleting all due to gorm warning, return here.
+	}
+	return repo.tx.Where("task_id = ?", taskID).Delete(&model.Attachment{}).Error
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"fmt"
+	"taskboard-api-go/common"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+////
+/// Model specific functions (Only replace model name, take care names are casesencitive!!)
+//
+func newTxAndAttachmentRepository() (tx *gorm.DB, repo *AttachmentRepository) {
+	tx = orm.GetDB().Begin()
+	repo = NewAttachmentRepository(tx)
+	return
+}
+
+func createAttachmentTestData(tx *gorm.DB, idFormat string, findIdentify string, count int) []*model.Attachment {
+	result := make([]*model.Attachment, 0, count)
+	for i := 0; i < count; i++ {
+		attachment := model.NewAttachment(
+			findIdentify,
+			fmt.Sprintf("file-%03d.txt", i),
+			"text/plain",
+			int64(i),
+			"uploader"+common.GenerateID(),
+			time.Now().UTC(),
+		)
+		attachment.ID = fmt.Sprintf("%s-%03d", idFormat, i)
+		result = append(result, attachment)
+	}
+	return result
+}
+
+func insertAttachmentTestData(tx *gorm.DB, attachments []*model.Attachment) (err error) {
+	for _, attachment := range attachments {
+		err = tx.Create(attachment).Error
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+////
+/// Common repository functions' test
+//
+func TestAttachmentRepository_FindFirstAttachment(t *testing.T) {
+	tx, repo := newTxAndAttachmentRepository()
+	defer tx.Rollback()
+
+	firstAttachments := createAttachmentTestData(tx, "attachmentID-find", "findTaskID", 5)
+	secondAttachments := createAttachmentTestData(tx, "attachmentID-not-find", "notFindTaskID", 4)
+	insertAttachments := append(firstAttachments, secondAttachments...)
+	err := insertAttachmentTestData(tx, insertAttachments)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+	// Sort by id
+	attachment, err := repo.FindFirstAttachment(&model.Attachment{TaskID: "findTaskID"}, []string{"id"})
+	if err != nil {
+		t.Fatalf("Failed to execute find first: %+v", err)
+	}
+	expected := "attachmentID-find-000"
+	if attachment.ID != expected {
+		t.Errorf("expected attachment ID is %s, but got %s", expected, attachment.ID)
+	}
+}
+
+func TestAttachmentRepository_FindAttachments(t *testing.T) {
+	tx, repo := newTxAndAttachmentRepository()
+	defer tx.Rollback()
+
+	firstAttachments := createAttachmentTestData(tx, "attachmentID-find", "findTaskID", 5)
+	secondAttachments := createAttachmentTestData(tx, "attachmentID-not-find", "notFindTaskID", 4)
+	insertAttachments := append(firstAttachments, secondAttachments...)
+	err := insertAttachmentTestData(tx, insertAttachments)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	// Get 3 data from 2nd(index=1) position
+	const (
+		offset = 1
+		limit  = 3
+	)
+	attachments, err := repo.FindAttachments(&model.Attachment{TaskID: "findTaskID"}, offset, limit, []string{"id"})
+	if err != nil {
+		t.Fatalf("Failed to execute find: %+v", err)
+	}
+
+	// Length of data must be limit
+	if len(attachments) != limit {
+		t.Errorf("Expected result size = %d, but got %d", limit, len(attachments))
+		return
+	}
+	// Head must be 001
+	head := attachments[0]
+	headExpected := "attachmentID-find-001"
+	if head.ID != headExpected {
+		t.Errorf("head ID must be %s, but got %s", headExpected, head.ID)
+	}
+	// Tail must be 003
+	tail := attachments[len(attachments)-1]
+	tailExpected := "attachmentID-find-003"
+	if tail.ID != tailExpected {
+		t.Errorf("tail ID must be %s, but got %s", tailExpected, tail.ID)
+	}
+}
+
+func TestAttachmentRepository_CountAttachments(t *testing.T) {
+	tx, repo := newTxAndAttachmentRepository()
+	defer tx.Rollback()
+
+	expected := 5
+	firstAttachments := createAttachmentTestData(tx, "attachmentID-find", "findTaskID", 5)
+	secondAttachments := createAttachmentTestData(tx, "attachmentID-not-find", "notFindTaskID", 4)
+	insertAttachments := append(firstAttachments, secondAttachments...)
+	err := insertAttachmentTestData(tx, insertAttachments)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	count, err := repo.CountAttachments(&model.Attachment{TaskID: "findTaskID"})
+	if err != nil {
+		t.Fatalf("failed to count Attachment: %+v", err)
+	}
+	if expected != count {
+		t.Errorf("expected %d records, but got %d record", expected, count)
+	}
+}
+
+func TestAttachmentRepository_CreateAttachment(t *testing.T) {
+	tx, repo := newTxAndAttachmentRepository()
+	defer tx.Rollback()
+
+	// Create 1 record
+	insertAttachments := createAttachmentTestData(tx, "attachmentID-create", "createTaskID", 1)
+	created := insertAttachments[0]
+	if err := repo.CreateAttachment(created); err != nil {
+		t.Fatalf("Failed to create attachment: %+v", err)
+	}
+
+	// Find by ID
+	var find = model.Attachment{}
+	if err := tx.Where(&model.Attachment{ID: created.ID}).First(&find).Error; err != nil {
+		t.Fatalf("Failed to find attachment: %+v", err)
+	}
+	// Verify created equals find
+	if !assert.Equal(t, find, *created) {
+		t.Errorf("expected: %+v, but got %+v", created.ID, find.ID)
+	}
+}
+
+func TestAttachmentRepository_DeleteAttachment(t *testing.T) {
+	tx, repo := newTxAndAttachmentRepository()
+	defer tx.Rollback()
+
+	// Create 1 record
+	insertAttachments := createAttachmentTestData(tx, "attachmentId-delete", "deleteTaskID", 1)
+	err := insertAttachmentTestData(tx, insertAttachments)
+	if err != nil {
+		t.Fatalf("Failed to create Attachment: %+v", err)
+	}
+	deleted := insertAttachments[0]
+	t.Run("Record will not be deleted if ID is empty", func(t *testing.T) {
+		deletedID := deleted.ID
+		deleted.ID = "" // Clear ID
+		if err := repo.DeleteAttachment(deleted); err != nil {
+			t.Errorf("Failed to delete: %+v", err)
+		}
+		// record must exist
+		result := model.Attachment{}
+		if err := tx.Where(&model.Attachment{ID: deletedID}).Find(&result).Error; err != nil {
+			t.Errorf("Failed to find: %+v", err)
+		}
+		result.ID = deletedID
+		deleted.ID = deletedID // Restore ID
+
+		// Verify not deleted
+		if !assert.Equal(t, *deleted, result) {
+			t.Error("Record is not same")
+		}
+	})
+
+	t.Run("Record will be deleted if ID is set", func(t *testing.T) {
+		if err := repo.DeleteAttachment(deleted); err != nil {
+			t.Errorf("Failed to delete: %+v", err)
+		}
+
+		// Check record will not be found
+		result := model.Attachment{}
+		err := tx.Where(&model.Attachment{ID: deleted.ID}).First(&result).Error
+		if !orm.IsRecordNotFoundError(err) {
+			t.Errorf("Record must be deleted, but got: %+v", err)
+		}
+	})
+}
+
+// Test for CreateAttachments, DeleteAttachments are ommitted,
+// because that they are called internally in each single version
+
+func TestAttachmentRepository_DeleteAttachmentsByTaskID(t *testing.T) {
+	tx, repo := newTxAndAttachmentRepository()
+	defer tx.Rollback()
+
+	deleteAttachments := createAttachmentTestData(tx, "attachmentID-delete", "deleteTaskID", 3)
+	keepAttachments := createAttachmentTestData(tx, "attachmentID-keep", "keepTaskID", 2)
+	err := insertAttachmentTestData(tx, append(deleteAttachments, keepAttachments...))
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	if err := repo.DeleteAttachmentsByTaskID("deleteTaskID"); err != nil {
+		t.Fatalf("Failed to delete attachments: %+v", err)
+	}
+	count, err := repo.CountAttachments(&model.Attachment{TaskID: "deleteTaskID"})
+	if err != nil {
+		t.Fatalf("failed to count Attachment: %+v", err)
+	}
+	assert.Equal(t, 0, count)
+	count, err = repo.CountAttachments(&model.Attachment{TaskID: "keepTaskID"})
+	if err != nil {
+		t.Fatalf("failed to count Attachment: %+v", err)
+	}
+	assert.Equal(t, 2, count)
+}
+
+////
+/// Other fuctions' test should be written in below
+//
@@ -1,15 +1,13 @@
 package repository
 
 import (
-	"sync"
+	"taskboard-api-go/common"
 	"taskboard-api-go/model"
 	"taskboard-api-go/orm"
 
 	"github.com/jinzhu/gorm"
 )
 
-var lockBoard = &sync.Mutex{}
-
 // BoardRepository is repository of board table
 type BoardRepository struct {
 	tx *gorm.DB
@@ -84,18 +82,20 @@ func (repo *BoardRepository) DeleteBoard(board *model.Board) error {
 }
 
 // CreateBoards inserts new Board records.
+// Boards without rank are placed after the last board.
 func (repo *BoardRepository) CreateBoards(boards []*model.Board) (err error) {
-	lockBoard.Lock()
-	defer lockBoard.Unlock()
-
-	count, err := repo.CountBoards(&model.Board{})
-	if err != nil {
-		return
-	}
 	for _, board := range boards {
+		if board.Rank == "" {
+			max, err := repo.MaxBoardRank()
+			if err != nil {
+				return err
+			}
+			board.Rank, err = common.RankBetween(max, "")
+			if err != nil {
+				return err
+			}
+		}
 		err = repo.tx.Create(board).Error
-		count++
-		board.DispOrder = count
 		if err != nil {
 			return
 		}
@@ -105,20 +105,13 @@ func (repo *BoardRepository) CreateBoards(boards []*model.Board) (err error) {
 
 // UpdateBoards updates board records
 func (repo *BoardRepository) UpdateBoards(boards []*model.Board) (err error) {
-	lockBoard.Lock()
-	defer lockBoard.Unlock()
-
 	for _, board := range boards {
 		oldVersion := board.Version
 		board.Version++
-		db := repo.tx.Model(&model.Board{}).Where("version = ?", oldVersion).Save(board)
-		count := db.RowsAffected
-		err = db.Error
-		// return ErrorRecordNotFoud as optimistic lock error
-		if err == nil && count == 0 {
-			return orm.ErrorRecordNotFound
-		}
+		// ErrorRecordNotFound if deleted, ErrorOptimisticLock if updated by others
+		err = orm.UpdateWithVersion(repo.tx, board, oldVersion)
 		if err != nil {
+			board.Version = oldVersion
 			return
 		}
 	}
@@ -139,14 +132,13 @@ func (repo *BoardRepository) DeleteBoards(boards []*model.Board) (err error) {
 	return
 }
 
-// UpdateBoardOrders changes display orders of boards
-func (repo *BoardRepository) UpdateBoardOrders(boardIDs []string) (err error) {
-	for i, boardID := range boardIDs {
-		err = repo.tx.Model(&model.Board{}).Where("id = ?", boardID).
-			Update(model.Board{DispOrder: i}).Error
-		if err != nil {
-			return
-		}
-	}
-	return nil
+// MaxBoardRank returns max of rank of boards, returns empty if no board exists
+func (repo *BoardRepository) MaxBoardRank() (string, error) {
+	return orm.MaxString(repo.tx.Model(&model.Board{}), "sort_rank")
+}
+
+// UpdateBoardRank changes rank of the board, only the row of the board is updated
+func (repo *BoardRepository) UpdateBoardRank(boardID, rank string) error {
+	return repo.tx.Model(&model.Board{}).Where("id = ?", boardID).
+		Update(model.Board{Rank: rank}).Error
 }
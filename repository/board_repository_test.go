@@ -238,12 +238,9 @@ func TestBoardRepository_DeleteBoard(t *testing.T) {
 /// Optimistic lock test (if version lock supported)
 //
 func TestBoardRepository_UpdateBoardOptimisticCheck(t *testing.T) {
+	// Transactions are serialized, so each transaction begins after the previous one is committed
 	tx1, _ := newTxAndBoardRepository()
-	tx2, repo2 := newTxAndBoardRepository()
-	tx3, repo3 := newTxAndBoardRepository()
 	defer tx1.Rollback()
-	defer tx2.Rollback()
-	defer tx3.Rollback()
 
 	// Create and commit 1 record in tx1
 	insertBoards := createBoardTestData(tx1, "boardID-optimistic", true, 1)
@@ -254,6 +251,8 @@ func TestBoardRepository_UpdateBoardOptimisticCheck(t *testing.T) {
 	if err := tx1.Commit().Error; err != nil {
 		t.Fatalf("Failed to commit data in tx1")
 	}
+	tx2, repo2 := newTxAndBoardRepository()
+	defer tx2.Rollback()
 	// Get commited data in tx2
 	data := insertBoards[0]
 	find, err := repo2.FindFirstBoard(model.Board{ID: data.ID}, []string{})
@@ -273,8 +272,10 @@ func TestBoardRepository_UpdateBoardOptimisticCheck(t *testing.T) {
 		deleteCommitedBoardData(t, data)
 		t.Fatalf("Failed to commit data in tx2")
 	}
+	tx3, repo3 := newTxAndBoardRepository()
+	defer tx3.Rollback()
 	// Check to not update due to optimistic error
-	if !assert.Error(t, repo3.UpdateBoard(data)) {
+	if !assert.Equal(t, orm.ErrorOptimisticLock, repo3.UpdateBoard(data)) {
 		deleteCommitedBoardData(t, data)
 		t.Fatalf("Not failed to update, No error occurred")
 	}
@@ -291,12 +292,32 @@ func TestBoardRepository_UpdateBoardOptimisticCheck(t *testing.T) {
 	if err := tx4.Where(model.Board{ID: find.ID}).Find(&result).Error; err != nil {
 		t.Fatalf("failed to retrieve Board: %+v", err)
 	}
+	tx4.Rollback()
 	deleteCommitedBoardData(t, data)
 	if !assert.Equal(t, find, result) {
 		t.Errorf("expected %v, but got %v", find, result)
 	}
 }
 
+func TestBoardRepository_UpdateBoardNotFound(t *testing.T) {
+	tx, repo := newTxAndBoardRepository()
+	defer tx.Rollback()
+
+	// Update not inserted record
+	data := createBoardTestData(tx, "boardID-notfound", true, 1)[0]
+	version := data.Version
+	if !assert.Equal(t, orm.ErrorRecordNotFound, repo.UpdateBoard(data)) {
+		t.Fatalf("Not failed to update, No error occurred")
+	}
+	// Version is not changed and record is not inserted
+	assert.Equal(t, version, data.Version)
+	count := -1
+	if err := tx.Model(&model.Board{}).Where("id = ?", data.ID).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count Board: %+v", err)
+	}
+	assert.Equal(t, 0, count)
+}
+
 func deleteCommitedBoardData(t *testing.T, data *model.Board) {
 	// Try to delete data in another transaction
 	tx, repo := newTxAndBoardRepository()
@@ -315,3 +336,34 @@ func deleteCommitedBoardData(t *testing.T, data *model.Board) {
 ////
 /// Other fuctions' test should be written in below
 //
+func TestBoardRepository_UpdateBoardRank(t *testing.T) {
+	tx, repo := newTxAndBoardRepository()
+	defer tx.Rollback()
+
+	// Created boards are placed after the last board
+	insertBoards := createBoardTestData(tx, "boardID-rank", true, 2)
+	if err := repo.CreateBoards(insertBoards); err != nil {
+		t.Fatalf("Failed to create boards: %+v", err)
+	}
+	assert.True(t, insertBoards[0].Rank < insertBoards[1].Rank, "%s < %s", insertBoards[0].Rank, insertBoards[1].Rank)
+	max, err := repo.MaxBoardRank()
+	if err != nil {
+		t.Fatalf("Failed to get max of rank of boards: %+v", err)
+	}
+	assert.Equal(t, insertBoards[1].Rank, max)
+
+	// Move the last board to the first
+	rank, err := common.RankBetween("", insertBoards[0].Rank)
+	if err != nil {
+		t.Fatalf("Failed to get rank: %+v", err)
+	}
+	if err = repo.UpdateBoardRank(insertBoards[1].ID, rank); err != nil {
+		t.Fatalf("Failed to update rank of board: %+v", err)
+	}
+	find, err := repo.FindFirstBoard(&model.Board{ID: insertBoards[1].ID}, []string{})
+	if err != nil {
+		t.Fatalf("Failed to find board: %+v", err)
+	}
+	insertBoards[1].Rank = rank
+	assert.Equal(t, *insertBoards[1], find)
+}
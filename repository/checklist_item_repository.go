@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ChecklistItemRepository is repository of checklistItem table
+type ChecklistItemRepository struct {
+	tx *gorm.DB
+}
+
+// NewChecklistItemRepository returns new instance of ChecklistItemRepository
+func NewChecklistItemRepository(tx *gorm.DB) *ChecklistItemRepository {
+	if tx == nil {
+		// Programing error!!
+		panic("tx must be set")
+	}
+	return &ChecklistItemRepository{
+		tx: tx,
+	}
+}
+
+// FindFirstChecklistItem returns first ChecklistItem matching with specified condition
+func (repo *ChecklistItemRepository) FindFirstChecklistItem(condition interface{}, sortOrders []string) (result model.ChecklistItem, err error) {
+	query := repo.tx.Where(condition)
+	if sortOrders == nil {
+		sortOrders = []string{}
+	}
+
+	for _, sortOrder := range sortOrders {
+		query = query.Order(sortOrder)
+	}
+	err = query.First(&result).Error
+	return
+}
+
+// FindChecklistItems returns ChecklistItems matching with specified condition
+func (repo *ChecklistItemRepository) FindChecklistItems(condition interface{}, offset int, limit int, sortOrders []string) (result []model.ChecklistItem, err error) {
+	query := repo.tx.Where(condition)
+	if offset >= 0 {
+		query = query.Offset(offset)
+	}
+	if limit >= 0 {
+		query = query.Limit(limit)
+	}
+
+	if sortOrders == nil {
+		sortOrders = []string{}
+	}
+	for _, checklistItem := range sortOrders {
+		query = query.Order(checklistItem)
+	}
+
+	err = query.Find(&result).Error
+	return
+}
+
+// CountChecklistItems returns the number of ChecklistItems matching specfied condition
+func (repo *ChecklistItemRepository) CountChecklistItems(condition interface{}) (count int, err error) {
+	var checklistItems []model.ChecklistItem
+	err = repo.tx.Where(condition).Find(&checklistItems).Count(&count).Error
+	return
+}
+
+// CreateChecklistItem inserts new ChecklistItem record
+func (repo *ChecklistItemRepository) CreateChecklistItem(checklistItem *model.ChecklistItem) error {
+	return repo.CreateChecklistItems([]*model.ChecklistItem{checklistItem})
+}
+
+// UpdateChecklistItem updates ChecklistItem record
+func (repo *ChecklistItemRepository) UpdateChecklistItem(checklistItem *model.ChecklistItem) error {
+	return repo.UpdateChecklistItems([]*model.ChecklistItem{checklistItem})
+}
+
+// DeleteChecklistItem deletes ChecklistItem record
+func (repo *ChecklistItemRepository) DeleteChecklistItem(checklistItem *model.ChecklistItem) error {
+	return repo.DeleteChecklistItems([]*model.ChecklistItem{checklistItem})
+}
+
+// CreateChecklistItems inserts new ChecklistItem records.
+func (repo *ChecklistItemRepository) CreateChecklistItems(checklistItems []*model.ChecklistItem) (err error) {
+	for _, checklistItem := range checklistItems {
+		err = repo.tx.Create(checklistItem).Error
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// UpdateChecklistItems updates checklistItem records
+func (repo *ChecklistItemRepository) UpdateChecklistItems(checklistItems []*model.ChecklistItem) (err error) {
+	for _, checklistItem := range checklistItems {
+		oldVersion := checklistItem.Version
+		checklistItem.Version++
+		// ErrorRecordNotFound if deleted, ErrorOptimisticLock if updated by others
+		err = orm.UpdateWithVersion(repo.tx, checklistItem, oldVersion)
+		if err != nil {
+			checklistItem.Version = oldVersion
+			return
+		}
+	}
+	return
+}
+
+// DeleteChecklistItems deletes ChecklistItem records
+func (repo *ChecklistItemRepository) DeleteChecklistItems(checklistItems []*model.ChecklistItem) (err error) {
+	for _, checklistItem := range checklistItems {
+		if checklistItem.ID == "" {
+			continue // To avoid deleting all due to gorm warning, continue here.
+		}
+		err = repo.tx.Delete(checklistItem).Error
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// DeleteChecklistItemsByTaskID deletes all ChecklistItem records of specified task
+func (repo *ChecklistItemRepository) DeleteChecklistItemsByTaskID(taskID string) error {
+	if taskID == "" {
+		return nil // To avoid deleting all due to gorm warning, return here.
+	}
+	return repo.tx.Where("task_id = ?", taskID).Delete(&model.ChecklistItem{}).Error
+}
+
+// MaxChecklistItemDispOrder returns max of disp order of ChecklistItems in specified task
+func (repo *ChecklistItemRepository) MaxChecklistItemDispOrder(taskID string) (max int, err error) {
+	out, err := orm.MaxInt(repo.tx.Model(&model.ChecklistItem{}).Where("task_id = ?", taskID), "disp_order")
+	return int(out), err
+}
@@ -0,0 +1,388 @@
+package repository
+
+import (
+	"fmt"
+	"taskboard-api-go/common"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+////
+/// Model specific functions (Only replace model name, take care names are casesencitive!!)
+//
+func newTxAndChecklistItemRepository() (tx *gorm.DB, repo *ChecklistItemRepository) {
+	tx = orm.GetDB().Begin()
+	repo = NewChecklistItemRepository(tx)
+	return
+}
+
+func createChecklistItemTestData(tx *gorm.DB, idFormat string, findIdentify string, count int) []*model.ChecklistItem {
+	result := make([]*model.ChecklistItem, 0, count)
+	for i := 0; i < count; i++ {
+		checklistItem := model.NewChecklistItem(
+			findIdentify,
+			"name"+common.GenerateID(),
+			time.Now().UTC(),
+		)
+		checklistItem.ID = fmt.Sprintf("%s-%03d", idFormat, i)
+		checklistItem.DispOrder = i + 1
+		result = append(result, checklistItem)
+	}
+	return result
+}
+
+func insertChecklistItemTestData(tx *gorm.DB, checklistItems []*model.ChecklistItem) (err error) {
+	for _, checklistItem := range checklistItems {
+		err = tx.Create(checklistItem).Error
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+////
+/// Common repository functions' test
+//
+func TestChecklistItemRepository_FindFirstChecklistItem(t *testing.T) {
+	tx, repo := newTxAndChecklistItemRepository()
+	defer tx.Rollback()
+
+	firstChecklistItems := createChecklistItemTestData(tx, "checklistItemID-find", "findTaskID", 5)
+	secondChecklistItems := createChecklistItemTestData(tx, "checklistItemID-not-find", "notFindTaskID", 4)
+	insertChecklistItems := append(firstChecklistItems, secondChecklistItems...)
+	err := insertChecklistItemTestData(tx, insertChecklistItems)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+	// Sort by id
+	checklistItem, err := repo.FindFirstChecklistItem(&model.ChecklistItem{TaskID: "findTaskID"}, []string{"id"})
+	if err != nil {
+		t.Fatalf("Failed to execute find first: %+v", err)
+	}
+	expected := "checklistItemID-find-000"
+	if checklistItem.ID != expected {
+		t.Errorf("expected checklistItem ID is %s, but got %s", expected, checklistItem.ID)
+	}
+}
+
+func TestChecklistItemRepository_FindChecklistItems(t *testing.T) {
+	tx, repo := newTxAndChecklistItemRepository()
+	defer tx.Rollback()
+
+	firstChecklistItems := createChecklistItemTestData(tx, "checklistItemID-find", "findTaskID", 5)
+	secondChecklistItems := createChecklistItemTestData(tx, "checklistItemID-not-find", "notFindTaskID", 4)
+	insertChecklistItems := append(firstChecklistItems, secondChecklistItems...)
+	err := insertChecklistItemTestData(tx, insertChecklistItems)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	// Get 3 data from 2nd(index=1) position
+	const (
+		offset = 1
+		limit  = 3
+	)
+	checklistItems, err := repo.FindChecklistItems(&model.ChecklistItem{TaskID: "findTaskID"}, offset, limit, []string{"id"})
+	if err != nil {
+		t.Fatalf("Failed to execute find: %+v", err)
+	}
+
+	// Length of data must be limit
+	if len(checklistItems) != limit {
+		t.Errorf("Expected result size = %d, but got %d", limit, len(checklistItems))
+		return
+	}
+	// Head must be 001
+	head := checklistItems[0]
+	headExpected := "checklistItemID-find-001"
+	if head.ID != headExpected {
+		t.Errorf("head ID must be %s, but got %s", headExpected, head.ID)
+	}
+	// Tail must be 003
+	tail := checklistItems[len(checklistItems)-1]
+	tailExpected := "checklistItemID-find-003"
+	if tail.ID != tailExpected {
+		t.Errorf("tail ID must be %s, but got %s", tailExpected, tail.ID)
+	}
+}
+
+func TestChecklistItemRepository_CountChecklistItems(t *testing.T) {
+	tx, repo := newTxAndChecklistItemRepository()
+	defer tx.Rollback()
+
+	expected := 5
+	firstChecklistItems := createChecklistItemTestData(tx, "checklistItemID-find", "findTaskID", 5)
+	secondChecklistItems := createChecklistItemTestData(tx, "checklistItemID-not-find", "notFindTaskID", 4)
+	insertChecklistItems := append(firstChecklistItems, secondChecklistItems...)
+	err := insertChecklistItemTestData(tx, insertChecklistItems)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	count, err := repo.CountChecklistItems(&model.ChecklistItem{TaskID: "findTaskID"})
+	if err != nil {
+		t.Fatalf("failed to count ChecklistItem: %+v", err)
+	}
+	if expected != count {
+		t.Errorf("expected %d records, but got %d record", expected, count)
+	}
+}
+
+func TestChecklistItemRepository_CreateChecklistItem(t *testing.T) {
+	tx, repo := newTxAndChecklistItemRepository()
+	defer tx.Rollback()
+
+	// Create 1 record
+	insertChecklistItems := createChecklistItemTestData(tx, "checklistItemID-create", "createTaskID", 1)
+	created := insertChecklistItems[0]
+	if err := repo.CreateChecklistItem(created); err != nil {
+		t.Fatalf("Failed to create checklistItem: %+v", err)
+	}
+
+	// Find by ID
+	var find = model.ChecklistItem{}
+	if err := tx.Where(&model.ChecklistItem{ID: created.ID}).First(&find).Error; err != nil {
+		t.Fatalf("Failed to find checklistItem: %+v", err)
+	}
+	// Verify created equals find
+	if !assert.Equal(t, find, *created) {
+		t.Errorf("expected: %+v, but got %+v", created.ID, find.ID)
+	}
+}
+
+func TestChecklistItemRepository_UpdateChecklistItem(t *testing.T) {
+	tx, repo := newTxAndChecklistItemRepository()
+	defer tx.Rollback()
+
+	// Create 1 record
+	insertChecklistItems := createChecklistItemTestData(tx, "checklistItemID-create", "createTaskID", 1)
+	created := insertChecklistItems[0]
+	if err := repo.CreateChecklistItem(created); err != nil {
+		t.Fatalf("Failed to create checklistItem: %+v", err)
+	}
+
+	// Update the record
+	updated := insertChecklistItems[0]
+	updated.Name = "updatedName"
+	updated.IsDone = true
+	if err := repo.UpdateChecklistItem(updated); err != nil {
+		t.Fatalf("failed to update: %+v", err)
+	}
+
+	// Find by ID
+	var find = model.ChecklistItem{}
+	if err := tx.Where(&model.ChecklistItem{ID: updated.ID}).First(&find).Error; err != nil {
+		t.Fatalf("Failed to find checklistItem: %+v", err)
+	}
+
+	// Verify updated equals find
+	if !assert.Equal(t, find, *updated) {
+		t.Errorf("expected %v, but got %v", find, updated)
+	}
+}
+
+func TestChecklistItemRepository_DeleteChecklistItem(t *testing.T) {
+	tx, repo := newTxAndChecklistItemRepository()
+	defer tx.Rollback()
+
+	// Create 1 record
+	insertChecklistItems := createChecklistItemTestData(tx, "checklistItemId-delete", "deleteTaskID", 1)
+	err := insertChecklistItemTestData(tx, insertChecklistItems)
+	if err != nil {
+		t.Fatalf("Failed to create ChecklistItem: %+v", err)
+	}
+	deleted := insertChecklistItems[0]
+	t.Run("Record will not be deleted if ID is empty", func(t *testing.T) {
+		deletedID := deleted.ID
+		deleted.ID = "" // Clear ID
+		if err := repo.DeleteChecklistItem(deleted); err != nil {
+			t.Errorf("Failed to delete: %+v", err)
+		}
+		// record must exist
+		result := model.ChecklistItem{}
+		if err := tx.Where(&model.ChecklistItem{ID: deletedID}).Find(&result).Error; err != nil {
+			t.Errorf("Failed to find: %+v", err)
+		}
+		result.ID = deletedID
+		deleted.ID = deletedID // Restore ID
+
+		// Verify not deleted
+		if !assert.Equal(t, *deleted, result) {
+			t.Error("Record is not same")
+		}
+	})
+
+	t.Run("Record will be deleted if ID is set", func(t *testing.T) {
+		if err := repo.DeleteChecklistItem(deleted); err != nil {
+			t.Errorf("Failed to delete: %+v", err)
+		}
+
+		// Check record will not be found
+		result := model.ChecklistItem{}
+		err := tx.Where(&model.ChecklistItem{ID: deleted.ID}).First(&result).Error
+		if !orm.IsRecordNotFoundError(err) {
+			t.Errorf("Record must be deleted, but got: %+v", err)
+		}
+	})
+}
+
+// Test for CreateChecklistItems, UpdateChecklistItems, DeleteChecklistItems are ommitted,
+// because that they are called internally in each single version
+
+func TestChecklistItemRepository_DeleteChecklistItemsByTaskID(t *testing.T) {
+	tx, repo := newTxAndChecklistItemRepository()
+	defer tx.Rollback()
+
+	deleteChecklistItems := createChecklistItemTestData(tx, "checklistItemID-delete", "deleteTaskID", 3)
+	keepChecklistItems := createChecklistItemTestData(tx, "checklistItemID-keep", "keepTaskID", 2)
+	err := insertChecklistItemTestData(tx, append(deleteChecklistItems, keepChecklistItems...))
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	if err := repo.DeleteChecklistItemsByTaskID("deleteTaskID"); err != nil {
+		t.Fatalf("Failed to delete checklistItems: %+v", err)
+	}
+	count, err := repo.CountChecklistItems(&model.ChecklistItem{TaskID: "deleteTaskID"})
+	if err != nil {
+		t.Fatalf("failed to count ChecklistItem: %+v", err)
+	}
+	assert.Equal(t, 0, count)
+	count, err = repo.CountChecklistItems(&model.ChecklistItem{TaskID: "keepTaskID"})
+	if err != nil {
+		t.Fatalf("failed to count ChecklistItem: %+v", err)
+	}
+	assert.Equal(t, 2, count)
+}
+
+func TestChecklistItemRepository_MaxChecklistItemDispOrder(t *testing.T) {
+	tx, repo := newTxAndChecklistItemRepository()
+	defer tx.Rollback()
+
+	checklistItems := createChecklistItemTestData(tx, "checklistItemID-max", "maxTaskID", 3)
+	err := insertChecklistItemTestData(tx, checklistItems)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	max, err := repo.MaxChecklistItemDispOrder("maxTaskID")
+	if err != nil {
+		t.Fatalf("Failed to get max disp order: %+v", err)
+	}
+	assert.Equal(t, 3, max)
+	// No item returns 0
+	max, err = repo.MaxChecklistItemDispOrder("emptyTaskID")
+	if err != nil {
+		t.Fatalf("Failed to get max disp order: %+v", err)
+	}
+	assert.Equal(t, 0, max)
+}
+
+////
+/// Optimistic lock test (if version lock supported)
+//
+func TestChecklistItemRepository_UpdateChecklistItemOptimisticCheck(t *testing.T) {
+	// Transactions are serialized, so each transaction begins after the previous one is committed
+	tx1, _ := newTxAndChecklistItemRepository()
+	defer tx1.Rollback()
+
+	// Create and commit 1 record in tx1
+	insertChecklistItems := createChecklistItemTestData(tx1, "checklistItemID-optimistic", "", 1)
+	err := insertChecklistItemTestData(tx1, insertChecklistItems)
+	if err != nil {
+		t.Fatalf("Failed to create checklistItem: %+v", err)
+	}
+	if err := tx1.Commit().Error; err != nil {
+		t.Fatalf("Failed to commit data in tx1")
+	}
+	tx2, repo2 := newTxAndChecklistItemRepository()
+	defer tx2.Rollback()
+	// Get commited data in tx2
+	data := insertChecklistItems[0]
+	find, err := repo2.FindFirstChecklistItem(model.ChecklistItem{ID: data.ID}, []string{})
+	if err != nil {
+		deleteCommitedChecklistItemData(t, data)
+	}
+	find.Name = "UpdateInTx2"
+	data.Name = "NotUpdateInTx3"
+	// Find updated in tx2 (Version number incremented!!)
+	err = repo2.UpdateChecklistItem(&find)
+	if err != nil {
+		deleteCommitedChecklistItemData(t, data)
+		t.Fatalf("Failed to update in tx2.")
+	}
+	err = tx2.Commit().Error
+	if err != nil {
+		deleteCommitedChecklistItemData(t, data)
+		t.Fatalf("Failed to commit data in tx2")
+	}
+	tx3, repo3 := newTxAndChecklistItemRepository()
+	defer tx3.Rollback()
+	// Check to not update due to optimistic error
+	if !assert.Equal(t, orm.ErrorOptimisticLock, repo3.UpdateChecklistItem(data)) {
+		deleteCommitedChecklistItemData(t, data)
+		t.Fatalf("Not failed to update, No error occurred")
+	}
+	err = tx3.Commit().Error
+	if err != nil {
+		deleteCommitedChecklistItemData(t, data)
+		t.Fatalf("Failed to Commit tx3, no affected row")
+	}
+
+	tx4, _ := newTxAndChecklistItemRepository()
+	defer tx4.Rollback()
+	var result = model.ChecklistItem{}
+	// Can be found as same as find(tx2)
+	if err := tx4.Where(model.ChecklistItem{ID: find.ID}).Find(&result).Error; err != nil {
+		t.Fatalf("failed to retrieve ChecklistItem: %+v", err)
+	}
+	tx4.Rollback()
+	deleteCommitedChecklistItemData(t, data)
+	if !assert.Equal(t, find, result) {
+		t.Errorf("expected %v, but got %v", find, result)
+	}
+}
+
+func TestChecklistItemRepository_UpdateChecklistItemNotFound(t *testing.T) {
+	tx, repo := newTxAndChecklistItemRepository()
+	defer tx.Rollback()
+
+	// Update not inserted record
+	data := createChecklistItemTestData(tx, "checklistItemID-notfound", "findIdentify-notfound", 1)[0]
+	version := data.Version
+	if !assert.Equal(t, orm.ErrorRecordNotFound, repo.UpdateChecklistItem(data)) {
+		t.Fatalf("Not failed to update, No error occurred")
+	}
+	// Version is not changed and record is not inserted
+	assert.Equal(t, version, data.Version)
+	count := -1
+	if err := tx.Model(&model.ChecklistItem{}).Where("id = ?", data.ID).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count ChecklistItem: %+v", err)
+	}
+	assert.Equal(t, 0, count)
+}
+
+func deleteCommitedChecklistItemData(t *testing.T, data *model.ChecklistItem) {
+	// Try to delete data in another transaction
+	tx, repo := newTxAndChecklistItemRepository()
+	defer tx.Rollback()
+	err := repo.DeleteChecklistItem(data)
+	if err != nil {
+		t.Log("*** Failed to delete test data, please restart test. ***")
+	} else {
+		err := tx.Commit().Error
+		if err != nil {
+			t.Log("*** Failed to delete test data, please restart test. ***")
+		}
+	}
+}
+
+////
+/// Other fuctions' test should be written in below
+//
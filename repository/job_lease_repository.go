@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"taskboard-api-go/model"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// JobLeaseRepository is repository of job lease table
+type JobLeaseRepository struct {
+	tx *gorm.DB
+}
+
+// NewJobLeaseRepository returns new instance of JobLeaseRepository
+func NewJobLeaseRepository(tx *gorm.DB) *JobLeaseRepository {
+	if tx == nil {
+		// Programing error!!
+		panic("tx must be set")
+	}
+	return &JobLeaseRepository{
+		tx: tx,
+	}
+}
+
+// AcquireJobLease acquires or renews the lease of the job until expiresDate, and returns whether holder holds it.
+// The lease is acquired if it is held by holder, expired at now, or not created yet.
+// If another holder creates the lease concurrently, creation fails by duplicated key.
+func (repo *JobLeaseRepository) AcquireJobLease(name, holder string, now, expiresDate time.Time) (bool, error) {
+	result := repo.tx.Model(&model.JobLease{}).
+		Where("name = ? AND (holder = ? OR expires_date <= ?)", name, holder, now).
+		Updates(map[string]interface{}{"holder": holder, "expires_date": expiresDate})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+	// Not updated if the lease is held by another holder
+	count := 0
+	if err := repo.tx.Model(&model.JobLease{}).Where(&model.JobLease{Name: name}).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if err := repo.tx.Create(&model.JobLease{Name: name, Holder: holder, ExpiresDate: expiresDate}).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"taskboard-api-go/orm"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobLeaseRepository_AcquireJobLease(t *testing.T) {
+	tx := orm.GetDB().Begin()
+	defer tx.Rollback()
+	repo := NewJobLeaseRepository(tx)
+	now := time.Now().UTC()
+
+	// Created by the first holder
+	acquired, err := repo.AcquireJobLease("job-test", "instance1", now, now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	// Another holder can not acquire it until it expires
+	acquired, err = repo.AcquireJobLease("job-test", "instance2", now.Add(30*time.Second), now.Add(90*time.Second))
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+
+	// Renewed by the holder
+	acquired, err = repo.AcquireJobLease("job-test", "instance1", now.Add(30*time.Second), now.Add(90*time.Second))
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	acquired, err = repo.AcquireJobLease("job-test", "instance2", now.Add(time.Minute), now.Add(2*time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+
+	// Taken over after it expires
+	acquired, err = repo.AcquireJobLease("job-test", "instance2", now.Add(90*time.Second), now.Add(150*time.Second))
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	acquired, err = repo.AcquireJobLease("job-test", "instance1", now.Add(2*time.Minute), now.Add(3*time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+}
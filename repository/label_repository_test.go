@@ -0,0 +1,384 @@
+package repository
+
+import (
+	"fmt"
+	"taskboard-api-go/common"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+////
+/// Model specific functions (Only replace model name, take care names are casesencitive!!)
+//
+func newTxAndLabelRepository() (tx *gorm.DB, repo *LabelRepository) {
+	tx = orm.GetDB().Begin()
+	repo = NewLabelRepository(tx)
+	return
+}
+
+func createLabelTestData(tx *gorm.DB, idFormat string, findIdentify string, count int) []*model.Label {
+	result := make([]*model.Label, 0, count)
+	for i := 0; i < count; i++ {
+		label := model.NewLabel(
+			"name"+common.GenerateID(),
+			findIdentify,
+			time.Now().UTC(),
+		)
+		label.ID = fmt.Sprintf("%s-%03d", idFormat, i)
+		result = append(result, label)
+	}
+	return result
+}
+
+func insertLabelTestData(tx *gorm.DB, labels []*model.Label) (err error) {
+	for _, label := range labels {
+		err = tx.Create(label).Error
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+////
+/// Common repository functions' test
+//
+func TestLabelRepository_FindFirstLabel(t *testing.T) {
+	tx, repo := newTxAndLabelRepository()
+	defer tx.Rollback()
+
+	firstLabels := createLabelTestData(tx, "labelID-find", "#000001", 5)
+	secondLabels := createLabelTestData(tx, "labelID-not-find", "#000002", 4)
+	insertLabels := append(firstLabels, secondLabels...)
+	err := insertLabelTestData(tx, insertLabels)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+	// Sort by id
+	label, err := repo.FindFirstLabel(&model.Label{Color: "#000001"}, []string{"id"})
+	if err != nil {
+		t.Fatalf("Failed to execute find first: %+v", err)
+	}
+	expected := "labelID-find-000"
+	if label.ID != expected {
+		t.Errorf("expected label ID is %s, but got %s", expected, label.ID)
+	}
+}
+
+func TestLabelRepository_FindLabels(t *testing.T) {
+	tx, repo := newTxAndLabelRepository()
+	defer tx.Rollback()
+
+	firstLabels := createLabelTestData(tx, "labelID-find", "#000001", 5)
+	secondLabels := createLabelTestData(tx, "labelID-not-find", "#000002", 4)
+	insertLabels := append(firstLabels, secondLabels...)
+	err := insertLabelTestData(tx, insertLabels)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	// Get 3 data from 2nd(index=1) position
+	const (
+		offset = 1
+		limit  = 3
+	)
+	labels, err := repo.FindLabels(&model.Label{Color: "#000001"}, offset, limit, []string{"id"})
+	if err != nil {
+		t.Fatalf("Failed to execute find: %+v", err)
+	}
+
+	// Length of data must be limit
+	if len(labels) != limit {
+		t.Errorf("Expected result size = %d, but got %d", limit, len(labels))
+		return
+	}
+	// Head must be 001
+	head := labels[0]
+	headExpected := "labelID-find-001"
+	if head.ID != headExpected {
+		t.Errorf("head ID must be %s, but got %s", headExpected, head.ID)
+	}
+	// Tail must be 003
+	tail := labels[len(labels)-1]
+	tailExpected := "labelID-find-003"
+	if tail.ID != tailExpected {
+		t.Errorf("tail ID must be %s, but got %s", tailExpected, tail.ID)
+	}
+}
+
+func TestLabelRepository_CountLabels(t *testing.T) {
+	tx, repo := newTxAndLabelRepository()
+	defer tx.Rollback()
+
+	expected := 5
+	firstLabels := createLabelTestData(tx, "labelID-find", "#000001", 5)
+	secondLabels := createLabelTestData(tx, "labelID-not-find", "#000002", 4)
+	insertLabels := append(firstLabels, secondLabels...)
+	err := insertLabelTestData(tx, insertLabels)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	count, err := repo.CountLabels(&model.Label{Color: "#000001"})
+	if err != nil {
+		t.Fatalf("failed to count Label: %+v", err)
+	}
+	if expected != count {
+		t.Errorf("expected %d records, but got %d record", expected, count)
+	}
+}
+
+func TestLabelRepository_CreateLabel(t *testing.T) {
+	tx, repo := newTxAndLabelRepository()
+	defer tx.Rollback()
+
+	// Create 1 record
+	insertLabels := createLabelTestData(tx, "labelID-create", "#000003", 1)
+	created := insertLabels[0]
+	if err := repo.CreateLabel(created); err != nil {
+		t.Fatalf("Failed to create label: %+v", err)
+	}
+
+	// Find by ID
+	var find = model.Label{}
+	if err := tx.Where(&model.Label{ID: created.ID}).First(&find).Error; err != nil {
+		t.Fatalf("Failed to find label: %+v", err)
+	}
+	// Verify created equals find
+	if !assert.Equal(t, find, *created) {
+		t.Errorf("expected: %+v, but got %+v", created.ID, find.ID)
+	}
+}
+
+func TestLabelRepository_UpdateLabel(t *testing.T) {
+	tx, repo := newTxAndLabelRepository()
+	defer tx.Rollback()
+
+	// Create 1 record
+	insertLabels := createLabelTestData(tx, "labelID-create", "#000003", 1)
+	created := insertLabels[0]
+	if err := repo.CreateLabel(created); err != nil {
+		t.Fatalf("Failed to create label: %+v", err)
+	}
+
+	// Update the record
+	updated := insertLabels[0]
+	updated.Color = "#000005"
+	if err := repo.UpdateLabel(updated); err != nil {
+		t.Fatalf("failed to update: %+v", err)
+	}
+
+	// Find by ID
+	var find = model.Label{}
+	if err := tx.Where(&model.Label{ID: updated.ID}).First(&find).Error; err != nil {
+		t.Fatalf("Failed to find label: %+v", err)
+	}
+
+	// Verify updated equals find
+	if !assert.Equal(t, find, *updated) {
+		t.Errorf("expected %v, but got %v", find, updated)
+	}
+}
+
+func TestLabelRepository_DeleteLabel(t *testing.T) {
+	tx, repo := newTxAndLabelRepository()
+	defer tx.Rollback()
+
+	// Create 1 record
+	insertLabels := createLabelTestData(tx, "labelId-delete", "#000004", 1)
+	err := insertLabelTestData(tx, insertLabels)
+	if err != nil {
+		t.Fatalf("Failed to create Label: %+v", err)
+	}
+	deleted := insertLabels[0]
+	t.Run("Record will not be deleted if ID is empty", func(t *testing.T) {
+		deletedID := deleted.ID
+		deleted.ID = "" // Clear ID
+		if err := repo.DeleteLabel(deleted); err != nil {
+			t.Errorf("Failed to delete: %+v", err)
+		}
+		// record must exist
+		result := model.Label{}
+		if err := tx.Where(&model.Label{ID: deletedID}).Find(&result).Error; err != nil {
+			t.Errorf("Failed to find: %+v", err)
+		}
+		result.ID = deletedID
+		deleted.ID = deletedID // Restore ID
+
+		// Verify not deleted
+		if !assert.Equal(t, *deleted, result) {
+			t.Error("Record is not same")
+		}
+	})
+
+	t.Run("Record will be deleted if ID is set", func(t *testing.T) {
+		if err := repo.DeleteLabel(deleted); err != nil {
+			t.Errorf("Failed to delete: %+v", err)
+		}
+
+		// Check record will not be found
+		result := model.Label{}
+		err := tx.Where(&model.Label{ID: deleted.ID}).First(&result).Error
+		if !orm.IsRecordNotFoundError(err) {
+			t.Errorf("Record must be deleted, but got: %+v", err)
+		}
+	})
+}
+
+// Test for CreateLabels, UpdateLabels, DeleteLabels are ommitted,
+// because that they are called internally in each single version
+
+func TestLabelRepository_ReplaceTaskLabels(t *testing.T) {
+	tx, repo := newTxAndLabelRepository()
+	defer tx.Rollback()
+
+	if err := repo.ReplaceTaskLabels("taskID-label-1", []string{"labelID-a", "labelID-b"}); err != nil {
+		t.Fatalf("Failed to replace task labels: %+v", err)
+	}
+	if err := repo.ReplaceTaskLabels("taskID-label-2", []string{"labelID-a"}); err != nil {
+		t.Fatalf("Failed to replace task labels: %+v", err)
+	}
+	// Replace all labels of task 1
+	if err := repo.ReplaceTaskLabels("taskID-label-1", []string{"labelID-c"}); err != nil {
+		t.Fatalf("Failed to replace task labels: %+v", err)
+	}
+
+	taskLabels, err := repo.FindTaskLabels([]string{"taskID-label-1", "taskID-label-2"})
+	if err != nil {
+		t.Fatalf("Failed to find task labels: %+v", err)
+	}
+	expected := []model.TaskLabel{
+		{TaskID: "taskID-label-1", LabelID: "labelID-c"},
+		{TaskID: "taskID-label-2", LabelID: "labelID-a"},
+	}
+	assert.Equal(t, expected, taskLabels)
+}
+
+func TestLabelRepository_DeleteTaskLabelsByLabelID(t *testing.T) {
+	tx, repo := newTxAndLabelRepository()
+	defer tx.Rollback()
+
+	if err := repo.ReplaceTaskLabels("taskID-label-1", []string{"labelID-a", "labelID-b"}); err != nil {
+		t.Fatalf("Failed to replace task labels: %+v", err)
+	}
+	if err := repo.DeleteTaskLabelsByLabelID("labelID-a"); err != nil {
+		t.Fatalf("Failed to delete task labels: %+v", err)
+	}
+
+	taskLabels, err := repo.FindTaskLabels([]string{"taskID-label-1"})
+	if err != nil {
+		t.Fatalf("Failed to find task labels: %+v", err)
+	}
+	expected := []model.TaskLabel{
+		{TaskID: "taskID-label-1", LabelID: "labelID-b"},
+	}
+	assert.Equal(t, expected, taskLabels)
+}
+
+////
+/// Optimistic lock test (if version lock supported)
+//
+func TestLabelRepository_UpdateLabelOptimisticCheck(t *testing.T) {
+	// Transactions are serialized, so each transaction begins after the previous one is committed
+	tx1, _ := newTxAndLabelRepository()
+	defer tx1.Rollback()
+
+	// Create and commit 1 record in tx1
+	insertLabels := createLabelTestData(tx1, "labelID-optimistic", "", 1)
+	err := insertLabelTestData(tx1, insertLabels)
+	if err != nil {
+		t.Fatalf("Failed to create label: %+v", err)
+	}
+	if err := tx1.Commit().Error; err != nil {
+		t.Fatalf("Failed to commit data in tx1")
+	}
+	tx2, repo2 := newTxAndLabelRepository()
+	defer tx2.Rollback()
+	// Get commited data in tx2
+	data := insertLabels[0]
+	find, err := repo2.FindFirstLabel(model.Label{ID: data.ID}, []string{})
+	if err != nil {
+		deleteCommitedLabelData(t, data)
+	}
+	find.Color = "#000006"
+	data.Color = "#000007"
+	// Find updated in tx2 (Version number incremented!!)
+	err = repo2.UpdateLabel(&find)
+	if err != nil {
+		deleteCommitedLabelData(t, data)
+		t.Fatalf("Failed to update in tx2.")
+	}
+	err = tx2.Commit().Error
+	if err != nil {
+		deleteCommitedLabelData(t, data)
+		t.Fatalf("Failed to commit data in tx2")
+	}
+	tx3, repo3 := newTxAndLabelRepository()
+	defer tx3.Rollback()
+	// Check to not update due to optimistic error
+	if !assert.Equal(t, orm.ErrorOptimisticLock, repo3.UpdateLabel(data)) {
+		deleteCommitedLabelData(t, data)
+		t.Fatalf("Not failed to update, No error occurred")
+	}
+	err = tx3.Commit().Error
+	if err != nil {
+		deleteCommitedLabelData(t, data)
+		t.Fatalf("Failed to Commit tx3, no affected row")
+	}
+
+	tx4, _ := newTxAndLabelRepository()
+	defer tx4.Rollback()
+	var result = model.Label{}
+	// Can be found as same as find(tx2)
+	if err := tx4.Where(model.Label{ID: find.ID}).Find(&result).Error; err != nil {
+		t.Fatalf("failed to retrieve Label: %+v", err)
+	}
+	tx4.Rollback()
+	deleteCommitedLabelData(t, data)
+	if !assert.Equal(t, find, result) {
+		t.Errorf("expected %v, but got %v", find, result)
+	}
+}
+
+func TestLabelRepository_UpdateLabelNotFound(t *testing.T) {
+	tx, repo := newTxAndLabelRepository()
+	defer tx.Rollback()
+
+	// Update not inserted record
+	data := createLabelTestData(tx, "labelID-notfound", "findIdentify-notfound", 1)[0]
+	version := data.Version
+	if !assert.Equal(t, orm.ErrorRecordNotFound, repo.UpdateLabel(data)) {
+		t.Fatalf("Not failed to update, No error occurred")
+	}
+	// Version is not changed and record is not inserted
+	assert.Equal(t, version, data.Version)
+	count := -1
+	if err := tx.Model(&model.Label{}).Where("id = ?", data.ID).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count Label: %+v", err)
+	}
+	assert.Equal(t, 0, count)
+}
+
+func deleteCommitedLabelData(t *testing.T, data *model.Label) {
+	// Try to delete data in another transaction
+	tx, repo := newTxAndLabelRepository()
+	defer tx.Rollback()
+	err := repo.DeleteLabel(data)
+	if err != nil {
+		t.Log("*** Failed to delete test data, please restart test. ***")
+	} else {
+		err := tx.Commit().Error
+		if err != nil {
+			t.Log("*** Failed to delete test data, please restart test. ***")
+		}
+	}
+}
+
+////
+/// Other fuctions' test should be written in below
+//
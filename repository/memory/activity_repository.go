@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+)
+
+// ActivityRepository is in-memory repository of activities
+type ActivityRepository struct {
+	store *Store
+}
+
+// NewActivityRepository returns new instance of ActivityRepository
+func NewActivityRepository(store *Store) *ActivityRepository {
+	return &ActivityRepository{
+		store: store,
+	}
+}
+
+// FindActivities returns Activities matching with specified condition
+func (repo *ActivityRepository) FindActivities(condition interface{}, offset int, limit int, sortOrders []string) (result []model.Activity, err error) {
+	err = repo.store.find(&result, condition, nil, offset, limit, sortOrders)
+	return
+}
+
+// FindChangesAfterVersion returns update and reorder activities of the entity recorded after specified version,
+// in chronological order
+func (repo *ActivityRepository) FindChangesAfterVersion(entityKind, entityID string, version int) (result []model.Activity, err error) {
+	condition := map[string]interface{}{
+		"entity_kind": entityKind,
+		"entity_id":   entityID,
+		"action":      []string{model.ActivityActionUpdate, model.ActivityActionReorder},
+	}
+	err = repo.store.find(&result, condition, func(record interface{}) bool {
+		return record.(*model.Activity).EntityVersion > version
+	}, 0, orm.NoLimit, []string{"entity_version, created_date, id"})
+	return
+}
+
+// CreateActivity inserts new Activity record
+func (repo *ActivityRepository) CreateActivity(activity *model.Activity) error {
+	return repo.store.insert(activity)
+}
+
+// CreateActivities inserts new Activity records.
+func (repo *ActivityRepository) CreateActivities(activities []*model.Activity) error {
+	for _, activity := range activities {
+		if err := repo.store.insert(activity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
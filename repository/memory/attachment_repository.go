@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"taskboard-api-go/model"
+)
+
+// AttachmentRepository is in-memory repository of attachments
+type AttachmentRepository struct {
+	store *Store
+}
+
+// NewAttachmentRepository returns new instance of AttachmentRepository
+func NewAttachmentRepository(store *Store) *AttachmentRepository {
+	return &AttachmentRepository{
+		store: store,
+	}
+}
+
+// FindFirstAttachment returns first Attachment matching with specified condition
+func (repo *AttachmentRepository) FindFirstAttachment(condition interface{}, sortOrders []string) (result model.Attachment, err error) {
+	err = repo.store.first(&result, condition, sortOrders)
+	return
+}
+
+// FindAttachments returns Attachments matching with specified condition
+func (repo *AttachmentRepository) FindAttachments(condition interface{}, offset int, limit int, sortOrders []string) (result []model.Attachment, err error) {
+	err = repo.store.find(&result, condition, nil, offset, limit, sortOrders)
+	return
+}
+
+// CreateAttachment inserts new Attachment record
+func (repo *AttachmentRepository) CreateAttachment(attachment *model.Attachment) error {
+	return repo.store.insert(attachment)
+}
+
+// DeleteAttachment deletes Attachment record
+func (repo *AttachmentRepository) DeleteAttachment(attachment *model.Attachment) error {
+	if attachment.ID == "" {
+		return nil
+	}
+	return repo.store.delete(&model.Attachment{}, &model.Attachment{ID: attachment.ID}, nil)
+}
+
+// DeleteAttachmentsByTaskID deletes all Attachment records of specified task
+func (repo *AttachmentRepository) DeleteAttachmentsByTaskID(taskID string) error {
+	if taskID == "" {
+		return nil
+	}
+	return repo.store.delete(&model.Attachment{}, &model.Attachment{TaskID: taskID}, nil)
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"taskboard-api-go/common"
+	"taskboard-api-go/model"
+)
+
+// BoardRepository is in-memory repository of boards
+type BoardRepository struct {
+	store *Store
+}
+
+// NewBoardRepository returns new instance of BoardRepository
+func NewBoardRepository(store *Store) *BoardRepository {
+	return &BoardRepository{
+		store: store,
+	}
+}
+
+// FindFirstBoard returns first Board matching with specified condition
+func (repo *BoardRepository) FindFirstBoard(condition interface{}, sortOrders []string) (result model.Board, err error) {
+	err = repo.store.first(&result, condition, sortOrders)
+	return
+}
+
+// FindBoards returns Boards matching with specified condition
+func (repo *BoardRepository) FindBoards(condition interface{}, offset int, limit int, sortOrders []string) (result []model.Board, err error) {
+	err = repo.store.find(&result, condition, nil, offset, limit, sortOrders)
+	return
+}
+
+// CreateBoard inserts new Board record.
+// Board without rank is placed after the last board.
+func (repo *BoardRepository) CreateBoard(board *model.Board) error {
+	if board.Rank == "" {
+		last, err := repo.FindBoards(&model.Board{}, 0, 1, []string{"sort_rank desc"})
+		if err != nil {
+			return err
+		}
+		max := ""
+		if len(last) > 0 {
+			max = last[0].Rank
+		}
+		if board.Rank, err = common.RankBetween(max, ""); err != nil {
+			return err
+		}
+	}
+	return repo.store.insert(board)
+}
+
+// UpdateBoard updates Board record.
+// It returns ErrorRecordNotFound if deleted, and ErrorOptimisticLock if updated by others.
+func (repo *BoardRepository) UpdateBoard(board *model.Board) error {
+	oldVersion := board.Version
+	board.Version++
+	err := repo.store.updateWithVersion(board, oldVersion)
+	if err != nil {
+		board.Version = oldVersion
+	}
+	return err
+}
+
+// DeleteBoard deletes Board record
+func (repo *BoardRepository) DeleteBoard(board *model.Board) error {
+	if board.ID == "" {
+		return nil
+	}
+	return repo.store.delete(&model.Board{}, &model.Board{ID: board.ID}, nil)
+}
+
+// UpdateBoardRank changes rank of the board, its version is not changed
+func (repo *BoardRepository) UpdateBoardRank(boardID, rank string) error {
+	if boardID == "" {
+		return nil
+	}
+	return repo.store.update(&model.Board{}, &model.Board{ID: boardID}, func(record interface{}) {
+		record.(*model.Board).Rank = rank
+	})
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"taskboard-api-go/model"
+)
+
+// ChecklistItemRepository is in-memory repository of checklist items
+type ChecklistItemRepository struct {
+	store *Store
+}
+
+// NewChecklistItemRepository returns new instance of ChecklistItemRepository
+func NewChecklistItemRepository(store *Store) *ChecklistItemRepository {
+	return &ChecklistItemRepository{
+		store: store,
+	}
+}
+
+// FindFirstChecklistItem returns first ChecklistItem matching with specified condition
+func (repo *ChecklistItemRepository) FindFirstChecklistItem(condition interface{}, sortOrders []string) (result model.ChecklistItem, err error) {
+	err = repo.store.first(&result, condition, sortOrders)
+	return
+}
+
+// FindChecklistItems returns ChecklistItems matching with specified condition
+func (repo *ChecklistItemRepository) FindChecklistItems(condition interface{}, offset int, limit int, sortOrders []string) (result []model.ChecklistItem, err error) {
+	err = repo.store.find(&result, condition, nil, offset, limit, sortOrders)
+	return
+}
+
+// MaxChecklistItemDispOrder returns max of disp order of ChecklistItems in specified task
+func (repo *ChecklistItemRepository) MaxChecklistItemDispOrder(taskID string) (int, error) {
+	last, err := repo.FindChecklistItems(&model.ChecklistItem{TaskID: taskID}, 0, 1, []string{"disp_order desc"})
+	if err != nil || len(last) == 0 {
+		return 0, err
+	}
+	return last[0].DispOrder, nil
+}
+
+// CreateChecklistItem inserts new ChecklistItem record
+func (repo *ChecklistItemRepository) CreateChecklistItem(checklistItem *model.ChecklistItem) error {
+	return repo.store.insert(checklistItem)
+}
+
+// UpdateChecklistItem updates ChecklistItem record.
+// It returns ErrorRecordNotFound if deleted, and ErrorOptimisticLock if updated by others.
+func (repo *ChecklistItemRepository) UpdateChecklistItem(checklistItem *model.ChecklistItem) error {
+	oldVersion := checklistItem.Version
+	checklistItem.Version++
+	err := repo.store.updateWithVersion(checklistItem, oldVersion)
+	if err != nil {
+		checklistItem.Version = oldVersion
+	}
+	return err
+}
+
+// DeleteChecklistItem deletes ChecklistItem record
+func (repo *ChecklistItemRepository) DeleteChecklistItem(checklistItem *model.ChecklistItem) error {
+	if checklistItem.ID == "" {
+		return nil
+	}
+	return repo.store.delete(&model.ChecklistItem{}, &model.ChecklistItem{ID: checklistItem.ID}, nil)
+}
+
+// DeleteChecklistItemsByTaskID deletes all ChecklistItem records of specified task
+func (repo *ChecklistItemRepository) DeleteChecklistItemsByTaskID(taskID string) error {
+	if taskID == "" {
+		return nil
+	}
+	return repo.store.delete(&model.ChecklistItem{}, &model.ChecklistItem{TaskID: taskID}, nil)
+}
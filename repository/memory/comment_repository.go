@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"taskboard-api-go/model"
+)
+
+// CommentRepository is in-memory repository of comments
+type CommentRepository struct {
+	store *Store
+}
+
+// NewCommentRepository returns new instance of CommentRepository
+func NewCommentRepository(store *Store) *CommentRepository {
+	return &CommentRepository{
+		store: store,
+	}
+}
+
+// FindFirstComment returns first Comment matching with specified condition
+func (repo *CommentRepository) FindFirstComment(condition interface{}, sortOrders []string) (result model.Comment, err error) {
+	err = repo.store.first(&result, condition, sortOrders)
+	return
+}
+
+// FindComments returns Comments matching with specified condition
+func (repo *CommentRepository) FindComments(condition interface{}, offset int, limit int, sortOrders []string) (result []model.Comment, err error) {
+	err = repo.store.find(&result, condition, nil, offset, limit, sortOrders)
+	return
+}
+
+// CreateComment inserts new Comment record
+func (repo *CommentRepository) CreateComment(comment *model.Comment) error {
+	return repo.store.insert(comment)
+}
+
+// UpdateComment updates Comment record.
+// It returns ErrorRecordNotFound if deleted, and ErrorOptimisticLock if updated by others.
+func (repo *CommentRepository) UpdateComment(comment *model.Comment) error {
+	oldVersion := comment.Version
+	comment.Version++
+	err := repo.store.updateWithVersion(comment, oldVersion)
+	if err != nil {
+		comment.Version = oldVersion
+	}
+	return err
+}
+
+// DeleteComment deletes Comment record
+func (repo *CommentRepository) DeleteComment(comment *model.Comment) error {
+	if comment.ID == "" {
+		return nil
+	}
+	return repo.store.delete(&model.Comment{}, &model.Comment{ID: comment.ID}, nil)
+}
+
+// DeleteCommentsByTaskID deletes all Comment records of specified task
+func (repo *CommentRepository) DeleteCommentsByTaskID(taskID string) error {
+	if taskID == "" {
+		return nil
+	}
+	return repo.store.delete(&model.Comment{}, &model.Comment{TaskID: taskID}, nil)
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+)
+
+// LabelRepository is in-memory repository of labels and their relations to tasks
+type LabelRepository struct {
+	store *Store
+}
+
+// NewLabelRepository returns new instance of LabelRepository
+func NewLabelRepository(store *Store) *LabelRepository {
+	return &LabelRepository{
+		store: store,
+	}
+}
+
+// FindFirstLabel returns first Label matching with specified condition
+func (repo *LabelRepository) FindFirstLabel(condition interface{}, sortOrders []string) (result model.Label, err error) {
+	err = repo.store.first(&result, condition, sortOrders)
+	return
+}
+
+// FindLabels returns Labels matching with specified condition
+func (repo *LabelRepository) FindLabels(condition interface{}, offset int, limit int, sortOrders []string) (result []model.Label, err error) {
+	err = repo.store.find(&result, condition, nil, offset, limit, sortOrders)
+	return
+}
+
+// CreateLabel inserts new Label record
+func (repo *LabelRepository) CreateLabel(label *model.Label) error {
+	return repo.store.insert(label)
+}
+
+// UpdateLabel updates Label record.
+// It returns ErrorRecordNotFound if deleted, and ErrorOptimisticLock if updated by others.
+func (repo *LabelRepository) UpdateLabel(label *model.Label) error {
+	oldVersion := label.Version
+	label.Version++
+	err := repo.store.updateWithVersion(label, oldVersion)
+	if err != nil {
+		label.Version = oldVersion
+	}
+	return err
+}
+
+// DeleteLabel deletes Label record
+func (repo *LabelRepository) DeleteLabel(label *model.Label) error {
+	if label.ID == "" {
+		return nil
+	}
+	return repo.store.delete(&model.Label{}, &model.Label{ID: label.ID}, nil)
+}
+
+// FindTaskLabels returns relations between tasks and labels of specified tasks
+func (repo *LabelRepository) FindTaskLabels(taskIDs []string) (result []model.TaskLabel, err error) {
+	if len(taskIDs) == 0 {
+		return []model.TaskLabel{}, nil
+	}
+	err = repo.store.find(&result, map[string]interface{}{"task_id": taskIDs}, nil, 0, orm.NoLimit, []string{"task_id, label_id"})
+	return
+}
+
+// ReplaceTaskLabels replaces all labels of specified task by specified labels
+func (repo *LabelRepository) ReplaceTaskLabels(taskID string, labelIDs []string) error {
+	if err := repo.DeleteTaskLabelsByTaskID(taskID); err != nil {
+		return err
+	}
+	for _, labelID := range labelIDs {
+		if err := repo.store.insert(&model.TaskLabel{TaskID: taskID, LabelID: labelID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteTaskLabelsByTaskID deletes all relations of specified task
+func (repo *LabelRepository) DeleteTaskLabelsByTaskID(taskID string) error {
+	if taskID == "" {
+		return nil
+	}
+	return repo.store.delete(&model.TaskLabel{}, &model.TaskLabel{TaskID: taskID}, nil)
+}
+
+// DeleteTaskLabelsByLabelID deletes all relations of specified label
+func (repo *LabelRepository) DeleteTaskLabelsByLabelID(labelID string) error {
+	if labelID == "" {
+		return nil
+	}
+	return repo.store.delete(&model.TaskLabel{}, &model.TaskLabel{LabelID: labelID}, nil)
+}
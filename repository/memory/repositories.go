@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"taskboard-api-go/repository"
+)
+
+// NewRepositories returns repositories which share specified store
+func NewRepositories(store *Store) *repository.Repositories {
+	return &repository.Repositories{
+		Tasks:            NewTaskRepository(store),
+		Boards:           NewBoardRepository(store),
+		Users:            NewUserRepository(store),
+		Activities:       NewActivityRepository(store),
+		Comments:         NewCommentRepository(store),
+		Labels:           NewLabelRepository(store),
+		ChecklistItems:   NewChecklistItemRepository(store),
+		TaskDependencies: NewTaskDependencyRepository(store),
+		Attachments:      NewAttachmentRepository(store),
+		TaskLocks:        NewTaskLockRepository(store),
+	}
+}
+
+// Repositories in memory must have same methods as ones on database
+var (
+	_ repository.Tasks            = (*TaskRepository)(nil)
+	_ repository.Boards           = (*BoardRepository)(nil)
+	_ repository.Users            = (*UserRepository)(nil)
+	_ repository.Activities       = (*ActivityRepository)(nil)
+	_ repository.Comments         = (*CommentRepository)(nil)
+	_ repository.Labels           = (*LabelRepository)(nil)
+	_ repository.ChecklistItems   = (*ChecklistItemRepository)(nil)
+	_ repository.TaskDependencies = (*TaskDependencyRepository)(nil)
+	_ repository.Attachments      = (*AttachmentRepository)(nil)
+	_ repository.TaskLocks        = (*TaskLockRepository)(nil)
+)
@@ -0,0 +1,539 @@
+package memory
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"taskboard-api-go/orm"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// ErrorUniqueConstraint is an error when a record has same values as another record in primary key or unique columns
+var ErrorUniqueConstraint = errors.New("unique constraint failed")
+
+// Store holds records of all tables in memory, which repositories share like a database.
+// Changes are applied immediately, there is no transaction to rollback them.
+type Store struct {
+	lock   *sync.Mutex
+	tables map[reflect.Type]*table
+}
+
+// NewStore returns new empty store
+func NewStore() *Store {
+	return &Store{
+		lock:   new(sync.Mutex),
+		tables: make(map[reflect.Type]*table),
+	}
+}
+
+// table holds records of a model in order of insertion.
+// Records are pointers to copies owned by the table, which are replaced on update and never modified.
+type table struct {
+	schema  *schema
+	records []interface{}
+}
+
+// schema presents columns and constraints of a model which are read from gorm tags
+type schema struct {
+	columns       map[string]int // Field index keyed by column name
+	primaryKeys   []int
+	uniqueIndexes [][]int
+	version       int // Field index of Version, -1 if the model has no version
+}
+
+func newSchema(t reflect.Type) *schema {
+	s := &schema{
+		columns: make(map[string]int),
+		version: -1,
+	}
+	indexes := map[string][]int{}
+	indexNames := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		settings := parseTag(field.Tag.Get("gorm"))
+		column, ok := settings["COLUMN"]
+		if !ok {
+			column = gorm.ToColumnName(field.Name)
+		}
+		s.columns[column] = i
+		if _, ok := settings["PRIMARY_KEY"]; ok {
+			s.primaryKeys = append(s.primaryKeys, i)
+		}
+		if _, ok := settings["UNIQUE"]; ok {
+			s.uniqueIndexes = append(s.uniqueIndexes, []int{i})
+		}
+		if name, ok := settings["UNIQUE_INDEX"]; ok {
+			if name == "" {
+				name = "uix_" + column
+			}
+			if _, exists := indexes[name]; !exists {
+				indexNames = append(indexNames, name)
+			}
+			indexes[name] = append(indexes[name], i)
+		}
+		if field.Name == "Version" {
+			s.version = i
+		}
+	}
+	for _, name := range indexNames {
+		s.uniqueIndexes = append(s.uniqueIndexes, indexes[name])
+	}
+	return s
+}
+
+// parseTag parses gorm tag such as `column:sort_rank;not null`, keys are upper case
+func parseTag(tag string) map[string]string {
+	settings := map[string]string{}
+	for _, setting := range strings.Split(tag, ";") {
+		if setting == "" {
+			continue
+		}
+		pair := strings.SplitN(setting, ":", 2)
+		key := strings.ToUpper(strings.TrimSpace(pair[0]))
+		if len(pair) == 2 {
+			settings[key] = strings.TrimSpace(pair[1])
+		} else {
+			settings[key] = ""
+		}
+	}
+	return settings
+}
+
+// table returns the table of model type t, caller must hold the lock
+func (s *Store) table(t reflect.Type) *table {
+	found, ok := s.tables[t]
+	if !ok {
+		found = &table{schema: newSchema(t)}
+		s.tables[t] = found
+	}
+	return found
+}
+
+// find appends records matching condition and filter to out, which is a pointer to slice of model.
+// filter is called with pointer to the record and must not modify it, nil matches all.
+func (s *Store) find(out interface{}, condition interface{}, filter func(record interface{}) bool,
+	offset int, limit int, sortOrders []string,
+) error {
+	slice := reflect.ValueOf(out).Elem()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t := s.table(slice.Type().Elem())
+	records, err := t.selectRecords(condition, filter, sortOrders)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		if offset > len(records) {
+			offset = len(records)
+		}
+		records = records[offset:]
+	}
+	if limit >= 0 && limit < len(records) {
+		records = records[:limit]
+	}
+	result := reflect.MakeSlice(slice.Type(), 0, len(records))
+	for _, record := range records {
+		result = reflect.Append(result, reflect.ValueOf(record).Elem())
+	}
+	slice.Set(result)
+	return nil
+}
+
+// first sets the first record matching condition to out, which is a pointer to model.
+// It returns ErrorRecordNotFound if no record matches.
+func (s *Store) first(out interface{}, condition interface{}, sortOrders []string) error {
+	value := reflect.ValueOf(out).Elem()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	records, err := s.table(value.Type()).selectRecords(condition, nil, sortOrders)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return orm.ErrorRecordNotFound
+	}
+	value.Set(reflect.ValueOf(records[0]).Elem())
+	return nil
+}
+
+// count returns the number of records of model matching condition
+func (s *Store) count(model interface{}, condition interface{}) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	records, err := s.table(reflect.TypeOf(model).Elem()).selectRecords(condition, nil, nil)
+	return len(records), err
+}
+
+// insert inserts copy of value, which is a pointer to model
+func (s *Store) insert(value interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t := s.table(reflect.TypeOf(value).Elem())
+	record := copyRecord(value)
+	if err := t.checkUnique(record, nil); err != nil {
+		return err
+	}
+	t.records = append(t.records, record)
+	return nil
+}
+
+// updateWithVersion replaces the record of value's primary key by copy of value, only if its version is oldVersion.
+// It returns ErrorRecordNotFound if the record does not exist, and ErrorOptimisticLock if the version does not match.
+func (s *Store) updateWithVersion(value interface{}, oldVersion int) error {
+	return s.updateColumnsWithVersion(value, oldVersion, func(record interface{}) {
+		reflect.ValueOf(record).Elem().Set(reflect.ValueOf(value).Elem())
+	})
+}
+
+// updateColumnsWithVersion changes the record of key's primary key by set, only if its version is oldVersion.
+// set is called with pointer to copy of the record, and it should set the new version. Errors are same as updateWithVersion.
+func (s *Store) updateColumnsWithVersion(key interface{}, oldVersion int, set func(record interface{})) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t := s.table(reflect.TypeOf(key).Elem())
+	for i, current := range t.records {
+		if !t.samePrimaryKey(current, key) {
+			continue
+		}
+		if t.schema.version >= 0 && reflect.ValueOf(current).Elem().Field(t.schema.version).Int() != int64(oldVersion) {
+			return orm.ErrorOptimisticLock
+		}
+		record := copyRecord(current)
+		set(record)
+		if err := t.checkUnique(record, current); err != nil {
+			return err
+		}
+		t.records[i] = record
+		return nil
+	}
+	return orm.ErrorRecordNotFound
+}
+
+// update changes records of model matching condition by set, which is called with pointer to copy of each record.
+// Records are updated one by one, and it stops at the first record which violates unique constraint.
+func (s *Store) update(model interface{}, condition interface{}, set func(record interface{})) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t := s.table(reflect.TypeOf(model).Elem())
+	matches, err := t.matcher(condition)
+	if err != nil {
+		return err
+	}
+	for i, current := range t.records {
+		if !matches(current) {
+			continue
+		}
+		record := copyRecord(current)
+		set(record)
+		if err := t.checkUnique(record, current); err != nil {
+			return err
+		}
+		t.records[i] = record
+	}
+	return nil
+}
+
+// delete deletes records of model matching condition and filter
+func (s *Store) delete(model interface{}, condition interface{}, filter func(record interface{}) bool) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t := s.table(reflect.TypeOf(model).Elem())
+	matches, err := t.matcher(condition)
+	if err != nil {
+		return err
+	}
+	remaining := make([]interface{}, 0, len(t.records))
+	for _, record := range t.records {
+		if !matches(record) || (filter != nil && !filter(record)) {
+			remaining = append(remaining, record)
+		}
+	}
+	t.records = remaining
+	return nil
+}
+
+func copyRecord(value interface{}) interface{} {
+	source := reflect.ValueOf(value).Elem()
+	record := reflect.New(source.Type())
+	record.Elem().Set(source)
+	return record.Interface()
+}
+
+func (t *table) selectRecords(condition interface{}, filter func(record interface{}) bool, sortOrders []string) ([]interface{}, error) {
+	matches, err := t.matcher(condition)
+	if err != nil {
+		return nil, err
+	}
+	less, err := t.comparator(sortOrders)
+	if err != nil {
+		return nil, err
+	}
+	result := []interface{}{}
+	for _, record := range t.records {
+		if matches(record) && (filter == nil || filter(record)) {
+			result = append(result, record)
+		}
+	}
+	// Records of same sort keys remain in order of insertion
+	sort.SliceStable(result, func(i, j int) bool {
+		return less(result[i], result[j])
+	})
+	return result, nil
+}
+
+// matcher returns function which checks whether a record matches condition.
+// Condition is a model (or its pointer) whose non-blank fields must equal, as gorm does,
+// or a map of column names to values, where a slice value means any of them.
+func (t *table) matcher(condition interface{}) (func(record interface{}) bool, error) {
+	type term struct {
+		index  int
+		values []interface{}
+	}
+	terms := []term{}
+	value := reflect.ValueOf(condition)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Invalid, reflect.Ptr:
+		// No condition
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if !isBlank(value.Field(i)) {
+				terms = append(terms, term{i, []interface{}{columnValue(value.Field(i))}})
+			}
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			index, ok := t.schema.columns[fmt.Sprint(key.Interface())]
+			if !ok {
+				return nil, errors.Errorf("no such column: %v", key.Interface())
+			}
+			item := value.MapIndex(key)
+			for item.Kind() == reflect.Interface && !item.IsNil() {
+				item = item.Elem()
+			}
+			values := []interface{}{}
+			if item.Kind() == reflect.Slice && item.Type().Elem().Kind() != reflect.Uint8 {
+				for i := 0; i < item.Len(); i++ {
+					values = append(values, columnValue(item.Index(i)))
+				}
+			} else {
+				values = append(values, columnValue(item))
+			}
+			terms = append(terms, term{index, values})
+		}
+	default:
+		return nil, errors.Errorf("unsupported condition: %T", condition)
+	}
+	return func(record interface{}) bool {
+		fields := reflect.ValueOf(record).Elem()
+		for _, term := range terms {
+			actual := columnValue(fields.Field(term.index))
+			matched := false
+			for _, expected := range term.values {
+				// NULL never equals to any value in SQL
+				if actual != nil && expected != nil && compareValues(actual, expected) == 0 {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// comparator returns function which compares records by sort orders such as "sort_rank, created_date desc"
+func (t *table) comparator(sortOrders []string) (func(a, b interface{}) bool, error) {
+	type key struct {
+		index int
+		desc  bool
+	}
+	keys := []key{}
+	for _, sortOrder := range sortOrders {
+		for _, item := range strings.Split(sortOrder, ",") {
+			words := strings.Fields(item)
+			if len(words) == 0 {
+				continue
+			}
+			index, ok := t.schema.columns[words[0]]
+			if !ok {
+				return nil, errors.Errorf("no such column: %s", words[0])
+			}
+			keys = append(keys, key{index, len(words) > 1 && strings.EqualFold(words[1], "desc")})
+		}
+	}
+	return func(a, b interface{}) bool {
+		fieldsA := reflect.ValueOf(a).Elem()
+		fieldsB := reflect.ValueOf(b).Elem()
+		for _, key := range keys {
+			result := compareValues(columnValue(fieldsA.Field(key.index)), columnValue(fieldsB.Field(key.index)))
+			if result != 0 {
+				return (result < 0) != key.desc
+			}
+		}
+		return false
+	}, nil
+}
+
+func (t *table) samePrimaryKey(a, b interface{}) bool {
+	return t.sameColumns(a, b, t.schema.primaryKeys)
+}
+
+func (t *table) sameColumns(a, b interface{}, indexes []int) bool {
+	fieldsA := reflect.ValueOf(a).Elem()
+	fieldsB := reflect.ValueOf(b).Elem()
+	for _, index := range indexes {
+		valueA := columnValue(fieldsA.Field(index))
+		valueB := columnValue(fieldsB.Field(index))
+		if valueA == nil || valueB == nil || compareValues(valueA, valueB) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// checkUnique checks that record does not have same primary key or unique columns as other records except old
+func (t *table) checkUnique(record interface{}, old interface{}) error {
+	indexes := append([][]int{t.schema.primaryKeys}, t.schema.uniqueIndexes...)
+	for _, other := range t.records {
+		if other == old {
+			continue
+		}
+		for _, index := range indexes {
+			if len(index) > 0 && t.sameColumns(record, other, index) {
+				return errors.WithStack(ErrorUniqueConstraint)
+			}
+		}
+	}
+	return nil
+}
+
+// isBlank checks whether value is zero value, fields of blank value are ignored in conditions as gorm does
+func isBlank(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String:
+		return value.Len() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return value.IsNil()
+	}
+	return reflect.DeepEqual(value.Interface(), reflect.Zero(value.Type()).Interface())
+}
+
+// columnValue converts value of field to a value stored in database: nil, string, int64, float64, bool or time.Time
+func columnValue(value reflect.Value) interface{} {
+	if valuer, ok := value.Interface().(driver.Valuer); ok {
+		if value.Kind() == reflect.Ptr && value.IsNil() {
+			return nil
+		}
+		converted, err := valuer.Value()
+		if err != nil || converted == nil {
+			return nil
+		}
+		value = reflect.ValueOf(converted)
+	}
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Bool:
+		return value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	}
+	return value.Interface()
+}
+
+// compareValues compares values converted by columnValue, NULL comes first as SQLite does
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	switch valueA := a.(type) {
+	case string:
+		if valueB, ok := b.(string); ok {
+			return strings.Compare(valueA, valueB)
+		}
+	case int64:
+		if valueB, ok := b.(int64); ok {
+			return compareFloats(float64(valueA), float64(valueB))
+		}
+		if valueB, ok := b.(float64); ok {
+			return compareFloats(float64(valueA), valueB)
+		}
+	case float64:
+		if valueB, ok := b.(float64); ok {
+			return compareFloats(valueA, valueB)
+		}
+		if valueB, ok := b.(int64); ok {
+			return compareFloats(valueA, float64(valueB))
+		}
+	case bool:
+		if valueB, ok := b.(bool); ok {
+			return compareBools(valueA, valueB)
+		}
+	case time.Time:
+		if valueB, ok := b.(time.Time); ok {
+			switch {
+			case valueA.Before(valueB):
+				return -1
+			case valueA.After(valueB):
+				return 1
+			}
+			return 0
+		}
+	}
+	// Values of different types are compared as strings
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	}
+	return 1
+}
@@ -0,0 +1,184 @@
+package memory
+
+import (
+	"database/sql"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTasks(t *testing.T, repo *TaskRepository, boardID string, names ...string) []*model.Task {
+	result := make([]*model.Task, 0, len(names))
+	for _, name := range names {
+		task := model.NewTask(name, "", false, time.Now().UTC())
+		task.BoardID = boardID
+		if err := repo.CreateTask(task); err != nil {
+			t.Fatalf("Failed to create task: %+v", err)
+		}
+		result = append(result, task)
+	}
+	return result
+}
+
+func taskNames(tasks []model.Task) []string {
+	names := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		names = append(names, task.Name)
+	}
+	return names
+}
+
+func TestStore_FindByCondition(t *testing.T) {
+	repo := NewTaskRepository(NewStore())
+	created := createTasks(t, repo, "board1", "a", "b", "c")
+	createTasks(t, repo, "board2", "d")
+	created[1].IsClosed = true
+	created[1].SetParentTaskID(created[0].ID)
+	if err := repo.UpdateTask(created[1]); err != nil {
+		t.Fatalf("Failed to update task: %+v", err)
+	}
+
+	// Blank fields of struct are ignored, as gorm does
+	tasks, err := repo.FindTasks(&model.Task{BoardID: "board1", IsClosed: false}, 0, orm.NoLimit, []string{"sort_rank"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, taskNames(tasks))
+
+	// Slice of map means any of values, and null value never matches
+	tasks, err = repo.FindTasks(map[string]interface{}{"parent_task_id": []string{created[0].ID, "none"}}, 0, orm.NoLimit, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, taskNames(tasks))
+	tasks, err = repo.FindTasks(map[string]interface{}{"is_closed": true, "board_id": "board1"}, 0, orm.NoLimit, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, taskNames(tasks))
+
+	_, err = repo.FindTasks(map[string]interface{}{"unknown": 1}, 0, orm.NoLimit, nil)
+	assert.Error(t, err)
+	_, err = repo.FindFirstTask(&model.Task{ID: "none"}, nil)
+	assert.Equal(t, orm.ErrorRecordNotFound, err)
+}
+
+func TestStore_FindWithSortOrders(t *testing.T) {
+	repo := NewTaskRepository(NewStore())
+	now := time.Now().UTC()
+	for i, name := range []string{"b", "a", "c", "a"} {
+		task := model.NewTask(name, "", false, now.Add(time.Duration(i)*time.Second))
+		if err := repo.CreateTask(task); err != nil {
+			t.Fatalf("Failed to create task: %+v", err)
+		}
+	}
+
+	tasks, err := repo.FindTasks(&model.Task{}, 0, orm.NoLimit, []string{"name, created_date desc"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "a", "b", "c"}, taskNames(tasks))
+	assert.True(t, tasks[0].CreatedDate.After(tasks[1].CreatedDate))
+
+	tasks, err = repo.FindTasks(&model.Task{}, 1, 2, []string{"created_date"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, taskNames(tasks))
+
+	_, err = repo.FindTasks(&model.Task{}, 0, orm.NoLimit, []string{"unknown"})
+	assert.Error(t, err)
+}
+
+func TestStore_RecordsAreCopied(t *testing.T) {
+	repo := NewTaskRepository(NewStore())
+	created := createTasks(t, repo, "board1", "a")[0]
+	created.Name = "changed"
+
+	find, err := repo.FindFirstTask(&model.Task{ID: created.ID}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", find.Name)
+}
+
+func TestStore_UpdateOptimisticCheck(t *testing.T) {
+	repo := NewTaskRepository(NewStore())
+	created := createTasks(t, repo, "board1", "a")[0]
+	stale := *created
+
+	created.Name = "first"
+	assert.NoError(t, repo.UpdateTask(created))
+	assert.Equal(t, 2, created.Version)
+
+	// Update based on old version fails, and its version is restored
+	stale.Name = "second"
+	assert.Equal(t, orm.ErrorOptimisticLock, repo.UpdateTask(&stale))
+	assert.Equal(t, 1, stale.Version)
+	find, err := repo.FindFirstTask(&model.Task{ID: created.ID}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", find.Name)
+
+	assert.NoError(t, repo.DeleteTask(created))
+	assert.Equal(t, orm.ErrorRecordNotFound, repo.UpdateTask(created))
+}
+
+func TestStore_UniqueConstraint(t *testing.T) {
+	repo := NewTaskRepository(NewStore())
+	created := createTasks(t, repo, "board1", "a", "b")
+
+	// Same primary key
+	duplicated := *created[0]
+	duplicated.Rank = "z"
+	assert.Error(t, repo.CreateTask(&duplicated))
+
+	// Rank is unique in the board
+	assert.Error(t, repo.MoveTask(created[1], "board1", created[0].Rank))
+	assert.NoError(t, repo.MoveTask(created[1], "board2", created[0].Rank))
+
+	users := NewUserRepository(repo.store)
+	assert.NoError(t, users.CreateUser(model.NewUser("user", "", "", model.RoleMember)))
+	assert.Error(t, users.CreateUser(model.NewUser("user", "", "", model.RoleMember)))
+}
+
+func TestTaskRepository_FindTasksByFilter(t *testing.T) {
+	repos := NewRepositories(NewStore())
+	repo := repos.Tasks.(*TaskRepository)
+	now := time.Now().UTC()
+	yesterday := now.Add(-24 * time.Hour)
+	created := createTasks(t, repo, "board1", "overdue", "closed", "future", "none")
+	for _, task := range created[:2] {
+		task.DueDate = &yesterday
+	}
+	created[1].IsClosed = true
+	tomorrow := now.Add(24 * time.Hour)
+	created[2].DueDate = &tomorrow
+	for _, task := range created[:3] {
+		if err := repo.UpdateTask(task); err != nil {
+			t.Fatalf("Failed to update task: %+v", err)
+		}
+	}
+	if err := repos.Labels.ReplaceTaskLabels(created[2].ID, []string{"label1"}); err != nil {
+		t.Fatalf("Failed to set labels: %+v", err)
+	}
+
+	tasks, err := repo.FindTasksByFilter(&model.Task{}, &repository.TaskFilter{OverdueAt: &now}, 0, orm.NoLimit, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"overdue"}, taskNames(tasks))
+
+	tasks, err = repo.FindTasksByFilter(&model.Task{}, &repository.TaskFilter{DueBefore: &now}, 0, orm.NoLimit, []string{"name"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"closed", "overdue"}, taskNames(tasks))
+
+	tasks, err = repo.FindTasksByFilter(&model.Task{}, &repository.TaskFilter{LabelIDs: []string{"label1", "label2"}, DueAfter: &now}, 0, orm.NoLimit, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"future"}, taskNames(tasks))
+}
+
+func TestTaskRepository_ClearParentTaskID(t *testing.T) {
+	repo := NewTaskRepository(NewStore())
+	created := createTasks(t, repo, "board1", "parent", "child")
+	created[1].ParentTaskID = sql.NullString{String: created[0].ID, Valid: true}
+	if err := repo.UpdateTask(created[1]); err != nil {
+		t.Fatalf("Failed to update task: %+v", err)
+	}
+
+	assert.NoError(t, repo.ClearParentTaskID(created[0].ID))
+	find, err := repo.FindFirstTask(&model.Task{ID: created[1].ID}, nil)
+	assert.NoError(t, err)
+	assert.False(t, find.ParentTaskID.Valid)
+	// Version is not changed
+	assert.Equal(t, created[1].Version, find.Version)
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+)
+
+// TaskDependencyRepository is in-memory repository of dependencies between tasks
+type TaskDependencyRepository struct {
+	store *Store
+}
+
+// NewTaskDependencyRepository returns new instance of TaskDependencyRepository
+func NewTaskDependencyRepository(store *Store) *TaskDependencyRepository {
+	return &TaskDependencyRepository{
+		store: store,
+	}
+}
+
+// FindTaskDependencies returns TaskDependencies matching with specified condition
+func (repo *TaskDependencyRepository) FindTaskDependencies(condition interface{}, sortOrders []string) (result []model.TaskDependency, err error) {
+	err = repo.store.find(&result, condition, nil, 0, orm.NoLimit, sortOrders)
+	return
+}
+
+// FindTaskDependenciesByTaskIDs returns TaskDependencies which specified tasks are blocking or blocked
+func (repo *TaskDependencyRepository) FindTaskDependenciesByTaskIDs(taskIDs []string) (result []model.TaskDependency, err error) {
+	if len(taskIDs) == 0 {
+		return []model.TaskDependency{}, nil
+	}
+	ids := make(map[string]bool, len(taskIDs))
+	for _, taskID := range taskIDs {
+		ids[taskID] = true
+	}
+	err = repo.store.find(&result, nil, func(record interface{}) bool {
+		dependency := record.(*model.TaskDependency)
+		return ids[dependency.BlockingTaskID] || ids[dependency.BlockedTaskID]
+	}, 0, orm.NoLimit, []string{"blocking_task_id, blocked_task_id"})
+	return
+}
+
+// CreateTaskDependency inserts new TaskDependency record
+func (repo *TaskDependencyRepository) CreateTaskDependency(dependency *model.TaskDependency) error {
+	return repo.store.insert(dependency)
+}
+
+// DeleteTaskDependency deletes TaskDependency record
+func (repo *TaskDependencyRepository) DeleteTaskDependency(dependency *model.TaskDependency) error {
+	if dependency.BlockingTaskID == "" || dependency.BlockedTaskID == "" {
+		return nil
+	}
+	condition := &model.TaskDependency{BlockingTaskID: dependency.BlockingTaskID, BlockedTaskID: dependency.BlockedTaskID}
+	return repo.store.delete(&model.TaskDependency{}, condition, nil)
+}
+
+// DeleteTaskDependenciesByTaskID deletes all TaskDependency records which specified task is blocking or blocked
+func (repo *TaskDependencyRepository) DeleteTaskDependenciesByTaskID(taskID string) error {
+	if taskID == "" {
+		return nil
+	}
+	return repo.store.delete(&model.TaskDependency{}, nil, func(record interface{}) bool {
+		dependency := record.(*model.TaskDependency)
+		return dependency.BlockingTaskID == taskID || dependency.BlockedTaskID == taskID
+	})
+}
@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"time"
+)
+
+// TaskLockRepository is in-memory repository of task locks
+type TaskLockRepository struct {
+	store *Store
+}
+
+// NewTaskLockRepository returns new instance of TaskLockRepository
+func NewTaskLockRepository(store *Store) *TaskLockRepository {
+	return &TaskLockRepository{
+		store: store,
+	}
+}
+
+// FindFirstTaskLock returns first TaskLock matching with specified condition
+func (repo *TaskLockRepository) FindFirstTaskLock(condition interface{}, sortOrders []string) (result model.TaskLock, err error) {
+	err = repo.store.first(&result, condition, sortOrders)
+	return
+}
+
+// FindExpiredTaskLocks returns TaskLocks expired at specified time
+func (repo *TaskLockRepository) FindExpiredTaskLocks(now time.Time) (result []model.TaskLock, err error) {
+	err = repo.store.find(&result, nil, func(record interface{}) bool {
+		return record.(*model.TaskLock).IsExpired(now)
+	}, 0, orm.NoLimit, []string{"task_id"})
+	return
+}
+
+// CreateTaskLock inserts new TaskLock record
+func (repo *TaskLockRepository) CreateTaskLock(taskLock *model.TaskLock) error {
+	return repo.store.insert(taskLock)
+}
+
+// UpdateTaskLock updates TaskLock record.
+// It returns ErrorRecordNotFound if deleted, and ErrorOptimisticLock if updated by others.
+func (repo *TaskLockRepository) UpdateTaskLock(taskLock *model.TaskLock) error {
+	oldVersion := taskLock.Version
+	taskLock.Version++
+	err := repo.store.updateWithVersion(taskLock, oldVersion)
+	if err != nil {
+		taskLock.Version = oldVersion
+	}
+	return err
+}
+
+// DeleteTaskLock deletes TaskLock record
+func (repo *TaskLockRepository) DeleteTaskLock(taskLock *model.TaskLock) error {
+	if taskLock.TaskID == "" {
+		return nil
+	}
+	return repo.store.delete(&model.TaskLock{}, &model.TaskLock{TaskID: taskLock.TaskID}, nil)
+}
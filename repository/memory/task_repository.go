@@ -0,0 +1,185 @@
+package memory
+
+import (
+	"database/sql"
+	"taskboard-api-go/common"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/repository"
+)
+
+// TaskRepository is in-memory repository of tasks
+type TaskRepository struct {
+	store *Store
+}
+
+// NewTaskRepository returns new instance of TaskRepository
+func NewTaskRepository(store *Store) *TaskRepository {
+	return &TaskRepository{
+		store: store,
+	}
+}
+
+// FindFirstTask returns first Task matching with specified condition
+func (repo *TaskRepository) FindFirstTask(condition interface{}, sortOrders []string) (result model.Task, err error) {
+	err = repo.store.first(&result, condition, sortOrders)
+	return
+}
+
+// FindTasks returns Tasks matching with specified condition
+func (repo *TaskRepository) FindTasks(condition interface{}, offset int, limit int, sortOrders []string) (result []model.Task, err error) {
+	err = repo.store.find(&result, condition, nil, offset, limit, sortOrders)
+	return
+}
+
+// FindTasksByFilter returns Tasks matching with specified condition and filter
+func (repo *TaskRepository) FindTasksByFilter(condition interface{}, filter *repository.TaskFilter, offset int, limit int, sortOrders []string) (result []model.Task, err error) {
+	if filter == nil {
+		return repo.FindTasks(condition, offset, limit, sortOrders)
+	}
+	var labeled map[string]bool
+	if len(filter.LabelIDs) > 0 {
+		var taskLabels []model.TaskLabel
+		err = repo.store.find(&taskLabels, map[string]interface{}{"label_id": filter.LabelIDs}, nil, 0, orm.NoLimit, nil)
+		if err != nil {
+			return
+		}
+		labeled = make(map[string]bool, len(taskLabels))
+		for _, taskLabel := range taskLabels {
+			labeled[taskLabel.TaskID] = true
+		}
+	}
+	err = repo.store.find(&result, condition, func(record interface{}) bool {
+		task := record.(*model.Task)
+		if labeled != nil && !labeled[task.ID] {
+			return false
+		}
+		if filter.DueAfter != nil && (task.DueDate == nil || !task.DueDate.After(*filter.DueAfter)) {
+			return false
+		}
+		if filter.DueBefore != nil && (task.DueDate == nil || !task.DueDate.Before(*filter.DueBefore)) {
+			return false
+		}
+		if filter.OverdueAt != nil && !task.IsOverdue(*filter.OverdueAt) {
+			return false
+		}
+		return true
+	}, offset, limit, sortOrders)
+	return
+}
+
+// CreateTask inserts new Task record.
+// Task without rank is placed after the last task of the board.
+func (repo *TaskRepository) CreateTask(task *model.Task) (err error) {
+	if task.Rank == "" {
+		task.Rank, err = repo.NextTaskRank(task.BoardID)
+		if err != nil {
+			return
+		}
+	}
+	return repo.store.insert(task)
+}
+
+// UpdateTask updates Task record.
+// It returns ErrorRecordNotFound if deleted, and ErrorOptimisticLock if updated by others.
+func (repo *TaskRepository) UpdateTask(task *model.Task) error {
+	oldVersion := task.Version
+	task.Version++
+	err := repo.store.updateWithVersion(task, oldVersion)
+	if err != nil {
+		task.Version = oldVersion
+	}
+	return err
+}
+
+// DeleteTask deletes Task record
+func (repo *TaskRepository) DeleteTask(task *model.Task) error {
+	if task.ID == "" {
+		return nil
+	}
+	return repo.store.delete(&model.Task{}, &model.Task{ID: task.ID}, nil)
+}
+
+// NextTaskRank returns rank to place a task after the last task of the board
+func (repo *TaskRepository) NextTaskRank(boardID string) (string, error) {
+	last, err := repo.FindTasks(&model.Task{BoardID: boardID}, 0, 1, []string{"sort_rank desc"})
+	if err != nil {
+		return "", err
+	}
+	max := ""
+	if len(last) > 0 {
+		max = last[0].Rank
+	}
+	return common.RankBetween(max, "")
+}
+
+// MoveToIceboxBoard move tasks to icebox board which matches specified board id.
+// Tasks are placed after the last task of icebox board, keeping their order.
+func (repo *TaskRepository) MoveToIceboxBoard(boardID string) error {
+	tasks, err := repo.FindTasks(&model.Task{BoardID: boardID}, 0, orm.NoLimit, []string{"sort_rank, id"})
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		rank, err := repo.NextTaskRank(model.SystemBoardIcebox.ID)
+		if err != nil {
+			return err
+		}
+		if err = repo.MoveTask(&task, model.SystemBoardIcebox.ID, rank); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClearParentTaskID clears parent of tasks whose parent is specified task
+func (repo *TaskRepository) ClearParentTaskID(parentTaskID string) error {
+	if parentTaskID == "" {
+		return nil
+	}
+	return repo.store.update(&model.Task{}, map[string]interface{}{"parent_task_id": parentTaskID}, func(record interface{}) {
+		record.(*model.Task).ParentTaskID = sql.NullString{}
+	})
+}
+
+// MoveTask changes board and rank of the task and increments its version, other fields are not updated.
+// It returns ErrorRecordNotFound if deleted, and ErrorOptimisticLock if updated by others.
+func (repo *TaskRepository) MoveTask(task *model.Task, boardID, rank string) error {
+	err := repo.store.updateColumnsWithVersion(&model.Task{ID: task.ID}, task.Version, func(record interface{}) {
+		moved := record.(*model.Task)
+		moved.BoardID = boardID
+		moved.Rank = rank
+		moved.Version++
+	})
+	if err != nil {
+		return err
+	}
+	task.BoardID = boardID
+	task.Rank = rank
+	task.Version++
+	return nil
+}
+
+// updateTaskRank changes rank of the task without changing its version
+func (repo *TaskRepository) updateTaskRank(taskID, rank string) error {
+	return repo.store.update(&model.Task{}, &model.Task{ID: taskID}, func(record interface{}) {
+		record.(*model.Task).Rank = rank
+	})
+}
+
+// RerankTasks changes ranks of tasks to specified ranks, ranks[i] is for tasks[i].
+// Tasks are moved to temporary ranks at first, so that ranks are unique in the board while updating.
+// Versions are not changed, because the order of tasks is kept.
+func (repo *TaskRepository) RerankTasks(tasks []model.Task, ranks []string) error {
+	for _, task := range tasks {
+		if err := repo.updateTaskRank(task.ID, "~"+task.ID); err != nil {
+			return err
+		}
+	}
+	for i, task := range tasks {
+		if err := repo.updateTaskRank(task.ID, ranks[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"taskboard-api-go/model"
+)
+
+// UserRepository is in-memory repository of users
+type UserRepository struct {
+	store *Store
+}
+
+// NewUserRepository returns new instance of UserRepository
+func NewUserRepository(store *Store) *UserRepository {
+	return &UserRepository{
+		store: store,
+	}
+}
+
+// FindFirstUser returns first User matching with specified condition
+func (repo *UserRepository) FindFirstUser(condition interface{}, sortOrders []string) (result model.User, err error) {
+	err = repo.store.first(&result, condition, sortOrders)
+	return
+}
+
+// FindUsers returns Users matching with specified condition
+func (repo *UserRepository) FindUsers(condition interface{}, offset int, limit int, sortOrders []string) (result []model.User, err error) {
+	err = repo.store.find(&result, condition, nil, offset, limit, sortOrders)
+	return
+}
+
+// CountUsers returns the number of Users matching specfied condition
+func (repo *UserRepository) CountUsers(condition interface{}) (int, error) {
+	return repo.store.count(&model.User{}, condition)
+}
+
+// CreateUser inserts new User record
+func (repo *UserRepository) CreateUser(user *model.User) error {
+	return repo.store.insert(user)
+}
+
+// UpdateUser updates User record.
+// It returns ErrorRecordNotFound if deleted, and ErrorOptimisticLock if updated by others.
+func (repo *UserRepository) UpdateUser(user *model.User) error {
+	oldVersion := user.Version
+	user.Version++
+	err := repo.store.updateWithVersion(user, oldVersion)
+	if err != nil {
+		user.Version = oldVersion
+	}
+	return err
+}
+
+// DeleteUser deletes User record
+func (repo *UserRepository) DeleteUser(user *model.User) error {
+	if user.ID == "" {
+		return nil
+	}
+	return repo.store.delete(&model.User{}, &model.User{ID: user.ID}, nil)
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// RealtimeEventRepository is repository of realtime event table.
+// Realtime events are immutable, and only old ones are deleted.
+type RealtimeEventRepository struct {
+	tx *gorm.DB
+}
+
+// NewRealtimeEventRepository returns new instance of RealtimeEventRepository
+func NewRealtimeEventRepository(tx *gorm.DB) *RealtimeEventRepository {
+	if tx == nil {
+		// Programing error!!
+		panic("tx must be set")
+	}
+	return &RealtimeEventRepository{
+		tx: tx,
+	}
+}
+
+// FindRealtimeEventsAfter returns realtime events whose ID is greater than specified ID in order of ID
+func (repo *RealtimeEventRepository) FindRealtimeEventsAfter(id int64, limit int) (result []model.RealtimeEvent, err error) {
+	err = repo.tx.Where("id > ?", id).Order("id").Limit(limit).Find(&result).Error
+	return
+}
+
+// MaxRealtimeEventID returns the max ID of realtime events, returns 0 if no event exists
+func (repo *RealtimeEventRepository) MaxRealtimeEventID() (max int64, err error) {
+	return orm.MaxInt(repo.tx.Model(&model.RealtimeEvent{}), "id")
+}
+
+// CreateRealtimeEvent inserts new RealtimeEvent record, and its ID is set
+func (repo *RealtimeEventRepository) CreateRealtimeEvent(event *model.RealtimeEvent) error {
+	return repo.tx.Create(event).Error
+}
+
+// DeleteRealtimeEventsBefore deletes realtime events created before specified date
+func (repo *RealtimeEventRepository) DeleteRealtimeEventsBefore(date time.Time) error {
+	return repo.tx.Where("created_date < ?", date).Delete(&model.RealtimeEvent{}).Error
+}
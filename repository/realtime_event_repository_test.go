@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"fmt"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+////
+/// Model specific functions (Only replace model name, take care names are casesencitive!!)
+//
+func newTxAndRealtimeEventRepository() (tx *gorm.DB, repo *RealtimeEventRepository) {
+	tx = orm.GetDB().Begin()
+	repo = NewRealtimeEventRepository(tx)
+	return
+}
+
+func createRealtimeEventTestData(messageFormat string, createdDate time.Time, count int) []*model.RealtimeEvent {
+	result := make([]*model.RealtimeEvent, 0, count)
+	for i := 0; i < count; i++ {
+		result = append(result, model.NewRealtimeEvent(fmt.Sprintf("%s-%03d", messageFormat, i), createdDate))
+	}
+	return result
+}
+
+func insertRealtimeEventTestData(repo *RealtimeEventRepository, events []*model.RealtimeEvent) (err error) {
+	for _, event := range events {
+		err = repo.CreateRealtimeEvent(event)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+////
+/// Repository functions' test
+//
+func TestRealtimeEventRepository_CreateRealtimeEvent(t *testing.T) {
+	tx, repo := newTxAndRealtimeEventRepository()
+	defer tx.Rollback()
+
+	events := createRealtimeEventTestData("message-create", time.Now().UTC(), 2)
+	if err := insertRealtimeEventTestData(repo, events); err != nil {
+		t.Fatalf("Failed to create realtime event: %+v", err)
+	}
+	// ID is assigned in order of creation
+	if events[0].ID == 0 || events[1].ID <= events[0].ID {
+		t.Errorf("IDs must be assigned in order, but got %d, %d", events[0].ID, events[1].ID)
+	}
+	max, err := repo.MaxRealtimeEventID()
+	if err != nil {
+		t.Fatalf("Failed to get max ID: %+v", err)
+	}
+	assert.Equal(t, events[1].ID, max)
+}
+
+func TestRealtimeEventRepository_FindRealtimeEventsAfter(t *testing.T) {
+	tx, repo := newTxAndRealtimeEventRepository()
+	defer tx.Rollback()
+
+	events := createRealtimeEventTestData("message-find", time.Now().UTC(), 5)
+	if err := insertRealtimeEventTestData(repo, events); err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	// Get 2 events after 2nd(index=1) event
+	result, err := repo.FindRealtimeEventsAfter(events[1].ID, 2)
+	if err != nil {
+		t.Fatalf("Failed to execute find: %+v", err)
+	}
+	if assert.Len(t, result, 2) {
+		assert.Equal(t, "message-find-002", result[0].Message)
+		assert.Equal(t, "message-find-003", result[1].Message)
+	}
+}
+
+func TestRealtimeEventRepository_DeleteRealtimeEventsBefore(t *testing.T) {
+	tx, repo := newTxAndRealtimeEventRepository()
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	oldEvents := createRealtimeEventTestData("message-old", now.Add(-2*time.Hour), 3)
+	newEvents := createRealtimeEventTestData("message-new", now, 2)
+	if err := insertRealtimeEventTestData(repo, append(oldEvents, newEvents...)); err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	if err := repo.DeleteRealtimeEventsBefore(now.Add(-time.Hour)); err != nil {
+		t.Fatalf("Failed to delete realtime events: %+v", err)
+	}
+	result, err := repo.FindRealtimeEventsAfter(oldEvents[0].ID-1, orm.NoLimit)
+	if err != nil {
+		t.Fatalf("Failed to execute find: %+v", err)
+	}
+	if assert.Len(t, result, 2) {
+		assert.Equal(t, newEvents[0].ID, result[0].ID)
+		assert.Equal(t, newEvents[1].ID, result[1].ID)
+	}
+}
+
+////
+/// Other fuctions' test should be written in below
+//
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"taskboard-api-go/model"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Interfaces of repositories used by services.
+// Repositories of this package implement them on database, and package memory implements them in memory for tests.
+
+// Tasks is repository of tasks
+type Tasks interface {
+	FindFirstTask(condition interface{}, sortOrders []string) (model.Task, error)
+	FindTasks(condition interface{}, offset int, limit int, sortOrders []string) ([]model.Task, error)
+	FindTasksByFilter(condition interface{}, filter *TaskFilter, offset int, limit int, sortOrders []string) ([]model.Task, error)
+	CreateTask(task *model.Task) error
+	UpdateTask(task *model.Task) error
+	DeleteTask(task *model.Task) error
+	// NextTaskRank returns rank to place a task after the last task of the board
+	NextTaskRank(boardID string) (string, error)
+	// MoveToIceboxBoard moves tasks of the board after the last task of icebox board, keeping their order.
+	// Versions of moved tasks are incremented.
+	MoveToIceboxBoard(boardID string) error
+	// ClearParentTaskID clears parent of subtasks of the task
+	ClearParentTaskID(parentTaskID string) error
+	// MoveTask changes board and rank of the task and increments its version, task is updated on success
+	MoveTask(task *model.Task, boardID, rank string) error
+	// RerankTasks changes ranks of tasks in a board without changing their versions, ranks[i] is for tasks[i]
+	RerankTasks(tasks []model.Task, ranks []string) error
+}
+
+// Boards is repository of boards
+type Boards interface {
+	FindFirstBoard(condition interface{}, sortOrders []string) (model.Board, error)
+	FindBoards(condition interface{}, offset int, limit int, sortOrders []string) ([]model.Board, error)
+	CreateBoard(board *model.Board) error
+	UpdateBoard(board *model.Board) error
+	DeleteBoard(board *model.Board) error
+	// UpdateBoardRank changes rank of the board without changing its version
+	UpdateBoardRank(boardID, rank string) error
+}
+
+// Users is repository of users
+type Users interface {
+	FindFirstUser(condition interface{}, sortOrders []string) (model.User, error)
+	FindUsers(condition interface{}, offset int, limit int, sortOrders []string) ([]model.User, error)
+	CountUsers(condition interface{}) (int, error)
+	CreateUser(user *model.User) error
+	UpdateUser(user *model.User) error
+	DeleteUser(user *model.User) error
+}
+
+// Activities is repository of activities
+type Activities interface {
+	FindActivities(condition interface{}, offset int, limit int, sortOrders []string) ([]model.Activity, error)
+	// FindChangesAfterVersion returns update and reorder activities of the entity recorded after the version, in chronological order
+	FindChangesAfterVersion(entityKind, entityID string, version int) ([]model.Activity, error)
+	CreateActivity(activity *model.Activity) error
+	CreateActivities(activities []*model.Activity) error
+}
+
+// Comments is repository of comments
+type Comments interface {
+	FindFirstComment(condition interface{}, sortOrders []string) (model.Comment, error)
+	FindComments(condition interface{}, offset int, limit int, sortOrders []string) ([]model.Comment, error)
+	CreateComment(comment *model.Comment) error
+	UpdateComment(comment *model.Comment) error
+	DeleteComment(comment *model.Comment) error
+	DeleteCommentsByTaskID(taskID string) error
+}
+
+// Labels is repository of labels and their relations to tasks
+type Labels interface {
+	FindFirstLabel(condition interface{}, sortOrders []string) (model.Label, error)
+	FindLabels(condition interface{}, offset int, limit int, sortOrders []string) ([]model.Label, error)
+	CreateLabel(label *model.Label) error
+	UpdateLabel(label *model.Label) error
+	DeleteLabel(label *model.Label) error
+	FindTaskLabels(taskIDs []string) ([]model.TaskLabel, error)
+	ReplaceTaskLabels(taskID string, labelIDs []string) error
+	DeleteTaskLabelsByTaskID(taskID string) error
+	DeleteTaskLabelsByLabelID(labelID string) error
+}
+
+// ChecklistItems is repository of checklist items
+type ChecklistItems interface {
+	FindFirstChecklistItem(condition interface{}, sortOrders []string) (model.ChecklistItem, error)
+	FindChecklistItems(condition interface{}, offset int, limit int, sortOrders []string) ([]model.ChecklistItem, error)
+	// MaxChecklistItemDispOrder returns max of disp order of checklist items in the task, 0 if there is no item
+	MaxChecklistItemDispOrder(taskID string) (int, error)
+	CreateChecklistItem(checklistItem *model.ChecklistItem) error
+	UpdateChecklistItem(checklistItem *model.ChecklistItem) error
+	DeleteChecklistItem(checklistItem *model.ChecklistItem) error
+	DeleteChecklistItemsByTaskID(taskID string) error
+}
+
+// TaskDependencies is repository of dependencies between tasks
+type TaskDependencies interface {
+	FindTaskDependencies(condition interface{}, sortOrders []string) ([]model.TaskDependency, error)
+	// FindTaskDependenciesByTaskIDs returns dependencies which the tasks are blocking or blocked
+	FindTaskDependenciesByTaskIDs(taskIDs []string) ([]model.TaskDependency, error)
+	CreateTaskDependency(dependency *model.TaskDependency) error
+	DeleteTaskDependency(dependency *model.TaskDependency) error
+	DeleteTaskDependenciesByTaskID(taskID string) error
+}
+
+// Attachments is repository of attachments
+type Attachments interface {
+	FindFirstAttachment(condition interface{}, sortOrders []string) (model.Attachment, error)
+	FindAttachments(condition interface{}, offset int, limit int, sortOrders []string) ([]model.Attachment, error)
+	CreateAttachment(attachment *model.Attachment) error
+	DeleteAttachment(attachment *model.Attachment) error
+	DeleteAttachmentsByTaskID(taskID string) error
+}
+
+// TaskLocks is repository of task locks
+type TaskLocks interface {
+	FindFirstTaskLock(condition interface{}, sortOrders []string) (model.TaskLock, error)
+	// FindExpiredTaskLocks returns locks expired at the time, ordered by task ID
+	FindExpiredTaskLocks(now time.Time) ([]model.TaskLock, error)
+	CreateTaskLock(taskLock *model.TaskLock) error
+	UpdateTaskLock(taskLock *model.TaskLock) error
+	DeleteTaskLock(taskLock *model.TaskLock) error
+}
+
+// Repositories is a set of repositories which share a transaction (or a store)
+type Repositories struct {
+	Tasks            Tasks
+	Boards           Boards
+	Users            Users
+	Activities       Activities
+	Comments         Comments
+	Labels           Labels
+	ChecklistItems   ChecklistItems
+	TaskDependencies TaskDependencies
+	Attachments      Attachments
+	TaskLocks        TaskLocks
+}
+
+// NewRepositories returns repositories on database which execute queries in specified transaction
+func NewRepositories(tx *gorm.DB) *Repositories {
+	return &Repositories{
+		Tasks:            NewTaskRepository(tx),
+		Boards:           NewBoardRepository(tx),
+		Users:            NewUserRepository(tx),
+		Activities:       NewActivityRepository(tx),
+		Comments:         NewCommentRepository(tx),
+		Labels:           NewLabelRepository(tx),
+		ChecklistItems:   NewChecklistItemRepository(tx),
+		TaskDependencies: NewTaskDependencyRepository(tx),
+		Attachments:      NewAttachmentRepository(tx),
+		TaskLocks:        NewTaskLockRepository(tx),
+	}
+}
@@ -3,51 +3,72 @@ package repository_test
 import (
 	"fmt"
 	"os"
-	"taskboard-api-go/model"
+	"taskboard-api-go/migration"
 	"taskboard-api-go/orm"
 	"testing"
 )
 
+// Test database is sqlite3 file by default.
+// Set TASKBOARD_API_TEST_DB_DIALECT and TASKBOARD_API_TEST_DB_DSN to test with other databases,
+// and build with the tag of the dialect. ex) go test -tags postgres ./repository/
+// All tables of the database are dropped, so never use a database in service!!
 func TestMain(m *testing.M) {
+	dialect := os.Getenv("TASKBOARD_API_TEST_DB_DIALECT")
+	dsn := os.Getenv("TASKBOARD_API_TEST_DB_DSN")
+	if dialect == "" {
+		dialect = orm.DialectSQLite3
+	}
+	testDbFile := ""
+	if dialect == orm.DialectSQLite3 && dsn == "" {
+		testDbFile = "./repository_test.sqlite3"
+		dsn = testDbFile
+	}
+
 	// Check test database file exits or not
-	testDbFile := "./repository_test.sqlite3"
-	_, err := os.Stat(testDbFile)
-	if !os.IsNotExist(err) {
-		// Try to remove
-		err = os.Remove(testDbFile)
-		if err != nil {
-			fmt.Printf("Failed to remove [%s]\n", testDbFile)
-		}
+	if testDbFile != "" {
 		_, err := os.Stat(testDbFile)
 		if !os.IsNotExist(err) {
-			// Still exits, fail...
-			fmt.Printf("Test db file [%s] exists, please remove it before executing test\n", testDbFile)
-			os.Exit(1)
+			// Try to remove
+			err = os.Remove(testDbFile)
+			if err != nil {
+				fmt.Printf("Failed to remove [%s]\n", testDbFile)
+			}
+			_, err := os.Stat(testDbFile)
+			if !os.IsNotExist(err) {
+				// Still exits, fail...
+				fmt.Printf("Test db file [%s] exists, please remove it before executing test\n", testDbFile)
+				os.Exit(1)
+			}
 		}
 	}
 
-	// Prepare test database file
-	err = orm.Init(testDbFile)
+	// Prepare test database
+	err := orm.Init(dialect, dsn)
 	if err != nil {
-		fmt.Printf("Failed to init test db file [%s]\n", testDbFile)
+		fmt.Printf("Failed to init test db [%s] %s: %+v\n", dialect, dsn, err)
 		os.Exit(1)
 	}
 
 	// Create tables
-	err = orm.Migrate(
-		&model.User{},
-		&model.Task{},
-		&model.Board{},
-	)
+	db := orm.GetDB()
+	if testDbFile == "" {
+		// Remove tables which previous test left
+		_, err = migration.Down(db, len(migration.Migrations))
+	}
+	if err == nil {
+		_, err = migration.Up(db, 0)
+	}
 	if err != nil {
 		fmt.Printf("Failed to create tables: %+v\n", err)
 		err := orm.GetDB().Close()
 		if err != nil {
 			fmt.Printf("Failed to close database: %+v", err)
 		}
-		err = os.Remove(testDbFile)
-		if err != nil {
-			fmt.Printf("Failed to remove [%s] err:%+v\n", testDbFile, err)
+		if testDbFile != "" {
+			err = os.Remove(testDbFile)
+			if err != nil {
+				fmt.Printf("Failed to remove [%s] err:%+v\n", testDbFile, err)
+			}
 		}
 		os.Exit(1)
 	}
@@ -60,8 +81,8 @@ func TestMain(m *testing.M) {
 		fmt.Printf("Failed to close database: %+v\n", err)
 	}
 	if ret != 0 {
-		fmt.Printf("Test failed, the database file [%s] is kept for investigation\n", testDbFile)
-	} else {
+		fmt.Printf("Test failed, the database [%s] %s is kept for investigation\n", dialect, dsn)
+	} else if testDbFile != "" {
 		err = os.Remove(testDbFile)
 		if err != nil {
 			fmt.Printf("Failed to remove [%s]\n", testDbFile)
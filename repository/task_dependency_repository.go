@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"taskboard-api-go/model"
+
+	"github.com/jinzhu/gorm"
+)
+
+// TaskDependencyRepository is repository of task dependency table.
+// Dependencies have no attributes to change, so update is not provided.
+type TaskDependencyRepository struct {
+	tx *gorm.DB
+}
+
+// NewTaskDependencyRepository returns new instance of TaskDependencyRepository
+func NewTaskDependencyRepository(tx *gorm.DB) *TaskDependencyRepository {
+	if tx == nil {
+		// Programing error!!
+		panic("tx must be set")
+	}
+	return &TaskDependencyRepository{
+		tx: tx,
+	}
+}
+
+// FindTaskDependencies returns TaskDependencies matching with specified condition
+func (repo *TaskDependencyRepository) FindTaskDependencies(condition interface{}, sortOrders []string) (result []model.TaskDependency, err error) {
+	query := repo.tx.Where(condition)
+	if sortOrders == nil {
+		sortOrders = []string{}
+	}
+	for _, sortOrder := range sortOrders {
+		query = query.Order(sortOrder)
+	}
+	err = query.Find(&result).Error
+	return
+}
+
+// FindTaskDependenciesByTaskIDs returns TaskDependencies which specified tasks are blocking or blocked
+func (repo *TaskDependencyRepository) FindTaskDependenciesByTaskIDs(taskIDs []string) (result []model.TaskDependency, err error) {
+	if len(taskIDs) == 0 {
+		return []model.TaskDependency{}, nil
+	}
+	err = repo.tx.Where("blocking_task_id in (?) or blocked_task_id in (?)", taskIDs, taskIDs).
+		Order("blocking_task_id, blocked_task_id").Find(&result).Error
+	return
+}
+
+// CreateTaskDependency inserts new TaskDependency record
+func (repo *TaskDependencyRepository) CreateTaskDependency(dependency *model.TaskDependency) error {
+	return repo.tx.Create(dependency).Error
+}
+
+// DeleteTaskDependency deletes TaskDependency record
+func (repo *TaskDependencyRepository) DeleteTaskDependency(dependency *model.TaskDependency) error {
+	if dependency.BlockingTaskID == "" || dependency.BlockedTaskID == "" {
+		return nil // To avoid deleting all due to gorm warning, return here.
+	}
+	return repo.tx.Where("blocking_task_id = ? and blocked_task_id = ?", dependency.BlockingTaskID, dependency.BlockedTaskID).
+		Delete(&model.TaskDependency{}).Error
+}
+
+// DeleteTaskDependenciesByTaskID deletes all TaskDependency records which specified task is blocking or blocked
+func (repo *TaskDependencyRepository) DeleteTaskDependenciesByTaskID(taskID string) error {
+	if taskID == "" {
+		return nil // To avoid deleting all due to gorm warning, return here.
+	}
+	return repo.tx.Where("blocking_task_id = ? or blocked_task_id = ?", taskID, taskID).
+		Delete(&model.TaskDependency{}).Error
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+////
+/// Model specific functions (Only replace model name, take care names are casesencitive!!)
+//
+func newTxAndTaskDependencyRepository() (tx *gorm.DB, repo *TaskDependencyRepository) {
+	tx = orm.GetDB().Begin()
+	repo = NewTaskDependencyRepository(tx)
+	return
+}
+
+// createTaskDependencyTestData creates dependencies from pairs of (blocking, blocked)
+func createTaskDependencyTestData(tx *gorm.DB, pairs [][2]string) []*model.TaskDependency {
+	result := make([]*model.TaskDependency, 0, len(pairs))
+	for _, pair := range pairs {
+		result = append(result, model.NewTaskDependency(pair[0], pair[1], time.Now().UTC()))
+	}
+	return result
+}
+
+func insertTaskDependencyTestData(tx *gorm.DB, dependencies []*model.TaskDependency) (err error) {
+	for _, dependency := range dependencies {
+		err = tx.Create(dependency).Error
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func taskDependencyPairs(dependencies []model.TaskDependency) [][2]string {
+	result := make([][2]string, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		result = append(result, [2]string{dependency.BlockingTaskID, dependency.BlockedTaskID})
+	}
+	return result
+}
+
+////
+/// Common repository functions' test
+//
+func TestTaskDependencyRepository_FindTaskDependencies(t *testing.T) {
+	tx, repo := newTxAndTaskDependencyRepository()
+	defer tx.Rollback()
+
+	insertDependencies := createTaskDependencyTestData(tx, [][2]string{
+		{"taskID-dep-a", "taskID-dep-b"},
+		{"taskID-dep-a", "taskID-dep-c"},
+		{"taskID-dep-b", "taskID-dep-c"},
+	})
+	err := insertTaskDependencyTestData(tx, insertDependencies)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	dependencies, err := repo.FindTaskDependencies(&model.TaskDependency{BlockingTaskID: "taskID-dep-a"}, []string{"blocked_task_id"})
+	if err != nil {
+		t.Fatalf("Failed to execute find: %+v", err)
+	}
+	expected := [][2]string{
+		{"taskID-dep-a", "taskID-dep-b"},
+		{"taskID-dep-a", "taskID-dep-c"},
+	}
+	assert.Equal(t, expected, taskDependencyPairs(dependencies))
+}
+
+func TestTaskDependencyRepository_CreateTaskDependency(t *testing.T) {
+	tx, repo := newTxAndTaskDependencyRepository()
+	defer tx.Rollback()
+
+	created := createTaskDependencyTestData(tx, [][2]string{{"taskID-dep-a", "taskID-dep-b"}})[0]
+	if err := repo.CreateTaskDependency(created); err != nil {
+		t.Fatalf("Failed to create task dependency: %+v", err)
+	}
+	// Same dependency can not be created twice
+	if err := repo.CreateTaskDependency(created); !assert.Error(t, err) {
+		t.Errorf("Duplicated dependency must not be created")
+	}
+}
+
+func TestTaskDependencyRepository_DeleteTaskDependency(t *testing.T) {
+	tx, repo := newTxAndTaskDependencyRepository()
+	defer tx.Rollback()
+
+	insertDependencies := createTaskDependencyTestData(tx, [][2]string{
+		{"taskID-dep-a", "taskID-dep-b"},
+		{"taskID-dep-a", "taskID-dep-c"},
+	})
+	err := insertTaskDependencyTestData(tx, insertDependencies)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+	t.Run("Record will not be deleted if ID is empty", func(t *testing.T) {
+		if err := repo.DeleteTaskDependency(&model.TaskDependency{BlockingTaskID: "taskID-dep-a"}); err != nil {
+			t.Errorf("Failed to delete: %+v", err)
+		}
+		dependencies, err := repo.FindTaskDependencies(&model.TaskDependency{BlockingTaskID: "taskID-dep-a"}, []string{})
+		if err != nil {
+			t.Fatalf("Failed to find: %+v", err)
+		}
+		assert.Len(t, dependencies, 2)
+	})
+
+	t.Run("Record will be deleted if ID is set", func(t *testing.T) {
+		if err := repo.DeleteTaskDependency(insertDependencies[0]); err != nil {
+			t.Errorf("Failed to delete: %+v", err)
+		}
+		dependencies, err := repo.FindTaskDependencies(&model.TaskDependency{BlockingTaskID: "taskID-dep-a"}, []string{})
+		if err != nil {
+			t.Fatalf("Failed to find: %+v", err)
+		}
+		assert.Equal(t, [][2]string{{"taskID-dep-a", "taskID-dep-c"}}, taskDependencyPairs(dependencies))
+	})
+}
+
+////
+/// Other fuctions' test should be written in below
+//
+func TestTaskDependencyRepository_FindTaskDependenciesByTaskIDs(t *testing.T) {
+	tx, repo := newTxAndTaskDependencyRepository()
+	defer tx.Rollback()
+
+	insertDependencies := createTaskDependencyTestData(tx, [][2]string{
+		{"taskID-dep-a", "taskID-dep-b"},
+		{"taskID-dep-c", "taskID-dep-a"},
+		{"taskID-dep-c", "taskID-dep-d"},
+	})
+	err := insertTaskDependencyTestData(tx, insertDependencies)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	dependencies, err := repo.FindTaskDependenciesByTaskIDs([]string{"taskID-dep-a"})
+	if err != nil {
+		t.Fatalf("Failed to find: %+v", err)
+	}
+	expected := [][2]string{
+		{"taskID-dep-a", "taskID-dep-b"},
+		{"taskID-dep-c", "taskID-dep-a"},
+	}
+	assert.Equal(t, expected, taskDependencyPairs(dependencies))
+}
+
+func TestTaskDependencyRepository_DeleteTaskDependenciesByTaskID(t *testing.T) {
+	tx, repo := newTxAndTaskDependencyRepository()
+	defer tx.Rollback()
+
+	insertDependencies := createTaskDependencyTestData(tx, [][2]string{
+		{"taskID-dep-a", "taskID-dep-b"},
+		{"taskID-dep-c", "taskID-dep-a"},
+		{"taskID-dep-c", "taskID-dep-d"},
+	})
+	err := insertTaskDependencyTestData(tx, insertDependencies)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	if err := repo.DeleteTaskDependenciesByTaskID("taskID-dep-a"); err != nil {
+		t.Fatalf("Failed to delete: %+v", err)
+	}
+	dependencies, err := repo.FindTaskDependenciesByTaskIDs([]string{"taskID-dep-a", "taskID-dep-c"})
+	if err != nil {
+		t.Fatalf("Failed to find: %+v", err)
+	}
+	assert.Equal(t, [][2]string{{"taskID-dep-c", "taskID-dep-d"}}, taskDependencyPairs(dependencies))
+}
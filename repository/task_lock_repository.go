@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// TaskLockRepository is repository of task lock table
+type TaskLockRepository struct {
+	tx *gorm.DB
+}
+
+// NewTaskLockRepository returns new instance of TaskLockRepository
+func NewTaskLockRepository(tx *gorm.DB) *TaskLockRepository {
+	if tx == nil {
+		// Programing error!!
+		panic("tx must be set")
+	}
+	return &TaskLockRepository{
+		tx: tx,
+	}
+}
+
+// FindFirstTaskLock returns first TaskLock matching with specified condition
+func (repo *TaskLockRepository) FindFirstTaskLock(condition interface{}, sortOrders []string) (result model.TaskLock, err error) {
+	query := repo.tx.Where(condition)
+	if sortOrders == nil {
+		sortOrders = []string{}
+	}
+
+	for _, sortOrder := range sortOrders {
+		query = query.Order(sortOrder)
+	}
+	err = query.First(&result).Error
+	return
+}
+
+// FindTaskLocks returns TaskLocks matching with specified condition
+func (repo *TaskLockRepository) FindTaskLocks(condition interface{}, offset int, limit int, sortOrders []string) (result []model.TaskLock, err error) {
+	query := repo.tx.Where(condition)
+	if offset >= 0 {
+		query = query.Offset(offset)
+	}
+	if limit >= 0 {
+		query = query.Limit(limit)
+	}
+
+	if sortOrders == nil {
+		sortOrders = []string{}
+	}
+	for _, taskLock := range sortOrders {
+		query = query.Order(taskLock)
+	}
+
+	err = query.Find(&result).Error
+	return
+}
+
+// CountTaskLocks returns the number of TaskLocks matching specfied condition
+func (repo *TaskLockRepository) CountTaskLocks(condition interface{}) (count int, err error) {
+	var taskLocks []model.TaskLock
+	err = repo.tx.Where(condition).Find(&taskLocks).Count(&count).Error
+	return
+}
+
+// CreateTaskLock inserts new TaskLock record
+func (repo *TaskLockRepository) CreateTaskLock(taskLock *model.TaskLock) error {
+	return repo.CreateTaskLocks([]*model.TaskLock{taskLock})
+}
+
+// UpdateTaskLock updates TaskLock record
+func (repo *TaskLockRepository) UpdateTaskLock(taskLock *model.TaskLock) error {
+	return repo.UpdateTaskLocks([]*model.TaskLock{taskLock})
+}
+
+// DeleteTaskLock deletes TaskLock record
+func (repo *TaskLockRepository) DeleteTaskLock(taskLock *model.TaskLock) error {
+	return repo.DeleteTaskLocks([]*model.TaskLock{taskLock})
+}
+
+// CreateTaskLocks inserts new TaskLock records.
+func (repo *TaskLockRepository) CreateTaskLocks(taskLocks []*model.TaskLock) (err error) {
+	for _, taskLock := range taskLocks {
+		err = repo.tx.Create(taskLock).Error
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// UpdateTaskLocks updates taskLock records
+func (repo *TaskLockRepository) UpdateTaskLocks(taskLocks []*model.TaskLock) (err error) {
+	for _, taskLock := range taskLocks {
+		oldVersion := taskLock.Version
+		taskLock.Version++
+		// ErrorRecordNotFound if deleted, ErrorOptimisticLock if updated by others
+		err = orm.UpdateWithVersion(repo.tx, taskLock, oldVersion)
+		if err != nil {
+			taskLock.Version = oldVersion
+			return
+		}
+	}
+	return
+}
+
+// DeleteTaskLocks deletes TaskLock records
+func (repo *TaskLockRepository) DeleteTaskLocks(taskLocks []*model.TaskLock) (err error) {
+	for _, taskLock := range taskLocks {
+		if taskLock.TaskID == "" {
+			continue // To avoid deleting all due to gorm warning, continue here.
+		}
+		err = repo.tx.Delete(taskLock).Error
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// FindExpiredTaskLocks returns TaskLocks expired at specified time
+func (repo *TaskLockRepository) FindExpiredTaskLocks(now time.Time) (result []model.TaskLock, err error) {
+	err = repo.tx.Where("expires_date <= ?", now).Order("task_id").Find(&result).Error
+	return
+}
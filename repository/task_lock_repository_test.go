@@ -0,0 +1,376 @@
+package repository
+
+import (
+	"fmt"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+////
+/// Model specific functions (Only replace model name, take care names are casesencitive!!)
+//
+func newTxAndTaskLockRepository() (tx *gorm.DB, repo *TaskLockRepository) {
+	tx = orm.GetDB().Begin()
+	repo = NewTaskLockRepository(tx)
+	return
+}
+
+func createTaskLockTestData(tx *gorm.DB, idFormat string, findIdentify string, count int) []*model.TaskLock {
+	result := make([]*model.TaskLock, 0, count)
+	for i := 0; i < count; i++ {
+		taskLock := model.NewTaskLock(
+			fmt.Sprintf("%s-%03d", idFormat, i),
+			findIdentify,
+			time.Now().UTC(),
+		)
+		result = append(result, taskLock)
+	}
+	return result
+}
+
+func insertTaskLockTestData(tx *gorm.DB, taskLocks []*model.TaskLock) (err error) {
+	for _, taskLock := range taskLocks {
+		err = tx.Create(taskLock).Error
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+////
+/// Common repository functions' test
+//
+func TestTaskLockRepository_FindFirstTaskLock(t *testing.T) {
+	tx, repo := newTxAndTaskLockRepository()
+	defer tx.Rollback()
+
+	firstTaskLocks := createTaskLockTestData(tx, "taskLockTaskID-find", "findUserID", 5)
+	secondTaskLocks := createTaskLockTestData(tx, "taskLockTaskID-not-find", "notFindUserID", 4)
+	insertTaskLocks := append(firstTaskLocks, secondTaskLocks...)
+	err := insertTaskLockTestData(tx, insertTaskLocks)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+	// Sort by task id
+	taskLock, err := repo.FindFirstTaskLock(&model.TaskLock{UserID: "findUserID"}, []string{"task_id"})
+	if err != nil {
+		t.Fatalf("Failed to execute find first: %+v", err)
+	}
+	expected := "taskLockTaskID-find-000"
+	if taskLock.TaskID != expected {
+		t.Errorf("expected taskLock ID is %s, but got %s", expected, taskLock.TaskID)
+	}
+}
+
+func TestTaskLockRepository_FindTaskLocks(t *testing.T) {
+	tx, repo := newTxAndTaskLockRepository()
+	defer tx.Rollback()
+
+	firstTaskLocks := createTaskLockTestData(tx, "taskLockTaskID-find", "findUserID", 5)
+	secondTaskLocks := createTaskLockTestData(tx, "taskLockTaskID-not-find", "notFindUserID", 4)
+	insertTaskLocks := append(firstTaskLocks, secondTaskLocks...)
+	err := insertTaskLockTestData(tx, insertTaskLocks)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	// Get 3 data from 2nd(index=1) position
+	const (
+		offset = 1
+		limit  = 3
+	)
+	taskLocks, err := repo.FindTaskLocks(&model.TaskLock{UserID: "findUserID"}, offset, limit, []string{"task_id"})
+	if err != nil {
+		t.Fatalf("Failed to execute find: %+v", err)
+	}
+
+	// Length of data must be limit
+	if len(taskLocks) != limit {
+		t.Errorf("Expected result size = %d, but got %d", limit, len(taskLocks))
+		return
+	}
+	// Head must be 001
+	head := taskLocks[0]
+	headExpected := "taskLockTaskID-find-001"
+	if head.TaskID != headExpected {
+		t.Errorf("head ID must be %s, but got %s", headExpected, head.TaskID)
+	}
+	// Tail must be 003
+	tail := taskLocks[len(taskLocks)-1]
+	tailExpected := "taskLockTaskID-find-003"
+	if tail.TaskID != tailExpected {
+		t.Errorf("tail ID must be %s, but got %s", tailExpected, tail.TaskID)
+	}
+}
+
+func TestTaskLockRepository_CountTaskLocks(t *testing.T) {
+	tx, repo := newTxAndTaskLockRepository()
+	defer tx.Rollback()
+
+	expected := 5
+	firstTaskLocks := createTaskLockTestData(tx, "taskLockTaskID-find", "findUserID", 5)
+	secondTaskLocks := createTaskLockTestData(tx, "taskLockTaskID-not-find", "notFindUserID", 4)
+	insertTaskLocks := append(firstTaskLocks, secondTaskLocks...)
+	err := insertTaskLockTestData(tx, insertTaskLocks)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	count, err := repo.CountTaskLocks(&model.TaskLock{UserID: "findUserID"})
+	if err != nil {
+		t.Fatalf("failed to count TaskLock: %+v", err)
+	}
+	if expected != count {
+		t.Errorf("expected %d records, but got %d record", expected, count)
+	}
+}
+
+func TestTaskLockRepository_CreateTaskLock(t *testing.T) {
+	tx, repo := newTxAndTaskLockRepository()
+	defer tx.Rollback()
+
+	// Create 1 record
+	insertTaskLocks := createTaskLockTestData(tx, "taskLockTaskID-create", "createUserID", 1)
+	created := insertTaskLocks[0]
+	if err := repo.CreateTaskLock(created); err != nil {
+		t.Fatalf("Failed to create taskLock: %+v", err)
+	}
+
+	// Find by ID
+	var find = model.TaskLock{}
+	if err := tx.Where(&model.TaskLock{TaskID: created.TaskID}).First(&find).Error; err != nil {
+		t.Fatalf("Failed to find taskLock: %+v", err)
+	}
+	// Verify created equals find
+	if !assert.Equal(t, find, *created) {
+		t.Errorf("expected: %+v, but got %+v", created.TaskID, find.TaskID)
+	}
+}
+
+func TestTaskLockRepository_CreateTaskLockDuplicated(t *testing.T) {
+	tx, repo := newTxAndTaskLockRepository()
+	defer tx.Rollback()
+
+	created := createTaskLockTestData(tx, "taskLockTaskID-duplicated", "createUserID", 1)[0]
+	if err := repo.CreateTaskLock(created); err != nil {
+		t.Fatalf("Failed to create taskLock: %+v", err)
+	}
+	// The task is locked by another user concurrently
+	duplicated := model.NewTaskLock(created.TaskID, "anotherUserID", time.Now().UTC())
+	err := repo.CreateTaskLock(duplicated)
+	assert.True(t, orm.IsDuplicateKeyError(err), "%+v", err)
+	assert.False(t, orm.IsRetryableError(err), "%+v", err)
+}
+
+func TestTaskLockRepository_UpdateTaskLock(t *testing.T) {
+	tx, repo := newTxAndTaskLockRepository()
+	defer tx.Rollback()
+
+	// Create 1 record
+	insertTaskLocks := createTaskLockTestData(tx, "taskLockTaskID-create", "createUserID", 1)
+	created := insertTaskLocks[0]
+	if err := repo.CreateTaskLock(created); err != nil {
+		t.Fatalf("Failed to create taskLock: %+v", err)
+	}
+
+	// Update the record
+	updated := insertTaskLocks[0]
+	updated.UserID = "updatedUserID"
+	updated.Extend(updated.ExpiresDate)
+	if err := repo.UpdateTaskLock(updated); err != nil {
+		t.Fatalf("failed to update: %+v", err)
+	}
+
+	// Find by ID
+	var find = model.TaskLock{}
+	if err := tx.Where(&model.TaskLock{TaskID: updated.TaskID}).First(&find).Error; err != nil {
+		t.Fatalf("Failed to find taskLock: %+v", err)
+	}
+
+	// Verify updated equals find
+	if !assert.Equal(t, find, *updated) {
+		t.Errorf("expected %v, but got %v", find, updated)
+	}
+}
+
+func TestTaskLockRepository_DeleteTaskLock(t *testing.T) {
+	tx, repo := newTxAndTaskLockRepository()
+	defer tx.Rollback()
+
+	// Create 1 record
+	insertTaskLocks := createTaskLockTestData(tx, "taskLockTaskID-delete", "deleteUserID", 1)
+	err := insertTaskLockTestData(tx, insertTaskLocks)
+	if err != nil {
+		t.Fatalf("Failed to create TaskLock: %+v", err)
+	}
+	deleted := insertTaskLocks[0]
+	t.Run("Record will not be deleted if ID is empty", func(t *testing.T) {
+		deletedID := deleted.TaskID
+		deleted.TaskID = "" // Clear ID
+		if err := repo.DeleteTaskLock(deleted); err != nil {
+			t.Errorf("Failed to delete: %+v", err)
+		}
+		// record must exist
+		result := model.TaskLock{}
+		if err := tx.Where(&model.TaskLock{TaskID: deletedID}).Find(&result).Error; err != nil {
+			t.Errorf("Failed to find: %+v", err)
+		}
+		result.TaskID = deletedID
+		deleted.TaskID = deletedID // Restore ID
+
+		// Verify not deleted
+		if !assert.Equal(t, *deleted, result) {
+			t.Error("Record is not same")
+		}
+	})
+
+	t.Run("Record will be deleted if ID is set", func(t *testing.T) {
+		if err := repo.DeleteTaskLock(deleted); err != nil {
+			t.Errorf("Failed to delete: %+v", err)
+		}
+
+		// Check record will not be found
+		result := model.TaskLock{}
+		err := tx.Where(&model.TaskLock{TaskID: deleted.TaskID}).First(&result).Error
+		if !orm.IsRecordNotFoundError(err) {
+			t.Errorf("Record must be deleted, but got: %+v", err)
+		}
+	})
+}
+
+// Test for CreateTaskLocks, UpdateTaskLocks, DeleteTaskLocks are ommitted,
+// because that they are called internally in each single version
+
+func TestTaskLockRepository_FindExpiredTaskLocks(t *testing.T) {
+	tx, repo := newTxAndTaskLockRepository()
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	expiredTaskLocks := createTaskLockTestData(tx, "taskLockTaskID-expired", "expiredUserID", 2)
+	for _, taskLock := range expiredTaskLocks {
+		taskLock.ExpiresDate = now.Add(-time.Second)
+	}
+	activeTaskLocks := createTaskLockTestData(tx, "taskLockTaskID-active", "activeUserID", 2)
+	err := insertTaskLockTestData(tx, append(expiredTaskLocks, activeTaskLocks...))
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %+v", err)
+	}
+
+	result, err := repo.FindExpiredTaskLocks(now)
+	if err != nil {
+		t.Fatalf("Failed to find expired task locks: %+v", err)
+	}
+	if assert.Len(t, result, 2) {
+		assert.Equal(t, "taskLockTaskID-expired-000", result[0].TaskID)
+		assert.Equal(t, "taskLockTaskID-expired-001", result[1].TaskID)
+	}
+}
+
+////
+/// Optimistic lock test (if version lock supported)
+//
+func TestTaskLockRepository_UpdateTaskLockOptimisticCheck(t *testing.T) {
+	// Transactions are serialized, so each transaction begins after the previous one is committed
+	tx1, _ := newTxAndTaskLockRepository()
+	defer tx1.Rollback()
+
+	// Create and commit 1 record in tx1
+	insertTaskLocks := createTaskLockTestData(tx1, "taskLockTaskID-optimistic", "", 1)
+	err := insertTaskLockTestData(tx1, insertTaskLocks)
+	if err != nil {
+		t.Fatalf("Failed to create taskLock: %+v", err)
+	}
+	if err := tx1.Commit().Error; err != nil {
+		t.Fatalf("Failed to commit data in tx1")
+	}
+	tx2, repo2 := newTxAndTaskLockRepository()
+	defer tx2.Rollback()
+	// Get commited data in tx2
+	data := insertTaskLocks[0]
+	find, err := repo2.FindFirstTaskLock(model.TaskLock{TaskID: data.TaskID}, []string{})
+	if err != nil {
+		deleteCommitedTaskLockData(t, data)
+	}
+	find.UserID = "UserInTx2"
+	data.UserID = "UserNotInTx3"
+	// Find updated in tx2 (Version number incremented!!)
+	err = repo2.UpdateTaskLock(&find)
+	if err != nil {
+		deleteCommitedTaskLockData(t, data)
+		t.Fatalf("Failed to update in tx2.")
+	}
+	err = tx2.Commit().Error
+	if err != nil {
+		deleteCommitedTaskLockData(t, data)
+		t.Fatalf("Failed to commit data in tx2")
+	}
+	tx3, repo3 := newTxAndTaskLockRepository()
+	defer tx3.Rollback()
+	// Check to not update due to optimistic error
+	if !assert.Equal(t, orm.ErrorOptimisticLock, repo3.UpdateTaskLock(data)) {
+		deleteCommitedTaskLockData(t, data)
+		t.Fatalf("Not failed to update, No error occurred")
+	}
+	err = tx3.Commit().Error
+	if err != nil {
+		deleteCommitedTaskLockData(t, data)
+		t.Fatalf("Failed to Commit tx3, no affected row")
+	}
+
+	tx4, _ := newTxAndTaskLockRepository()
+	defer tx4.Rollback()
+	var result = model.TaskLock{}
+	// Can be found as same as find(tx2)
+	if err := tx4.Where(model.TaskLock{TaskID: find.TaskID}).Find(&result).Error; err != nil {
+		t.Fatalf("failed to retrieve TaskLock: %+v", err)
+	}
+	tx4.Rollback()
+	deleteCommitedTaskLockData(t, data)
+	if !assert.Equal(t, find, result) {
+		t.Errorf("expected %v, but got %v", find, result)
+	}
+}
+
+func TestTaskLockRepository_UpdateTaskLockNotFound(t *testing.T) {
+	tx, repo := newTxAndTaskLockRepository()
+	defer tx.Rollback()
+
+	// Update not inserted record
+	data := createTaskLockTestData(tx, "taskLockID-notfound", "findIdentify-notfound", 1)[0]
+	version := data.Version
+	if !assert.Equal(t, orm.ErrorRecordNotFound, repo.UpdateTaskLock(data)) {
+		t.Fatalf("Not failed to update, No error occurred")
+	}
+	// Version is not changed and record is not inserted
+	assert.Equal(t, version, data.Version)
+	count := -1
+	if err := tx.Model(&model.TaskLock{}).Where("task_id = ?", data.TaskID).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count TaskLock: %+v", err)
+	}
+	assert.Equal(t, 0, count)
+}
+
+func deleteCommitedTaskLockData(t *testing.T, data *model.TaskLock) {
+	// Try to delete data in another transaction
+	tx, repo := newTxAndTaskLockRepository()
+	defer tx.Rollback()
+	err := repo.DeleteTaskLock(data)
+	if err != nil {
+		t.Log("*** Failed to delete test data, please restart test. ***")
+	} else {
+		err := tx.Commit().Error
+		if err != nil {
+			t.Log("*** Failed to delete test data, please restart test. ***")
+		}
+	}
+}
+
+////
+/// Other fuctions' test should be written in below
+//
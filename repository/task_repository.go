@@ -1,16 +1,14 @@
 package repository
 
 import (
-	"database/sql"
-	"sync"
+	"taskboard-api-go/common"
 	"taskboard-api-go/model"
 	"taskboard-api-go/orm"
+	"time"
 
 	"github.com/jinzhu/gorm"
 )
 
-var lockTask = &sync.Mutex{}
-
 // TaskRepository is repository of task table
 type TaskRepository struct {
 	tx *gorm.DB
@@ -62,6 +60,50 @@ func (repo *TaskRepository) FindTasks(condition interface{}, offset int, limit i
 	return
 }
 
+// TaskFilter presents additional conditions to find tasks which can not be expressed by model condition
+type TaskFilter struct {
+	LabelIDs  []string   // Tasks which have any of labels
+	DueAfter  *time.Time // Tasks whose due date is after the time
+	DueBefore *time.Time // Tasks whose due date is before the time
+	OverdueAt *time.Time // Tasks which are overdue at the time (not closed and not done)
+}
+
+// FindTasksByFilter returns Tasks matching with specified condition and filter
+func (repo *TaskRepository) FindTasksByFilter(condition interface{}, filter *TaskFilter, offset int, limit int, sortOrders []string) (result []model.Task, err error) {
+	query := repo.tx.Where(condition)
+	if filter != nil {
+		if len(filter.LabelIDs) > 0 {
+			query = query.Where("id in (select task_id from task_labels where label_id in (?))", filter.LabelIDs)
+		}
+		if filter.DueAfter != nil {
+			query = query.Where("due_date > ?", *filter.DueAfter)
+		}
+		if filter.DueBefore != nil {
+			query = query.Where("due_date < ?", *filter.DueBefore)
+		}
+		if filter.OverdueAt != nil {
+			query = query.Where("due_date < ? and is_closed = ? and board_id <> ?",
+				*filter.OverdueAt, false, model.SystemBoardDone.ID)
+		}
+	}
+	if offset >= 0 {
+		query = query.Offset(offset)
+	}
+	if limit >= 0 {
+		query = query.Limit(limit)
+	}
+
+	if sortOrders == nil {
+		sortOrders = []string{}
+	}
+	for _, task := range sortOrders {
+		query = query.Order(task)
+	}
+
+	err = query.Find(&result).Error
+	return
+}
+
 // CountTasks returns the number of Tasks matching specfied condition
 func (repo *TaskRepository) CountTasks(condition interface{}) (count int, err error) {
 	var tasks []model.Task
@@ -85,19 +127,15 @@ func (repo *TaskRepository) DeleteTask(task *model.Task) error {
 }
 
 // CreateTasks inserts new Task records.
+// Tasks without rank are placed after the last task of the board.
 func (repo *TaskRepository) CreateTasks(tasks []*model.Task) (err error) {
-	lockTask.Lock()
-	defer lockTask.Unlock()
-
 	for _, task := range tasks {
-		max := 0
-		if task.BoardID != "" {
-			max, err = repo.MaxTaskDispOrder(&model.Task{BoardID: task.BoardID})
+		if task.Rank == "" {
+			task.Rank, err = repo.NextTaskRank(task.BoardID)
 			if err != nil {
 				return
 			}
 		}
-		task.DispOrder = max + 1
 		err = repo.tx.Create(task).Error
 		if err != nil {
 			return
@@ -108,20 +146,13 @@ func (repo *TaskRepository) CreateTasks(tasks []*model.Task) (err error) {
 
 // UpdateTasks updates task records
 func (repo *TaskRepository) UpdateTasks(tasks []*model.Task) (err error) {
-	lockTask.Lock()
-	defer lockTask.Unlock()
-
 	for _, task := range tasks {
 		oldVersion := task.Version
 		task.Version++
-		db := repo.tx.Model(&model.Task{}).Where("version = ?", oldVersion).Save(task)
-		count := db.RowsAffected
-		err = db.Error
-		// return ErrorRecordNotFoud as optimistic lock error
-		if err == nil && count == 0 {
-			return orm.ErrorRecordNotFound
-		}
+		// ErrorRecordNotFound if deleted, ErrorOptimisticLock if updated by others
+		err = orm.UpdateWithVersion(repo.tx, task, oldVersion)
 		if err != nil {
+			task.Version = oldVersion
 			return
 		}
 	}
@@ -142,70 +173,87 @@ func (repo *TaskRepository) DeleteTasks(tasks []*model.Task) (err error) {
 	return
 }
 
-// MaxTaskDispOrder return max of disp order matching specified condition
-func (repo *TaskRepository) MaxTaskDispOrder(condition interface{}) (max int, err error) {
-	var out sql.NullInt64
-	err = repo.tx.Model(&model.Task{}).Select("max(disp_order)").
-		Where(condition).Row().Scan(&out)
+// MaxTaskRank returns max of rank of tasks matching specified condition, returns empty if no task matches
+func (repo *TaskRepository) MaxTaskRank(condition interface{}) (string, error) {
+	return orm.MaxString(repo.tx.Model(&model.Task{}).Where(condition), "sort_rank")
+}
+
+// NextTaskRank returns rank to place a task after the last task of the board
+func (repo *TaskRepository) NextTaskRank(boardID string) (string, error) {
+	max, err := repo.MaxTaskRank(&model.Task{BoardID: boardID})
 	if err != nil {
-		return
+		return "", err
 	}
-	if !out.Valid {
-		// no row selected -> returns 0
-		return
-	}
-	return int(out.Int64), nil
+	return common.RankBetween(max, "")
 }
 
-// MoveToIceboxBoard move tasks to icebox board which matches specified board id
+// MoveToIceboxBoard move tasks to icebox board which matches specified board id.
+// Tasks are placed after the last task of icebox board, keeping their order.
 func (repo *TaskRepository) MoveToIceboxBoard(boardID string) (err error) {
-	lockTask.Lock()
-	defer lockTask.Unlock()
-	max, err := repo.MaxTaskDispOrder(&model.Task{BoardID: model.SystemBoardIcebox.ID})
-	err = repo.tx.Model(&model.Task{}).Where("board_id = ?", boardID).
-		Update("disp_order", gorm.Expr("disp_order + ?", max)).Error
+	var tasks []model.Task
+	err = repo.tx.Where(&model.Task{BoardID: boardID}).Order("sort_rank, id").Find(&tasks).Error
 	if err != nil {
 		return
 	}
-	return repo.tx.Model(&model.Task{}).Where("board_id = ?", boardID).
-		Update(&model.Task{BoardID: model.SystemBoardIcebox.ID}).Error
-}
-
-// MoveTaskDispOrders changes task order position.
-func (repo *TaskRepository) MoveTaskDispOrders(
-	taskID, fromBoardID string, fromDispOrder int,
-	toBoardID string, toDispOrder int,
-) (err error) {
-	if fromBoardID == toBoardID {
-		low := fromDispOrder     // ex) 1
-		high := toDispOrder      // ex) 3
-		expr := "disp_order - 1" // a1 b2 c3 d4 => b1 c2 a3 d4
-		if fromDispOrder > toDispOrder {
-			high = fromDispOrder    // ex) 3
-			low = toDispOrder       // ex) 1
-			expr = "disp_order + 1" // a1 b2 c3 d4 => c1 a2 b3 d4
-		}
-		err = repo.tx.Model(&model.Task{}).
-			Where("board_id = ? and disp_order >= ? and disp_order <= ?", fromBoardID, low, high).
-			Update("disp_order", gorm.Expr(expr)).Error
-	} else {
-		// ex) from=3 to=2
-		// a1 b2 c3 d4 e5  => a1 b2 d3 e4
-		// x1 y2 z3        => x1 c2 y3 z4
-		// shift - 1 (remove form source board order)
-		err = repo.tx.Model(&model.Task{}).Where("board_id = ? and disp_order >= ?", fromBoardID, fromDispOrder).
-			Update("disp_order", gorm.Expr("disp_order - 1")).Error
+	for _, task := range tasks {
+		rank, err := repo.NextTaskRank(model.SystemBoardIcebox.ID)
 		if err != nil {
-			return
+			return err
 		}
-		// shift + 1 (insert to destination board order)
-		err = repo.tx.Model(&model.Task{}).Where("board_id = ? and disp_order >= ?", toBoardID, toDispOrder).
-			Update("disp_order", gorm.Expr("disp_order + 1")).Error
+		err = repo.MoveTask(&task, model.SystemBoardIcebox.ID, rank)
 		if err != nil {
-			return
+			return err
 		}
 	}
-	// move
+	return
+}
+
+// ClearParentTaskID clears parent of tasks whose parent is specified task
+func (repo *TaskRepository) ClearParentTaskID(parentTaskID string) error {
+	if parentTaskID == "" {
+		return nil // To avoid updating tasks without parent, return here.
+	}
+	return repo.tx.Model(&model.Task{}).Where("parent_task_id = ?", parentTaskID).
+		Update("parent_task_id", gorm.Expr("NULL")).Error
+}
+
+// MoveTask changes board and rank of the task and increments its version, other columns are not updated.
+// It returns ErrorRecordNotFound if deleted, and ErrorOptimisticLock if updated by others.
+func (repo *TaskRepository) MoveTask(task *model.Task, boardID, rank string) error {
+	err := orm.UpdateColumnsWithVersion(repo.tx, &model.Task{ID: task.ID}, task.Version, map[string]interface{}{
+		"board_id":  boardID,
+		"sort_rank": rank,
+		"version":   task.Version + 1,
+	})
+	if err != nil {
+		return err
+	}
+	task.BoardID = boardID
+	task.Rank = rank
+	task.Version++
+	return nil
+}
+
+// updateTaskRank changes rank of the task without changing its version
+func (repo *TaskRepository) updateTaskRank(taskID, rank string) error {
 	return repo.tx.Model(&model.Task{}).Where("id = ?", taskID).
-		Update(&model.Task{DispOrder: toDispOrder, BoardID: toBoardID}).Error
+		Update(&model.Task{Rank: rank}).Error
+}
+
+// RerankTasks changes ranks of tasks to specified ranks, ranks[i] is for tasks[i].
+// Tasks are moved to temporary ranks at first, so that ranks are unique in the board while updating.
+// Versions are not changed, because the order of tasks is kept.
+func (repo *TaskRepository) RerankTasks(tasks []model.Task, ranks []string) error {
+	for _, task := range tasks {
+		// Temporary rank is not a valid rank, which never equals to others
+		if err := repo.updateTaskRank(task.ID, "~"+task.ID); err != nil {
+			return err
+		}
+	}
+	for i, task := range tasks {
+		if err := repo.updateTaskRank(task.ID, ranks[i]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
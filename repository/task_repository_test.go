@@ -2,6 +2,8 @@ package repository
 
 import (
 	"fmt"
+	"math/rand"
+	"sync"
 	"taskboard-api-go/common"
 	"taskboard-api-go/model"
 	"taskboard-api-go/orm"
@@ -23,6 +25,7 @@ func newTxAndTaskRepository() (tx *gorm.DB, repo *TaskRepository) {
 
 func createTaskTestData(tx *gorm.DB, idFormat string, findIdentify string, count int) []*model.Task {
 	result := make([]*model.Task, 0, count)
+	ranks := common.EvenRanks(count)
 	for i := 0; i < count; i++ {
 		task := model.NewTask(
 			"name"+common.GenerateID(),
@@ -31,7 +34,7 @@ func createTaskTestData(tx *gorm.DB, idFormat string, findIdentify string, count
 			time.Now().UTC(),
 		)
 		task.ID = fmt.Sprintf("%s-%03d", idFormat, i)
-		task.DispOrder = i + 1
+		task.Rank = ranks[i]
 		task.BoardID = "fixBoardID"
 		result = append(result, task)
 	}
@@ -240,12 +243,9 @@ func TestTaskRepository_DeleteTask(t *testing.T) {
 /// Optimistic lock test (if version lock supported)
 //
 func TestTaskRepository_UpdateTaskOptimisticCheck(t *testing.T) {
+	// Transactions are serialized, so each transaction begins after the previous one is committed
 	tx1, _ := newTxAndTaskRepository()
-	tx2, repo2 := newTxAndTaskRepository()
-	tx3, repo3 := newTxAndTaskRepository()
 	defer tx1.Rollback()
-	defer tx2.Rollback()
-	defer tx3.Rollback()
 
 	// Create and commit 1 record in tx1
 	insertTasks := createTaskTestData(tx1, "taskID-optimistic", "", 1)
@@ -256,6 +256,8 @@ func TestTaskRepository_UpdateTaskOptimisticCheck(t *testing.T) {
 	if err := tx1.Commit().Error; err != nil {
 		t.Fatalf("Failed to commit data in tx1")
 	}
+	tx2, repo2 := newTxAndTaskRepository()
+	defer tx2.Rollback()
 	// Get commited data in tx2
 	data := insertTasks[0]
 	find, err := repo2.FindFirstTask(model.Task{ID: data.ID}, []string{})
@@ -275,8 +277,10 @@ func TestTaskRepository_UpdateTaskOptimisticCheck(t *testing.T) {
 		deleteCommitedTaskData(t, data)
 		t.Fatalf("Failed to commit data in tx2")
 	}
+	tx3, repo3 := newTxAndTaskRepository()
+	defer tx3.Rollback()
 	// Check to not update due to optimistic error
-	if !assert.Error(t, repo3.UpdateTask(data)) {
+	if !assert.Equal(t, orm.ErrorOptimisticLock, repo3.UpdateTask(data)) {
 		deleteCommitedTaskData(t, data)
 		t.Fatalf("Not failed to update, No error occurred")
 	}
@@ -293,12 +297,32 @@ func TestTaskRepository_UpdateTaskOptimisticCheck(t *testing.T) {
 	if err := tx4.Where(model.Task{ID: find.ID}).Find(&result).Error; err != nil {
 		t.Fatalf("failed to retrieve Task: %+v", err)
 	}
+	tx4.Rollback()
 	deleteCommitedTaskData(t, data)
 	if !assert.Equal(t, find, result) {
 		t.Errorf("expected %v, but got %v", find, result)
 	}
 }
 
+func TestTaskRepository_UpdateTaskNotFound(t *testing.T) {
+	tx, repo := newTxAndTaskRepository()
+	defer tx.Rollback()
+
+	// Update not inserted record
+	data := createTaskTestData(tx, "taskID-notfound", "findIdentify-notfound", 1)[0]
+	version := data.Version
+	if !assert.Equal(t, orm.ErrorRecordNotFound, repo.UpdateTask(data)) {
+		t.Fatalf("Not failed to update, No error occurred")
+	}
+	// Version is not changed and record is not inserted
+	assert.Equal(t, version, data.Version)
+	count := -1
+	if err := tx.Model(&model.Task{}).Where("id = ?", data.ID).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count Task: %+v", err)
+	}
+	assert.Equal(t, 0, count)
+}
+
 func deleteCommitedTaskData(t *testing.T, data *model.Task) {
 	// Try to delete data in another transaction
 	tx, repo := newTxAndTaskRepository()
@@ -317,7 +341,7 @@ func deleteCommitedTaskData(t *testing.T, data *model.Task) {
 ////
 /// Other fuctions' test should be written in below
 //
-func TestTaskRepository_MaxTaskDispOrder(t *testing.T) {
+func TestTaskRepository_MaxTaskRank(t *testing.T) {
 	tx, repo := newTxAndTaskRepository()
 	defer tx.Rollback()
 
@@ -335,14 +359,59 @@ func TestTaskRepository_MaxTaskDispOrder(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create tasks: %+v", err)
 	}
-	max, err := repo.MaxTaskDispOrder(&model.Task{BoardID: "firstBoardID"})
+	max, err := repo.MaxTaskRank(&model.Task{BoardID: "firstBoardID"})
 	if err != nil {
-		t.Fatalf("Failed to get max of dispOrder of tasks: %+v", err)
+		t.Fatalf("Failed to get max of rank of tasks: %+v", err)
 	}
 	// Verify created equals find
-	if !assert.Equal(t, 10, max) {
-		t.Errorf("expected: %d, but got %d", 10, max)
+	assert.Equal(t, firstTasks[9].Rank, max)
+
+	// Empty if the board has no task
+	max, err = repo.MaxTaskRank(&model.Task{BoardID: "emptyBoardID"})
+	if err != nil {
+		t.Fatalf("Failed to get max of rank of tasks: %+v", err)
+	}
+	assert.Equal(t, "", max)
+	next, err := repo.NextTaskRank("secondBoardID")
+	if err != nil {
+		t.Fatalf("Failed to get next rank of tasks: %+v", err)
+	}
+	assert.True(t, next > secondTasks[19].Rank, next)
+}
+
+func TestTaskRepository_MoveTask(t *testing.T) {
+	tx, repo := newTxAndTaskRepository()
+	defer tx.Rollback()
+
+	insertTasks := createTaskTestData(tx, "taskID-moveTask", "moveTaskDescription", 3)
+	err := insertTaskTestData(tx, insertTasks)
+	if err != nil {
+		t.Fatalf("Failed to create tasks: %+v", err)
+	}
+	// Move the last task between the first and the second on another board
+	moved := *insertTasks[2]
+	err = repo.MoveTask(&moved, "otherBoardID", insertTasks[0].Rank+"i")
+	if err != nil {
+		t.Fatalf("Failed to move task: %+v", err)
+	}
+	// Moving by stale version fails
+	assert.Equal(t, orm.ErrorOptimisticLock, repo.MoveTask(insertTasks[2], "staleBoardID", "z"))
+	findTasks, err := repo.FindTasks(&model.Task{Description: "moveTaskDescription"},
+		0, orm.NoLimit, []string{"id"})
+	if err != nil {
+		t.Fatalf("Failed to find tasks: %+v", err)
 	}
+	if len(findTasks) != 3 {
+		t.Fatalf("expected: %d, but got %d", 3, len(findTasks))
+	}
+	// Only the moved task is changed, and its version is incremented
+	insertTasks[2].BoardID = "otherBoardID"
+	insertTasks[2].Rank = insertTasks[0].Rank + "i"
+	insertTasks[2].Version++
+	assert.Equal(t, *insertTasks[2], moved)
+	assert.Equal(t, *insertTasks[0], findTasks[0])
+	assert.Equal(t, *insertTasks[1], findTasks[1])
+	assert.Equal(t, *insertTasks[2], findTasks[2])
 }
 
 func TestTaskRepository_MoveToIceboxBoard(t *testing.T) {
@@ -371,10 +440,255 @@ func TestTaskRepository_MoveToIceboxBoard(t *testing.T) {
 	if len(findTasks) != 3 {
 		t.Fatalf("")
 	}
-	// 0 and 2 will be changed.
+	// 0 and 2 will be changed, keeping their order.
 	insertTasks[0].BoardID = model.SystemBoardIcebox.ID
 	insertTasks[2].BoardID = model.SystemBoardIcebox.ID
+	assert.True(t, findTasks[0].Rank < findTasks[2].Rank, "%s < %s", findTasks[0].Rank, findTasks[2].Rank)
+	insertTasks[0].Rank = findTasks[0].Rank
+	insertTasks[2].Rank = findTasks[2].Rank
+	insertTasks[0].Version++
+	insertTasks[2].Version++
+	assert.Equal(t, *insertTasks[0], findTasks[0])
+	assert.Equal(t, *insertTasks[1], findTasks[1])
+	assert.Equal(t, *insertTasks[2], findTasks[2])
+}
+
+func TestTaskRepository_ClearParentTaskID(t *testing.T) {
+	tx, repo := newTxAndTaskRepository()
+	defer tx.Rollback()
+
+	insertTasks := createTaskTestData(tx, "taskID-parent", "clearParentDescription", 3)
+	insertTasks[1].SetParentTaskID(insertTasks[0].ID)
+	insertTasks[2].SetParentTaskID("otherParentID")
+	err := insertTaskTestData(tx, insertTasks)
+	if err != nil {
+		t.Fatalf("Failed to create tasks: %+v", err)
+	}
+	err = repo.ClearParentTaskID(insertTasks[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to clear parent task ID: %+v", err)
+	}
+	findTasks, err := repo.FindTasks(&model.Task{Description: "clearParentDescription"},
+		0, orm.NoLimit, []string{"id"})
+	if err != nil {
+		t.Fatalf("Failed to find tasks: %+v", err)
+	}
+	if len(findTasks) != 3 {
+		t.Fatalf("expected 3 tasks, but got %d", len(findTasks))
+	}
+	// Only 1 will be changed.
+	insertTasks[1].SetParentTaskID("")
 	assert.Equal(t, *insertTasks[0], findTasks[0])
 	assert.Equal(t, *insertTasks[1], findTasks[1])
 	assert.Equal(t, *insertTasks[2], findTasks[2])
 }
+
+func TestTaskRepository_FindTasksByFilter(t *testing.T) {
+	tx, repo := newTxAndTaskRepository()
+	defer tx.Rollback()
+
+	insertTasks := createTaskTestData(tx, "taskID-filter", "filterDescription", 4)
+	err := insertTaskTestData(tx, insertTasks)
+	if err != nil {
+		t.Fatalf("Failed to create tasks: %+v", err)
+	}
+	labelRepo := NewLabelRepository(tx)
+	if err := labelRepo.ReplaceTaskLabels(insertTasks[0].ID, []string{"labelID-bug"}); err != nil {
+		t.Fatalf("Failed to set labels: %+v", err)
+	}
+	if err := labelRepo.ReplaceTaskLabels(insertTasks[2].ID, []string{"labelID-bug", "labelID-feature"}); err != nil {
+		t.Fatalf("Failed to set labels: %+v", err)
+	}
+	if err := labelRepo.ReplaceTaskLabels(insertTasks[3].ID, []string{"labelID-feature"}); err != nil {
+		t.Fatalf("Failed to set labels: %+v", err)
+	}
+
+	t.Run("Tasks which have the label are found", func(t *testing.T) {
+		findTasks, err := repo.FindTasksByFilter(&model.Task{Description: "filterDescription"},
+			&TaskFilter{LabelIDs: []string{"labelID-bug"}}, 0, orm.NoLimit, []string{"id"})
+		if err != nil {
+			t.Fatalf("Failed to find tasks: %+v", err)
+		}
+		if assert.Len(t, findTasks, 2) {
+			assert.Equal(t, insertTasks[0].ID, findTasks[0].ID)
+			assert.Equal(t, insertTasks[2].ID, findTasks[1].ID)
+		}
+	})
+
+	t.Run("Tasks which have any of labels are found", func(t *testing.T) {
+		findTasks, err := repo.FindTasksByFilter(&model.Task{Description: "filterDescription"},
+			&TaskFilter{LabelIDs: []string{"labelID-bug", "labelID-feature"}}, 0, orm.NoLimit, []string{"id"})
+		if err != nil {
+			t.Fatalf("Failed to find tasks: %+v", err)
+		}
+		assert.Len(t, findTasks, 3)
+	})
+
+	t.Run("All tasks are found without filter", func(t *testing.T) {
+		findTasks, err := repo.FindTasksByFilter(&model.Task{Description: "filterDescription"},
+			nil, 0, orm.NoLimit, []string{"id"})
+		if err != nil {
+			t.Fatalf("Failed to find tasks: %+v", err)
+		}
+		assert.Len(t, findTasks, 4)
+	})
+}
+
+func TestTaskRepository_FindTasksByFilter_DueDate(t *testing.T) {
+	tx, repo := newTxAndTaskRepository()
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	yesterday := now.Add(-24 * time.Hour)
+	tomorrow := now.Add(24 * time.Hour)
+	insertTasks := createTaskTestData(tx, "taskID-due", "dueDescription", 5)
+	insertTasks[0].DueDate = &yesterday
+	insertTasks[1].DueDate = &tomorrow
+	insertTasks[2].DueDate = &yesterday
+	insertTasks[2].BoardID = model.SystemBoardDone.ID
+	insertTasks[3].DueDate = &yesterday
+	insertTasks[3].IsClosed = true
+	err := insertTaskTestData(tx, insertTasks)
+	if err != nil {
+		t.Fatalf("Failed to create tasks: %+v", err)
+	}
+
+	t.Run("Tasks whose due date is before specified time are found", func(t *testing.T) {
+		findTasks, err := repo.FindTasksByFilter(&model.Task{Description: "dueDescription"},
+			&TaskFilter{DueBefore: &now}, 0, orm.NoLimit, []string{"id"})
+		if err != nil {
+			t.Fatalf("Failed to find tasks: %+v", err)
+		}
+		assert.Len(t, findTasks, 3)
+	})
+
+	t.Run("Only overdue tasks are found", func(t *testing.T) {
+		findTasks, err := repo.FindTasksByFilter(&model.Task{Description: "dueDescription"},
+			&TaskFilter{OverdueAt: &now}, 0, orm.NoLimit, []string{"id"})
+		if err != nil {
+			t.Fatalf("Failed to find tasks: %+v", err)
+		}
+		if assert.Len(t, findTasks, 1) {
+			assert.Equal(t, insertTasks[0].ID, findTasks[0].ID)
+			assert.True(t, findTasks[0].IsOverdue(now))
+		}
+	})
+
+	t.Run("Tasks becoming overdue in the period are found", func(t *testing.T) {
+		twoDaysAgo := now.Add(-48 * time.Hour)
+		findTasks, err := repo.FindTasksByFilter(&model.Task{Description: "dueDescription"},
+			&TaskFilter{DueAfter: &twoDaysAgo, OverdueAt: &now}, 0, orm.NoLimit, []string{"id"})
+		if err != nil {
+			t.Fatalf("Failed to find tasks: %+v", err)
+		}
+		assert.Len(t, findTasks, 1)
+	})
+}
+
+////
+/// Concurrency test
+//
+func TestTaskRepository_ConcurrentMoves(t *testing.T) {
+	const boardID = "concurrentBoardID"
+	const workers = 8
+	const operations = 20
+
+	// Create and commit initial tasks
+	initialTasks := createTaskTestData(nil, "taskID-concurrent", "concurrentDescription", 10)
+	err := orm.Transaction(orm.GetDB(), func(tx *gorm.DB) error {
+		for _, task := range initialTasks {
+			task.BoardID = boardID
+		}
+		return insertTaskTestData(tx, initialTasks)
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tasks: %+v", err)
+	}
+	defer func() {
+		err := orm.GetDB().Where("board_id = ?", boardID).Delete(&model.Task{}).Error
+		if err != nil {
+			t.Log("*** Failed to delete test data, please restart test. ***")
+		}
+	}()
+
+	// Each worker creates tasks and moves tasks to random positions concurrently
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*operations)
+	created := make(chan string, workers*operations)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			random := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < operations; i++ {
+				if i%4 == 0 {
+					task := model.NewTask("concurrent", "concurrentDescription", false, time.Now().UTC())
+					task.BoardID = boardID
+					err := orm.Transaction(orm.GetDB(), func(tx *gorm.DB) error {
+						task.Rank = ""
+						return NewTaskRepository(tx).CreateTask(task)
+					})
+					if err != nil {
+						errs <- err
+						continue
+					}
+					created <- task.ID
+					continue
+				}
+				err := orm.Transaction(orm.GetDB(), func(tx *gorm.DB) error {
+					return moveRandomTask(NewTaskRepository(tx), random, boardID)
+				})
+				if err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	close(created)
+	for err := range errs {
+		t.Errorf("Failed to operate tasks concurrently: %+v", err)
+	}
+
+	// All tasks remain, and their ranks are valid and unique
+	tasks, err := NewTaskRepository(orm.GetDB()).FindTasks(&model.Task{BoardID: boardID}, 0, orm.NoLimit, []string{"sort_rank"})
+	if err != nil {
+		t.Fatalf("Failed to find tasks: %+v", err)
+	}
+	assert.Len(t, tasks, len(initialTasks)+len(created))
+	for i, task := range tasks {
+		assert.True(t, common.IsValidRank(task.Rank), "invalid rank:%s", task.Rank)
+		if i > 0 {
+			assert.True(t, tasks[i-1].Rank < task.Rank, "ranks are not unique: %s %s", tasks[i-1].Rank, task.Rank)
+		}
+	}
+}
+
+// moveRandomTask moves a random task of the board to a random position, in the way same as a client
+func moveRandomTask(repo *TaskRepository, random *rand.Rand, boardID string) error {
+	tasks, err := repo.FindTasks(&model.Task{BoardID: boardID}, 0, orm.NoLimit, []string{"sort_rank"})
+	if err != nil {
+		return err
+	}
+	moving := tasks[random.Intn(len(tasks))]
+	others := make([]model.Task, 0, len(tasks)-1)
+	for _, task := range tasks {
+		if task.ID != moving.ID {
+			others = append(others, task)
+		}
+	}
+	position := random.Intn(len(others) + 1)
+	prevRank, nextRank := "", ""
+	if position > 0 {
+		prevRank = others[position-1].Rank
+	}
+	if position < len(others) {
+		nextRank = others[position].Rank
+	}
+	rank, err := common.RankBetween(prevRank, nextRank)
+	if err != nil {
+		return err
+	}
+	return repo.MoveTask(&moving, boardID, rank)
+}
@@ -1,15 +1,12 @@
 package repository
 
 import (
-	"sync"
 	"taskboard-api-go/model"
 	"taskboard-api-go/orm"
 
 	"github.com/jinzhu/gorm"
 )
 
-var lockUser = &sync.Mutex{}
-
 // UserRepository is repository of user table
 type UserRepository struct {
 	tx *gorm.DB
@@ -96,20 +93,13 @@ func (repo *UserRepository) CreateUsers(users []*model.User) (err error) {
 
 // UpdateUsers updates user records
 func (repo *UserRepository) UpdateUsers(users []*model.User) (err error) {
-	lockUser.Lock()
-	defer lockUser.Unlock()
-
 	for _, user := range users {
 		oldVersion := user.Version
 		user.Version++
-		db := repo.tx.Model(&model.User{}).Where("version = ?", oldVersion).Save(user)
-		count := db.RowsAffected
-		err = db.Error
-		// return ErrorRecordNotFoud as optimistic lock error
-		if err == nil && count == 0 {
-			return orm.ErrorRecordNotFound
-		}
+		// ErrorRecordNotFound if deleted, ErrorOptimisticLock if updated by others
+		err = orm.UpdateWithVersion(repo.tx, user, oldVersion)
 		if err != nil {
+			user.Version = oldVersion
 			return
 		}
 	}
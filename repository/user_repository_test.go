@@ -27,6 +27,7 @@ func createUserTestData(tx *gorm.DB, idFormat string, findIdentify string, count
 			"name"+common.GenerateID(),
 			fmt.Sprintf("password-%03d", i),
 			findIdentify,
+			model.RoleMember,
 		)
 		user.ID = fmt.Sprintf("%s-%03d", idFormat, i)
 		result = append(result, user)
@@ -236,12 +237,9 @@ func TestUserRepository_DeleteUser(t *testing.T) {
 /// Optimistic lock test (if version lock supported)
 //
 func TestUserRepository_UpdateUserOptimisticCheck(t *testing.T) {
+	// Transactions are serialized, so each transaction begins after the previous one is committed
 	tx1, _ := newTxAndUserRepository()
-	tx2, repo2 := newTxAndUserRepository()
-	tx3, repo3 := newTxAndUserRepository()
 	defer tx1.Rollback()
-	defer tx2.Rollback()
-	defer tx3.Rollback()
 
 	// Create and commit 1 record in tx1
 	insertUsers := createUserTestData(tx1, "userID-optimistic", "", 1)
@@ -252,6 +250,8 @@ func TestUserRepository_UpdateUserOptimisticCheck(t *testing.T) {
 	if err := tx1.Commit().Error; err != nil {
 		t.Fatalf("Failed to commit data in tx1")
 	}
+	tx2, repo2 := newTxAndUserRepository()
+	defer tx2.Rollback()
 	// Get commited data in tx2
 	data := insertUsers[0]
 	find, err := repo2.FindFirstUser(model.User{ID: data.ID}, []string{})
@@ -271,8 +271,10 @@ func TestUserRepository_UpdateUserOptimisticCheck(t *testing.T) {
 		deleteCommitedUserData(t, data)
 		t.Fatalf("Failed to commit data in tx2")
 	}
+	tx3, repo3 := newTxAndUserRepository()
+	defer tx3.Rollback()
 	// Check to not update due to optimistic error
-	if !assert.Error(t, repo3.UpdateUser(data)) {
+	if !assert.Equal(t, orm.ErrorOptimisticLock, repo3.UpdateUser(data)) {
 		deleteCommitedUserData(t, data)
 		t.Fatalf("Not failed to update, No error occurred")
 	}
@@ -289,12 +291,32 @@ func TestUserRepository_UpdateUserOptimisticCheck(t *testing.T) {
 	if err := tx4.Where(model.User{ID: find.ID}).Find(&result).Error; err != nil {
 		t.Fatalf("failed to retrieve User: %+v", err)
 	}
+	tx4.Rollback()
 	deleteCommitedUserData(t, data)
 	if !assert.Equal(t, find, result) {
 		t.Errorf("expected %v, but got %v", find, result)
 	}
 }
 
+func TestUserRepository_UpdateUserNotFound(t *testing.T) {
+	tx, repo := newTxAndUserRepository()
+	defer tx.Rollback()
+
+	// Update not inserted record
+	data := createUserTestData(tx, "userID-notfound", "findIdentify-notfound", 1)[0]
+	version := data.Version
+	if !assert.Equal(t, orm.ErrorRecordNotFound, repo.UpdateUser(data)) {
+		t.Fatalf("Not failed to update, No error occurred")
+	}
+	// Version is not changed and record is not inserted
+	assert.Equal(t, version, data.Version)
+	count := -1
+	if err := tx.Model(&model.User{}).Where("id = ?", data.ID).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count User: %+v", err)
+	}
+	assert.Equal(t, 0, count)
+}
+
 func deleteCommitedUserData(t *testing.T, data *model.User) {
 	// Try to delete data in another transaction
 	tx, repo := newTxAndUserRepository()
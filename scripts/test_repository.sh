@@ -0,0 +1,69 @@
+#!/bin/sh
+# Runs repository tests against all supported databases.
+# PostgreSQL and MySQL are started as docker containers, and removed after the test.
+# usage: scripts/test_repository.sh [sqlite3|postgres|mysql]...
+cd "$(dirname "$0")/.." || exit 1
+
+PASSWORD=taskboard
+POSTGRES_PORT=${TASKBOARD_TEST_POSTGRES_PORT:-15432}
+MYSQL_PORT=${TASKBOARD_TEST_MYSQL_PORT:-13306}
+
+# wait_for <container> <command...> waits until the command succeeds in the container
+wait_for() {
+	container=$1
+	shift
+	for i in $(seq 60); do
+		if docker exec "$container" "$@" >/dev/null 2>&1; then
+			return 0
+		fi
+		sleep 1
+	done
+	echo "Timed out waiting for $container"
+	return 1
+}
+
+test_sqlite3() {
+	go test ./repository/
+}
+
+test_postgres() {
+	container=taskboard-test-postgres
+	docker run -d --rm --name $container -p "$POSTGRES_PORT:5432" \
+		-e POSTGRES_USER=taskboard -e POSTGRES_PASSWORD=$PASSWORD -e POSTGRES_DB=taskboard_test \
+		postgres:11 >/dev/null || return 1
+	wait_for $container pg_isready -U taskboard -d taskboard_test &&
+		TASKBOARD_API_TEST_DB_DIALECT=postgres \
+		TASKBOARD_API_TEST_DB_DSN="host=localhost port=$POSTGRES_PORT user=taskboard dbname=taskboard_test password=$PASSWORD sslmode=disable" \
+		go test -tags postgres ./repository/
+	ret=$?
+	docker stop $container >/dev/null
+	return $ret
+}
+
+test_mysql() {
+	container=taskboard-test-mysql
+	docker run -d --rm --name $container -p "$MYSQL_PORT:3306" \
+		-e MYSQL_USER=taskboard -e MYSQL_PASSWORD=$PASSWORD -e MYSQL_ROOT_PASSWORD=$PASSWORD -e MYSQL_DATABASE=taskboard_test \
+		mysql:5.7 >/dev/null || return 1
+	wait_for $container mysql -utaskboard -p$PASSWORD -h127.0.0.1 -e "select 1" taskboard_test &&
+		TASKBOARD_API_TEST_DB_DIALECT=mysql \
+		TASKBOARD_API_TEST_DB_DSN="taskboard:$PASSWORD@tcp(localhost:$MYSQL_PORT)/taskboard_test?charset=utf8mb4&parseTime=true" \
+		go test -tags mysql ./repository/
+	ret=$?
+	docker stop $container >/dev/null
+	return $ret
+}
+
+dialects=${*:-sqlite3 postgres mysql}
+failed=""
+for dialect in $dialects; do
+	echo "=== Repository test: $dialect"
+	if ! "test_$dialect"; then
+		failed="$failed $dialect"
+	fi
+done
+if [ -n "$failed" ]; then
+	echo "Failed:$failed"
+	exit 1
+fi
+echo "All passed"
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"strconv"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/repository"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ActivityService provides apis for activity history.
+type ActivityService struct {
+	actor        *model.User
+	activityRepo repository.Activities
+}
+
+// NewActivityService return new instance of ActivityService.
+// actor is the user who calls apis, and whose role is checked.
+func NewActivityService(tx *gorm.DB, actor *model.User) *ActivityService {
+	return NewActivityServiceWithRepositories(repository.NewRepositories(tx), actor)
+}
+
+// NewActivityServiceWithRepositories return new instance of ActivityService which uses specified repositories.
+func NewActivityServiceWithRepositories(repos *repository.Repositories, actor *model.User) *ActivityService {
+	return &ActivityService{
+		actor:        actor,
+		activityRepo: repos.Activities,
+	}
+}
+
+// FindActivities finds activities matching specified condition, the newest comes first
+func (s *ActivityService) FindActivities(condition interface{}, offset, limit int) ([]model.Activity, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
+	activities, err := s.activityRepo.FindActivities(condition, offset, limit, []string{"created_date desc, id"})
+	if err != nil {
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find activities")
+	}
+	return activities, nil
+}
+
+// FindEntityHistory finds all activities of specified entity in chronological order
+func (s *ActivityService) FindEntityHistory(entityKind, entityID string) ([]model.Activity, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
+	condition := &model.Activity{EntityKind: entityKind, EntityID: entityID}
+	activities, err := s.activityRepo.FindActivities(condition, 0, orm.NoLimit, []string{"created_date, entity_version, id"})
+	if err != nil {
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find history")
+	}
+	return activities, nil
+}
+
+// fieldValue presents a value of entity's field which is recorded as activity
+type fieldValue struct {
+	field string
+	value string
+}
+
+func taskFieldValues(task *model.Task) []fieldValue {
+	return []fieldValue{
+		{"name", task.Name},
+		{"description", task.Description},
+		{"assigneeUserId", task.AssigneeUserID.String},
+		{"boardId", task.BoardID},
+		{"isClosed", strconv.FormatBool(task.IsClosed)},
+		{"estimateSize", strconv.Itoa(task.EstimateSize)},
+		{"startDate", formatNullTime(task.StartDate)},
+		{"dueDate", formatNullTime(task.DueDate)},
+		{"parentTaskId", task.ParentTaskID.String},
+	}
+}
+
+func formatNullTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func boardFieldValues(board *model.Board) []fieldValue {
+	return []fieldValue{
+		{"name", board.Name},
+		{"isSystem", strconv.FormatBool(board.IsSystem)},
+		{"isClosed", strconv.FormatBool(board.IsClosed)},
+	}
+}
+
+func userFieldValues(user *model.User) []fieldValue {
+	return []fieldValue{
+		{"name", user.Name},
+		{"avatar", user.Avatar},
+		{"role", user.Role},
+	}
+}
+
+// activityRecorder records activities of changes by actor. It is used by each service.
+type activityRecorder struct {
+	activityRepo repository.Activities
+	actor        *model.User
+}
+
+func newActivityRecorder(activityRepo repository.Activities, actor *model.User) *activityRecorder {
+	return &activityRecorder{
+		activityRepo: activityRepo,
+		actor:        actor,
+	}
+}
+
+func (r *activityRecorder) newActivity(entityKind, entityID string, entityVersion int, action string) *model.Activity {
+	actorUserID := ""
+	if r.actor != nil {
+		actorUserID = r.actor.ID
+	}
+	return model.NewActivity(entityKind, entityID, entityVersion, action, actorUserID, time.Now().UTC())
+}
+
+// recordCreate records creation of entity with its name
+func (r *activityRecorder) recordCreate(entityKind, entityID string, entityVersion int, name string) error {
+	activity := r.newActivity(entityKind, entityID, entityVersion, model.ActivityActionCreate)
+	activity.NewValue = name
+	if err := r.activityRepo.CreateActivity(activity); err != nil {
+		return NewSvcErrorf(ErrorCodeDB, err, "Failed to record activity. ID:%s", entityID)
+	}
+	return nil
+}
+
+// recordDelete records deletion of entity with its name
+func (r *activityRecorder) recordDelete(entityKind, entityID string, entityVersion int, name string) error {
+	activity := r.newActivity(entityKind, entityID, entityVersion, model.ActivityActionDelete)
+	activity.OldValue = name
+	if err := r.activityRepo.CreateActivity(activity); err != nil {
+		return NewSvcErrorf(ErrorCodeDB, err, "Failed to record activity. ID:%s", entityID)
+	}
+	return nil
+}
+
+// recordChanges records an activity per changed field. oldValues and newValues must have same fields in same order.
+func (r *activityRecorder) recordChanges(entityKind, entityID string, entityVersion int, action string,
+	oldValues, newValues []fieldValue,
+) error {
+	activities := make([]*model.Activity, 0, len(newValues))
+	for i, newValue := range newValues {
+		oldValue := oldValues[i]
+		if oldValue.value == newValue.value {
+			continue
+		}
+		activity := r.newActivity(entityKind, entityID, entityVersion, action)
+		activity.SetChange(newValue.field, oldValue.value, newValue.value)
+		activities = append(activities, activity)
+	}
+	if err := r.activityRepo.CreateActivities(activities); err != nil {
+		return NewSvcErrorf(ErrorCodeDB, err, "Failed to record activity. ID:%s", entityID)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/repository"
+	"taskboard-api-go/storage"
+
+	"github.com/jinzhu/gorm"
+)
+
+// MaxAttachmentSize is max size of an attachment file (bytes)
+const MaxAttachmentSize = 10 * 1024 * 1024
+
+// allowedAttachmentTypes are media types of files which can be attached
+var allowedAttachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"application/pdf": true,
+	"application/zip": true,
+	"text/plain":      true,
+}
+
+var attachmentStorage storage.Storage
+
+// SetAttachmentStorage sets the storage to store contents of attachments
+func SetAttachmentStorage(s storage.Storage) {
+	attachmentStorage = s
+}
+
+func getAttachmentStorage() storage.Storage {
+	if attachmentStorage == nil {
+		// Programing error!!
+		panic("attachment storage must be set")
+	}
+	return attachmentStorage
+}
+
+// AttachmentService provides apis for attachment management.
+type AttachmentService struct {
+	actor          *model.User
+	attachmentRepo repository.Attachments
+}
+
+// NewAttachmentService return new instance of AttachmentService.
+// actor is the user who calls apis, and whose role is checked.
+func NewAttachmentService(tx *gorm.DB, actor *model.User) *AttachmentService {
+	return NewAttachmentServiceWithRepositories(repository.NewRepositories(tx), actor)
+}
+
+// NewAttachmentServiceWithRepositories return new instance of AttachmentService which uses specified repositories.
+func NewAttachmentServiceWithRepositories(repos *repository.Repositories, actor *model.User) *AttachmentService {
+	return &AttachmentService{
+		actor:          actor,
+		attachmentRepo: repos.Attachments,
+	}
+}
+
+// FindAttachment returns attachment matching specified condition
+func (s *AttachmentService) FindAttachment(condition interface{}) (*model.Attachment, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
+	find, err := s.attachmentRepo.FindFirstAttachment(condition, []string{"id"})
+	if err != nil {
+		if err == orm.ErrorRecordNotFound {
+			return nil, NewSvcErrorf(ErrorCodeNotFound, err, "Attachment not found")
+		}
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find attachment")
+	}
+	return &find, nil
+}
+
+// FindAttachments finds all attachments
+func (s *AttachmentService) FindAttachments(condition interface{}, sortOrders []string) ([]model.Attachment, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
+	attachments, err := s.attachmentRepo.FindAttachments(condition, 0, orm.NoLimit, sortOrders)
+	if err != nil {
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find attachments")
+	}
+	return attachments, nil
+}
+
+// CreateAttachment stores content and creates new attachment.
+// Content type of attachment is detected from the content, and size of attachment is set by the content.
+func (s *AttachmentService) CreateAttachment(attachment *model.Attachment, content io.Reader) error {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return serr
+	}
+	if attachment.FileName == "" {
+		return NewSvcError(ErrorCodeInvalidArguments, nil, "File name is empty")
+	}
+	// Read 1 byte more than max to know whether the content is too large
+	data, err := ioutil.ReadAll(io.LimitReader(content, MaxAttachmentSize+1))
+	if err != nil {
+		return NewSvcError(ErrorCodeBadRequest, err, "Failed to read attachment")
+	}
+	if len(data) > MaxAttachmentSize {
+		return NewSvcErrorf(ErrorCodeInvalidArguments, nil, "Attachment is too large. Max size is %d bytes", MaxAttachmentSize)
+	}
+	contentType := http.DetectContentType(data)
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !allowedAttachmentTypes[mediaType] {
+		return NewSvcErrorf(ErrorCodeInvalidArguments, err, "Type of attachment is not allowed. Type:%s", contentType)
+	}
+	attachment.ContentType = contentType
+	attachment.Size = int64(len(data))
+
+	err = getAttachmentStorage().Save(attachment.StorageKey, bytes.NewReader(data))
+	if err != nil {
+		return NewSvcError(ErrorCodeUnexpected, err, "Failed to store attachment")
+	}
+	err = s.attachmentRepo.CreateAttachment(attachment)
+	if err != nil {
+		getAttachmentStorage().Delete(attachment.StorageKey)
+		return NewSvcError(ErrorCodeDB, err, "Failed to create attachment")
+	}
+	return nil
+}
+
+// OpenAttachment returns reader of content of specified attachment, the reader must be closed by caller
+func (s *AttachmentService) OpenAttachment(attachment *model.Attachment) (io.ReadCloser, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
+	reader, err := getAttachmentStorage().Open(attachment.StorageKey)
+	if err != nil {
+		return nil, NewSvcErrorf(ErrorCodeUnexpected, err, "Failed to open attachment. ID:%s", attachment.ID)
+	}
+	return reader, nil
+}
+
+// DeleteAttachment deletes specifed attachment.
+// Its content is not deleted, it must be deleted by DeleteAttachmentContents after the transaction is committed.
+func (s *AttachmentService) DeleteAttachment(attachment *model.Attachment) error {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return serr
+	}
+	err := s.attachmentRepo.DeleteAttachment(attachment)
+	if err != nil {
+		return NewSvcErrorf(ErrorCodeDB, err, "Failed to delete attachment. ID:%s", attachment.ID)
+	}
+	return nil
+}
+
+// DeleteAttachmentContents deletes contents of deleted attachments from storage.
+// It must be called after the transaction which deleted attachments is committed,
+// so that rows restored by rollback never refer to deleted contents.
+func DeleteAttachmentContents(attachments []model.Attachment) error {
+	for _, attachment := range attachments {
+		err := getAttachmentStorage().Delete(attachment.StorageKey)
+		if err != nil {
+			return NewSvcErrorf(ErrorCodeUnexpected, err, "Failed to delete content of attachment. ID:%s", attachment.ID)
+		}
+	}
+	return nil
+}
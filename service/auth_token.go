@@ -0,0 +1,126 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TokenType is type of authentication token
+type TokenType string
+
+// Definition of TokenType
+const (
+	TokenTypeAccess  TokenType = "access"
+	TokenTypeRefresh TokenType = "refresh"
+)
+
+// Lifetime of tokens
+const (
+	AccessTokenLifetime  = 1 * time.Hour
+	RefreshTokenLifetime = 7 * 24 * time.Hour
+)
+
+var tokenSecret []byte
+
+// AuthToken presents a pair of signed tokens issued at login
+type AuthToken struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+type tokenClaims struct {
+	UserID    string    `json:"uid"`
+	Type      TokenType `json:"typ"`
+	ExpiresAt int64     `json:"exp"`
+}
+
+// SetTokenSecret sets the secret key to sign tokens.
+// When secret is empty, random secret is generated. (All issued tokens are invalidated by restart)
+func SetTokenSecret(secret string) error {
+	if secret != "" {
+		tokenSecret = []byte(secret)
+		return nil
+	}
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return errors.WithStack(err)
+	}
+	tokenSecret = random
+	return nil
+}
+
+// IssueAuthToken issues new access token and refresh token for specified user
+func IssueAuthToken(userID string, now time.Time) (*AuthToken, error) {
+	accessExpiresAt := now.Add(AccessTokenLifetime)
+	accessToken, err := signToken(&tokenClaims{UserID: userID, Type: TokenTypeAccess, ExpiresAt: accessExpiresAt.Unix()})
+	if err != nil {
+		return nil, NewSvcError(ErrorCodeUnexpected, err, "Failed to issue access token")
+	}
+	refreshExpiresAt := now.Add(RefreshTokenLifetime)
+	refreshToken, err := signToken(&tokenClaims{UserID: userID, Type: TokenTypeRefresh, ExpiresAt: refreshExpiresAt.Unix()})
+	if err != nil {
+		return nil, NewSvcError(ErrorCodeUnexpected, err, "Failed to issue refresh token")
+	}
+	return &AuthToken{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+// VerifyAuthToken verifies signature, type and expiry of token, and returns user ID of the token
+func VerifyAuthToken(token string, tokenType TokenType, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return "", NewSvcError(ErrorCodeUnauthenticated, nil, "Invalid token")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, computeSignature(parts[0])) {
+		// Does not describe details
+		return "", NewSvcError(ErrorCodeUnauthenticated, err, "Invalid token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", NewSvcError(ErrorCodeUnauthenticated, err, "Invalid token")
+	}
+	var claims tokenClaims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return "", NewSvcError(ErrorCodeUnauthenticated, err, "Invalid token")
+	}
+	if claims.Type != tokenType || claims.UserID == "" {
+		return "", NewSvcError(ErrorCodeUnauthenticated, nil, "Invalid token")
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return "", NewSvcError(ErrorCodeUnauthenticated, nil, "Token expired")
+	}
+	return claims.UserID, nil
+}
+
+func signToken(claims *tokenClaims) (string, error) {
+	if len(tokenSecret) == 0 {
+		// Programing error!!
+		panic("token secret must be set")
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(computeSignature(encoded)), nil
+}
+
+func computeSignature(encodedPayload string) []byte {
+	mac := hmac.New(sha256.New, tokenSecret)
+	mac.Write([]byte(encodedPayload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// issueTestAuthToken issues tokens of user_a signed with specified secret
+func issueTestAuthToken(t *testing.T, secret string, now time.Time) *AuthToken {
+	if err := SetTokenSecret(secret); err != nil {
+		t.Fatalf("Failed to set token secret: %+v", err)
+	}
+	token, serr := IssueAuthToken("user_a", now)
+	if serr != nil {
+		t.Fatalf("Failed to issue token: %+v", serr)
+	}
+	return token
+}
+
+// assertInvalidToken asserts that err is unauthenticated error
+func assertInvalidToken(t *testing.T, err error) {
+	if serr, ok := err.(*SvcError); assert.True(t, ok, "expected SvcError, but got %v", err) {
+		assert.Equal(t, ErrorCodeUnauthenticated, serr.Code, serr.Message)
+	}
+}
+
+func TestVerifyAuthToken(t *testing.T) {
+	now := time.Now()
+	token := issueTestAuthToken(t, "secret", now)
+	assert.Equal(t, now.Add(AccessTokenLifetime), token.AccessExpiresAt)
+	assert.Equal(t, now.Add(RefreshTokenLifetime), token.RefreshExpiresAt)
+
+	userID, serr := VerifyAuthToken(token.AccessToken, TokenTypeAccess, now)
+	if assert.NoError(t, serr) {
+		assert.Equal(t, "user_a", userID)
+	}
+	userID, serr = VerifyAuthToken(token.RefreshToken, TokenTypeRefresh, now.Add(AccessTokenLifetime))
+	if assert.NoError(t, serr) {
+		assert.Equal(t, "user_a", userID)
+	}
+}
+
+func TestVerifyAuthTokenExpired(t *testing.T) {
+	now := time.Now()
+	token := issueTestAuthToken(t, "secret", now)
+
+	_, serr := VerifyAuthToken(token.AccessToken, TokenTypeAccess, now.Add(AccessTokenLifetime))
+	assertInvalidToken(t, serr)
+	_, serr = VerifyAuthToken(token.RefreshToken, TokenTypeRefresh, now.Add(RefreshTokenLifetime))
+	assertInvalidToken(t, serr)
+}
+
+func TestVerifyAuthTokenWrongType(t *testing.T) {
+	now := time.Now()
+	token := issueTestAuthToken(t, "secret", now)
+
+	_, serr := VerifyAuthToken(token.RefreshToken, TokenTypeAccess, now)
+	assertInvalidToken(t, serr)
+	_, serr = VerifyAuthToken(token.AccessToken, TokenTypeRefresh, now)
+	assertInvalidToken(t, serr)
+}
+
+func TestVerifyAuthTokenTampered(t *testing.T) {
+	now := time.Now()
+	token := issueTestAuthToken(t, "secret", now)
+	parts := strings.Split(token.AccessToken, ".")
+
+	// Payload of another user with original signature
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"uid":"user_b","typ":"access","exp":` +
+		strconv.FormatInt(now.Add(AccessTokenLifetime).Unix(), 10) + `}`))
+	_, serr := VerifyAuthToken(payload+"."+parts[1], TokenTypeAccess, now)
+	assertInvalidToken(t, serr)
+
+	// Signature with a flipped byte
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatalf("Failed to decode signature: %+v", err)
+	}
+	signature[0] ^= 0xff
+	_, serr = VerifyAuthToken(parts[0]+"."+base64.RawURLEncoding.EncodeToString(signature), TokenTypeAccess, now)
+	assertInvalidToken(t, serr)
+}
+
+func TestVerifyAuthTokenMalformed(t *testing.T) {
+	now := time.Now()
+	token := issueTestAuthToken(t, "secret", now)
+	parts := strings.Split(token.AccessToken, ".")
+
+	for _, malformed := range []string{
+		"",
+		parts[0],
+		token.AccessToken + "." + parts[1],
+		parts[0] + ".!!!",
+		"!!!." + base64.RawURLEncoding.EncodeToString(computeSignature("!!!")),
+		base64.RawURLEncoding.EncodeToString([]byte("{")) + "." +
+			base64.RawURLEncoding.EncodeToString(computeSignature(base64.RawURLEncoding.EncodeToString([]byte("{")))),
+	} {
+		_, serr := VerifyAuthToken(malformed, TokenTypeAccess, now)
+		assertInvalidToken(t, serr)
+	}
+}
+
+func TestVerifyAuthTokenOtherSecret(t *testing.T) {
+	now := time.Now()
+	token := issueTestAuthToken(t, "other secret", now)
+	if err := SetTokenSecret("secret"); err != nil {
+		t.Fatalf("Failed to set token secret: %+v", err)
+	}
+
+	_, serr := VerifyAuthToken(token.AccessToken, TokenTypeAccess, now)
+	assertInvalidToken(t, serr)
+}
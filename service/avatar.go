@@ -0,0 +1,103 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"  // to decode gif
+	_ "image/jpeg" // to decode jpeg
+	"image/png"
+	"io"
+	"io/ioutil"
+	"taskboard-api-go/common"
+	"taskboard-api-go/storage"
+)
+
+// Limits of uploaded avatar image
+const (
+	MaxAvatarSize      = 5 * 1024 * 1024 // bytes
+	MaxAvatarDimension = 4096            // pixels of width and height
+)
+
+// AvatarSizes are sizes(pixels) of square thumbnails generated from uploaded avatar
+var AvatarSizes = []int{32, 64, 128}
+
+var avatarStorage storage.Storage
+
+// SetAvatarStorage sets the storage to store uploaded avatar images
+func SetAvatarStorage(s storage.Storage) {
+	avatarStorage = s
+}
+
+func getAvatarStorage() storage.Storage {
+	if avatarStorage == nil {
+		// Programing error!!
+		panic("avatar storage must be set")
+	}
+	return avatarStorage
+}
+
+// AvatarFileName returns file name of avatar thumbnail of specified hash and size
+func AvatarFileName(hash string, size int) string {
+	return fmt.Sprintf("%s_%d.png", hash, size)
+}
+
+// OpenAvatar returns reader of avatar thumbnail file, the reader must be closed by caller
+func OpenAvatar(fileName string) (io.ReadCloser, error) {
+	reader, err := getAvatarStorage().Open(fileName)
+	if err != nil {
+		return nil, NewSvcErrorf(ErrorCodeNotFound, err, "Avatar not found. Name:%s", fileName)
+	}
+	return reader, nil
+}
+
+// storeAvatarImage decodes image(png, jpeg or gif), stores its thumbnails, and returns hash of the image
+func storeAvatarImage(content io.Reader) (string, error) {
+	// Read 1 byte more than max to know whether the content is too large
+	data, err := ioutil.ReadAll(io.LimitReader(content, MaxAvatarSize+1))
+	if err != nil {
+		return "", NewSvcError(ErrorCodeBadRequest, err, "Failed to read avatar image")
+	}
+	if len(data) > MaxAvatarSize {
+		return "", NewSvcErrorf(ErrorCodeInvalidArguments, nil, "Avatar image is too large. Max size is %d bytes", MaxAvatarSize)
+	}
+	// Check dimension before decoding not to allocate huge image
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", NewSvcError(ErrorCodeInvalidArguments, err, "Avatar image must be png, jpeg or gif")
+	}
+	if config.Width > MaxAvatarDimension || config.Height > MaxAvatarDimension {
+		return "", NewSvcErrorf(ErrorCodeInvalidArguments, nil,
+			"Avatar image is too large. Max dimension is %dx%d", MaxAvatarDimension, MaxAvatarDimension)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", NewSvcError(ErrorCodeInvalidArguments, err, "Avatar image must be png, jpeg or gif")
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:16])
+
+	square := common.CropSquare(src)
+	for _, size := range AvatarSizes {
+		var buf bytes.Buffer
+		if err = png.Encode(&buf, common.ResizeImage(square, size, size)); err != nil {
+			return "", NewSvcError(ErrorCodeUnexpected, err, "Failed to encode avatar image")
+		}
+		if err = getAvatarStorage().Save(AvatarFileName(hash, size), &buf); err != nil {
+			return "", NewSvcError(ErrorCodeUnexpected, err, "Failed to store avatar image")
+		}
+	}
+	return hash, nil
+}
+
+// deleteAvatarImage deletes thumbnails of avatar image of specified hash
+func deleteAvatarImage(hash string) error {
+	for _, size := range AvatarSizes {
+		if err := getAvatarStorage().Delete(AvatarFileName(hash, size)); err != nil {
+			return NewSvcErrorf(ErrorCodeUnexpected, err, "Failed to delete avatar image. Hash:%s", hash)
+		}
+	}
+	return nil
+}
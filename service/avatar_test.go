@@ -0,0 +1,176 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"taskboard-api-go/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setTestAvatarStorage sets avatar storage in a temporary directory, and returns the directory
+func setTestAvatarStorage(t *testing.T) string {
+	dir := filepath.Join(t.TempDir(), "avatars")
+	s, err := storage.NewLocalStorage(dir)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %+v", err)
+	}
+	SetAvatarStorage(s)
+	return dir
+}
+
+// encodeTestImage returns a non-square image encoded by encode, whose left half is red and right half is blue
+func encodeTestImage(t *testing.T, width, height int, encode func(buf *bytes.Buffer, img image.Image) error) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode image: %+v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(buf *bytes.Buffer, img image.Image) error {
+	return png.Encode(buf, img)
+}
+
+// assertInvalidAvatar asserts that err is invalid arguments error
+func assertInvalidAvatar(t *testing.T, err error) {
+	if serr, ok := err.(*SvcError); assert.True(t, ok, "expected SvcError, but got %v", err) {
+		assert.Equal(t, ErrorCodeInvalidArguments, serr.Code, serr.Message)
+	}
+}
+
+func TestStoreAvatarImage(t *testing.T) {
+	dir := setTestAvatarStorage(t)
+	encoders := map[string]func(buf *bytes.Buffer, img image.Image) error{
+		"png": encodePNG,
+		"jpeg": func(buf *bytes.Buffer, img image.Image) error {
+			return jpeg.Encode(buf, img, &jpeg.Options{Quality: 100})
+		},
+		"gif": func(buf *bytes.Buffer, img image.Image) error {
+			return gif.Encode(buf, img, nil)
+		},
+	}
+	for format, encode := range encoders {
+		data := encodeTestImage(t, 300, 200, encode)
+		hash, serr := storeAvatarImage(bytes.NewReader(data))
+		if !assert.NoError(t, serr, format) {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		assert.Equal(t, hex.EncodeToString(sum[:16]), hash, format)
+
+		for _, size := range AvatarSizes {
+			reader, serr := OpenAvatar(AvatarFileName(hash, size))
+			if !assert.NoError(t, serr, format) {
+				continue
+			}
+			thumbnail, err := png.Decode(reader)
+			reader.Close()
+			if !assert.NoError(t, err, format) {
+				continue
+			}
+			// Center square of the image is cropped, whose left half is red and right half is blue
+			assert.Equal(t, image.Rect(0, 0, size, size), thumbnail.Bounds(), format)
+			r, _, b, _ := thumbnail.At(0, size/2).RGBA()
+			assert.True(t, r > b, "%s: left of %d should be red", format, size)
+			r, _, b, _ = thumbnail.At(size-1, size/2).RGBA()
+			assert.True(t, b > r, "%s: right of %d should be blue", format, size)
+		}
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read dir: %+v", err)
+	}
+	assert.Len(t, files, len(encoders)*len(AvatarSizes))
+}
+
+func TestStoreAvatarImageStableName(t *testing.T) {
+	dir := setTestAvatarStorage(t)
+	data := encodeTestImage(t, 20, 10, encodePNG)
+
+	first, serr := storeAvatarImage(bytes.NewReader(data))
+	if !assert.NoError(t, serr) {
+		return
+	}
+	// Same content is stored as same files
+	second, serr := storeAvatarImage(bytes.NewReader(data))
+	if !assert.NoError(t, serr) {
+		return
+	}
+	assert.Equal(t, first, second)
+	// Different content is stored as different files
+	other, serr := storeAvatarImage(bytes.NewReader(encodeTestImage(t, 10, 20, encodePNG)))
+	if !assert.NoError(t, serr) {
+		return
+	}
+	assert.NotEqual(t, first, other)
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read dir: %+v", err)
+	}
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	expected := []string{}
+	for _, hash := range []string{first, other} {
+		for _, size := range AvatarSizes {
+			expected = append(expected, AvatarFileName(hash, size))
+		}
+	}
+	sort.Strings(expected)
+	assert.Equal(t, expected, names)
+}
+
+func TestStoreAvatarImageInvalid(t *testing.T) {
+	dir := setTestAvatarStorage(t)
+
+	// Too large size
+	tooLarge := append(encodeTestImage(t, 10, 10, encodePNG), make([]byte, MaxAvatarSize)...)
+	_, serr := storeAvatarImage(bytes.NewReader(tooLarge))
+	assertInvalidAvatar(t, serr)
+
+	// Too large dimension, which is small in bytes
+	for _, bounds := range [][2]int{{MaxAvatarDimension + 1, 1}, {1, MaxAvatarDimension + 1}} {
+		data := encodeTestImage(t, bounds[0], bounds[1], encodePNG)
+		assert.True(t, len(data) < MaxAvatarSize)
+		_, serr = storeAvatarImage(bytes.NewReader(data))
+		assertInvalidAvatar(t, serr)
+	}
+
+	// Not an image
+	_, serr = storeAvatarImage(bytes.NewReader([]byte("not an image")))
+	assertInvalidAvatar(t, serr)
+	// Broken image whose header is valid
+	broken := encodeTestImage(t, 10, 10, encodePNG)
+	_, serr = storeAvatarImage(bytes.NewReader(broken[:len(broken)/2]))
+	assertInvalidAvatar(t, serr)
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read dir: %+v", err)
+	}
+	assert.Empty(t, files)
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"taskboard-api-go/common"
 	"taskboard-api-go/model"
 	"taskboard-api-go/orm"
 	"taskboard-api-go/repository"
@@ -10,22 +11,33 @@ import (
 
 // BoardService provides apis for board management.
 type BoardService struct {
-	tx        *gorm.DB
-	boardRepo *repository.BoardRepository
-	taskRepo  *repository.TaskRepository
+	actor     *model.User
+	boardRepo repository.Boards
+	taskRepo  repository.Tasks
+	recorder  *activityRecorder
 }
 
 // NewBoardService return new instance of BoardService.
-func NewBoardService(tx *gorm.DB) *BoardService {
+// actor is the user who calls apis, and whose role is checked.
+func NewBoardService(tx *gorm.DB, actor *model.User) *BoardService {
+	return NewBoardServiceWithRepositories(repository.NewRepositories(tx), actor)
+}
+
+// NewBoardServiceWithRepositories return new instance of BoardService which uses specified repositories.
+func NewBoardServiceWithRepositories(repos *repository.Repositories, actor *model.User) *BoardService {
 	return &BoardService{
-		tx:        tx,
-		boardRepo: repository.NewBoardRepository(tx),
-		taskRepo:  repository.NewTaskRepository(tx),
+		actor:     actor,
+		boardRepo: repos.Boards,
+		taskRepo:  repos.Tasks,
+		recorder:  newActivityRecorder(repos.Activities, actor),
 	}
 }
 
 // FindBoard returns board matching specified condition
 func (s *BoardService) FindBoard(condition interface{}) (*model.Board, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
 	find, err := s.boardRepo.FindFirstBoard(condition, []string{"id"})
 	if err != nil {
 		if err == orm.ErrorRecordNotFound {
@@ -38,6 +50,9 @@ func (s *BoardService) FindBoard(condition interface{}) (*model.Board, error) {
 
 // FindBoards finds all boards
 func (s *BoardService) FindBoards(condition interface{}, sortOrders []string) ([]model.Board, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
 	boards, err := s.boardRepo.FindBoards(condition, 0, orm.NoLimit, sortOrders)
 	if err != nil {
 		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find boards")
@@ -47,43 +62,99 @@ func (s *BoardService) FindBoards(condition interface{}, sortOrders []string) ([
 
 // CreateBoard creates new board
 func (s *BoardService) CreateBoard(board *model.Board) error {
-	count, err := s.boardRepo.CountBoards(&model.Board{})
-	if err != nil {
-		return NewSvcError(ErrorCodeDB, err, "Failed to count boards")
+	if serr := authorize(s.actor, PermissionManageBoard); serr != nil {
+		return serr
 	}
-	board.DispOrder = count
-	err = s.boardRepo.CreateBoard(board)
+	// Rank is empty to place the board after the last board
+	board.Rank = ""
+	err := s.boardRepo.CreateBoard(board)
 	if err != nil {
 		return NewSvcError(ErrorCodeDB, err, "Failed to create board")
 	}
-	return nil
+	return s.recorder.recordCreate(model.EntityKindBoard, board.ID, board.Version, board.Name)
 }
 
 // UpdateBoard updates specifed board
-func (s *BoardService) UpdateBoard(board *model.Board) error {
+func (s *BoardService) UpdateBoard(find *model.Board, board *model.Board) error {
+	if serr := authorize(s.actor, PermissionManageBoard); serr != nil {
+		return serr
+	}
+	if board.Version != find.Version {
+		if serr := s.mergeBoard(find, board); serr != nil {
+			return serr
+		}
+	}
 	err := s.boardRepo.UpdateBoard(board)
 	if err != nil {
-		return NewSvcErrorf(ErrorCodeDB, err, "Failed to update board. ID:%s", board.ID)
+		return newUpdateError(model.EntityKindBoard, board.ID, err, func() (int, error) {
+			current, err := s.boardRepo.FindFirstBoard(&model.Board{ID: board.ID}, []string{})
+			return current.Version, err
+		})
+	}
+	return s.recorder.recordChanges(model.EntityKindBoard, board.ID, board.Version, model.ActivityActionUpdate,
+		boardFieldValues(find), boardFieldValues(board))
+}
+
+// mergeBoard merges changes of board which is based on an old version into the current board(find).
+// board is overwritten by merged one.
+func (s *BoardService) mergeBoard(find *model.Board, board *model.Board) error {
+	changed, serr := s.recorder.mergeChanges(model.EntityKindBoard, find.ID, find.Version, board.Version,
+		boardFieldValues(find), boardFieldValues(board))
+	if serr != nil {
+		return serr
+	}
+	merged := *find
+	for _, field := range changed {
+		switch field {
+		case "name":
+			merged.Name = board.Name
+		case "isSystem":
+			merged.IsSystem = board.IsSystem
+		case "isClosed":
+			merged.IsClosed = board.IsClosed
+		}
 	}
+	*board = merged
 	return nil
 }
 
 // DeleteBoard deletes specifed board
 func (s *BoardService) DeleteBoard(board *model.Board) error {
+	if serr := authorize(s.actor, PermissionManageBoard); serr != nil {
+		return serr
+	}
 	err := s.boardRepo.DeleteBoard(board)
 	if err != nil {
 		return NewSvcErrorf(ErrorCodeDB, err, "Failed to delete board. ID:%s", board.ID)
 	}
+	movedTasks, err := s.taskRepo.FindTasks(&model.Task{BoardID: board.ID}, 0, orm.NoLimit, []string{"sort_rank"})
+	if err != nil {
+		return NewSvcErrorf(ErrorCodeDB, err, "Failed to find tasks of board. BoardID:%s", board.ID)
+	}
 	err = s.taskRepo.MoveToIceboxBoard(board.ID)
 	if err != nil {
+		if orm.IsOptimisticLockError(err) || orm.IsRecordNotFoundError(err) {
+			return NewSvcErrorf(ErrorCodeConflict, err, "Tasks of board are updated by others. BoardID:%s", board.ID)
+		}
 		return NewSvcErrorf(ErrorCodeDB, err, "Failed to move tasks to iceboax. BoardID:%s", board.ID)
 	}
+	if serr := s.recorder.recordDelete(model.EntityKindBoard, board.ID, board.Version, board.Name); serr != nil {
+		return serr
+	}
+	for _, task := range movedTasks {
+		// Versions of moved tasks are incremented
+		serr := s.recorder.recordChanges(model.EntityKindTask, task.ID, task.Version+1, model.ActivityActionReorder,
+			[]fieldValue{{"boardId", task.BoardID}}, []fieldValue{{"boardId", model.SystemBoardIcebox.ID}})
+		if serr != nil {
+			return serr
+		}
+	}
 	return nil
 }
 
 // CreateSystemBoards creates all system boards if not exist
 func (s *BoardService) CreateSystemBoards() error {
-	boards, serr := s.FindBoards(&model.Board{IsSystem: true}, []string{"disp_order"})
+	boards, serr := s.FindBoards(&model.Board{IsSystem: true}, []string{"sort_rank"})
 	if serr != nil {
 		return serr
 	}
@@ -130,11 +201,77 @@ func (s *BoardService) CreateSystemBoards() error {
 	return nil
 }
 
-// UpdateBoardOrders updates order of boards
-func (s *BoardService) UpdateBoardOrders(boardIDs []string) error {
-	err := s.boardRepo.UpdateBoardOrders(boardIDs)
+// UpdateBoardOrders moves the board between previous and next boards.
+// prevBoardID is empty to move to the first, and nextBoardID is empty to move to the last.
+// Only the board is updated.
+func (s *BoardService) UpdateBoardOrders(boardID, prevBoardID, nextBoardID string) error {
+	if serr := authorize(s.actor, PermissionManageBoard); serr != nil {
+		return serr
+	}
+	find, serr := s.FindBoard(&model.Board{ID: boardID})
+	if serr != nil {
+		return serr
+	}
+	prevRank, serr := s.findNeighborBoardRank(boardID, prevBoardID)
+	if serr != nil {
+		return serr
+	}
+	nextRank, serr := s.findNeighborBoardRank(boardID, nextBoardID)
+	if serr != nil {
+		return serr
+	}
+	rank, serr := rankBetween(model.EntityKindBoard, prevRank, nextRank)
+	if serr != nil {
+		return serr
+	}
+	err := s.boardRepo.UpdateBoardRank(boardID, rank)
 	if err != nil {
 		return NewSvcErrorf(ErrorCodeDB, err, "Failed to update board's order")
 	}
-	return nil
+	return s.recorder.recordChanges(model.EntityKindBoard, boardID, find.Version, model.ActivityActionReorder,
+		[]fieldValue{{"rank", find.Rank}}, []fieldValue{{"rank", rank}})
+}
+
+// findNeighborBoardRank returns rank of the neighbor of moving board, returns empty if neighborID is empty
+func (s *BoardService) findNeighborBoardRank(boardID, neighborID string) (string, error) {
+	if neighborID == "" {
+		return "", nil
+	}
+	if neighborID == boardID {
+		return "", NewSvcError(ErrorCodeInvalidArguments, nil, "Board can not be placed next to itself")
+	}
+	neighbor, err := s.boardRepo.FindFirstBoard(&model.Board{ID: neighborID}, []string{})
+	if err != nil {
+		if err == orm.ErrorRecordNotFound {
+			return "", NewSvcErrorf(ErrorCodeConflict, err, "Neighbor board is deleted by others. ID:%s", neighborID)
+		}
+		return "", NewSvcError(ErrorCodeDB, err, "Failed to find board")
+	}
+	return neighbor.Rank, nil
+}
+
+// RebalanceBoardRanks reassigns evenly spaced ranks to boards if their ranks are too long or duplicated.
+// Order of boards is kept, and it returns whether ranks are rebalanced.
+func (s *BoardService) RebalanceBoardRanks(maxLength int) (bool, error) {
+	if serr := authorize(s.actor, PermissionManageBoard); serr != nil {
+		return false, serr
+	}
+	boards, err := s.boardRepo.FindBoards(&model.Board{}, 0, orm.NoLimit, []string{"sort_rank, id"})
+	if err != nil {
+		return false, NewSvcError(ErrorCodeDB, err, "Failed to find boards")
+	}
+	ranks := make([]string, 0, len(boards))
+	for _, board := range boards {
+		ranks = append(ranks, board.Rank)
+	}
+	if !needsRebalance(ranks, maxLength) {
+		return false, nil
+	}
+	for i, rank := range common.EvenRanks(len(boards)) {
+		err = s.boardRepo.UpdateBoardRank(boards[i].ID, rank)
+		if err != nil {
+			return false, NewSvcErrorf(ErrorCodeDB, err, "Failed to update rank of board. ID:%s", boards[i].ID)
+		}
+	}
+	return true, nil
 }
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"taskboard-api-go/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// boardNames returns names of all boards in display order
+func boardNames(t *testing.T, srvc *BoardService) []string {
+	boards, serr := srvc.FindBoards(&model.Board{}, []string{"sort_rank"})
+	if serr != nil {
+		t.Fatalf("Failed to find boards: %+v", serr)
+	}
+	names := make([]string, 0, len(boards))
+	for _, board := range boards {
+		names = append(names, board.Name)
+	}
+	return names
+}
+
+func TestBoardService_CreateSystemBoards(t *testing.T) {
+	repos := newTestRepositories(t)
+	srvc := NewBoardServiceWithRepositories(repos, testAdmin)
+	assert.Equal(t, []string{"Icebox", "Todo", "Doing", "Done"}, boardNames(t, srvc))
+
+	// Existing boards are not created again
+	assert.NoError(t, srvc.CreateSystemBoards())
+	assert.Equal(t, []string{"Icebox", "Todo", "Doing", "Done"}, boardNames(t, srvc))
+
+	// New board is placed after the last board
+	board := model.NewBoard("Review", false, false, time.Now().UTC())
+	assert.NoError(t, srvc.CreateBoard(board))
+	assert.Equal(t, []string{"Icebox", "Todo", "Doing", "Done", "Review"}, boardNames(t, srvc))
+
+	// Only admin can manage boards
+	board = model.NewBoard("Backlog", false, false, time.Now().UTC())
+	assertErrorCode(t, ErrorCodeForbidden, NewBoardServiceWithRepositories(repos, testMember).CreateBoard(board))
+}
+
+func TestBoardService_UpdateBoardOrders(t *testing.T) {
+	repos := newTestRepositories(t)
+	srvc := NewBoardServiceWithRepositories(repos, testAdmin)
+
+	// Move done board between icebox and todo
+	assert.NoError(t, srvc.UpdateBoardOrders(model.SystemBoardDone.ID, model.SystemBoardIcebox.ID, model.SystemBoardTodo.ID))
+	assert.Equal(t, []string{"Icebox", "Done", "Todo", "Doing"}, boardNames(t, srvc))
+
+	// Move icebox board to the last
+	assert.NoError(t, srvc.UpdateBoardOrders(model.SystemBoardIcebox.ID, model.SystemBoardDoing.ID, ""))
+	assert.Equal(t, []string{"Done", "Todo", "Doing", "Icebox"}, boardNames(t, srvc))
+
+	// Version of moved board is kept
+	find, serr := srvc.FindBoard(&model.Board{ID: model.SystemBoardIcebox.ID})
+	assert.NoError(t, serr)
+	assert.Equal(t, 1, find.Version)
+	activities := findActivities(t, repos, &model.Activity{EntityID: find.ID, Action: model.ActivityActionReorder})
+	assert.Len(t, activities, 1)
+
+	// Neighbors are reversed
+	assertErrorCode(t, ErrorCodeConflict, srvc.UpdateBoardOrders(model.SystemBoardTodo.ID, model.SystemBoardIcebox.ID, model.SystemBoardDone.ID))
+	// Neighbor is not found
+	assertErrorCode(t, ErrorCodeConflict, srvc.UpdateBoardOrders(model.SystemBoardTodo.ID, "board_none", ""))
+	// Neighbor is itself
+	assertErrorCode(t, ErrorCodeInvalidArguments, srvc.UpdateBoardOrders(model.SystemBoardTodo.ID, "", model.SystemBoardTodo.ID))
+}
+
+func TestBoardService_UpdateBoardMerge(t *testing.T) {
+	srvc := NewBoardServiceWithRepositories(newTestRepositories(t), testAdmin)
+	created := model.NewBoard("board", false, false, time.Now().UTC())
+	assert.NoError(t, srvc.CreateBoard(created))
+	base := *created
+
+	client1 := base
+	client1.Name = "name1"
+	assert.NoError(t, srvc.UpdateBoard(&base, &client1))
+
+	// Changes of other fields are merged
+	find, serr := srvc.FindBoard(&model.Board{ID: created.ID})
+	assert.NoError(t, serr)
+	client2 := base
+	client2.IsClosed = true
+	assert.NoError(t, srvc.UpdateBoard(find, &client2))
+	assert.Equal(t, "name1", client2.Name)
+	assert.True(t, client2.IsClosed)
+
+	// Changes of same field conflict
+	find, serr = srvc.FindBoard(&model.Board{ID: created.ID})
+	assert.NoError(t, serr)
+	client3 := base
+	client3.Name = "name3"
+	serr = srvc.UpdateBoard(find, &client3)
+	if assertErrorCode(t, ErrorCodeOptimisticLockFailure, serr) {
+		assert.Equal(t, []string{"3", "name"}, serr.(*SvcError).Details)
+	}
+
+	// Version newer than the current is never merged
+	client4 := *find
+	client4.Version++
+	assertErrorCode(t, ErrorCodeOptimisticLockFailure, srvc.UpdateBoard(find, &client4))
+}
+
+func TestBoardService_DeleteBoard(t *testing.T) {
+	repos := newTestRepositories(t)
+	srvc := NewBoardServiceWithRepositories(repos, testAdmin)
+	taskSrvc := NewTaskServiceWithRepositories(repos, testAdmin)
+	board := model.NewBoard("board", false, false, time.Now().UTC())
+	assert.NoError(t, srvc.CreateBoard(board))
+	createTestTasks(t, taskSrvc, model.SystemBoardIcebox.ID, "i")
+	moved := createTestTasks(t, taskSrvc, board.ID, "x", "y")
+
+	// Tasks on the board are moved after tasks on icebox, keeping their order
+	assert.NoError(t, srvc.DeleteBoard(board))
+	_, serr := srvc.FindBoard(&model.Board{ID: board.ID})
+	assertErrorCode(t, ErrorCodeNotFound, serr)
+	assert.Equal(t, []string{"i", "x", "y"}, boardTaskNames(t, taskSrvc, model.SystemBoardIcebox.ID))
+
+	activities := findActivities(t, repos, &model.Activity{EntityID: moved[0].ID, Action: model.ActivityActionReorder})
+	if assert.Len(t, activities, 1) {
+		assert.Equal(t, board.ID, activities[0].OldValue)
+		assert.Equal(t, model.SystemBoardIcebox.ID, activities[0].NewValue)
+	}
+}
+
+func TestBoardService_RebalanceBoardRanks(t *testing.T) {
+	srvc := NewBoardServiceWithRepositories(newTestRepositories(t), testAdmin)
+	// Move the third board next to the first board repeatedly, then ranks become longer
+	for i := 0; i < 10; i++ {
+		boards, serr := srvc.FindBoards(&model.Board{}, []string{"sort_rank"})
+		if serr != nil {
+			t.Fatalf("Failed to find boards: %+v", serr)
+		}
+		if serr = srvc.UpdateBoardOrders(boards[2].ID, boards[0].ID, boards[1].ID); serr != nil {
+			t.Fatalf("Failed to move board: %+v", serr)
+		}
+	}
+	order := boardNames(t, srvc)
+
+	rebalanced, serr := srvc.RebalanceBoardRanks(2)
+	assert.NoError(t, serr)
+	assert.True(t, rebalanced)
+	assert.Equal(t, order, boardNames(t, srvc))
+	boards, serr := srvc.FindBoards(&model.Board{}, []string{"sort_rank"})
+	assert.NoError(t, serr)
+	for _, board := range boards {
+		assert.True(t, len(board.Rank) <= 2, board.Rank)
+	}
+
+	rebalanced, serr = srvc.RebalanceBoardRanks(2)
+	assert.NoError(t, serr)
+	assert.False(t, rebalanced)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"strings"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/repository"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ChecklistService provides apis for checklist items of tasks.
+type ChecklistService struct {
+	actor         *model.User
+	checklistRepo repository.ChecklistItems
+}
+
+// NewChecklistService return new instance of ChecklistService.
+// actor is the user who calls apis, and whose role is checked.
+func NewChecklistService(tx *gorm.DB, actor *model.User) *ChecklistService {
+	return NewChecklistServiceWithRepositories(repository.NewRepositories(tx), actor)
+}
+
+// NewChecklistServiceWithRepositories return new instance of ChecklistService which uses specified repositories.
+func NewChecklistServiceWithRepositories(repos *repository.Repositories, actor *model.User) *ChecklistService {
+	return &ChecklistService{
+		actor:         actor,
+		checklistRepo: repos.ChecklistItems,
+	}
+}
+
+// FindChecklistItem returns checklist item matching specified condition
+func (s *ChecklistService) FindChecklistItem(condition interface{}) (*model.ChecklistItem, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
+	find, err := s.checklistRepo.FindFirstChecklistItem(condition, []string{"id"})
+	if err != nil {
+		if err == orm.ErrorRecordNotFound {
+			return nil, NewSvcErrorf(ErrorCodeNotFound, err, "Checklist item not found")
+		}
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find checklist item")
+	}
+	return &find, nil
+}
+
+// FindChecklistItems finds all checklist items
+func (s *ChecklistService) FindChecklistItems(condition interface{}, sortOrders []string) ([]model.ChecklistItem, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
+	items, err := s.checklistRepo.FindChecklistItems(condition, 0, orm.NoLimit, sortOrders)
+	if err != nil {
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find checklist items")
+	}
+	return items, nil
+}
+
+// CreateChecklistItem creates new checklist item at the end of the checklist
+func (s *ChecklistService) CreateChecklistItem(item *model.ChecklistItem) error {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return serr
+	}
+	if strings.TrimSpace(item.Name) == "" {
+		return NewSvcError(ErrorCodeInvalidArguments, nil, "Checklist item name is empty")
+	}
+	max, err := s.checklistRepo.MaxChecklistItemDispOrder(item.TaskID)
+	if err != nil {
+		return NewSvcError(ErrorCodeDB, err, "Failed to get max disp order")
+	}
+	item.DispOrder = max + 1
+	err = s.checklistRepo.CreateChecklistItem(item)
+	if err != nil {
+		return NewSvcError(ErrorCodeDB, err, "Failed to create checklist item")
+	}
+	return nil
+}
+
+// UpdateChecklistItem updates specifed checklist item
+func (s *ChecklistService) UpdateChecklistItem(item *model.ChecklistItem) error {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return serr
+	}
+	if strings.TrimSpace(item.Name) == "" {
+		return NewSvcError(ErrorCodeInvalidArguments, nil, "Checklist item name is empty")
+	}
+	err := s.checklistRepo.UpdateChecklistItem(item)
+	if err != nil {
+		return newUpdateError("checklist item", item.ID, err, func() (int, error) {
+			current, err := s.checklistRepo.FindFirstChecklistItem(&model.ChecklistItem{ID: item.ID}, []string{})
+			return current.Version, err
+		})
+	}
+	return nil
+}
+
+// DeleteChecklistItem deletes specifed checklist item
+func (s *ChecklistService) DeleteChecklistItem(item *model.ChecklistItem) error {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return serr
+	}
+	err := s.checklistRepo.DeleteChecklistItem(item)
+	if err != nil {
+		return NewSvcErrorf(ErrorCodeDB, err, "Failed to delete checklist item. ID:%s", item.ID)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"strings"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/repository"
+
+	"github.com/jinzhu/gorm"
+)
+
+// CommentService provides apis for comment management.
+type CommentService struct {
+	actor       *model.User
+	commentRepo repository.Comments
+}
+
+// NewCommentService return new instance of CommentService.
+// actor is the user who calls apis, and whose role is checked.
+func NewCommentService(tx *gorm.DB, actor *model.User) *CommentService {
+	return NewCommentServiceWithRepositories(repository.NewRepositories(tx), actor)
+}
+
+// NewCommentServiceWithRepositories return new instance of CommentService which uses specified repositories.
+func NewCommentServiceWithRepositories(repos *repository.Repositories, actor *model.User) *CommentService {
+	return &CommentService{
+		actor:       actor,
+		commentRepo: repos.Comments,
+	}
+}
+
+// FindComment returns comment matching specified condition
+func (s *CommentService) FindComment(condition interface{}) (*model.Comment, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
+	find, err := s.commentRepo.FindFirstComment(condition, []string{"id"})
+	if err != nil {
+		if err == orm.ErrorRecordNotFound {
+			return nil, NewSvcErrorf(ErrorCodeNotFound, err, "Comment not found")
+		}
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find comment")
+	}
+	return &find, nil
+}
+
+// FindComments finds all comments
+func (s *CommentService) FindComments(condition interface{}, sortOrders []string) ([]model.Comment, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
+	comments, err := s.commentRepo.FindComments(condition, 0, orm.NoLimit, sortOrders)
+	if err != nil {
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find comments")
+	}
+	return comments, nil
+}
+
+// CreateComment creates new comment
+func (s *CommentService) CreateComment(comment *model.Comment) error {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return serr
+	}
+	if strings.TrimSpace(comment.Body) == "" {
+		return NewSvcError(ErrorCodeInvalidArguments, nil, "Comment body is empty")
+	}
+	err := s.commentRepo.CreateComment(comment)
+	if err != nil {
+		return NewSvcError(ErrorCodeDB, err, "Failed to create comment")
+	}
+	return nil
+}
+
+// UpdateComment updates specifed comment. Only the author or admin can update it.
+func (s *CommentService) UpdateComment(find *model.Comment, comment *model.Comment) error {
+	if serr := s.authorizeAuthor(find); serr != nil {
+		return serr
+	}
+	if strings.TrimSpace(comment.Body) == "" {
+		return NewSvcError(ErrorCodeInvalidArguments, nil, "Comment body is empty")
+	}
+	err := s.commentRepo.UpdateComment(comment)
+	if err != nil {
+		return newUpdateError("comment", comment.ID, err, func() (int, error) {
+			current, err := s.commentRepo.FindFirstComment(&model.Comment{ID: comment.ID}, []string{})
+			return current.Version, err
+		})
+	}
+	return nil
+}
+
+// DeleteComment deletes specifed comment. Only the author or admin can delete it.
+func (s *CommentService) DeleteComment(comment *model.Comment) error {
+	if serr := s.authorizeAuthor(comment); serr != nil {
+		return serr
+	}
+	err := s.commentRepo.DeleteComment(comment)
+	if err != nil {
+		return NewSvcErrorf(ErrorCodeDB, err, "Failed to delete comment. ID:%s", comment.ID)
+	}
+	return nil
+}
+
+func (s *CommentService) authorizeAuthor(comment *model.Comment) error {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return serr
+	}
+	if s.actor.ID != comment.AuthorUserID {
+		return authorize(s.actor, PermissionManageComment)
+	}
+	return nil
+}
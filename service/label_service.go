@@ -0,0 +1,126 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/repository"
+
+	"github.com/jinzhu/gorm"
+)
+
+var labelColorPattern = regexp.MustCompile("^#[0-9a-fA-F]{6}$")
+
+// LabelService provides apis for label management.
+type LabelService struct {
+	actor     *model.User
+	labelRepo repository.Labels
+}
+
+// NewLabelService return new instance of LabelService.
+// actor is the user who calls apis, and whose role is checked.
+func NewLabelService(tx *gorm.DB, actor *model.User) *LabelService {
+	return NewLabelServiceWithRepositories(repository.NewRepositories(tx), actor)
+}
+
+// NewLabelServiceWithRepositories return new instance of LabelService which uses specified repositories.
+func NewLabelServiceWithRepositories(repos *repository.Repositories, actor *model.User) *LabelService {
+	return &LabelService{
+		actor:     actor,
+		labelRepo: repos.Labels,
+	}
+}
+
+// FindLabel returns label matching specified condition
+func (s *LabelService) FindLabel(condition interface{}) (*model.Label, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
+	find, err := s.labelRepo.FindFirstLabel(condition, []string{"id"})
+	if err != nil {
+		if err == orm.ErrorRecordNotFound {
+			return nil, NewSvcErrorf(ErrorCodeNotFound, err, "Label not found")
+		}
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find label")
+	}
+	return &find, nil
+}
+
+// FindLabels finds all labels
+func (s *LabelService) FindLabels(condition interface{}, sortOrders []string) ([]model.Label, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
+	labels, err := s.labelRepo.FindLabels(condition, 0, orm.NoLimit, sortOrders)
+	if err != nil {
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find labels")
+	}
+	return labels, nil
+}
+
+// CreateLabel creates new label
+func (s *LabelService) CreateLabel(label *model.Label) error {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return serr
+	}
+	if serr := s.validateLabel(label); serr != nil {
+		return serr
+	}
+	err := s.labelRepo.CreateLabel(label)
+	if err != nil {
+		return NewSvcError(ErrorCodeDB, err, "Failed to create label")
+	}
+	return nil
+}
+
+// UpdateLabel updates specifed label
+func (s *LabelService) UpdateLabel(label *model.Label) error {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return serr
+	}
+	if serr := s.validateLabel(label); serr != nil {
+		return serr
+	}
+	err := s.labelRepo.UpdateLabel(label)
+	if err != nil {
+		return newUpdateError("label", label.ID, err, func() (int, error) {
+			current, err := s.labelRepo.FindFirstLabel(&model.Label{ID: label.ID}, []string{})
+			return current.Version, err
+		})
+	}
+	return nil
+}
+
+// DeleteLabel deletes specifed label and detaches it from all tasks
+func (s *LabelService) DeleteLabel(label *model.Label) error {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return serr
+	}
+	err := s.labelRepo.DeleteLabel(label)
+	if err != nil {
+		return NewSvcErrorf(ErrorCodeDB, err, "Failed to delete label. ID:%s", label.ID)
+	}
+	err = s.labelRepo.DeleteTaskLabelsByLabelID(label.ID)
+	if err != nil {
+		return NewSvcErrorf(ErrorCodeDB, err, "Failed to detach label from tasks. ID:%s", label.ID)
+	}
+	return nil
+}
+
+func (s *LabelService) validateLabel(label *model.Label) error {
+	if strings.TrimSpace(label.Name) == "" {
+		return NewSvcError(ErrorCodeInvalidArguments, nil, "Label name is empty")
+	}
+	if !labelColorPattern.MatchString(label.Color) {
+		return NewSvcErrorf(ErrorCodeInvalidArguments, nil, "Invalid label color:%s", label.Color)
+	}
+	find, err := s.labelRepo.FindLabels(&model.Label{Name: label.Name}, 0, 1, []string{})
+	if err != nil {
+		return NewSvcError(ErrorCodeDB, err, "Failed to find label")
+	}
+	if len(find) > 0 && find[0].ID != label.ID {
+		return NewSvcErrorf(ErrorCodeAlreadyExist, nil, "Label already exists. Name:%s", label.Name)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"strconv"
+	"taskboard-api-go/orm"
+)
+
+// mergeChanges merges changes of client into current entity by three-way merge.
+// The base is the entity at baseVersion (the version client read), which is restored from activities.
+// It returns fields changed by client, or OptimisticLockFailure if both client and others changed the same fields.
+// It also returns OptimisticLockFailure if the base can not be restored, that is, some of the versions after baseVersion
+// have no recorded changes (ex. entities older than activity history, or requests without version).
+// current and client must have same fields in same order.
+func (r *activityRecorder) mergeChanges(entityKind, entityID string, currentVersion, baseVersion int,
+	current, client []fieldValue,
+) ([]string, error) {
+	if baseVersion < 1 || baseVersion > currentVersion {
+		return nil, newOptimisticLockError(entityKind, entityID, currentVersion, nil)
+	}
+	activities, err := r.activityRepo.FindChangesAfterVersion(entityKind, entityID, baseVersion)
+	if err != nil {
+		return nil, NewSvcErrorf(ErrorCodeDB, err, "Failed to find changes. ID:%s", entityID)
+	}
+	// Values at base version of fields changed by others, the oldest change has it
+	baseValues := make(map[string]string, len(activities))
+	recordedVersions := make(map[int]bool, currentVersion-baseVersion)
+	for _, activity := range activities {
+		if _, exists := baseValues[activity.Field]; !exists {
+			baseValues[activity.Field] = activity.OldValue
+		}
+		recordedVersions[activity.EntityVersion] = true
+	}
+	for version := baseVersion + 1; version <= currentVersion; version++ {
+		if !recordedVersions[version] {
+			return nil, newOptimisticLockError(entityKind, entityID, currentVersion, nil)
+		}
+	}
+	changed, conflicts := mergeFieldValues(current, client, baseValues)
+	if len(conflicts) > 0 {
+		return nil, newOptimisticLockError(entityKind, entityID, currentVersion, conflicts)
+	}
+	return changed, nil
+}
+
+// mergeFieldValues returns fields changed by client, and fields changed by both client and others to different values.
+// baseValues has values at base version of fields changed by others.
+func mergeFieldValues(current, client []fieldValue, baseValues map[string]string) (changed, conflicts []string) {
+	for i, clientValue := range client {
+		currentValue := current[i].value
+		baseValue, changedByOthers := baseValues[clientValue.field]
+		if !changedByOthers {
+			baseValue = currentValue
+		}
+		if clientValue.value == baseValue {
+			continue // Not changed by client
+		}
+		if changedByOthers && clientValue.value != currentValue {
+			conflicts = append(conflicts, clientValue.field)
+			continue
+		}
+		changed = append(changed, clientValue.field)
+	}
+	return
+}
+
+// newOptimisticLockError creates OptimisticLockFailure error.
+// Its details has the current version of the entity at first, and conflicting fields follow it.
+func newOptimisticLockError(entityKind, entityID string, currentVersion int, conflicts []string) error {
+	details := append([]string{strconv.Itoa(currentVersion)}, conflicts...)
+	return NewSvcErrorWithDetailsf(ErrorCodeOptimisticLockFailure, orm.ErrorOptimisticLock,
+		"Failed to update %s, it is updated by others. ID:%s", details, entityKind, entityID)
+}
+
+// newUpdateError converts error of updating the entity to service error.
+// findVersion is called to get the current version, when the entity was updated by others.
+func newUpdateError(entityKind, entityID string, err error, findVersion func() (int, error)) error {
+	switch {
+	case orm.IsRecordNotFoundError(err):
+		return NewSvcErrorf(ErrorCodeNotFound, err, "Failed to update %s, it is not found. ID:%s", entityKind, entityID)
+	case orm.IsOptimisticLockError(err):
+		version, ferr := findVersion()
+		if ferr != nil {
+			return NewSvcErrorf(ErrorCodeDB, ferr, "Failed to find %s. ID:%s", entityKind, entityID)
+		}
+		return newOptimisticLockError(entityKind, entityID, version, nil)
+	default:
+		return NewSvcErrorf(ErrorCodeDB, err, "Failed to update %s. ID:%s", entityKind, entityID)
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"taskboard-api-go/model"
+)
+
+// Permission presents an operation which requires authorization
+type Permission string
+
+// Definition of Permission
+const (
+	PermissionRead        Permission = "Read"
+	PermissionEditTask    Permission = "EditTask"
+	PermissionManageBoard Permission = "ManageBoard"
+	PermissionManageUser  Permission = "ManageUser"
+	// PermissionManageComment allows to edit or delete comments of others
+	PermissionManageComment Permission = "ManageComment"
+)
+
+var rolePermissions = map[string][]Permission{
+	model.RoleAdmin:  {PermissionRead, PermissionEditTask, PermissionManageBoard, PermissionManageUser, PermissionManageComment},
+	model.RoleMember: {PermissionRead, PermissionEditTask},
+	model.RoleViewer: {PermissionRead},
+}
+
+// HasPermission checks whether the role of user has specified permission
+func HasPermission(user *model.User, permission Permission) bool {
+	if user == nil {
+		return false
+	}
+	for _, p := range rolePermissions[user.Role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize returns forbidden error if actor doesn't have specified permission
+func authorize(actor *model.User, permission Permission) error {
+	if !HasPermission(actor, permission) {
+		return NewSvcErrorf(ErrorCodeForbidden, nil, "Permission denied. Required permission:%s", permission)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"taskboard-api-go/common"
+)
+
+// MaxRankLength is length of rank which rebalancing is required over.
+// Ranks become longer every time an item is inserted between adjacent ranks.
+const MaxRankLength = 12
+
+// rankBetween returns rank between ranks of previous and next items of kind.
+// Conflict error is returned if they are not in order, which means the order is changed by others.
+func rankBetween(kind, prevRank, nextRank string) (string, error) {
+	rank, err := common.RankBetween(prevRank, nextRank)
+	if err != nil {
+		return "", NewSvcErrorf(ErrorCodeConflict, err, "Order of %ss is changed by others, reload them", kind)
+	}
+	return rank, nil
+}
+
+// needsRebalance checks whether ranks sorted in ascending order are too long or duplicated
+func needsRebalance(ranks []string, maxLength int) bool {
+	for i, rank := range ranks {
+		if len(rank) > maxLength || !common.IsValidRank(rank) {
+			return true
+		}
+		if i > 0 && ranks[i-1] == rank {
+			return true
+		}
+	}
+	return false
+}
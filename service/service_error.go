@@ -21,6 +21,8 @@ const (
 	ErrorCodeOptimisticLockFailure ErrorCode = "OptimisticLockFailure"
 	ErrorCodePreconditionInvalid   ErrorCode = "PreconditionInvalid"
 	ErrorCodeUnauthenticated       ErrorCode = "Unauthenticated"
+	ErrorCodeForbidden             ErrorCode = "Forbidden"
+	ErrorCodeConflict              ErrorCode = "Conflict"
 )
 
 // SvcError presents error of logic service, This has error code, message and cause error.
@@ -36,6 +38,11 @@ func (e *SvcError) Error() string {
 	return e.Message
 }
 
+// Unwrap returns the cause error, so that the cause is checked through SvcError. ex) orm.IsRetryableError
+func (e *SvcError) Unwrap() error {
+	return e.Cause
+}
+
 // NewSvcErrorWithDetails creates new server error with details
 func NewSvcErrorWithDetails(code ErrorCode, err error, message string, details []string) error {
 	if err != nil {
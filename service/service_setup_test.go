@@ -0,0 +1,48 @@
+package service
+
+import (
+	"fmt"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/repository"
+	"taskboard-api-go/repository/memory"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Services are tested with repositories in memory, which have same semantics as ones on database.
+// Users are created without password not to wait for hashing, except tests of login.
+
+var (
+	testAdmin  = &model.User{ID: "user_admin", Name: "admin", Role: model.RoleAdmin, Version: 1}
+	testMember = &model.User{ID: "user_member", Name: "member", Role: model.RoleMember, Version: 1}
+	testViewer = &model.User{ID: "user_viewer", Name: "viewer", Role: model.RoleViewer, Version: 1}
+)
+
+// newTestRepositories returns repositories in new empty store, which has only system boards
+func newTestRepositories(t *testing.T) *repository.Repositories {
+	repos := memory.NewRepositories(memory.NewStore())
+	if serr := NewBoardServiceWithRepositories(repos, model.SystemUser).CreateSystemBoards(); serr != nil {
+		t.Fatalf("Failed to create system boards: %+v", serr)
+	}
+	return repos
+}
+
+// assertErrorCode asserts that err is SvcError of expected code
+func assertErrorCode(t *testing.T, expected ErrorCode, err error) bool {
+	serr, ok := err.(*SvcError)
+	if !ok {
+		return assert.Fail(t, fmt.Sprintf("expected SvcError of %s, but got %v", expected, err))
+	}
+	return assert.Equal(t, expected, serr.Code, serr.Message)
+}
+
+// findActivities returns activities of the entity in chronological order
+func findActivities(t *testing.T, repos *repository.Repositories, condition *model.Activity) []model.Activity {
+	activities, err := repos.Activities.FindActivities(condition, 0, orm.NoLimit, []string{"created_date, entity_version, id"})
+	if err != nil {
+		t.Fatalf("Failed to find activities: %+v", err)
+	}
+	return activities
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/repository"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// TaskLockService provides apis for soft locks of tasks being edited.
+type TaskLockService struct {
+	actor    *model.User
+	lockRepo repository.TaskLocks
+	userRepo repository.Users
+}
+
+// NewTaskLockService return new instance of TaskLockService.
+// actor is the user who calls apis, and whose role is checked.
+func NewTaskLockService(tx *gorm.DB, actor *model.User) *TaskLockService {
+	return NewTaskLockServiceWithRepositories(repository.NewRepositories(tx), actor)
+}
+
+// NewTaskLockServiceWithRepositories return new instance of TaskLockService which uses specified repositories.
+func NewTaskLockServiceWithRepositories(repos *repository.Repositories, actor *model.User) *TaskLockService {
+	return &TaskLockService{
+		actor:    actor,
+		lockRepo: repos.TaskLocks,
+		userRepo: repos.Users,
+	}
+}
+
+// FindTaskLock returns the lock of the task which is not expired
+func (s *TaskLockService) FindTaskLock(task *model.Task, now time.Time) (*model.TaskLock, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
+	find, err := s.lockRepo.FindFirstTaskLock(&model.TaskLock{TaskID: task.ID}, []string{})
+	if err != nil {
+		if err == orm.ErrorRecordNotFound {
+			return nil, NewSvcErrorf(ErrorCodeNotFound, err, "Task is not locked. ID:%s", task.ID)
+		}
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find task lock")
+	}
+	if find.IsExpired(now) {
+		return nil, NewSvcErrorf(ErrorCodeNotFound, nil, "Task is not locked. ID:%s", task.ID)
+	}
+	return &find, nil
+}
+
+// AcquireTaskLock locks the task by actor. If actor already holds the lock, it is extended.
+// Returns conflict error if another user holds the lock, or another request locks the task concurrently.
+func (s *TaskLockService) AcquireTaskLock(task *model.Task, now time.Time) (*model.TaskLock, error) {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return nil, serr
+	}
+	find, err := s.lockRepo.FindFirstTaskLock(&model.TaskLock{TaskID: task.ID}, []string{})
+	if err != nil {
+		if err != orm.ErrorRecordNotFound {
+			return nil, NewSvcError(ErrorCodeDB, err, "Failed to find task lock")
+		}
+		lock := model.NewTaskLock(task.ID, s.actor.ID, now)
+		err = s.lockRepo.CreateTaskLock(lock)
+		if err != nil {
+			if orm.IsDuplicateKeyError(err) {
+				// Another request locked the task concurrently
+				return nil, NewSvcErrorf(ErrorCodeConflict, err, "Task is being edited by others. ID:%s", task.ID)
+			}
+			return nil, NewSvcErrorf(ErrorCodeDB, err, "Failed to create task lock. ID:%s", task.ID)
+		}
+		return lock, nil
+	}
+	if !find.IsExpired(now) && find.UserID != s.actor.ID {
+		return nil, newTaskLockConflictError(s.userRepo, &find)
+	}
+	if find.UserID != s.actor.ID {
+		// Take over expired lock
+		find.UserID = s.actor.ID
+		find.CreatedDate = now
+	}
+	return s.extendTaskLock(&find, now)
+}
+
+// RenewTaskLock extends the lock held by actor, which is called as heartbeat while editing.
+// Returns not found error if the lock is released, or conflict error if another user took over it.
+func (s *TaskLockService) RenewTaskLock(task *model.Task, now time.Time) (*model.TaskLock, error) {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return nil, serr
+	}
+	find, err := s.lockRepo.FindFirstTaskLock(&model.TaskLock{TaskID: task.ID}, []string{})
+	if err != nil {
+		if err == orm.ErrorRecordNotFound {
+			return nil, NewSvcErrorf(ErrorCodeNotFound, err, "Task is not locked. ID:%s", task.ID)
+		}
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find task lock")
+	}
+	if find.UserID != s.actor.ID {
+		return nil, newTaskLockConflictError(s.userRepo, &find)
+	}
+	// Expired lock can be renewed unless another user took over it
+	return s.extendTaskLock(&find, now)
+}
+
+func (s *TaskLockService) extendTaskLock(lock *model.TaskLock, now time.Time) (*model.TaskLock, error) {
+	lock.Extend(now)
+	err := s.lockRepo.UpdateTaskLock(lock)
+	if err != nil {
+		return nil, newUpdateError("task lock", lock.TaskID, err, func() (int, error) {
+			current, err := s.lockRepo.FindFirstTaskLock(&model.TaskLock{TaskID: lock.TaskID}, []string{})
+			return current.Version, err
+		})
+	}
+	return lock, nil
+}
+
+// ReleaseTaskLock releases the lock held by actor. It does nothing if the task is not locked.
+// Returns conflict error if another user holds the lock.
+func (s *TaskLockService) ReleaseTaskLock(task *model.Task, now time.Time) error {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return serr
+	}
+	find, err := s.lockRepo.FindFirstTaskLock(&model.TaskLock{TaskID: task.ID}, []string{})
+	if err != nil {
+		if err == orm.ErrorRecordNotFound {
+			return nil
+		}
+		return NewSvcError(ErrorCodeDB, err, "Failed to find task lock")
+	}
+	if !find.IsExpired(now) && find.UserID != s.actor.ID {
+		return newTaskLockConflictError(s.userRepo, &find)
+	}
+	err = s.lockRepo.DeleteTaskLock(&find)
+	if err != nil {
+		return NewSvcErrorf(ErrorCodeDB, err, "Failed to delete task lock. ID:%s", task.ID)
+	}
+	return nil
+}
+
+// ReleaseExpiredTaskLocks deletes locks expired at specified time, and returns them
+func (s *TaskLockService) ReleaseExpiredTaskLocks(now time.Time) ([]model.TaskLock, error) {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return nil, serr
+	}
+	locks, err := s.lockRepo.FindExpiredTaskLocks(now)
+	if err != nil {
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find expired task locks")
+	}
+	for i := range locks {
+		err = s.lockRepo.DeleteTaskLock(&locks[i])
+		if err != nil {
+			return nil, NewSvcErrorf(ErrorCodeDB, err, "Failed to delete task lock. ID:%s", locks[i].TaskID)
+		}
+	}
+	return locks, nil
+}
+
+// validateTaskLock returns conflict error if the task is locked by another user than actor
+func validateTaskLock(lockRepo repository.TaskLocks, userRepo repository.Users,
+	actor *model.User, taskID string, now time.Time) error {
+	find, err := lockRepo.FindFirstTaskLock(&model.TaskLock{TaskID: taskID}, []string{})
+	if err != nil {
+		if err == orm.ErrorRecordNotFound {
+			return nil
+		}
+		return NewSvcError(ErrorCodeDB, err, "Failed to find task lock")
+	}
+	if find.IsExpired(now) || find.UserID == actor.ID {
+		return nil
+	}
+	return newTaskLockConflictError(userRepo, &find)
+}
+
+// newTaskLockConflictError returns conflict error whose details are ID and name of the holder
+func newTaskLockConflictError(userRepo repository.Users, lock *model.TaskLock) error {
+	holderName := lock.UserID
+	holder, err := userRepo.FindFirstUser(&model.User{ID: lock.UserID}, []string{})
+	if err == nil {
+		holderName = holder.Name
+	}
+	return NewSvcErrorWithDetailsf(ErrorCodeConflict, nil, "Task is being edited by %s until %s",
+		[]string{lock.UserID, holderName}, holderName, lock.ExpiresDate.Format(time.RFC3339))
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"taskboard-api-go/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskLockService_AcquireTaskLock(t *testing.T) {
+	repos := newTestRepositories(t)
+	if err := repos.Users.CreateUser(testAdmin); err != nil {
+		t.Fatalf("Failed to create user: %+v", err)
+	}
+	task := &model.Task{ID: "task_locked"}
+	now := time.Now().UTC()
+	memberSrvc := NewTaskLockServiceWithRepositories(repos, testMember)
+	adminSrvc := NewTaskLockServiceWithRepositories(repos, testAdmin)
+
+	lock, serr := adminSrvc.AcquireTaskLock(task, now)
+	if !assert.NoError(t, serr) {
+		return
+	}
+	assert.Equal(t, testAdmin.ID, lock.UserID)
+
+	// Lock held by another user can not be acquired nor released
+	_, serr = memberSrvc.AcquireTaskLock(task, now.Add(time.Second))
+	if assertErrorCode(t, ErrorCodeConflict, serr) {
+		assert.Equal(t, []string{testAdmin.ID, testAdmin.Name}, serr.(*SvcError).Details)
+	}
+	assertErrorCode(t, ErrorCodeConflict, memberSrvc.ReleaseTaskLock(task, now.Add(time.Second)))
+
+	// Holder extends the lock
+	renewed, serr := adminSrvc.RenewTaskLock(task, now.Add(time.Minute))
+	if assert.NoError(t, serr) {
+		assert.Equal(t, now.Add(time.Minute+model.TaskLockDuration), renewed.ExpiresDate)
+		assert.Equal(t, lock.Version+1, renewed.Version)
+	}
+
+	// Expired lock is taken over
+	expired := now.Add(time.Minute + model.TaskLockDuration)
+	taken, serr := memberSrvc.AcquireTaskLock(task, expired)
+	if assert.NoError(t, serr) {
+		assert.Equal(t, testMember.ID, taken.UserID)
+	}
+	_, serr = adminSrvc.RenewTaskLock(task, expired)
+	assertErrorCode(t, ErrorCodeConflict, serr)
+
+	assert.NoError(t, memberSrvc.ReleaseTaskLock(task, expired))
+	_, serr = memberSrvc.FindTaskLock(task, expired)
+	assertErrorCode(t, ErrorCodeNotFound, serr)
+}
+
+func TestTaskLockService_ReleaseExpiredTaskLocks(t *testing.T) {
+	repos := newTestRepositories(t)
+	now := time.Now().UTC()
+	for i, taskID := range []string{"task_b", "task_a", "task_c"} {
+		lock := model.NewTaskLock(taskID, testMember.ID, now.Add(time.Duration(i)*time.Minute))
+		if err := repos.TaskLocks.CreateTaskLock(lock); err != nil {
+			t.Fatalf("Failed to create task lock: %+v", err)
+		}
+	}
+
+	released, serr := NewTaskLockServiceWithRepositories(repos, model.SystemUser).
+		ReleaseExpiredTaskLocks(now.Add(time.Minute + model.TaskLockDuration))
+	if !assert.NoError(t, serr) {
+		return
+	}
+	if assert.Len(t, released, 2) {
+		assert.Equal(t, "task_a", released[0].TaskID)
+		assert.Equal(t, "task_b", released[1].TaskID)
+	}
+	_, err := repos.TaskLocks.FindFirstTaskLock(&model.TaskLock{TaskID: "task_c"}, []string{})
+	assert.NoError(t, err)
+	_, err = repos.TaskLocks.FindFirstTaskLock(&model.TaskLock{TaskID: "task_a"}, []string{})
+	assert.Error(t, err)
+}
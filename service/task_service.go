@@ -1,29 +1,78 @@
 package service
 
 import (
+	"sort"
+	"strings"
+	"taskboard-api-go/common"
 	"taskboard-api-go/model"
 	"taskboard-api-go/orm"
 	"taskboard-api-go/repository"
+	"time"
 
 	"github.com/jinzhu/gorm"
 )
 
 // TaskService provides apis for task management.
 type TaskService struct {
-	tx       *gorm.DB
-	taskRepo *repository.TaskRepository
+	actor          *model.User
+	taskRepo       repository.Tasks
+	commentRepo    repository.Comments
+	labelRepo      repository.Labels
+	checklistRepo  repository.ChecklistItems
+	dependencyRepo repository.TaskDependencies
+	attachmentRepo repository.Attachments
+	lockRepo       repository.TaskLocks
+	userRepo       repository.Users
+	recorder       *activityRecorder
 }
 
+// TaskRelations presents entities related to tasks, which are keyed by task ID
+type TaskRelations struct {
+	LabelIDs        map[string][]string
+	Progress        map[string]*TaskProgress
+	BlockingTaskIDs map[string][]string // Tasks which block the task
+	BlockedTaskIDs  map[string][]string // Tasks which are blocked by the task
+}
+
+// TaskProgress presents progress of checklist items and subtasks of a task
+type TaskProgress struct {
+	ChecklistDone       int
+	ChecklistTotal      int
+	SubtaskDone         int
+	SubtaskTotal        int
+	SubtaskEstimateSize int // Sum of estimate size of subtasks
+}
+
+// maxTaskDepth is limit of depth to trace parent tasks
+const maxTaskDepth = 100
+
 // NewTaskService return new instance of TaskService.
-func NewTaskService(tx *gorm.DB) *TaskService {
+// actor is the user who calls apis, and whose role is checked.
+func NewTaskService(tx *gorm.DB, actor *model.User) *TaskService {
+	return NewTaskServiceWithRepositories(repository.NewRepositories(tx), actor)
+}
+
+// NewTaskServiceWithRepositories return new instance of TaskService which uses specified repositories.
+func NewTaskServiceWithRepositories(repos *repository.Repositories, actor *model.User) *TaskService {
 	return &TaskService{
-		tx:       tx,
-		taskRepo: repository.NewTaskRepository(tx),
+		actor:          actor,
+		taskRepo:       repos.Tasks,
+		commentRepo:    repos.Comments,
+		labelRepo:      repos.Labels,
+		checklistRepo:  repos.ChecklistItems,
+		dependencyRepo: repos.TaskDependencies,
+		attachmentRepo: repos.Attachments,
+		lockRepo:       repos.TaskLocks,
+		userRepo:       repos.Users,
+		recorder:       newActivityRecorder(repos.Activities, actor),
 	}
 }
 
 // FindTask returns task matching specified condition
 func (s *TaskService) FindTask(condition interface{}) (*model.Task, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
 	find, err := s.taskRepo.FindFirstTask(condition, []string{"id"})
 	if err != nil {
 		if err == orm.ErrorRecordNotFound {
@@ -36,6 +85,9 @@ func (s *TaskService) FindTask(condition interface{}) (*model.Task, error) {
 
 // FindTasks finds all tasks
 func (s *TaskService) FindTasks(condition interface{}, sortOrders []string) ([]model.Task, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
 	tasks, err := s.taskRepo.FindTasks(condition, 0, orm.NoLimit, sortOrders)
 	if err != nil {
 		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find tasks")
@@ -43,54 +95,561 @@ func (s *TaskService) FindTasks(condition interface{}, sortOrders []string) ([]m
 	return tasks, nil
 }
 
+// FindTasksByFilter finds tasks matching specified condition and filter
+func (s *TaskService) FindTasksByFilter(condition interface{}, filter *repository.TaskFilter, sortOrders []string) ([]model.Task, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
+	tasks, err := s.taskRepo.FindTasksByFilter(condition, filter, 0, orm.NoLimit, sortOrders)
+	if err != nil {
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find tasks")
+	}
+	return tasks, nil
+}
+
+// FindTaskRelations finds entities related to specified tasks
+func (s *TaskService) FindTaskRelations(tasks []model.Task) (*TaskRelations, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
+	taskIDs := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		taskIDs = append(taskIDs, task.ID)
+	}
+	taskLabels, err := s.labelRepo.FindTaskLabels(taskIDs)
+	if err != nil {
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find labels of tasks")
+	}
+	relations := &TaskRelations{
+		LabelIDs:        make(map[string][]string, len(tasks)),
+		Progress:        make(map[string]*TaskProgress, len(tasks)),
+		BlockingTaskIDs: make(map[string][]string, len(tasks)),
+		BlockedTaskIDs:  make(map[string][]string, len(tasks)),
+	}
+	for _, taskLabel := range taskLabels {
+		relations.LabelIDs[taskLabel.TaskID] = append(relations.LabelIDs[taskLabel.TaskID], taskLabel.LabelID)
+	}
+	for _, taskID := range taskIDs {
+		relations.Progress[taskID] = &TaskProgress{}
+	}
+	if len(taskIDs) == 0 {
+		return relations, nil
+	}
+	items, err := s.checklistRepo.FindChecklistItems(map[string]interface{}{"task_id": taskIDs}, 0, orm.NoLimit, []string{})
+	if err != nil {
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find checklist items of tasks")
+	}
+	for _, item := range items {
+		progress := relations.Progress[item.TaskID]
+		progress.ChecklistTotal++
+		if item.IsDone {
+			progress.ChecklistDone++
+		}
+	}
+	subtasks, err := s.taskRepo.FindTasks(map[string]interface{}{"parent_task_id": taskIDs}, 0, orm.NoLimit, []string{})
+	if err != nil {
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find subtasks of tasks")
+	}
+	for _, subtask := range subtasks {
+		progress := relations.Progress[subtask.ParentTaskID.String]
+		progress.SubtaskTotal++
+		progress.SubtaskEstimateSize += subtask.EstimateSize
+		if subtask.IsDone() {
+			progress.SubtaskDone++
+		}
+	}
+	dependencies, err := s.dependencyRepo.FindTaskDependenciesByTaskIDs(taskIDs)
+	if err != nil {
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find dependencies of tasks")
+	}
+	for _, dependency := range dependencies {
+		// Keys which are not in taskIDs are never referred
+		relations.BlockingTaskIDs[dependency.BlockedTaskID] = append(relations.BlockingTaskIDs[dependency.BlockedTaskID], dependency.BlockingTaskID)
+		relations.BlockedTaskIDs[dependency.BlockingTaskID] = append(relations.BlockedTaskIDs[dependency.BlockingTaskID], dependency.BlockedTaskID)
+	}
+	return relations, nil
+}
+
+// AddTaskDependency adds dependency that blocking task blocks blocked task
+func (s *TaskService) AddTaskDependency(blocking *model.Task, blocked *model.Task) error {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return serr
+	}
+	if blocking.ID == blocked.ID {
+		return NewSvcError(ErrorCodeInvalidArguments, nil, "Task can not block itself")
+	}
+	old, serr := s.findBlockingTaskIDs(blocked.ID)
+	if serr != nil {
+		return serr
+	}
+	for _, blockingTaskID := range old {
+		if blockingTaskID == blocking.ID {
+			return NewSvcErrorf(ErrorCodeAlreadyExist, nil, "Dependency already exists. ID:%s", blocking.ID)
+		}
+	}
+	cycle, serr := s.isBlockedTransitively(blocking.ID, blocked.ID)
+	if serr != nil {
+		return serr
+	}
+	if cycle {
+		return NewSvcErrorf(ErrorCodeInvalidArguments, nil, "Dependency makes a cycle. ID:%s", blocking.ID)
+	}
+	err := s.dependencyRepo.CreateTaskDependency(model.NewTaskDependency(blocking.ID, blocked.ID, time.Now().UTC()))
+	if err != nil {
+		return NewSvcErrorf(ErrorCodeDB, err, "Failed to add dependency of task. ID:%s", blocked.ID)
+	}
+	return s.recordBlockingTaskIDs(blocked, old, append(old, blocking.ID))
+}
+
+// RemoveTaskDependency removes dependency that blocking task blocks blocked task
+func (s *TaskService) RemoveTaskDependency(blocking *model.Task, blocked *model.Task) error {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return serr
+	}
+	old, serr := s.findBlockingTaskIDs(blocked.ID)
+	if serr != nil {
+		return serr
+	}
+	remaining := make([]string, 0, len(old))
+	for _, blockingTaskID := range old {
+		if blockingTaskID != blocking.ID {
+			remaining = append(remaining, blockingTaskID)
+		}
+	}
+	if len(remaining) == len(old) {
+		return NewSvcErrorf(ErrorCodeNotFound, nil, "Dependency not found. ID:%s", blocking.ID)
+	}
+	err := s.dependencyRepo.DeleteTaskDependency(&model.TaskDependency{BlockingTaskID: blocking.ID, BlockedTaskID: blocked.ID})
+	if err != nil {
+		return NewSvcErrorf(ErrorCodeDB, err, "Failed to remove dependency of task. ID:%s", blocked.ID)
+	}
+	return s.recordBlockingTaskIDs(blocked, old, remaining)
+}
+
+func (s *TaskService) findBlockingTaskIDs(taskID string) ([]string, error) {
+	dependencies, err := s.dependencyRepo.FindTaskDependencies(&model.TaskDependency{BlockedTaskID: taskID}, []string{"blocking_task_id"})
+	if err != nil {
+		return nil, NewSvcErrorf(ErrorCodeDB, err, "Failed to find dependencies of task. ID:%s", taskID)
+	}
+	result := make([]string, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		result = append(result, dependency.BlockingTaskID)
+	}
+	return result, nil
+}
+
+// isBlockedTransitively checks whether target task is blocked by source task directly or indirectly (depth first search)
+func (s *TaskService) isBlockedTransitively(targetTaskID string, sourceTaskID string) (bool, error) {
+	visited := map[string]bool{sourceTaskID: true}
+	stack := []string{sourceTaskID}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		dependencies, err := s.dependencyRepo.FindTaskDependencies(&model.TaskDependency{BlockingTaskID: current}, []string{})
+		if err != nil {
+			return false, NewSvcError(ErrorCodeDB, err, "Failed to find dependencies of tasks")
+		}
+		for _, dependency := range dependencies {
+			if dependency.BlockedTaskID == targetTaskID {
+				return true, nil
+			}
+			if !visited[dependency.BlockedTaskID] {
+				visited[dependency.BlockedTaskID] = true
+				stack = append(stack, dependency.BlockedTaskID)
+			}
+		}
+	}
+	return false, nil
+}
+
+func (s *TaskService) recordBlockingTaskIDs(task *model.Task, oldIDs []string, newIDs []string) error {
+	return s.recorder.recordChanges(model.EntityKindTask, task.ID, task.Version, model.ActivityActionUpdate,
+		[]fieldValue{{"blockingTaskIds", joinSortedStrings(oldIDs)}}, []fieldValue{{"blockingTaskIds", joinSortedStrings(newIDs)}})
+}
+
+// validateBlockingTasksDone checks that all tasks blocking the task are done, when the task is moved to done board
+func (s *TaskService) validateBlockingTasksDone(taskID string, fromBoardID string, toBoardID string) error {
+	if fromBoardID == toBoardID || toBoardID != model.SystemBoardDone.ID {
+		return nil
+	}
+	blockingTaskIDs, serr := s.findBlockingTaskIDs(taskID)
+	if serr != nil || len(blockingTaskIDs) == 0 {
+		return serr
+	}
+	blockingTasks, err := s.taskRepo.FindTasks(map[string]interface{}{"id": blockingTaskIDs}, 0, orm.NoLimit, []string{"id"})
+	if err != nil {
+		return NewSvcError(ErrorCodeDB, err, "Failed to find blocking tasks")
+	}
+	openTaskIDs := make([]string, 0, len(blockingTasks))
+	for _, blockingTask := range blockingTasks {
+		if !blockingTask.IsDone() {
+			openTaskIDs = append(openTaskIDs, blockingTask.ID)
+		}
+	}
+	if len(openTaskIDs) > 0 {
+		return NewSvcErrorWithDetailsf(ErrorCodePreconditionInvalid, nil,
+			"Task is blocked by open tasks. ID:%s", openTaskIDs, taskID)
+	}
+	return nil
+}
+
+// SetTaskLabels replaces labels of specified task
+func (s *TaskService) SetTaskLabels(task *model.Task, labelIDs []string) error {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return serr
+	}
+	labelIDs = uniqueStrings(labelIDs)
+	if len(labelIDs) > 0 {
+		labels, err := s.labelRepo.FindLabels(map[string]interface{}{"id": labelIDs}, 0, orm.NoLimit, []string{})
+		if err != nil {
+			return NewSvcError(ErrorCodeDB, err, "Failed to find labels")
+		}
+		if len(labels) != len(labelIDs) {
+			return NewSvcErrorf(ErrorCodeInvalidArguments, nil, "Label not found. IDs:%s", strings.Join(labelIDs, ","))
+		}
+	}
+	old, err := s.labelRepo.FindTaskLabels([]string{task.ID})
+	if err != nil {
+		return NewSvcErrorf(ErrorCodeDB, err, "Failed to find labels of task. ID:%s", task.ID)
+	}
+	oldLabelIDs := make([]string, 0, len(old))
+	for _, taskLabel := range old {
+		oldLabelIDs = append(oldLabelIDs, taskLabel.LabelID)
+	}
+	err = s.labelRepo.ReplaceTaskLabels(task.ID, labelIDs)
+	if err != nil {
+		return NewSvcErrorf(ErrorCodeDB, err, "Failed to set labels of task. ID:%s", task.ID)
+	}
+	sort.Strings(labelIDs)
+	return s.recorder.recordChanges(model.EntityKindTask, task.ID, task.Version, model.ActivityActionUpdate,
+		[]fieldValue{{"labelIds", strings.Join(oldLabelIDs, ",")}}, []fieldValue{{"labelIds", strings.Join(labelIDs, ",")}})
+}
+
+// FindTasksBecomingOverdue finds tasks whose due date passed in the period (from, to] and are not done yet
+func (s *TaskService) FindTasksBecomingOverdue(from, to time.Time) ([]model.Task, error) {
+	return s.FindTasksByFilter(&model.Task{}, &repository.TaskFilter{DueAfter: &from, OverdueAt: &to}, []string{"due_date"})
+}
+
 // CreateTask creates new task
 func (s *TaskService) CreateTask(task *model.Task) error {
-	max, err := s.taskRepo.MaxTaskDispOrder(&model.Task{BoardID: task.BoardID})
-	if err != nil {
-		return NewSvcError(ErrorCodeDB, err, "Failed to get max disp order")
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return serr
+	}
+	if serr := validateTaskDates(task); serr != nil {
+		return serr
+	}
+	if serr := s.validateParentTask(task); serr != nil {
+		return serr
 	}
-	task.DispOrder = max + 1
-	err = s.taskRepo.CreateTask(task)
+	// Rank is empty to place the task after the last task of the board
+	task.Rank = ""
+	err := s.taskRepo.CreateTask(task)
 	if err != nil {
 		return NewSvcError(ErrorCodeDB, err, "Failed to create task")
 	}
-	return nil
+	return s.recorder.recordCreate(model.EntityKindTask, task.ID, task.Version, task.Name)
 }
 
 // UpdateTask updates specifed task
 func (s *TaskService) UpdateTask(find *model.Task, task *model.Task) error {
-	// Set dispOrder
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return serr
+	}
+	if serr := validateTaskLock(s.lockRepo, s.userRepo, s.actor, task.ID, time.Now().UTC()); serr != nil {
+		return serr
+	}
+	if task.Version != find.Version {
+		if serr := s.mergeTask(find, task); serr != nil {
+			return serr
+		}
+	}
+	if serr := validateTaskDates(task); serr != nil {
+		return serr
+	}
+	if find.ParentTaskID != task.ParentTaskID {
+		if serr := s.validateParentTask(task); serr != nil {
+			return serr
+		}
+	}
+	if serr := s.validateBlockingTasksDone(task.ID, find.BoardID, task.BoardID); serr != nil {
+		return serr
+	}
+	// Place after the last task of the new board
 	if find.BoardID != task.BoardID {
-		dispOrder, err := s.taskRepo.MaxTaskDispOrder(&model.Task{BoardID: task.BoardID})
+		rank, err := s.taskRepo.NextTaskRank(task.BoardID)
 		if err != nil {
-			return NewSvcErrorf(ErrorCodeDB, err, "Failed to get max disp order of task. ID:%s", task.ID)
+			return NewSvcErrorf(ErrorCodeDB, err, "Failed to get rank of task. ID:%s", task.ID)
 		}
-		task.DispOrder = dispOrder + 1
+		task.Rank = rank
 	}
 
 	err := s.taskRepo.UpdateTask(task)
 	if err != nil {
-		return NewSvcErrorf(ErrorCodeDB, err, "Failed to update task. ID:%s", task.ID)
+		return newUpdateError(model.EntityKindTask, task.ID, err, func() (int, error) {
+			current, err := s.taskRepo.FindFirstTask(&model.Task{ID: task.ID}, []string{})
+			return current.Version, err
+		})
 	}
+	return s.recorder.recordChanges(model.EntityKindTask, task.ID, task.Version, model.ActivityActionUpdate,
+		taskFieldValues(find), taskFieldValues(task))
+}
+
+// mergeTask merges changes of task which is based on an old version into the current task(find).
+// task is overwritten by merged one.
+func (s *TaskService) mergeTask(find *model.Task, task *model.Task) error {
+	changed, serr := s.recorder.mergeChanges(model.EntityKindTask, find.ID, find.Version, task.Version,
+		taskFieldValues(find), taskFieldValues(task))
+	if serr != nil {
+		return serr
+	}
+	merged := *find
+	for _, field := range changed {
+		switch field {
+		case "name":
+			merged.Name = task.Name
+		case "description":
+			merged.Description = task.Description
+		case "assigneeUserId":
+			merged.AssigneeUserID = task.AssigneeUserID
+		case "boardId":
+			merged.BoardID = task.BoardID
+		case "isClosed":
+			merged.IsClosed = task.IsClosed
+		case "estimateSize":
+			merged.EstimateSize = task.EstimateSize
+		case "startDate":
+			merged.StartDate = task.StartDate
+		case "dueDate":
+			merged.DueDate = task.DueDate
+		case "parentTaskId":
+			merged.ParentTaskID = task.ParentTaskID
+		}
+	}
+	*task = merged
 	return nil
 }
 
-// DeleteTask deletes specifed task
-func (s *TaskService) DeleteTask(task *model.Task) error {
+// DeleteTask deletes specifed task, and returns its deleted attachments.
+// Contents of them are not deleted, they must be deleted by DeleteAttachmentContents after the transaction is committed.
+func (s *TaskService) DeleteTask(task *model.Task) ([]model.Attachment, error) {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return nil, serr
+	}
 	err := s.taskRepo.DeleteTask(task)
 	if err != nil {
-		return NewSvcErrorf(ErrorCodeDB, err, "Failed to delete task. ID:%s", task.ID)
+		return nil, NewSvcErrorf(ErrorCodeDB, err, "Failed to delete task. ID:%s", task.ID)
+	}
+	err = s.commentRepo.DeleteCommentsByTaskID(task.ID)
+	if err != nil {
+		return nil, NewSvcErrorf(ErrorCodeDB, err, "Failed to delete comments of task. ID:%s", task.ID)
+	}
+	err = s.labelRepo.DeleteTaskLabelsByTaskID(task.ID)
+	if err != nil {
+		return nil, NewSvcErrorf(ErrorCodeDB, err, "Failed to delete labels of task. ID:%s", task.ID)
+	}
+	err = s.checklistRepo.DeleteChecklistItemsByTaskID(task.ID)
+	if err != nil {
+		return nil, NewSvcErrorf(ErrorCodeDB, err, "Failed to delete checklist items of task. ID:%s", task.ID)
+	}
+	err = s.dependencyRepo.DeleteTaskDependenciesByTaskID(task.ID)
+	if err != nil {
+		return nil, NewSvcErrorf(ErrorCodeDB, err, "Failed to delete dependencies of task. ID:%s", task.ID)
+	}
+	err = s.lockRepo.DeleteTaskLock(&model.TaskLock{TaskID: task.ID})
+	if err != nil {
+		return nil, NewSvcErrorf(ErrorCodeDB, err, "Failed to delete lock of task. ID:%s", task.ID)
+	}
+	attachments, err := s.attachmentRepo.FindAttachments(&model.Attachment{TaskID: task.ID}, 0, orm.NoLimit, []string{})
+	if err != nil {
+		return nil, NewSvcErrorf(ErrorCodeDB, err, "Failed to find attachments of task. ID:%s", task.ID)
+	}
+	err = s.attachmentRepo.DeleteAttachmentsByTaskID(task.ID)
+	if err != nil {
+		return nil, NewSvcErrorf(ErrorCodeDB, err, "Failed to delete attachments of task. ID:%s", task.ID)
+	}
+	// Subtasks remain as top level tasks
+	err = s.taskRepo.ClearParentTaskID(task.ID)
+	if err != nil {
+		return nil, NewSvcErrorf(ErrorCodeDB, err, "Failed to clear parent of subtasks. ID:%s", task.ID)
+	}
+	if serr := s.recorder.recordDelete(model.EntityKindTask, task.ID, task.Version, task.Name); serr != nil {
+		return nil, serr
+	}
+	return attachments, nil
+}
+
+// UpdateTaskOrders moves the task between previous and next tasks on the board.
+// prevTaskID is empty to move to the top, and nextTaskID is empty to move to the bottom.
+// Only the task is updated and its version is incremented, and it returns ID of the board which the task was on.
+func (s *TaskService) UpdateTaskOrders(taskID, toBoardID, prevTaskID, nextTaskID string) (string, error) {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return "", serr
+	}
+	find, serr := s.FindTask(&model.Task{ID: taskID})
+	if serr != nil {
+		return "", serr
+	}
+	if toBoardID == "" {
+		return "", NewSvcError(ErrorCodeInvalidArguments, nil, "Board of task is empty")
+	}
+	prevRank, serr := s.findNeighborTaskRank(taskID, toBoardID, prevTaskID)
+	if serr != nil {
+		return "", serr
+	}
+	nextRank, serr := s.findNeighborTaskRank(taskID, toBoardID, nextTaskID)
+	if serr != nil {
+		return "", serr
+	}
+	rank, serr := s.availableTaskRank(taskID, toBoardID, prevRank, nextRank)
+	if serr != nil {
+		return "", serr
+	}
+	if serr := s.validateBlockingTasksDone(taskID, find.BoardID, toBoardID); serr != nil {
+		return "", serr
+	}
+	// Version is incremented, so that updates based on the old board are merged instead of moving it back
+	moved := *find
+	err := s.taskRepo.MoveTask(&moved, toBoardID, rank)
+	if err != nil {
+		if orm.IsOptimisticLockError(err) || orm.IsRecordNotFoundError(err) {
+			return "", NewSvcErrorf(ErrorCodeConflict, err, "Task is updated by others. ID:%s", taskID)
+		}
+		return "", NewSvcError(ErrorCodeDB, err, "Failed to update task's order")
+	}
+	return find.BoardID, s.recorder.recordChanges(model.EntityKindTask, taskID, moved.Version, model.ActivityActionReorder,
+		taskOrderValues(find.BoardID, find.Rank), taskOrderValues(toBoardID, rank))
+}
+
+// findNeighborTaskRank returns rank of the neighbor of moving task, returns empty if neighborID is empty
+func (s *TaskService) findNeighborTaskRank(taskID, boardID, neighborID string) (string, error) {
+	if neighborID == "" {
+		return "", nil
+	}
+	if neighborID == taskID {
+		return "", NewSvcError(ErrorCodeInvalidArguments, nil, "Task can not be placed next to itself")
+	}
+	neighbor, err := s.taskRepo.FindFirstTask(&model.Task{ID: neighborID}, []string{})
+	if err != nil {
+		if err == orm.ErrorRecordNotFound {
+			return "", NewSvcErrorf(ErrorCodeConflict, err, "Neighbor task is deleted by others. ID:%s", neighborID)
+		}
+		return "", NewSvcError(ErrorCodeDB, err, "Failed to find task")
+	}
+	if neighbor.BoardID != boardID {
+		return "", NewSvcErrorf(ErrorCodeConflict, nil, "Neighbor task is moved to another board by others. ID:%s", neighborID)
+	}
+	return neighbor.Rank, nil
+}
+
+// availableTaskRank returns rank between prevRank and nextRank which no other task on the board has.
+// Another task may have been placed between same neighbors, then the task is placed before it.
+// Rank is unique in the board, so a rank taken by an uncommitted transaction fails at update and it is retried.
+func (s *TaskService) availableTaskRank(taskID, boardID, prevRank, nextRank string) (string, error) {
+	for {
+		rank, serr := rankBetween(model.EntityKindTask, prevRank, nextRank)
+		if serr != nil {
+			return "", serr
+		}
+		taken, err := s.taskRepo.FindFirstTask(&model.Task{BoardID: boardID, Rank: rank}, []string{})
+		if err == orm.ErrorRecordNotFound || (err == nil && taken.ID == taskID) {
+			return rank, nil
+		}
+		if err != nil {
+			return "", NewSvcError(ErrorCodeDB, err, "Failed to find task")
+		}
+		nextRank = rank
+	}
+}
+
+func taskOrderValues(boardID string, rank string) []fieldValue {
+	return []fieldValue{
+		{"boardId", boardID},
+		{"rank", rank},
 	}
-	return nil
 }
 
-// UpdateTaskOrders changes display order of tasks.
-func (s *TaskService) UpdateTaskOrders(taskID, fromBoardID string, fromDispOrder int,
-	toBoardID string, toDispOrder int,
-) (err error) {
-	err = s.taskRepo.MoveTaskDispOrders(taskID, fromBoardID, fromDispOrder, toBoardID, toDispOrder)
+// RebalanceTaskRanks reassigns evenly spaced ranks to tasks of boards which have too long or duplicated ranks.
+// Order of tasks is kept, and it returns IDs of rebalanced boards.
+func (s *TaskService) RebalanceTaskRanks(maxLength int) ([]string, error) {
+	if serr := authorize(s.actor, PermissionEditTask); serr != nil {
+		return nil, serr
+	}
+	tasks, err := s.taskRepo.FindTasks(&model.Task{}, 0, orm.NoLimit, []string{"board_id, sort_rank, id"})
 	if err != nil {
-		return
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find tasks")
+	}
+	boardIDs := []string{}
+	for start := 0; start < len(tasks); {
+		end := start + 1
+		for end < len(tasks) && tasks[end].BoardID == tasks[start].BoardID {
+			end++
+		}
+		boardTasks := tasks[start:end]
+		start = end
+		ranks := make([]string, 0, len(boardTasks))
+		for _, task := range boardTasks {
+			ranks = append(ranks, task.Rank)
+		}
+		if !needsRebalance(ranks, maxLength) {
+			continue
+		}
+		err = s.taskRepo.RerankTasks(boardTasks, common.EvenRanks(len(boardTasks)))
+		if err != nil {
+			return nil, NewSvcErrorf(ErrorCodeDB, err, "Failed to update ranks of tasks. BoardID:%s", boardTasks[0].BoardID)
+		}
+		boardIDs = append(boardIDs, boardTasks[0].BoardID)
+	}
+	return boardIDs, nil
+}
+
+func validateTaskDates(task *model.Task) error {
+	if task.StartDate != nil && task.DueDate != nil && task.DueDate.Before(*task.StartDate) {
+		return NewSvcError(ErrorCodeInvalidArguments, nil, "Due date must not be before start date")
+	}
+	return nil
+}
+
+// validateParentTask checks that parent task exists and the task is not its own ancestor
+func (s *TaskService) validateParentTask(task *model.Task) error {
+	if !task.ParentTaskID.Valid {
+		return nil
+	}
+	parentTaskID := task.ParentTaskID.String
+	for depth := 0; parentTaskID != ""; depth++ {
+		if parentTaskID == task.ID {
+			return NewSvcErrorf(ErrorCodeInvalidArguments, nil, "Parent task makes a cycle. ID:%s", task.ParentTaskID.String)
+		}
+		if depth >= maxTaskDepth {
+			return NewSvcErrorf(ErrorCodeInvalidArguments, nil, "Subtasks are nested too deeply. ID:%s", task.ParentTaskID.String)
+		}
+		parent, err := s.taskRepo.FindFirstTask(&model.Task{ID: parentTaskID}, []string{"id"})
+		if err != nil {
+			if err == orm.ErrorRecordNotFound {
+				return NewSvcErrorf(ErrorCodeInvalidArguments, err, "Parent task not found. ID:%s", parentTaskID)
+			}
+			return NewSvcError(ErrorCodeDB, err, "Failed to find parent task")
+		}
+		parentTaskID = parent.ParentTaskID.String
+	}
+	return nil
+}
+
+// joinSortedStrings joins sorted copy of values, values are not changed
+func joinSortedStrings(values []string) string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+func uniqueStrings(values []string) []string {
+	result := make([]string, 0, len(values))
+	exists := make(map[string]bool, len(values))
+	for _, value := range values {
+		if value == "" || exists[value] {
+			continue
+		}
+		exists[value] = true
+		result = append(result, value)
 	}
-	return
+	return result
 }
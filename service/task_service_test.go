@@ -0,0 +1,422 @@
+package service
+
+import (
+	"strings"
+	"taskboard-api-go/model"
+	"taskboard-api-go/orm"
+	"taskboard-api-go/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestTasks(t *testing.T, srvc *TaskService, boardID string, names ...string) []*model.Task {
+	result := make([]*model.Task, 0, len(names))
+	for _, name := range names {
+		task := model.NewTask(name, "", false, time.Now().UTC())
+		task.BoardID = boardID
+		if serr := srvc.CreateTask(task); serr != nil {
+			t.Fatalf("Failed to create task: %+v", serr)
+		}
+		result = append(result, task)
+	}
+	return result
+}
+
+// boardTaskNames returns names of tasks on the board in display order
+func boardTaskNames(t *testing.T, srvc *TaskService, boardID string) []string {
+	tasks, serr := srvc.FindTasks(&model.Task{BoardID: boardID}, []string{"sort_rank"})
+	if serr != nil {
+		t.Fatalf("Failed to find tasks: %+v", serr)
+	}
+	names := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		names = append(names, task.Name)
+	}
+	return names
+}
+
+func TestTaskService_CreateTask(t *testing.T) {
+	repos := newTestRepositories(t)
+	srvc := NewTaskServiceWithRepositories(repos, testMember)
+	created := createTestTasks(t, srvc, model.SystemBoardTodo.ID, "a", "b")
+
+	// Tasks are placed after the last task, even if rank is specified
+	task := model.NewTask("c", "", false, time.Now().UTC())
+	task.BoardID = model.SystemBoardTodo.ID
+	task.Rank = "0"
+	assert.NoError(t, srvc.CreateTask(task))
+	assert.True(t, created[0].Rank < created[1].Rank && created[1].Rank < task.Rank)
+	assert.Equal(t, []string{"a", "b", "c"}, boardTaskNames(t, srvc, model.SystemBoardTodo.ID))
+
+	activities := findActivities(t, repos, &model.Activity{EntityID: task.ID})
+	if assert.Len(t, activities, 1) {
+		assert.Equal(t, model.ActivityActionCreate, activities[0].Action)
+		assert.Equal(t, testMember.ID, activities[0].ActorUserID)
+	}
+
+	// Viewer can not create task
+	task = model.NewTask("d", "", false, time.Now().UTC())
+	assertErrorCode(t, ErrorCodeForbidden, NewTaskServiceWithRepositories(repos, testViewer).CreateTask(task))
+
+	// Due date before start date is invalid
+	task = model.NewTask("e", "", false, time.Now().UTC())
+	start := time.Now().UTC()
+	due := start.Add(-time.Hour)
+	task.StartDate, task.DueDate = &start, &due
+	assertErrorCode(t, ErrorCodeInvalidArguments, srvc.CreateTask(task))
+}
+
+func TestTaskService_UpdateTaskOrders(t *testing.T) {
+	repos := newTestRepositories(t)
+	srvc := NewTaskServiceWithRepositories(repos, testMember)
+	created := createTestTasks(t, srvc, model.SystemBoardTodo.ID, "a", "b", "c")
+	todo := model.SystemBoardTodo.ID
+
+	// Move c between a and b
+	fromBoardID, serr := srvc.UpdateTaskOrders(created[2].ID, todo, created[0].ID, created[1].ID)
+	assert.NoError(t, serr)
+	assert.Equal(t, todo, fromBoardID)
+	assert.Equal(t, []string{"a", "c", "b"}, boardTaskNames(t, srvc, todo))
+
+	// Version is incremented, and the move is recorded as its change
+	moved, serr := srvc.FindTask(&model.Task{ID: created[2].ID})
+	assert.NoError(t, serr)
+	assert.Equal(t, 2, moved.Version)
+	activities := findActivities(t, repos, &model.Activity{EntityID: created[2].ID, Action: model.ActivityActionReorder})
+	if assert.Len(t, activities, 1) {
+		assert.Equal(t, 2, activities[0].EntityVersion)
+		assert.Equal(t, "rank", activities[0].Field)
+		assert.Equal(t, created[2].Rank, activities[0].OldValue)
+		assert.Equal(t, moved.Rank, activities[0].NewValue)
+	}
+
+	// Move a to the top of doing board
+	fromBoardID, serr = srvc.UpdateTaskOrders(created[0].ID, model.SystemBoardDoing.ID, "", "")
+	assert.NoError(t, serr)
+	assert.Equal(t, todo, fromBoardID)
+	assert.Equal(t, []string{"c", "b"}, boardTaskNames(t, srvc, todo))
+	assert.Equal(t, []string{"a"}, boardTaskNames(t, srvc, model.SystemBoardDoing.ID))
+
+	// Move b to the top of todo board
+	_, serr = srvc.UpdateTaskOrders(created[1].ID, todo, "", created[2].ID)
+	assert.NoError(t, serr)
+	assert.Equal(t, []string{"b", "c"}, boardTaskNames(t, srvc, todo))
+}
+
+func TestTaskService_UpdateTaskOrdersBetweenSameNeighbors(t *testing.T) {
+	srvc := NewTaskServiceWithRepositories(newTestRepositories(t), testMember)
+	created := createTestTasks(t, srvc, model.SystemBoardTodo.ID, "a", "b", "c", "d")
+	todo := model.SystemBoardTodo.ID
+
+	// Clients which read same order move c and d between a and b, the latter is placed before the former
+	_, serr := srvc.UpdateTaskOrders(created[2].ID, todo, created[0].ID, created[1].ID)
+	assert.NoError(t, serr)
+	_, serr = srvc.UpdateTaskOrders(created[3].ID, todo, created[0].ID, created[1].ID)
+	assert.NoError(t, serr)
+	assert.Equal(t, []string{"a", "d", "c", "b"}, boardTaskNames(t, srvc, todo))
+}
+
+func TestTaskService_UpdateTaskOrdersConflict(t *testing.T) {
+	srvc := NewTaskServiceWithRepositories(newTestRepositories(t), testMember)
+	created := createTestTasks(t, srvc, model.SystemBoardTodo.ID, "a", "b", "c")
+	todo := model.SystemBoardTodo.ID
+
+	// Neighbors are reversed, which means the order is changed by others
+	_, serr := srvc.UpdateTaskOrders(created[2].ID, todo, created[1].ID, created[0].ID)
+	assertErrorCode(t, ErrorCodeConflict, serr)
+
+	// Neighbor is moved to another board
+	_, serr = srvc.UpdateTaskOrders(created[1].ID, model.SystemBoardDoing.ID, "", "")
+	assert.NoError(t, serr)
+	_, serr = srvc.UpdateTaskOrders(created[2].ID, todo, created[1].ID, "")
+	assertErrorCode(t, ErrorCodeConflict, serr)
+
+	// Neighbor is deleted
+	_, serr = srvc.DeleteTask(created[0])
+	assert.NoError(t, serr)
+	_, serr = srvc.UpdateTaskOrders(created[2].ID, todo, "", created[0].ID)
+	assertErrorCode(t, ErrorCodeConflict, serr)
+
+	// Neighbor is itself
+	_, serr = srvc.UpdateTaskOrders(created[2].ID, todo, created[2].ID, "")
+	assertErrorCode(t, ErrorCodeInvalidArguments, serr)
+
+	// Task is deleted
+	_, serr = srvc.UpdateTaskOrders(created[0].ID, todo, "", "")
+	assertErrorCode(t, ErrorCodeNotFound, serr)
+}
+
+func TestTaskService_UpdateTask(t *testing.T) {
+	repos := newTestRepositories(t)
+	srvc := NewTaskServiceWithRepositories(repos, testMember)
+	createTestTasks(t, srvc, model.SystemBoardDoing.ID, "x")
+	created := createTestTasks(t, srvc, model.SystemBoardTodo.ID, "a")[0]
+
+	// Task moved to another board by update is placed after the last task of the board
+	find, serr := srvc.FindTask(&model.Task{ID: created.ID})
+	assert.NoError(t, serr)
+	task := *find
+	task.Name = "renamed"
+	task.BoardID = model.SystemBoardDoing.ID
+	assert.NoError(t, srvc.UpdateTask(find, &task))
+	assert.Equal(t, 2, task.Version)
+	assert.Equal(t, []string{"x", "renamed"}, boardTaskNames(t, srvc, model.SystemBoardDoing.ID))
+
+	activities := findActivities(t, repos, &model.Activity{EntityID: created.ID, Action: model.ActivityActionUpdate})
+	fields := []string{}
+	for _, activity := range activities {
+		fields = append(fields, activity.Field)
+	}
+	assert.ElementsMatch(t, []string{"name", "boardId"}, fields)
+
+	// Deleted task
+	_, serr = srvc.DeleteTask(&task)
+	assert.NoError(t, serr)
+	deleted := task
+	task.Name = "deleted"
+	assertErrorCode(t, ErrorCodeNotFound, srvc.UpdateTask(&deleted, &task))
+}
+
+func TestTaskService_UpdateTaskMerge(t *testing.T) {
+	repos := newTestRepositories(t)
+	srvc := NewTaskServiceWithRepositories(repos, testMember)
+	created := createTestTasks(t, srvc, model.SystemBoardTodo.ID, "a")[0]
+	base := *created
+
+	// Client 1 changes name
+	client1 := base
+	client1.Name = "name1"
+	assert.NoError(t, srvc.UpdateTask(&base, &client1))
+
+	// Client 2 changes description based on version 1, which is merged
+	find, serr := srvc.FindTask(&model.Task{ID: created.ID})
+	assert.NoError(t, serr)
+	client2 := base
+	client2.Description = "description2"
+	assert.NoError(t, srvc.UpdateTask(find, &client2))
+	assert.Equal(t, "name1", client2.Name)
+	assert.Equal(t, "description2", client2.Description)
+	assert.Equal(t, 3, client2.Version)
+
+	// Client 3 changes name based on version 1, which conflicts with client 1
+	find, serr = srvc.FindTask(&model.Task{ID: created.ID})
+	assert.NoError(t, serr)
+	client3 := base
+	client3.Name = "name3"
+	serr = srvc.UpdateTask(find, &client3)
+	if assertErrorCode(t, ErrorCodeOptimisticLockFailure, serr) {
+		assert.Equal(t, []string{"3", "name"}, serr.(*SvcError).Details)
+	}
+
+	// Task moved by others is not moved back by update based on the old board
+	_, serr = srvc.UpdateTaskOrders(created.ID, model.SystemBoardDoing.ID, "", "")
+	assert.NoError(t, serr)
+	find, serr = srvc.FindTask(&model.Task{ID: created.ID})
+	assert.NoError(t, serr)
+	stale := client2
+	stale.Description = "description3"
+	assert.NoError(t, srvc.UpdateTask(find, &stale))
+	assert.Equal(t, model.SystemBoardDoing.ID, stale.BoardID)
+	assert.Equal(t, "description3", stale.Description)
+	find, serr = srvc.FindTask(&model.Task{ID: created.ID})
+	assert.NoError(t, serr)
+
+	// Request without version is never merged
+	client4 := *find
+	client4.Version = 0
+	client4.Description = "description4"
+	assertErrorCode(t, ErrorCodeOptimisticLockFailure, srvc.UpdateTask(find, &client4))
+
+	// Changes without activities (ex. before activities are recorded) are never merged
+	unrecorded := *find
+	unrecorded.Name = "unrecorded"
+	assert.NoError(t, repos.Tasks.UpdateTask(&unrecorded))
+	client5 := *find
+	client5.Description = "description5"
+	serr = srvc.UpdateTask(&unrecorded, &client5)
+	if assertErrorCode(t, ErrorCodeOptimisticLockFailure, serr) {
+		assert.Equal(t, []string{"6"}, serr.(*SvcError).Details)
+	}
+}
+
+func TestTaskService_UpdateTaskLocked(t *testing.T) {
+	repos := newTestRepositories(t)
+	srvc := NewTaskServiceWithRepositories(repos, testMember)
+	created := createTestTasks(t, srvc, model.SystemBoardTodo.ID, "a")[0]
+	if err := repos.Users.CreateUser(testAdmin); err != nil {
+		t.Fatalf("Failed to create user: %+v", err)
+	}
+	if err := repos.TaskLocks.CreateTaskLock(model.NewTaskLock(created.ID, testAdmin.ID, time.Now().UTC())); err != nil {
+		t.Fatalf("Failed to create task lock: %+v", err)
+	}
+
+	// Task locked by another user can not be updated
+	task := *created
+	task.Name = "renamed"
+	serr := srvc.UpdateTask(created, &task)
+	if assertErrorCode(t, ErrorCodeConflict, serr) {
+		assert.Equal(t, []string{testAdmin.ID, testAdmin.Name}, serr.(*SvcError).Details)
+	}
+
+	// Holder of the lock can update
+	assert.NoError(t, NewTaskServiceWithRepositories(repos, testAdmin).UpdateTask(created, &task))
+}
+
+func TestTaskService_DeleteTask(t *testing.T) {
+	repos := newTestRepositories(t)
+	srvc := NewTaskServiceWithRepositories(repos, testMember)
+	created := createTestTasks(t, srvc, model.SystemBoardTodo.ID, "parent", "child", "other")
+	parent, child, other := created[0], created[1], created[2]
+	subtask := *child
+	subtask.SetParentTaskID(parent.ID)
+	assert.NoError(t, srvc.UpdateTask(child, &subtask))
+	label := model.NewLabel("label", "#ff0000", time.Now().UTC())
+	assert.NoError(t, repos.Labels.CreateLabel(label))
+	assert.NoError(t, srvc.SetTaskLabels(parent, []string{label.ID}))
+	assert.NoError(t, srvc.AddTaskDependency(parent, other))
+	assert.NoError(t, repos.ChecklistItems.CreateChecklistItem(model.NewChecklistItem(parent.ID, "item", time.Now().UTC())))
+	attachment := model.NewAttachment(parent.ID, "file.txt", "text/plain", 1, testMember.ID, time.Now().UTC())
+	assert.NoError(t, repos.Attachments.CreateAttachment(attachment))
+
+	relations, serr := srvc.FindTaskRelations([]model.Task{*parent, *other})
+	assert.NoError(t, serr)
+	assert.Equal(t, []string{label.ID}, relations.LabelIDs[parent.ID])
+	assert.Equal(t, []string{parent.ID}, relations.BlockingTaskIDs[other.ID])
+	assert.Equal(t, &TaskProgress{ChecklistTotal: 1, SubtaskTotal: 1}, relations.Progress[parent.ID])
+
+	// Deleted attachments are returned to delete their contents after commit
+	attachments, serr := srvc.DeleteTask(parent)
+	assert.NoError(t, serr)
+	if assert.Len(t, attachments, 1) {
+		assert.Equal(t, attachment.StorageKey, attachments[0].StorageKey)
+	}
+	remaining, err := repos.Attachments.FindAttachments(&model.Attachment{TaskID: parent.ID}, 0, orm.NoLimit, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, remaining)
+	_, serr = srvc.FindTask(&model.Task{ID: parent.ID})
+	assertErrorCode(t, ErrorCodeNotFound, serr)
+	relations, serr = srvc.FindTaskRelations([]model.Task{*parent, *other})
+	assert.NoError(t, serr)
+	assert.Empty(t, relations.LabelIDs[parent.ID])
+	assert.Empty(t, relations.BlockingTaskIDs[other.ID])
+	assert.Equal(t, &TaskProgress{}, relations.Progress[parent.ID])
+
+	// Subtask remains as top level task
+	find, serr := srvc.FindTask(&model.Task{ID: child.ID})
+	assert.NoError(t, serr)
+	assert.False(t, find.ParentTaskID.Valid)
+}
+
+func TestTaskService_TaskDependency(t *testing.T) {
+	srvc := NewTaskServiceWithRepositories(newTestRepositories(t), testMember)
+	created := createTestTasks(t, srvc, model.SystemBoardTodo.ID, "a", "b", "c")
+	a, b, c := created[0], created[1], created[2]
+
+	// a blocks b, and b blocks c
+	assert.NoError(t, srvc.AddTaskDependency(a, b))
+	assert.NoError(t, srvc.AddTaskDependency(b, c))
+	assertErrorCode(t, ErrorCodeAlreadyExist, srvc.AddTaskDependency(a, b))
+	assertErrorCode(t, ErrorCodeInvalidArguments, srvc.AddTaskDependency(c, a))
+	assertErrorCode(t, ErrorCodeInvalidArguments, srvc.AddTaskDependency(a, a))
+
+	// b can not be done until a is done
+	_, serr := srvc.UpdateTaskOrders(b.ID, model.SystemBoardDone.ID, "", "")
+	if assertErrorCode(t, ErrorCodePreconditionInvalid, serr) {
+		assert.Equal(t, []string{a.ID}, serr.(*SvcError).Details)
+	}
+	_, serr = srvc.UpdateTaskOrders(a.ID, model.SystemBoardDone.ID, "", "")
+	assert.NoError(t, serr)
+	_, serr = srvc.UpdateTaskOrders(b.ID, model.SystemBoardDone.ID, a.ID, "")
+	assert.NoError(t, serr)
+
+	assert.NoError(t, srvc.RemoveTaskDependency(b, c))
+	assertErrorCode(t, ErrorCodeNotFound, srvc.RemoveTaskDependency(b, c))
+}
+
+func TestTaskService_FindTasksBecomingOverdue(t *testing.T) {
+	srvc := NewTaskServiceWithRepositories(newTestRepositories(t), testMember)
+	created := createTestTasks(t, srvc, model.SystemBoardTodo.ID, "overdue", "passed before", "not yet", "done")
+	now := time.Now().UTC()
+	for i, due := range []time.Time{now.Add(-time.Minute), now.Add(-time.Hour), now.Add(time.Minute), now.Add(-time.Minute)} {
+		due := due
+		task := *created[i]
+		task.DueDate = &due
+		task.IsClosed = i == 3
+		if serr := srvc.UpdateTask(created[i], &task); serr != nil {
+			t.Fatalf("Failed to update task: %+v", serr)
+		}
+	}
+
+	tasks, serr := srvc.FindTasksBecomingOverdue(now.Add(-10*time.Minute), now)
+	assert.NoError(t, serr)
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, "overdue", tasks[0].Name)
+	}
+}
+
+func TestTaskService_RebalanceTaskRanks(t *testing.T) {
+	repos := newTestRepositories(t)
+	srvc := NewTaskServiceWithRepositories(repos, testAdmin)
+	createTestTasks(t, srvc, model.SystemBoardTodo.ID, "a", "b", "c")
+	createTestTasks(t, srvc, model.SystemBoardDoing.ID, "x")
+
+	// Move the last task next to the first task repeatedly, then ranks become longer
+	var tasks []model.Task
+	var err error
+	for i := 0; i < 20; i++ {
+		tasks, err = repos.Tasks.FindTasks(&model.Task{BoardID: model.SystemBoardTodo.ID}, 0, orm.NoLimit, []string{"sort_rank"})
+		if err != nil {
+			t.Fatalf("Failed to find tasks: %+v", err)
+		}
+		if _, serr := srvc.UpdateTaskOrders(tasks[2].ID, model.SystemBoardTodo.ID, tasks[0].ID, tasks[1].ID); serr != nil {
+			t.Fatalf("Failed to move task: %+v", serr)
+		}
+	}
+	tasks, err = repos.Tasks.FindTasks(&model.Task{BoardID: model.SystemBoardTodo.ID}, 0, orm.NoLimit, []string{"sort_rank"})
+	assert.NoError(t, err)
+	order := boardTaskNames(t, srvc, model.SystemBoardTodo.ID)
+
+	// Shorter max length than ranks to force rebalancing
+	maxLength := 0
+	for _, task := range tasks {
+		if len(task.Rank) > maxLength {
+			maxLength = len(task.Rank)
+		}
+	}
+	boardIDs, serr := srvc.RebalanceTaskRanks(maxLength - 1)
+	assert.NoError(t, serr)
+	assert.Equal(t, []string{model.SystemBoardTodo.ID}, boardIDs)
+	assert.Equal(t, order, boardTaskNames(t, srvc, model.SystemBoardTodo.ID))
+
+	tasks, err = repos.Tasks.FindTasks(&model.Task{BoardID: model.SystemBoardTodo.ID}, 0, orm.NoLimit, []string{"sort_rank"})
+	assert.NoError(t, err)
+	for _, task := range tasks {
+		assert.True(t, len(task.Rank) < maxLength, task.Rank)
+		assert.False(t, strings.HasPrefix(task.Rank, "~"))
+	}
+
+	// Nothing to rebalance
+	boardIDs, serr = srvc.RebalanceTaskRanks(MaxRankLength)
+	assert.NoError(t, serr)
+	assert.Empty(t, boardIDs)
+}
+
+func TestTaskService_FindTasksByFilter(t *testing.T) {
+	repos := newTestRepositories(t)
+	srvc := NewTaskServiceWithRepositories(repos, testViewer)
+	created := createTestTasks(t, NewTaskServiceWithRepositories(repos, testMember), model.SystemBoardTodo.ID, "a", "b")
+	if err := repos.Labels.ReplaceTaskLabels(created[1].ID, []string{"label1"}); err != nil {
+		t.Fatalf("Failed to set labels: %+v", err)
+	}
+
+	tasks, serr := srvc.FindTasksByFilter(&model.Task{BoardID: model.SystemBoardTodo.ID},
+		&repository.TaskFilter{LabelIDs: []string{"label1"}}, []string{"sort_rank"})
+	assert.NoError(t, serr)
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, "b", tasks[0].Name)
+	}
+	_, serr = NewTaskServiceWithRepositories(repos, nil).FindTasks(&model.Task{}, []string{})
+	assertErrorCode(t, ErrorCodeForbidden, serr)
+}
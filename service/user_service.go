@@ -1,29 +1,45 @@
 package service
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"io"
 	"taskboard-api-go/model"
 	"taskboard-api-go/orm"
 	"taskboard-api-go/repository"
+	"time"
 
 	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
 )
 
 // UserService provides apis for user management.
 type UserService struct {
-	tx       *gorm.DB
-	userRepo *repository.UserRepository
+	actor    *model.User
+	userRepo repository.Users
+	recorder *activityRecorder
 }
 
 // NewUserService return new instance of UserService.
-func NewUserService(tx *gorm.DB) *UserService {
+// actor is the user who calls apis, and whose role is checked. (nil is allowed only for login)
+func NewUserService(tx *gorm.DB, actor *model.User) *UserService {
+	return NewUserServiceWithRepositories(repository.NewRepositories(tx), actor)
+}
+
+// NewUserServiceWithRepositories return new instance of UserService which uses specified repositories.
+func NewUserServiceWithRepositories(repos *repository.Repositories, actor *model.User) *UserService {
 	return &UserService{
-		tx:       tx,
-		userRepo: repository.NewUserRepository(tx),
+		actor:    actor,
+		userRepo: repos.Users,
+		recorder: newActivityRecorder(repos.Activities, actor),
 	}
 }
 
 // FindUser returns user matching specified condition
 func (s *UserService) FindUser(condition interface{}) (*model.User, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
 	find, err := s.userRepo.FindFirstUser(condition, []string{"id"})
 	if err != nil {
 		if err == orm.ErrorRecordNotFound {
@@ -36,6 +52,9 @@ func (s *UserService) FindUser(condition interface{}) (*model.User, error) {
 
 // FindUsers finds all users
 func (s *UserService) FindUsers(condition interface{}, sortOrders []string) ([]model.User, error) {
+	if serr := authorize(s.actor, PermissionRead); serr != nil {
+		return nil, serr
+	}
 	users, err := s.userRepo.FindUsers(condition, 0, orm.NoLimit, sortOrders)
 	if err != nil {
 		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find users")
@@ -45,29 +64,133 @@ func (s *UserService) FindUsers(condition interface{}, sortOrders []string) ([]m
 
 // CreateUser creates new user
 func (s *UserService) CreateUser(user *model.User) error {
+	if serr := authorize(s.actor, PermissionManageUser); serr != nil {
+		return serr
+	}
+	if !model.IsValidRole(user.Role) {
+		return NewSvcErrorf(ErrorCodeInvalidArguments, nil, "Invalid role:%s", user.Role)
+	}
+	if user.UploadedAvatarHash() != "" {
+		return NewSvcError(ErrorCodeInvalidArguments, nil, "Uploaded avatar can not be set directly, upload the image instead")
+	}
 	err := s.userRepo.CreateUser(user)
 	if err != nil {
 		return NewSvcError(ErrorCodeDB, err, "Failed to create user")
 	}
+	return s.recorder.recordCreate(model.EntityKindUser, user.ID, user.Version, user.Name)
+}
+
+// UpdateUser updates specifed user.
+// Users can update themselves except role, and only admin can update others.
+// Images of the old avatar are not deleted, they must be deleted by DeleteUnusedAvatar after the transaction is committed.
+func (s *UserService) UpdateUser(find *model.User, user *model.User) error {
+	// Checked before merging not to reveal changes of others, and checked again with merged role when updating
+	if s.actor == nil || s.actor.ID != find.ID {
+		if serr := authorize(s.actor, PermissionManageUser); serr != nil {
+			return serr
+		}
+	}
+	if user.Version != find.Version {
+		if serr := s.mergeUser(find, user); serr != nil {
+			return serr
+		}
+	}
+	if user.Avatar != find.Avatar && user.UploadedAvatarHash() != "" {
+		return NewSvcError(ErrorCodeInvalidArguments, nil, "Uploaded avatar can not be set directly, upload the image instead")
+	}
+	return s.updateUser(find, user)
+}
+
+// UploadAvatar stores uploaded avatar image(png, jpeg or gif) as thumbnails, and sets it to specified user.
+// Images of the old avatar are not deleted, they must be deleted by DeleteUnusedAvatar after the transaction is committed.
+func (s *UserService) UploadAvatar(find *model.User, content io.Reader) (*model.User, error) {
+	// Check permission before storing image
+	if serr := s.authorizeUpdateUser(find, find); serr != nil {
+		return nil, serr
+	}
+	hash, serr := storeAvatarImage(content)
+	if serr != nil {
+		return nil, serr
+	}
+	user := *find
+	user.SetUploadedAvatar(hash)
+	if serr = s.updateUser(find, &user); serr != nil {
+		return nil, serr
+	}
+	return &user, nil
+}
+
+func (s *UserService) authorizeUpdateUser(find *model.User, user *model.User) error {
+	if s.actor == nil || s.actor.ID != find.ID || find.Role != user.Role {
+		return authorize(s.actor, PermissionManageUser)
+	}
 	return nil
 }
 
-// UpdateUser updates specifed user
-func (s *UserService) UpdateUser(user *model.User) error {
+func (s *UserService) updateUser(find *model.User, user *model.User) error {
+	if serr := s.authorizeUpdateUser(find, user); serr != nil {
+		return serr
+	}
+	if !model.IsValidRole(user.Role) {
+		return NewSvcErrorf(ErrorCodeInvalidArguments, nil, "Invalid role:%s", user.Role)
+	}
 	err := s.userRepo.UpdateUser(user)
 	if err != nil {
-		return NewSvcErrorf(ErrorCodeDB, err, "Failed to update user. ID:%s", user.ID)
+		return newUpdateError(model.EntityKindUser, user.ID, err, func() (int, error) {
+			current, err := s.userRepo.FindFirstUser(&model.User{ID: user.ID}, []string{})
+			return current.Version, err
+		})
+	}
+	oldValues := userFieldValues(find)
+	newValues := userFieldValues(user)
+	if find.PasswordHash != user.PasswordHash {
+		// Password itself is never recorded, only the fact it is changed
+		oldValues = append(oldValues, fieldValue{"password", ""})
+		newValues = append(newValues, fieldValue{"password", "(changed)"})
 	}
+	return s.recorder.recordChanges(model.EntityKindUser, user.ID, user.Version, model.ActivityActionUpdate,
+		oldValues, newValues)
+}
+
+// mergeUser merges changes of user which is based on an old version into the current user(find).
+// user is overwritten by merged one.
+func (s *UserService) mergeUser(find *model.User, user *model.User) error {
+	// Password is compared by its hash, because it is recorded only as changed
+	currentValues := append(userFieldValues(find), fieldValue{"password", find.PasswordHash})
+	clientValues := append(userFieldValues(user), fieldValue{"password", user.PasswordHash})
+	changed, serr := s.recorder.mergeChanges(model.EntityKindUser, find.ID, find.Version, user.Version,
+		currentValues, clientValues)
+	if serr != nil {
+		return serr
+	}
+	merged := *find
+	for _, field := range changed {
+		switch field {
+		case "name":
+			merged.Name = user.Name
+		case "avatar":
+			merged.Avatar = user.Avatar
+		case "role":
+			merged.Role = user.Role
+		case "password":
+			merged.PasswordHash = user.PasswordHash
+		}
+	}
+	*user = merged
 	return nil
 }
 
-// DeleteUser deletes specifed user
+// DeleteUser deletes specifed user.
+// Images of the avatar are not deleted, they must be deleted by DeleteUnusedAvatar after the transaction is committed.
 func (s *UserService) DeleteUser(user *model.User) error {
+	if serr := authorize(s.actor, PermissionManageUser); serr != nil {
+		return serr
+	}
 	err := s.userRepo.DeleteUser(user)
 	if err != nil {
 		return NewSvcErrorf(ErrorCodeDB, err, "Failed to delete user. ID:%s", user.ID)
 	}
-	return nil
+	return s.recorder.recordDelete(model.EntityKindUser, user.ID, user.Version, user.Name)
 }
 
 // Login returns valid user or nil
@@ -83,3 +206,91 @@ func (s *UserService) Login(name, password string) (*model.User, error) {
 	}
 	return &find, nil
 }
+
+// CreateAdminUserIfNotExists creates an admin user if there is no admin, and returns its password.
+// If password is empty, random password is generated. Returns empty password if no user is created.
+// Existing user of the name is never promoted to admin, another name must be chosen.
+func (s *UserService) CreateAdminUserIfNotExists(name, password string) (string, error) {
+	count, err := s.userRepo.CountUsers(&model.User{Role: model.RoleAdmin})
+	if err != nil {
+		return "", NewSvcError(ErrorCodeDB, err, "Failed to count admin users")
+	}
+	if count > 0 {
+		return "", nil
+	}
+	count, err = s.userRepo.CountUsers(&model.User{Name: name})
+	if err != nil {
+		return "", NewSvcError(ErrorCodeDB, err, "Failed to count users")
+	}
+	if count > 0 {
+		return "", NewSvcErrorf(ErrorCodeAlreadyExist, nil,
+			"User %s already exists and it is not admin. Choose another name for admin user", name)
+	}
+	if password == "" {
+		if password, err = generatePassword(); err != nil {
+			return "", NewSvcError(ErrorCodeUnexpected, err, "Failed to generate password of admin user")
+		}
+	}
+	if serr := s.CreateUser(model.NewUser(name, password, "", model.RoleAdmin)); serr != nil {
+		return "", serr
+	}
+	return password, nil
+}
+
+// generatePassword returns random password which has 144 bits entropy
+func generatePassword() (string, error) {
+	random := make([]byte, 18)
+	if _, err := rand.Read(random); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// RefreshLogin verifies refresh token and returns the user of the token
+func (s *UserService) RefreshLogin(refreshToken string, now time.Time) (*model.User, error) {
+	userID, serr := VerifyAuthToken(refreshToken, TokenTypeRefresh, now)
+	if serr != nil {
+		return nil, serr
+	}
+	find, err := s.userRepo.FindFirstUser(&model.User{ID: userID}, []string{})
+	if err != nil {
+		// Does not describe details
+		return nil, NewSvcError(ErrorCodeUnauthenticated, err, "Login failed")
+	}
+	return &find, nil
+}
+
+// Authenticate verifies access token and returns the user of the token
+func (s *UserService) Authenticate(accessToken string, now time.Time) (*model.User, error) {
+	userID, serr := VerifyAuthToken(accessToken, TokenTypeAccess, now)
+	if serr != nil {
+		return nil, serr
+	}
+	find, err := s.userRepo.FindFirstUser(&model.User{ID: userID}, []string{})
+	if err != nil {
+		if err == orm.ErrorRecordNotFound {
+			return nil, NewSvcError(ErrorCodeUnauthenticated, err, "User of token not found")
+		}
+		return nil, NewSvcError(ErrorCodeDB, err, "Failed to find user")
+	}
+	return &find, nil
+}
+
+// DeleteUnusedAvatar deletes uploaded avatar images of old user if no user uses them.
+// (Same image uploaded by several users shares the images)
+// It must be called after the transaction which changed or deleted the user is committed,
+// so that users are counted after rows restored by rollback or committed by concurrent uploads of the same image.
+func (s *UserService) DeleteUnusedAvatar(old *model.User) error {
+	hash := old.UploadedAvatarHash()
+	if hash == "" {
+		return nil
+	}
+	count, err := s.userRepo.CountUsers(&model.User{Avatar: old.Avatar})
+	if err != nil {
+		return NewSvcError(ErrorCodeDB, err, "Failed to count users of avatar")
+	}
+	if count > 0 {
+		return nil
+	}
+	return deleteAvatarImage(hash)
+}
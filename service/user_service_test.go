@@ -0,0 +1,108 @@
+package service
+
+import (
+	"taskboard-api-go/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserService_CreateAdminUserIfNotExists(t *testing.T) {
+	repos := newTestRepositories(t)
+	srvc := NewUserServiceWithRepositories(repos, model.SystemUser)
+
+	// Existing user of the name is never promoted to admin
+	member := model.NewUser("admin", "password", "", model.RoleMember)
+	assert.NoError(t, srvc.CreateUser(member))
+	_, serr := srvc.CreateAdminUserIfNotExists("admin", "other")
+	assertErrorCode(t, ErrorCodeAlreadyExist, serr)
+	find, serr := srvc.FindUser(&model.User{Name: "admin"})
+	assert.NoError(t, serr)
+	assert.Equal(t, model.RoleMember, find.Role)
+
+	// Random password is generated if not specified
+	password, serr := srvc.CreateAdminUserIfNotExists("root", "")
+	assert.NoError(t, serr)
+	assert.Len(t, password, 24)
+	login, serr := NewUserServiceWithRepositories(repos, nil).Login("root", password)
+	if assert.NoError(t, serr) {
+		assert.Equal(t, model.RoleAdmin, login.Role)
+	}
+
+	// Nothing is done if an admin exists
+	password, serr = srvc.CreateAdminUserIfNotExists("another", "password")
+	assert.NoError(t, serr)
+	assert.Equal(t, "", password)
+	users, serr := srvc.FindUsers(&model.User{}, []string{"name"})
+	assert.NoError(t, serr)
+	assert.Len(t, users, 2)
+}
+
+func TestUserService_UpdateUser(t *testing.T) {
+	repos := newTestRepositories(t)
+	for _, user := range []*model.User{testAdmin, testMember} {
+		created := *user
+		if err := repos.Users.CreateUser(&created); err != nil {
+			t.Fatalf("Failed to create user: %+v", err)
+		}
+	}
+	srvc := NewUserServiceWithRepositories(repos, testMember)
+	find, serr := srvc.FindUser(&model.User{ID: testMember.ID})
+	assert.NoError(t, serr)
+
+	// Users can update themselves except role
+	user := *find
+	user.Avatar = "cat"
+	assert.NoError(t, srvc.UpdateUser(find, &user))
+	promoted := user
+	promoted.Role = model.RoleAdmin
+	assertErrorCode(t, ErrorCodeForbidden, srvc.UpdateUser(&user, &promoted))
+
+	// Only admin can update others
+	admin, serr := srvc.FindUser(&model.User{ID: testAdmin.ID})
+	assert.NoError(t, serr)
+	renamed := *admin
+	renamed.Name = "renamed"
+	assertErrorCode(t, ErrorCodeForbidden, srvc.UpdateUser(admin, &renamed))
+	// Permission is checked before merging changes of others
+	renamed.Version = 0
+	assertErrorCode(t, ErrorCodeForbidden, srvc.UpdateUser(admin, &renamed))
+	assert.NoError(t, NewUserServiceWithRepositories(repos, testAdmin).UpdateUser(&user, &promoted))
+
+	// Uploaded avatar can not be set directly
+	uploaded := promoted
+	uploaded.SetUploadedAvatar("hash")
+	assertErrorCode(t, ErrorCodeInvalidArguments, srvc.UpdateUser(&promoted, &uploaded))
+
+	// Role must be valid
+	invalid := promoted
+	invalid.Role = "owner"
+	assertErrorCode(t, ErrorCodeInvalidArguments, NewUserServiceWithRepositories(repos, testAdmin).UpdateUser(&promoted, &invalid))
+}
+
+func TestUserService_Authenticate(t *testing.T) {
+	repos := newTestRepositories(t)
+	created := *testMember
+	if err := repos.Users.CreateUser(&created); err != nil {
+		t.Fatalf("Failed to create user: %+v", err)
+	}
+	srvc := NewUserServiceWithRepositories(repos, nil)
+	if serr := SetTokenSecret(""); serr != nil {
+		t.Fatalf("Failed to set token secret: %+v", serr)
+	}
+	now := time.Now().UTC()
+	token, serr := IssueAuthToken(created.ID, now)
+	if serr != nil {
+		t.Fatalf("Failed to issue token: %+v", serr)
+	}
+
+	user, serr := srvc.Authenticate(token.AccessToken, now)
+	assert.NoError(t, serr)
+	assert.Equal(t, created.ID, user.ID)
+
+	// User of the token is deleted
+	assert.NoError(t, NewUserServiceWithRepositories(repos, testAdmin).DeleteUser(&created))
+	_, serr = srvc.Authenticate(token.AccessToken, now)
+	assertErrorCode(t, ErrorCodeUnauthenticated, serr)
+}
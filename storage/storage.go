@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrorInvalidKey is an error when key of file is invalid
+var ErrorInvalidKey = errors.New("invalid storage key")
+
+// Storage stores contents of files by key
+type Storage interface {
+	// Save stores content as specified key, overwrites if exists
+	Save(key string, content io.Reader) error
+	// Open returns reader of content stored as specified key
+	Open(key string) (io.ReadCloser, error)
+	// Delete deletes content stored as specified key, does nothing if not exists
+	Delete(key string) error
+}
+
+// LocalStorage is Storage on local file system
+type LocalStorage struct {
+	dir string
+}
+
+// NewLocalStorage returns new instance of LocalStorage which stores files in specified directory.
+// The directory is created if not exists.
+func NewLocalStorage(dir string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &LocalStorage{
+		dir: dir,
+	}, nil
+}
+
+// Save stores content as specified key, overwrites if exists
+func (s *LocalStorage) Save(key string, content io.Reader) (err error) {
+	path, err := s.path(key)
+	if err != nil {
+		return
+	}
+	// Write to temporary file at first, not to leave broken file
+	tmp, err := os.OpenFile(path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = io.Copy(tmp, content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmp.Name(), path))
+}
+
+// Open returns reader of content stored as specified key
+func (s *LocalStorage) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return file, nil
+}
+
+// Delete deletes content stored as specified key, does nothing if not exists
+func (s *LocalStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// path returns file path of key, key must not contain path separators
+func (s *LocalStorage) path(key string) (string, error) {
+	if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) {
+		return "", errors.WithStack(ErrorInvalidKey)
+	}
+	return filepath.Join(s.dir, key), nil
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStorage(t *testing.T) (*LocalStorage, string) {
+	dir := filepath.Join(t.TempDir(), "files")
+	s, err := NewLocalStorage(dir)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %+v", err)
+	}
+	return s, dir
+}
+
+func TestLocalStorage_InvalidKey(t *testing.T) {
+	s, _ := newTestStorage(t)
+
+	for _, key := range []string{"", ".", "..", "a/b", `a\b`, "../a"} {
+		assert.Equal(t, ErrorInvalidKey, errors.Cause(s.Save(key, strings.NewReader("content"))), key)
+		_, err := s.Open(key)
+		assert.Equal(t, ErrorInvalidKey, errors.Cause(err), key)
+		assert.Equal(t, ErrorInvalidKey, errors.Cause(s.Delete(key)), key)
+	}
+}
+
+func TestLocalStorage_SaveOpenDelete(t *testing.T) {
+	s, dir := newTestStorage(t)
+
+	if err := s.Save("a", strings.NewReader("first")); err != nil {
+		t.Fatalf("Failed to save: %+v", err)
+	}
+	assert.Equal(t, "first", readTestContent(t, s, "a"))
+
+	// Overwrites existing content
+	if err := s.Save("a", strings.NewReader("second")); err != nil {
+		t.Fatalf("Failed to save: %+v", err)
+	}
+	assert.Equal(t, "second", readTestContent(t, s, "a"))
+	assert.Equal(t, []string{"a"}, listTestFiles(t, dir))
+
+	assert.NoError(t, s.Delete("a"))
+	_, err := s.Open("a")
+	assert.True(t, os.IsNotExist(errors.Cause(err)))
+	// Deleting again does nothing
+	assert.NoError(t, s.Delete("a"))
+	assert.Empty(t, listTestFiles(t, dir))
+}
+
+func TestLocalStorage_SaveFailure(t *testing.T) {
+	s, dir := newTestStorage(t)
+	if err := s.Save("a", strings.NewReader("original")); err != nil {
+		t.Fatalf("Failed to save: %+v", err)
+	}
+
+	failure := errors.New("read failure")
+	content := io.MultiReader(bytes.NewReader([]byte("partial")), &failingReader{err: failure})
+	assert.Equal(t, failure, errors.Cause(s.Save("a", content)))
+
+	// Original content is kept, and temporary file is removed
+	assert.Equal(t, "original", readTestContent(t, s, "a"))
+	assert.Equal(t, []string{"a"}, listTestFiles(t, dir))
+}
+
+type failingReader struct {
+	err error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+func readTestContent(t *testing.T, s Storage, key string) string {
+	reader, err := s.Open(key)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %+v", key, err)
+	}
+	defer reader.Close()
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %+v", key, err)
+	}
+	return string(content)
+}
+
+func listTestFiles(t *testing.T, dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read dir: %+v", err)
+	}
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	return names
+}